@@ -2,13 +2,26 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"github.com/julienschmidt/httprouter"
+	"google.golang.org/grpc"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"project/internal/config"
+	"project/internal/middleware"
+	"project/internal/openapi"
 	"project/internal/user"
+	"project/internal/user/bolt"
 	"project/internal/user/db"
+	"project/internal/user/graphqlapi"
+	"project/internal/user/grpcapi"
+	"project/internal/user/memory"
+	"project/internal/user/postgres"
+	"project/pkg/client/boltdb"
 	"project/pkg/client/mongodb"
+	"project/pkg/client/postgresql"
 	"project/pkg/logging"
 	"project/pkg/shutdown"
 	"syscall"
@@ -26,16 +39,33 @@ func main() {
 	// Get data from config
 	cfg := config.GetConfig()
 
-	// Connect to MongoDB
-	cfgMongo := cfg.MongoDB
-	mongoDBClient, err := mongodb.NewClient(context.Background(), cfgMongo.Host, cfgMongo.Port, cfgMongo.Username, cfgMongo.Password, cfgMongo.Database, cfgMongo.AuthDB)
+	// Create user, friend request, relation, refresh token and API key storages
+	storages, err := newUserStorages(cfg, logger)
 	if err != nil {
 		logger.Fatal(err)
 	}
-	userStorage := db.NewStorage(mongoDBClient, cfg.MongoDB.Collection, logger)
 
 	// Initialize user service
-	userService, err := user.NewService(userStorage, *logger)
+	userService, err := user.NewService(storages.users, storages.requests, storages.relations, *logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	// Create verifier of bearer tokens of users API
+	auth, err := middleware.NewJWTAuth(middleware.JWTConfig{
+		Secret:        cfg.Auth.HS256Secret,
+		PublicKeyFile: cfg.Auth.PublicKeyFile,
+		JWKSFile:      cfg.Auth.JWKSFile,
+		Issuer:        cfg.Auth.Issuer,
+		Audience:      cfg.Auth.Audience,
+		AccessTTL:     cfg.Auth.AccessTTL,
+	})
+	if err != nil {
+		logger.Fatalf("failed to create authentication, set AUTH_HS256_SECRET or auth.public_key_file or auth.jwks_file. error: %v", err)
+	}
+
+	// Initialize registration and login of users
+	authService, err := user.NewAuthService(storages.users, storages.tokens, cfg.Auth.RefreshTTL, *logger)
 	if err != nil {
 		logger.Fatal(err)
 	}
@@ -44,17 +74,168 @@ func main() {
 	usersHandler := user.Handler{
 		Logger:      logger,
 		UserService: userService,
+		Auth:        auth,
+		AuthService: authService,
+		APIKeys:     user.NewAPIKeyService(storages.apiKeys, *logger),
 	}
 
+	// Create handler of OpenAPI specification
+	openAPIHandler := openapi.Handler{
+		Logger:    logger,
+		SwaggerUI: cfg.OpenAPI.SwaggerUI,
+	}
+
+	// Create handler of GraphQL queries with the same user service and authentication
+	graphQLHandler, err := graphqlapi.NewHandler(logger, userService, graphqlapi.Limits{
+		MaxDepth:      cfg.GraphQL.MaxDepth,
+		MaxComplexity: cfg.GraphQL.MaxComplexity,
+	})
+	if err != nil {
+		logger.Fatal(err)
+	}
+	graphQLHandler.Auth = usersHandler.Auth
+	graphQLHandler.APIKeys = usersHandler.APIKeys
+
 	// Routing init
 	usersHandler.Register(router)
+	openAPIHandler.Register(router)
+	graphQLHandler.Register(router)
+
+	// Create gRPC server with the same user service and authentication
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		grpcapi.PanicRecovery,
+		grpcapi.Authenticate(usersHandler.Auth, usersHandler.APIKeys),
+	))
+	usersGRPC := grpcapi.Server{
+		Logger:      logger,
+		UserService: userService,
+	}
+	usersGRPC.Register(grpcServer)
+
+	// Purge soft deleted users in background
+	go userService.RunPurger(context.Background(), cfg.Purge.Interval, cfg.Purge.Retention)
 
 	// Start application
 	logger.Println("start application")
-	start(router, cfg, logger)
+	go startGRPC(grpcServer, cfg, logger)
+	start(router, cfg, logger, grpcCloser{grpcServer})
+}
+
+// grpcCloser - closer of gRPC server for graceful shutdown, calls are finished before closing
+type grpcCloser struct {
+	server *grpc.Server
+}
+
+// Close - stop gRPC server after finishing calls
+func (c grpcCloser) Close() error {
+	c.server.GracefulStop()
+	return nil
+}
+
+// startGRPC - start gRPC server on its port, application stops if it can't listen
+func startGRPC(server *grpc.Server, cfg *config.Config, logger *logging.Logger) {
+	listener, err := net.Listen("tcp", ":"+cfg.GRPC.Port)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Infof("gRPC server is listening port: %s", cfg.GRPC.Port)
+	if err := server.Serve(listener); err != nil {
+		logger.Fatal(err)
+	}
+}
+
+// userStorages - storages of user service, all of them use the same driver
+type userStorages struct {
+	users     user.Storage
+	requests  user.RequestStorage
+	relations user.RelationStorage
+	tokens    user.TokenStorage
+	apiKeys   user.APIKeyStorage
+}
+
+// newUserStorages - create user, friend request, relation, refresh token and API key storages selected by storage.driver
+func newUserStorages(cfg *config.Config, logger *logging.Logger) (s userStorages, err error) {
+	logger.Infof("use %s storage", cfg.Storage.Driver)
+
+	switch cfg.Storage.Driver {
+	case config.StorageMemory:
+		s.users = memory.NewStorage(logger)
+		s.requests = memory.NewRequestStorage(logger)
+		s.relations = memory.NewRelationStorage(logger)
+		s.tokens = memory.NewTokenStorage(logger)
+		s.apiKeys = memory.NewAPIKeyStorage(logger)
+	case config.StorageMongoDB, "":
+		// Connect to MongoDB
+		cfgMongo := cfg.MongoDB
+		mongoDBClient, err := mongodb.NewClient(context.Background(), cfgMongo.Host, cfgMongo.Port, cfgMongo.Username, cfgMongo.Password, cfgMongo.Database, cfgMongo.AuthDB)
+		if err != nil {
+			return s, err
+		}
+		if err = db.CreateIndexes(context.Background(), mongoDBClient, cfgMongo.Collection, logger); err != nil {
+			return s, err
+		}
+		if err = db.CreateRequestIndexes(context.Background(), mongoDBClient, cfgMongo.RequestsCollection, logger); err != nil {
+			return s, err
+		}
+		if err = db.CreateRelationIndexes(context.Background(), mongoDBClient, cfgMongo.RelationsCollection, logger); err != nil {
+			return s, err
+		}
+		if err = db.CreateTokenIndexes(context.Background(), mongoDBClient, cfgMongo.TokensCollection, logger); err != nil {
+			return s, err
+		}
+		if err = db.CreateAPIKeyIndexes(context.Background(), mongoDBClient, cfgMongo.APIKeysCollection, logger); err != nil {
+			return s, err
+		}
+		s.users = db.NewStorage(mongoDBClient, cfgMongo.Collection, logger)
+		s.requests = db.NewRequestStorage(mongoDBClient, cfgMongo.RequestsCollection, logger)
+		s.relations = db.NewRelationStorage(mongoDBClient, cfgMongo.RelationsCollection, logger)
+		s.tokens = db.NewTokenStorage(mongoDBClient, cfgMongo.TokensCollection, logger)
+		s.apiKeys = db.NewAPIKeyStorage(mongoDBClient, cfgMongo.APIKeysCollection, logger)
+	case config.StoragePostgreSQL:
+		// Connect to PostgreSQL and migrate schema
+		cfgPostgres := cfg.PostgreSQL
+		postgreSQLClient, err := postgresql.NewClient(context.Background(), cfgPostgres.Host, cfgPostgres.Port, cfgPostgres.Username, cfgPostgres.Password, cfgPostgres.Database, cfgPostgres.SSLMode)
+		if err != nil {
+			return s, err
+		}
+		if err = postgres.Migrate(context.Background(), postgreSQLClient, logger); err != nil {
+			return s, err
+		}
+		s.users = postgres.NewStorage(postgreSQLClient, logger)
+		s.requests = postgres.NewRequestStorage(postgreSQLClient, logger)
+		s.relations = postgres.NewRelationStorage(postgreSQLClient, logger)
+		s.tokens = postgres.NewTokenStorage(postgreSQLClient, logger)
+		s.apiKeys = postgres.NewAPIKeyStorage(postgreSQLClient, logger)
+	case config.StorageBolt:
+		// Open BoltDB file
+		boltDBClient, err := boltdb.NewClient(cfg.Storage.Path)
+		if err != nil {
+			return s, err
+		}
+		if s.users, err = bolt.NewStorage(boltDBClient, logger); err != nil {
+			return s, err
+		}
+		if s.requests, err = bolt.NewRequestStorage(boltDBClient, logger); err != nil {
+			return s, err
+		}
+		if s.relations, err = bolt.NewRelationStorage(boltDBClient, logger); err != nil {
+			return s, err
+		}
+		if s.tokens, err = bolt.NewTokenStorage(boltDBClient, logger); err != nil {
+			return s, err
+		}
+		if s.apiKeys, err = bolt.NewAPIKeyStorage(boltDBClient, logger); err != nil {
+			return s, err
+		}
+	default:
+		return s, fmt.Errorf("unknown storage driver: %s", cfg.Storage.Driver)
+	}
+
+	return s, nil
 }
 
-func start(router *httprouter.Router, cfg *config.Config, logger *logging.Logger) {
+func start(router *httprouter.Router, cfg *config.Config, logger *logging.Logger, closers ...io.Closer) {
 	logger.Info("start application")
 
 	// Star server
@@ -65,11 +246,11 @@ func start(router *httprouter.Router, cfg *config.Config, logger *logging.Logger
 		ReadTimeout:  cfg.Timeout.Read * time.Second,
 	}
 
-	// Graceful shutdown
+	// Graceful shutdown, http server is closed the last, because application exits when it is closed
 	go shutdown.Graceful([]os.Signal{syscall.SIGABRT, syscall.SIGQUIT, syscall.SIGHUP, os.Interrupt, syscall.SIGTERM},
-		server)
+		append(closers, server)...)
 
 	// Start server
-	logger.Info("server is listening port: %s", cfg.Listen.Port)
+	logger.Infof("server is listening port: %s", cfg.Listen.Port)
 	logger.Fatalln(server.ListenAndServe())
 }
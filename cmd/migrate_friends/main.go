@@ -0,0 +1,43 @@
+// Command migrate_friends rewrites friends of users in MongoDB from usernames to ObjectIDs.
+// It reads config.yml like the application, run it once before starting new version of application:
+//
+//	go run ./cmd/migrate_friends -dry-run
+//	go run ./cmd/migrate_friends
+package main
+
+import (
+	"context"
+	"flag"
+	"project/internal/config"
+	"project/internal/user/db"
+	"project/pkg/client/mongodb"
+	"project/pkg/logging"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only log changes, don't write them")
+	flag.Parse()
+
+	// Create logger
+	logger := logging.GetLogger()
+
+	// Get data from config
+	cfg := config.GetConfig()
+	if cfg.Storage.Driver != config.StorageMongoDB && cfg.Storage.Driver != "" {
+		logger.Fatalf("friends are kept by usernames only in %s storage, storage.driver is %s", config.StorageMongoDB, cfg.Storage.Driver)
+	}
+
+	// Connect to MongoDB
+	cfgMongo := cfg.MongoDB
+	mongoDBClient, err := mongodb.NewClient(context.Background(), cfgMongo.Host, cfgMongo.Port, cfgMongo.Username, cfgMongo.Password, cfgMongo.Database, cfgMongo.AuthDB)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	// Migrate friends
+	result, err := db.MigrateFriends(context.Background(), mongoDBClient, cfgMongo.Collection, *dryRun, logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infof("migrated friends of %d users, dropped %d unresolved friends (dry run: %t)", result.Migrated, result.Dropped, *dryRun)
+}
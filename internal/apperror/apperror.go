@@ -0,0 +1,46 @@
+// Package apperror - errors of application which know their http status and view for clients
+package apperror
+
+import (
+	"net/http"
+)
+
+// AppError - error for answer to client, Err is only logged
+type AppError struct {
+	Err     error  `json:"-"`
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+
+	// Details - per-field details of error, for example failed validation rules
+	Details interface{} `json:"details,omitempty"`
+}
+
+// New - create application error with http status, code and message for client
+func New(status int, code, message string, err error) *AppError {
+	return &AppError{
+		Err:     err,
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// SystemError - internal error, its details are not shown to client
+func SystemError(err error) *AppError {
+	return New(http.StatusInternalServerError, "internal_error", http.StatusText(http.StatusInternalServerError), err)
+}
+
+// Error - text of wrapped error for logs
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap - wrapped error for errors.Is and errors.As
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
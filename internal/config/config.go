@@ -16,6 +16,10 @@ type Config struct {
 		BindIP string `yaml:"bind_ip" env-default:"localhost"`
 		Port   string `yaml:"port" env-default:"8080"`
 	} `yaml:"listen"`
+	// GRPC - gRPC server listens its own port on the same bind ip
+	GRPC struct {
+		Port string `yaml:"port" env-default:"9091"`
+	} `yaml:"grpc"`
 	Timeout struct {
 		Write time.Duration `yaml:"write" env-default:"15"`
 		Read  time.Duration `yaml:"read" env-default:"15"`
@@ -28,9 +32,64 @@ type Config struct {
 		Username   string `json:"username"`
 		Password   string `json:"password"`
 		Collection string `json:"collection"`
+
+		// RequestsCollection - collection of friend requests
+		RequestsCollection string `yaml:"requests_collection" env-default:"friend_requests"`
+		// RelationsCollection - collection of blocks and mutes
+		RelationsCollection string `yaml:"relations_collection" env-default:"relations"`
+		// TokensCollection - collection of refresh tokens, they are removed by TTL index after expiration
+		TokensCollection string `yaml:"tokens_collection" env-default:"refresh_tokens"`
+		// APIKeysCollection - collection of API keys of services
+		APIKeysCollection string `yaml:"api_keys_collection" env-default:"api_keys"`
 	} `json:"mongodb"`
+	PostgreSQL struct {
+		Host     string `yaml:"host" env-default:"localhost"`
+		Port     string `yaml:"port" env-default:"5432"`
+		Database string `yaml:"database"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+		SSLMode  string `yaml:"ssl_mode" env-default:"disable"`
+	} `yaml:"postgresql"`
+	Storage struct {
+		Driver string `yaml:"driver" env-default:"mongodb"`
+		Path   string `yaml:"path" env-default:"data/users.db"`
+	} `yaml:"storage"`
+	// Purge - soft deleted users are purged after retention, purger runs every interval
+	Purge struct {
+		Retention time.Duration `yaml:"retention" env-default:"720h"`
+		Interval  time.Duration `yaml:"interval" env-default:"1h"`
+	} `yaml:"purge"`
+	// OpenAPI - specification is always served at /openapi.json, Swagger UI at /docs only if it is enabled
+	OpenAPI struct {
+		SwaggerUI bool `yaml:"swagger_ui" env-default:"false"`
+	} `yaml:"openapi"`
+	// GraphQL - limits of queries at /graphql, they protect storage from deep friend graphs
+	GraphQL struct {
+		MaxDepth      int `yaml:"max_depth" env-default:"5"`
+		MaxComplexity int `yaml:"max_complexity" env-default:"1000"`
+	} `yaml:"graphql"`
+	// Auth - keys of JWT of callers of users API, at least HS256 secret, public key file or JWKS file is required.
+	// Access tokens of login are signed with HS256 secret, so login needs it. The secret is set only by AUTH_HS256_SECRET,
+	// config.yml is built into image, so it keeps the secret empty
+	Auth struct {
+		HS256Secret   string        `yaml:"hs256_secret" env:"AUTH_HS256_SECRET"`
+		PublicKeyFile string        `yaml:"public_key_file" env:"AUTH_PUBLIC_KEY_FILE"`
+		JWKSFile      string        `yaml:"jwks_file" env:"AUTH_JWKS_FILE"`
+		Issuer        string        `yaml:"issuer"`
+		Audience      string        `yaml:"audience"`
+		AccessTTL     time.Duration `yaml:"access_ttl" env-default:"15m"`
+		RefreshTTL    time.Duration `yaml:"refresh_ttl" env-default:"720h"`
+	} `yaml:"auth"`
 }
 
+// Storage drivers which can be selected with storage.driver
+const (
+	StorageMongoDB    = "mongodb"
+	StorageMemory     = "memory"
+	StoragePostgreSQL = "postgresql"
+	StorageBolt       = "bolt"
+)
+
 var instance *Config
 var once sync.Once
 
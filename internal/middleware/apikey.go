@@ -0,0 +1,48 @@
+package middleware
+
+// file for authentication of service callers by API key
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// scopes of API keys, admin scope includes write scope and write scope includes read scope
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+	ScopeAdmin      = "admin"
+)
+
+// Scopes - all scopes of API keys from the narrowest one
+var Scopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopeAdmin}
+
+// KeyVerifier - verifier of API keys from header "Authorization: ApiKey <key>"
+type KeyVerifier interface {
+	// VerifyKey - principal of service which owns key, error if key is unknown or revoked
+	VerifyKey(ctx context.Context, key string) (Principal, error)
+}
+
+// errInvalidAPIKey - key can't be verified, reason is only logged
+var errInvalidAPIKey = errors.New("invalid api key")
+
+// scopeRank - position of scope in Scopes, -1 for unknown scope
+func scopeRank(scope string) int {
+	for i, s := range Scopes {
+		if s == scope {
+			return i
+		}
+	}
+	return -1
+}
+
+// methodScope - scope which is required for request with method, safe methods only read users
+func methodScope(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ScopeUsersRead
+	default:
+		return ScopeUsersWrite
+	}
+}
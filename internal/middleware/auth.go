@@ -0,0 +1,129 @@
+package middleware
+
+// file for authentication of callers by bearer token or API key, principal of caller is kept in context of request
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"project/internal/apperror"
+	"strings"
+)
+
+// Principal - authenticated caller, Subject is id of his user or of API key of service
+type Principal struct {
+	Subject string
+	Roles   []string
+
+	// KeyID - id of API key of service caller, empty for users
+	KeyID string
+	// Scopes - scopes of API key, users aren't limited by scopes
+	Scopes []string
+}
+
+// HasScope - check that API key of principal has scope or wider one
+func (p Principal) HasScope(scope string) bool {
+	want := scopeRank(scope)
+	for _, s := range p.Scopes {
+		if rank := scopeRank(s); want >= 0 && rank >= want {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole - check that principal has role
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin - check that principal has admin role or API key with admin scope
+func (p Principal) IsAdmin() bool {
+	return p.HasRole(RoleAdmin) || p.HasScope(ScopeAdmin)
+}
+
+// principalKey - key of principal in context
+type principalKey struct{}
+
+// WithPrincipal - context with principal of caller
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom - principal of caller, ok is false if request isn't authenticated
+func PrincipalFrom(ctx context.Context) (p Principal, ok bool) {
+	p, ok = ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Authenticate - middleware which answers with 401 to request without valid bearer token or API key
+// and puts principal of caller to context of request. API key is accepted only if keys is not nil
+// and it must have scope of method of request, nil auth lets request without API key through without principal
+func Authenticate(auth *JWTAuth, keys KeyVerifier, h appHandler) appHandler {
+	return authenticate(auth, keys, func(r *http.Request) string { return methodScope(r.Method) }, h)
+}
+
+// AuthenticateScope - Authenticate for route whose API keys must have scope whatever method of request is,
+// e.g. GraphQL queries only read users, but they are posted
+func AuthenticateScope(auth *JWTAuth, keys KeyVerifier, scope string, h appHandler) appHandler {
+	return authenticate(auth, keys, func(*http.Request) string { return scope }, h)
+}
+
+// authenticate - authentication of request whose API key must have scope of request
+func authenticate(auth *JWTAuth, keys KeyVerifier, scope func(r *http.Request) string, h appHandler) appHandler {
+	if auth == nil && keys == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) error {
+		header := r.Header.Get("Authorization")
+		if key := strings.TrimSpace(strings.TrimPrefix(header, "ApiKey ")); keys != nil && key != header {
+			return authenticateKey(w, r, keys, key, scope(r), h)
+		}
+		// routes which change users fail without principal
+		if auth == nil {
+			return h(w, r)
+		}
+
+		token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+		if header == "" || token == header {
+			challenge(w, keys, "")
+			return apperror.New(http.StatusUnauthorized, "unauthorized", "bearer token is required", nil)
+		}
+
+		principal, err := auth.Verify(token)
+		if err != nil {
+			challenge(w, keys, `Bearer error="invalid_token"`)
+			return apperror.New(http.StatusUnauthorized, "invalid_token", errInvalidToken.Error(), err)
+		}
+		return h(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// authenticateKey - call handler with principal of API key, if key has scope
+func authenticateKey(w http.ResponseWriter, r *http.Request, keys KeyVerifier, key string, scope string, h appHandler) error {
+	principal, err := keys.VerifyKey(r.Context(), key)
+	if err != nil {
+		challenge(w, keys, "")
+		return apperror.New(http.StatusUnauthorized, "invalid_api_key", errInvalidAPIKey.Error(), err)
+	}
+	if !principal.HasScope(scope) {
+		return apperror.New(http.StatusForbidden, "insufficient_scope", fmt.Sprintf("api key has no scope %s", scope), nil)
+	}
+	return h(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+}
+
+// challenge - WWW-Authenticate headers of accepted schemes, bearer is challenge of Bearer scheme with error if it is not empty
+func challenge(w http.ResponseWriter, keys KeyVerifier, bearer string) {
+	if bearer == "" {
+		bearer = "Bearer"
+	}
+	w.Header().Set("WWW-Authenticate", bearer)
+	if keys != nil {
+		w.Header().Add("WWW-Authenticate", "ApiKey")
+	}
+}
@@ -0,0 +1,216 @@
+package middleware
+
+// file for verifying JWT of callers, keys are loaded once from config
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"io/ioutil"
+	"math/big"
+	"time"
+)
+
+// RoleAdmin - role which is allowed to change any user and to call admin routes
+const RoleAdmin = "admin"
+
+// JWTConfig - keys and expected claims of tokens, at least one key is required.
+// HS256 tokens are accepted only with Secret, RS256 tokens only with PublicKeyFile or JWKSFile
+type JWTConfig struct {
+	// Secret - shared secret of HS256
+	Secret string
+	// PublicKeyFile - PEM file with RSA public key of RS256, it checks tokens without kid
+	PublicKeyFile string
+	// JWKSFile - JSON Web Key Set file with RSA keys of RS256, keys are selected by kid of token, so every key must have kid
+	JWKSFile string
+	// Issuer and Audience - expected iss and aud of tokens, empty value isn't checked
+	Issuer   string
+	Audience string
+	// AccessTTL - lifetime of tokens signed by Sign, defaultAccessTTL if it is zero
+	AccessTTL time.Duration
+}
+
+// defaultAccessTTL - lifetime of signed tokens by default
+const defaultAccessTTL = 15 * time.Minute
+
+// JWTAuth - verifier of tokens by keys of config
+type JWTAuth struct {
+	secret  []byte
+	rsaKeys map[string]*rsa.PublicKey
+	methods []string
+
+	issuer    string
+	audience  string
+	accessTTL time.Duration
+}
+
+// claims - claims of token, sub is id of user
+type claims struct {
+	Roles []string `json:"roles,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// errInvalidToken - token can't be verified, reason is only logged
+var errInvalidToken = errors.New("invalid token")
+
+// ErrDefaultSecret - HS256 secret is known default value of examples and configs, anybody could sign tokens with it
+var ErrDefaultSecret = errors.New("hs256 secret is a known default value, set AUTH_HS256_SECRET to a random secret")
+
+// defaultSecrets - known default HS256 secrets, they are rejected by NewJWTAuth
+var defaultSecrets = []string{"local-development-secret", "secret", "changeme", "change-me", "jwt-secret"}
+
+// NewJWTAuth - load keys of config, error if there is no key, key can't be loaded or secret is known default
+func NewJWTAuth(cfg JWTConfig) (*JWTAuth, error) {
+	a := &JWTAuth{rsaKeys: make(map[string]*rsa.PublicKey), issuer: cfg.Issuer, audience: cfg.Audience, accessTTL: cfg.AccessTTL}
+	if a.accessTTL <= 0 {
+		a.accessTTL = defaultAccessTTL
+	}
+
+	if cfg.Secret != "" {
+		for _, secret := range defaultSecrets {
+			if cfg.Secret == secret {
+				return nil, ErrDefaultSecret
+			}
+		}
+		a.secret = []byte(cfg.Secret)
+		a.methods = append(a.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.PublicKeyFile != "" {
+		content, err := ioutil.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key file. error: %w", err)
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key file. error: %w", err)
+		}
+		a.rsaKeys[""] = key
+	}
+	if cfg.JWKSFile != "" {
+		if err := a.loadJWKS(cfg.JWKSFile); err != nil {
+			return nil, err
+		}
+	}
+	if len(a.rsaKeys) > 0 {
+		a.methods = append(a.methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	if len(a.methods) == 0 {
+		return nil, errors.New("no key for JWT is configured")
+	}
+	return a, nil
+}
+
+// jwks - JSON Web Key Set, only RSA keys are used
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// errJWKSWithoutKid - RSA key of JWKS has no kid, such key would take slot of key of PublicKeyFile
+var errJWKSWithoutKid = errors.New("RSA key of JWKS file has no kid")
+
+// loadJWKS - add RSA signing keys of set from file by their kid, every RSA signing key must have kid
+func (a *JWTAuth) loadJWKS(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS file. error: %w", err)
+	}
+	var set jwks
+	if err = json.Unmarshal(content, &set); err != nil {
+		return fmt.Errorf("failed to parse JWKS file. error: %w", err)
+	}
+
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		if key.Kid == "" {
+			return errJWKSWithoutKid
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return fmt.Errorf("failed to decode modulus of JWKS key %q. error: %w", key.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return fmt.Errorf("failed to decode exponent of JWKS key %q. error: %w", key.Kid, err)
+		}
+		a.rsaKeys[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return nil
+}
+
+// Verify - check signature, expiration, issuer and audience of token and get its principal, sub and exp are required
+func (a *JWTAuth) Verify(token string) (Principal, error) {
+	var c claims
+	parser := jwt.NewParser(jwt.WithValidMethods(a.methods))
+	if _, err := parser.ParseWithClaims(token, &c, a.key); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+
+	switch {
+	case c.Subject == "":
+		return Principal{}, fmt.Errorf("%w: token has no subject", errInvalidToken)
+	case c.ExpiresAt == nil:
+		return Principal{}, fmt.Errorf("%w: token has no expiration", errInvalidToken)
+	case a.issuer != "" && !c.VerifyIssuer(a.issuer, true):
+		return Principal{}, fmt.Errorf("%w: unexpected issuer %q", errInvalidToken, c.Issuer)
+	case a.audience != "" && !c.VerifyAudience(a.audience, true):
+		return Principal{}, fmt.Errorf("%w: unexpected audience %v", errInvalidToken, c.Audience)
+	}
+	return Principal{Subject: c.Subject, Roles: c.Roles}, nil
+}
+
+// Sign - HS256 token of principal with issuer and audience of config, which expires after access TTL.
+// Only tokens with shared secret are signed, RS256 keys of config are public
+func (a *JWTAuth) Sign(p Principal) (token string, expiresAt time.Time, err error) {
+	if a.secret == nil {
+		return "", expiresAt, errors.New("tokens can't be signed without HS256 secret")
+	}
+
+	now := time.Now()
+	expiresAt = now.Add(a.accessTTL)
+	c := claims{
+		Roles: p.Roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   p.Subject,
+			Issuer:    a.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	if a.audience != "" {
+		c.Audience = jwt.ClaimStrings{a.audience}
+	}
+	if token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(a.secret); err != nil {
+		return "", expiresAt, fmt.Errorf("failed to sign token. error: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// key - key for method of token, RSA key is selected by kid, the only RSA key is used for token without kid
+func (a *JWTAuth) key(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		return a.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if key, ok := a.rsaKeys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(a.rsaKeys) == 1 {
+		for _, key := range a.rsaKeys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
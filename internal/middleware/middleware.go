@@ -2,22 +2,41 @@
 package middleware
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
+	"project/internal/apperror"
+	"project/internal/response"
+	"project/pkg/logging"
 	"runtime/debug"
+	"time"
 )
 
 // creating custom handler
 type appHandler func(w http.ResponseWriter, r *http.Request) error
 
-// Logging - logging middleware for handlers
-func Logging(h appHandler) http.HandlerFunc {
+// Errors - middleware which logs errors of handlers and answers them with json body, server keeps working
+func Errors(h appHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		err := h(w, r)
-		if err != nil {
-			log.Fatal(err)
+		if err == nil {
 			return
 		}
+
+		// errors which are not application errors are internal
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) {
+			appErr = apperror.SystemError(err)
+		}
+
+		logger := logging.GetLogger()
+		if appErr.Status >= http.StatusInternalServerError {
+			logger.Errorf("%s %s failed: %v", r.Method, r.URL.Path, err)
+		} else {
+			logger.Debugf("%s %s failed: %v", r.Method, r.URL.Path, err)
+		}
+
+		writeError(w, appErr)
 	}
 }
 
@@ -26,10 +45,30 @@ func PanicRecovery(h http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-				log.Println(string(debug.Stack()))
+				logging.GetLogger().Errorf("panic: %v\n%s", err, debug.Stack())
+				writeError(w, apperror.SystemError(fmt.Errorf("panic: %v", err)))
 			}
 		}()
 		h.ServeHTTP(w, r)
 	}
 }
+
+// writeError - write application error in response envelope
+func writeError(w http.ResponseWriter, appErr *apperror.AppError) {
+	if err := response.Error(w, appErr); err != nil {
+		logging.GetLogger().Errorf("failed to write error response. error: %v", err)
+	}
+}
+
+// Deprecated - middleware for deprecated paths, answers have Deprecation (RFC 9745) and Sunset (RFC 8594) headers
+// and Link to the successor of path which is made from request by successor
+func Deprecated(h http.HandlerFunc, deprecatedAt, sunset time.Time, successor func(r *http.Request) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", deprecatedAt.Unix()))
+		w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		if successor != nil {
+			w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor(r)))
+		}
+		h.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"project/internal/apperror"
+	"project/internal/response"
+	"testing"
+)
+
+// TestErrorsKeepServing - failing and panicking handlers are answered with json errors and server keeps answering next requests
+func TestErrorsKeepServing(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", PanicRecovery(Errors(func(w http.ResponseWriter, r *http.Request) error {
+		return response.JSON(w, http.StatusOK, "ok", nil)
+	})))
+	mux.HandleFunc("/invalid", PanicRecovery(Errors(func(w http.ResponseWriter, r *http.Request) error {
+		return apperror.New(http.StatusBadRequest, "invalid_id", "invalid user id", nil)
+	})))
+	mux.HandleFunc("/fail", PanicRecovery(Errors(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("storage is down")
+	})))
+	mux.HandleFunc("/panic", PanicRecovery(Errors(func(w http.ResponseWriter, r *http.Request) error {
+		panic("broken handler")
+	})))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/invalid", http.StatusBadRequest, "invalid_id"},
+		{"/ok", http.StatusOK, ""},
+		{"/fail", http.StatusInternalServerError, "internal_error"},
+		{"/ok", http.StatusOK, ""},
+		{"/panic", http.StatusInternalServerError, "internal_error"},
+		{"/ok", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(server.URL + tt.path)
+		if err != nil {
+			t.Fatalf("GET %s error: %v", tt.path, err)
+		}
+		var envelope struct {
+			Error *struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&envelope)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("GET %s answer is not json: %v", tt.path, err)
+		}
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("GET %s status = %d, want %d", tt.path, resp.StatusCode, tt.status)
+		}
+		switch {
+		case tt.code == "" && envelope.Error != nil:
+			t.Errorf("GET %s error = %+v, want none", tt.path, envelope.Error)
+		case tt.code != "" && (envelope.Error == nil || envelope.Error.Code != tt.code):
+			t.Errorf("GET %s error = %+v, want code %s", tt.path, envelope.Error, tt.code)
+		case tt.code == "internal_error" && envelope.Error.Message != http.StatusText(http.StatusInternalServerError):
+			t.Errorf("GET %s message = %q, details of internal error are answered", tt.path, envelope.Error.Message)
+		}
+	}
+}
+
+// TestNewJWTAuthDefaultSecret - known default secret fails creation of auth, other secrets are accepted
+func TestNewJWTAuthDefaultSecret(t *testing.T) {
+	for _, secret := range defaultSecrets {
+		if _, err := NewJWTAuth(JWTConfig{Secret: secret}); !errors.Is(err, ErrDefaultSecret) {
+			t.Errorf("NewJWTAuth with secret %q error = %v, want %v", secret, err, ErrDefaultSecret)
+		}
+	}
+	if _, err := NewJWTAuth(JWTConfig{Secret: "5f1c2b9e0d7a4c8e"}); err != nil {
+		t.Errorf("NewJWTAuth with random secret error = %v", err)
+	}
+}
+
+// TestNewJWTAuthJWKSKid - RSA signing keys of JWKS file must have kid, keys of other types and uses are skipped
+func TestNewJWTAuthJWKSKid(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk := func(kty, kid, use string) map[string]string {
+		return map[string]string{
+			"kty": kty,
+			"kid": kid,
+			"use": use,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	}
+
+	tests := []struct {
+		name string
+		keys []map[string]string
+		err  error
+	}{
+		{"with kid", []map[string]string{jwk("RSA", "key-1", "sig")}, nil},
+		{"without kid", []map[string]string{jwk("RSA", "key-1", "sig"), jwk("RSA", "", "sig")}, errJWKSWithoutKid},
+		{"encryption key without kid", []map[string]string{jwk("RSA", "key-1", "sig"), jwk("RSA", "", "enc")}, nil},
+	}
+	for _, tt := range tests {
+		content, err := json.Marshal(map[string]interface{}{"keys": tt.keys})
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		if err = ioutil.WriteFile(path, content, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = NewJWTAuth(JWTConfig{JWKSFile: path}); !errors.Is(err, tt.err) {
+			t.Errorf("%s: NewJWTAuth error = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Package openapi - OpenAPI 3 specification of API, handler serving it and checking of requests and answers against it
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"project/pkg/logging"
+	"sort"
+	"strings"
+)
+
+// document - specification of every route registered by user.Handler, it is checked by user/apitest
+//
+//go:embed openapi.json
+var document []byte
+
+// constants for paths of specification and Swagger UI
+const (
+	specURL = "/openapi.json"
+	docsURL = "/docs"
+)
+
+// Spec - parsed part of specification which is needed for checking requests and answers
+type Spec struct {
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas   map[string]*Schema   `json:"schemas"`
+		Responses map[string]*Response `json:"responses"`
+	} `json:"components"`
+}
+
+// Operation - one method of path
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Deprecated  bool                 `json:"deprecated"`
+	Parameters  []Parameter          `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter - path or query parameter of operation
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody - body of request by content type
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response - answer by content type, Ref points to components.responses
+type Response struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+// MediaType - schema of body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Route - route of specification, path is in form of httprouter
+type Route struct {
+	Method     string
+	Path       string
+	Deprecated bool
+}
+
+// Load - parse embedded specification
+func Load() (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(document, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse openapi document. error: %w", err)
+	}
+	return &spec, nil
+}
+
+// Routes - all operations of specification sorted by path and method
+func (s *Spec) Routes() []Route {
+	var routes []Route
+	for path, item := range s.Paths {
+		for method, op := range item {
+			routes = append(routes, Route{Method: strings.ToUpper(method), Path: routerPath(path), Deprecated: op.Deprecated})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// routerPath - change "{param}" segments of specification to ":param" of httprouter
+func routerPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + segment[1:len(segment)-1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Handler - handler of specification and optional Swagger UI page
+type Handler struct {
+	Logger    *logging.Logger
+	SwaggerUI bool
+}
+
+// Register - func for init routs of specification
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, specURL, h.Spec)
+	if h.SwaggerUI {
+		router.HandlerFunc(http.MethodGet, docsURL, h.Docs)
+	}
+}
+
+// Spec - answer with specification as it is, without envelope
+func (h *Handler) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(document); err != nil {
+		h.Logger.Errorf("failed to write openapi document. error: %v", err)
+	}
+}
+
+// swaggerPage - Swagger UI from CDN which shows specification of specURL
+const swaggerPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Users API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "` + specURL + `", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+// Docs - answer with Swagger UI page
+func (h *Handler) Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write([]byte(swaggerPage)); err != nil {
+		h.Logger.Errorf("failed to write swagger ui page. error: %v", err)
+	}
+}
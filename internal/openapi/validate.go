@@ -0,0 +1,317 @@
+package openapi
+
+// file for checking requests and answers against specification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Schema - subset of OpenAPI 3.0 schema which is used by specification,
+// additionalProperties can only be false which forbids properties not listed in Properties
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Enum                 []string           `json:"enum"`
+	Pattern              string             `json:"pattern"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Nullable             bool               `json:"nullable"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+}
+
+// Find - find operation and path of specification for method and path of request, static segments win over params
+func (s *Spec) Find(method string, path string) (string, *Operation, error) {
+	segments := strings.Split(path, "/")
+	var found string
+	var op *Operation
+	best := -1
+	for template, item := range s.Paths {
+		candidate, ok := item[strings.ToLower(method)]
+		if !ok {
+			continue
+		}
+		if static, ok := matchPath(strings.Split(template, "/"), segments); ok && static > best {
+			found, op, best = template, candidate, static
+		}
+	}
+	if op == nil {
+		return "", nil, fmt.Errorf("%s %s is not in specification", method, path)
+	}
+	return found, op, nil
+}
+
+// matchPath - check that segments of path match segments of template, returns number of matched static segments
+func matchPath(template []string, segments []string) (int, bool) {
+	if len(template) != len(segments) {
+		return 0, false
+	}
+	static := 0
+	for i, segment := range template {
+		if strings.HasPrefix(segment, "{") {
+			if segments[i] == "" {
+				return 0, false
+			}
+			continue
+		}
+		if segment != segments[i] {
+			return 0, false
+		}
+		static++
+	}
+	return static, true
+}
+
+// CheckRequest - check query and body of request, body must be read before and passed separately
+func (s *Spec) CheckRequest(r *http.Request, body []byte) error {
+	template, op, err := s.Find(r.Method, r.URL.Path)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("request of %s %s", r.Method, template)
+
+	// query params which are not in specification are ignored by handlers, so they are errors of client
+	query := r.URL.Query()
+	for key := range query {
+		if op.param(key, "query") == nil {
+			return fmt.Errorf("%s: query param %q is not in specification", name, key)
+		}
+	}
+
+	if op.RequestBody == nil {
+		if len(bytes.TrimSpace(body)) != 0 {
+			return fmt.Errorf("%s: body is not in specification", name)
+		}
+		return nil
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return fmt.Errorf("%s: body is required", name)
+		}
+		return nil
+	}
+	media := op.RequestBody.Content["application/json"]
+	if media == nil {
+		return fmt.Errorf("%s: json body is not in specification", name)
+	}
+	return s.validateJSON(name, media.Schema, body)
+}
+
+// CheckResponse - check status, headers and body of answer to request
+func (s *Spec) CheckResponse(r *http.Request, status int, header http.Header, body []byte) error {
+	template, op, err := s.Find(r.Method, r.URL.Path)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("answer %d of %s %s", status, r.Method, template)
+
+	if deprecated := header.Get("Deprecation") != ""; deprecated != op.Deprecated {
+		return fmt.Errorf("%s: Deprecation header is %t, operation deprecated is %t", name, deprecated, op.Deprecated)
+	}
+
+	response := op.Responses[strconv.Itoa(status)]
+	if response == nil {
+		response = op.Responses["default"]
+	}
+	if response == nil {
+		return fmt.Errorf("%s: status is not in specification", name)
+	}
+	if response.Ref != "" {
+		if response = s.Components.Responses[strings.TrimPrefix(response.Ref, "#/components/responses/")]; response == nil {
+			return fmt.Errorf("%s: unknown response reference", name)
+		}
+	}
+	media := response.Content["application/json"]
+	if media == nil {
+		return fmt.Errorf("%s: json answer is not in specification", name)
+	}
+	if contentType := header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		return fmt.Errorf("%s: content type is %q, want application/json", name, contentType)
+	}
+	return s.validateJSON(name, media.Schema, body)
+}
+
+// param - parameter of operation by name and location
+func (op *Operation) param(name string, in string) *Parameter {
+	for i := range op.Parameters {
+		if op.Parameters[i].Name == name && op.Parameters[i].In == in {
+			return &op.Parameters[i]
+		}
+	}
+	return nil
+}
+
+// validateJSON - decode json keeping numbers and validate it against schema
+func (s *Spec) validateJSON(name string, schema *Schema, body []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("%s: invalid json: %v", name, err)
+	}
+	if err := s.Validate(schema, value); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// Validate - validate value decoded from json with json.Number for numbers against schema
+func (s *Spec) Validate(schema *Schema, value interface{}) error {
+	return s.validate("$", schema, value)
+}
+
+// validate - validate value at path of json
+func (s *Spec) validate(path string, schema *Schema, value interface{}) error {
+	if schema.Ref != "" {
+		resolved := s.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+		if resolved == nil {
+			return fmt.Errorf("%s: unknown schema reference %s", path, schema.Ref)
+		}
+		return s.validate(path, resolved, value)
+	}
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not allowed", path)
+	}
+
+	switch schema.Type {
+	case "":
+		return nil
+	case "object":
+		return s.validateObject(path, schema, value)
+	case "array":
+		return s.validateArray(path, schema, value)
+	case "string":
+		return validateString(path, schema, value)
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: %T is not %s", path, value, schema.Type)
+		}
+		if _, err := number.Int64(); schema.Type == "integer" && err != nil {
+			return fmt.Errorf("%s: %s is not integer", path, number)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: %T is not boolean", path, value)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported schema type %s", path, schema.Type)
+	}
+	return nil
+}
+
+// validateObject - check required, listed and unknown properties of object
+func (s *Spec) validateObject(path string, schema *Schema, value interface{}) error {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: %T is not object", path, value)
+	}
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%s: required property %q is missing", path, name)
+		}
+	}
+
+	// properties are checked in order of names, so the same error is reported every time
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := schema.Properties[name]
+		if !ok {
+			if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+				return fmt.Errorf("%s: property %q is not in specification", path, name)
+			}
+			continue
+		}
+		if err := s.validate(path+"."+name, property, object[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateArray - check size and items of array
+func (s *Spec) validateArray(path string, schema *Schema, value interface{}) error {
+	items, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("%s: %T is not array", path, value)
+	}
+	if schema.MinItems != nil && len(items) < *schema.MinItems {
+		return fmt.Errorf("%s: %d items, want at least %d", path, len(items), *schema.MinItems)
+	}
+	if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+		return fmt.Errorf("%s: %d items, want at most %d", path, len(items), *schema.MaxItems)
+	}
+	if schema.Items == nil {
+		return nil
+	}
+	for i, item := range items {
+		if err := s.validate(fmt.Sprintf("%s[%d]", path, i), schema.Items, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateString - check length, enum, pattern and format of string
+func validateString(path string, schema *Schema, value interface{}) error {
+	str, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%s: %T is not string", path, value)
+	}
+	length := utf8.RuneCountInString(str)
+	if schema.MinLength != nil && length < *schema.MinLength {
+		return fmt.Errorf("%s: %q is shorter than %d", path, str, *schema.MinLength)
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		return fmt.Errorf("%s: %q is longer than %d", path, str, *schema.MaxLength)
+	}
+	if len(schema.Enum) != 0 && !contains(schema.Enum, str) {
+		return fmt.Errorf("%s: %q is not one of %v", path, str, schema.Enum)
+	}
+	if schema.Pattern != "" {
+		matched, err := regexp.MatchString(schema.Pattern, str)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern %q: %v", path, schema.Pattern, err)
+		}
+		if !matched {
+			return fmt.Errorf("%s: %q doesn't match %q", path, str, schema.Pattern)
+		}
+	}
+	if schema.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			return fmt.Errorf("%s: %q is not date-time", path, str)
+		}
+	}
+	return nil
+}
+
+// contains - check that values have value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
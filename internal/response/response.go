@@ -0,0 +1,38 @@
+// Package response - json envelope for all answers of API
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"project/internal/apperror"
+)
+
+// Envelope - body of every answer, data and error are never set together
+type Envelope struct {
+	Data  interface{}        `json:"data,omitempty"`
+	Error *apperror.AppError `json:"error,omitempty"`
+	Meta  interface{}        `json:"meta,omitempty"`
+}
+
+// JSON - write data and meta in envelope with status code
+func JSON(w http.ResponseWriter, status int, data interface{}, meta interface{}) error {
+	return write(w, status, Envelope{Data: data, Meta: meta})
+}
+
+// Error - write application error in envelope with its status code
+func Error(w http.ResponseWriter, appErr *apperror.AppError) error {
+	return write(w, appErr.Status, Envelope{Error: appErr})
+}
+
+// write - encode envelope before writing header, so encoding error can still be answered
+func write(w http.ResponseWriter, status int, envelope Envelope) error {
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	return err
+}
@@ -0,0 +1,93 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"project/internal/apperror"
+	"testing"
+)
+
+// TestEnvelope - data with meta and errors are answered in the same envelope, empty members are omitted
+func TestEnvelope(t *testing.T) {
+	type user struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	}
+
+	tests := []struct {
+		name   string
+		write  func(w http.ResponseWriter) error
+		status int
+		body   string
+	}{
+		{
+			"data",
+			func(w http.ResponseWriter) error {
+				return JSON(w, http.StatusCreated, user{ID: "1", Username: "alice"}, nil)
+			},
+			http.StatusCreated,
+			`{"data":{"id":"1","username":"alice"}}`,
+		},
+		{
+			"data with meta",
+			func(w http.ResponseWriter) error {
+				return JSON(w, http.StatusOK, []user{{ID: "1", Username: "alice"}}, map[string]int{"count": 1})
+			},
+			http.StatusOK,
+			`{"data":[{"id":"1","username":"alice"}],"meta":{"count":1}}`,
+		},
+		{
+			"empty list",
+			func(w http.ResponseWriter) error {
+				return JSON(w, http.StatusOK, []user{}, nil)
+			},
+			http.StatusOK,
+			`{"data":[]}`,
+		},
+		{
+			"error",
+			func(w http.ResponseWriter) error {
+				return Error(w, apperror.New(http.StatusNotFound, "not_found", "user not found", nil))
+			},
+			http.StatusNotFound,
+			`{"error":{"code":"not_found","message":"user not found"}}`,
+		},
+		{
+			"error of field",
+			func(w http.ResponseWriter) error {
+				appErr := apperror.New(http.StatusBadRequest, "validation_error", "must be a number", nil)
+				appErr.Field = "limit"
+				return Error(w, appErr)
+			},
+			http.StatusBadRequest,
+			`{"error":{"code":"validation_error","message":"must be a number","field":"limit"}}`,
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		if err := tt.write(w); err != nil {
+			t.Fatalf("%s: write error: %v", tt.name, err)
+		}
+		if w.Code != tt.status || w.Header().Get("Content-Type") != "application/json" || w.Body.String() != tt.body {
+			t.Errorf("%s: answered %d %q %s, want %d application/json %s", tt.name, w.Code, w.Header().Get("Content-Type"), w.Body.String(), tt.status, tt.body)
+		}
+	}
+}
+
+// TestEnvelopeEncodingError - data which can't be encoded is not answered, so caller can still answer error
+func TestEnvelopeEncodingError(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := JSON(w, http.StatusOK, make(chan int), nil); err == nil {
+		t.Fatal("JSON of channel error = nil, want encoding error")
+	}
+	if w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+		t.Errorf("answer %q with headers %v is written before encoding", w.Body.String(), w.Header())
+	}
+
+	if err := Error(w, apperror.SystemError(nil)); err != nil {
+		t.Fatalf("Error error: %v", err)
+	}
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status after encoding error = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
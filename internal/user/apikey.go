@@ -0,0 +1,28 @@
+package user
+
+// file for API key description
+
+import (
+	"time"
+)
+
+// APIKey - key of service caller with scopes of middleware, only hash of its value is stored.
+// Prefix is the beginning of value, so key is recognized in list without its value
+type APIKey struct {
+	ID         string     `json:"id" bson:"_id,omitempty"`
+	Name       string     `json:"name" bson:"name"`
+	Prefix     string     `json:"prefix" bson:"prefix"`
+	Hash       string     `json:"-" bson:"hash"`
+	Scopes     []string   `json:"scopes" bson:"scopes"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// NewerAPIKey - order of keys for storages which sort keys themselves, newer keys go first
+func NewerAPIKey(a, b APIKey) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return compareIDs(a.ID, b.ID) > 0
+}
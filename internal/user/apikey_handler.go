@@ -0,0 +1,94 @@
+package user
+
+// file for handle of API keys of service callers
+
+import (
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"project/internal/response"
+)
+
+// constants for API keys path, keys are managed only by admins
+const (
+	apiKeysURL = "/admin/api_keys"
+	apiKeyURL  = "/admin/api_keys/:keyId"
+)
+
+// errAPIKeysUnavailable - API key routes of handler without APIKeys answer internal error
+var errAPIKeysUnavailable = errors.New("api keys service is not configured")
+
+// issuedAPIKey - data of answer with created key, value of key is answered only here
+type issuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// registerAPIKeys - func for init routs of API keys, they have no legacy aliases
+func (h *Handler) registerAPIKeys(router *httprouter.Router) {
+	h.handle(router, http.MethodPost, apiKeysURL, h.IssueAPIKey)
+	h.handle(router, http.MethodGet, apiKeysURL, h.ListAPIKeys)
+	h.handle(router, http.MethodDelete, apiKeyURL, h.RevokeAPIKey)
+}
+
+// IssueAPIKey - creating API key with name and scopes from body
+func (h *Handler) IssueAPIKey(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Issue api key")
+	if err := AuthorizeAdmin(r.Context()); err != nil {
+		return err
+	}
+	if h.APIKeys == nil {
+		return errAPIKeysUnavailable
+	}
+
+	var input APIKeyInput
+	if err := readBody(r, &input); err != nil {
+		return err
+	}
+
+	key, value, err := h.APIKeys.IssueAPIKey(r.Context(), input)
+	if err != nil {
+		return err
+	}
+
+	// answer with created key and its value
+	return response.JSON(w, http.StatusCreated, issuedAPIKey{APIKey: key, Key: value}, nil)
+}
+
+// ListAPIKeys - getting all API keys without their values
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("List api keys")
+	if err := AuthorizeAdmin(r.Context()); err != nil {
+		return err
+	}
+	if h.APIKeys == nil {
+		return errAPIKeysUnavailable
+	}
+
+	keys, err := h.APIKeys.ListAPIKeys(r.Context())
+	if err != nil {
+		return err
+	}
+
+	return response.JSON(w, http.StatusOK, keys, listMeta{Count: len(keys)})
+}
+
+// RevokeAPIKey - revoking API key from path
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Revoke api key")
+	if err := AuthorizeAdmin(r.Context()); err != nil {
+		return err
+	}
+	if h.APIKeys == nil {
+		return errAPIKeysUnavailable
+	}
+
+	// getting id from url params
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	key, err := h.APIKeys.RevokeAPIKey(r.Context(), params.ByName("keyId"))
+	if err != nil {
+		return err
+	}
+
+	return response.JSON(w, http.StatusOK, key, nil)
+}
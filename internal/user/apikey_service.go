@@ -0,0 +1,142 @@
+package user
+
+// file for API keys of service callers
+
+import (
+	"context"
+	"fmt"
+	"project/internal/middleware"
+	"project/pkg/logging"
+	"project/pkg/validator"
+	"strings"
+	"time"
+)
+
+// apiKeyPrefix - beginning of every API key, so keys are recognized in configs and logs
+const apiKeyPrefix = "uak_"
+
+// apiKeyPrefixLen - length of beginning of key which is kept in Prefix
+const apiKeyPrefixLen = len(apiKeyPrefix) + 8
+
+// lastUsedPrecision - time of the last use of key is saved only if previous one is older, so not every request writes to storage
+const lastUsedPrecision = time.Minute
+
+// APIKeyInput - name and scopes of new API key
+type APIKeyInput struct {
+	Name   string   `json:"name" validate:"required,max=64"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIKeyService - interface for API keys, it verifies keys for middleware.Authenticate
+type APIKeyService interface {
+	middleware.KeyVerifier
+	// IssueAPIKey - create key and get its value, value is answered only once and only its hash is stored
+	IssueAPIKey(ctx context.Context, input APIKeyInput) (APIKey, string, error)
+	// ListAPIKeys - all keys, revoked ones included, newer keys go first
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	// RevokeAPIKey - revoke key, revoke of revoked key is not an error
+	RevokeAPIKey(ctx context.Context, id string) (APIKey, error)
+}
+
+// apiKeyService struct with logging
+type apiKeyService struct {
+	storage APIKeyStorage
+	logger  logging.Logger
+}
+
+// NewAPIKeyService - func for initialization API keys service
+func NewAPIKeyService(apiKeyStorage APIKeyStorage, logger logging.Logger) APIKeyService {
+	return &apiKeyService{
+		storage: apiKeyStorage,
+		logger:  logger,
+	}
+}
+
+// IssueAPIKey - func for creating API key with unique scopes
+func (s apiKeyService) IssueAPIKey(ctx context.Context, input APIKeyInput) (APIKey, string, error) {
+	s.logger.Infof("issue api key %s", input.Name)
+	if err := validator.Validate(input); err != nil {
+		return APIKey{}, "", err
+	}
+	scopes, err := uniqueScopes(input.Scopes)
+	if err != nil {
+		return APIKey{}, "", err
+	}
+
+	secret, err := randomString(32)
+	if err != nil {
+		return APIKey{}, "", fmt.Errorf("failed to issue api key. error: %w", err)
+	}
+	value := apiKeyPrefix + secret
+	key, err := s.storage.CreateAPIKey(ctx, APIKey{
+		Name:      input.Name,
+		Prefix:    value[:apiKeyPrefixLen],
+		Hash:      hashToken(value),
+		Scopes:    scopes,
+		CreatedAt: requestTime(),
+	})
+	if err != nil {
+		return APIKey{}, "", fmt.Errorf("failed to issue api key. error: %w", err)
+	}
+	return key, value, nil
+}
+
+// ListAPIKeys - func for getting all API keys
+func (s apiKeyService) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	keys, err := s.storage.FindAPIKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys. error: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey - func for revoking API key, requests with it are unauthorized after that
+func (s apiKeyService) RevokeAPIKey(ctx context.Context, id string) (APIKey, error) {
+	s.logger.Infof("revoke api key %s", id)
+	key, err := s.storage.RevokeAPIKey(ctx, id, requestTime())
+	if err != nil {
+		return APIKey{}, fmt.Errorf("failed to revoke api key. error: %w", err)
+	}
+	return key, nil
+}
+
+// VerifyKey - func for authentication of service by API key, time of use is saved with lastUsedPrecision
+func (s apiKeyService) VerifyKey(ctx context.Context, value string) (middleware.Principal, error) {
+	if !strings.HasPrefix(value, apiKeyPrefix) {
+		return middleware.Principal{}, fmt.Errorf("api key has no prefix %s: %w", apiKeyPrefix, ErrAPIKeyNotFound)
+	}
+	key, err := s.storage.FindAPIKey(ctx, hashToken(value))
+	if err != nil {
+		return middleware.Principal{}, err
+	}
+	if key.RevokedAt != nil {
+		return middleware.Principal{}, fmt.Errorf("api key %s is revoked", key.ID)
+	}
+
+	// failed save of time of use doesn't fail request
+	now := requestTime()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedPrecision {
+		if err = s.storage.TouchAPIKey(ctx, key.ID, now); err != nil {
+			s.logger.Warnf("failed to save time of use of api key %s. error: %v", key.ID, err)
+		}
+	}
+
+	return middleware.Principal{Subject: "api-key:" + key.ID, KeyID: key.ID, Scopes: key.Scopes}, nil
+}
+
+// uniqueScopes - scopes without duplicates, ValidationError for empty list or unknown scope
+func uniqueScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, &ValidationError{Field: "scopes", Message: "is required"}
+	}
+	unique := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !contains(middleware.Scopes, scope) {
+			return nil, &ValidationError{Field: "scopes", Message: fmt.Sprintf("unknown scope %q, must be one of %s", scope, strings.Join(middleware.Scopes, ", "))}
+		}
+		if !contains(unique, scope) {
+			unique = append(unique, scope)
+		}
+	}
+	return unique, nil
+}
@@ -0,0 +1,308 @@
+// Package apitest - checks that routes, requests and answers of user.Handler match OpenAPI specification of project/internal/openapi
+//
+// Test of package user runs the suite with handler of MemoryHandler:
+//
+//	func TestAPI(t *testing.T) {
+//		apitest.Run(t, apitest.MemoryHandler)
+//	}
+//
+// RunAuth checks that callers change only their own users unless they are admins,
+// that sessions of registration and login are rotated and revoked and that API keys are limited by their scopes.
+// Tests of client, gRPC server and GraphQL endpoint build their handler with MemoryHandler too.
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"project/internal/openapi"
+	"project/internal/user"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// invalidID - id which can't be produced by any storage
+const invalidID = "not-an-id"
+
+// NewHandler - func for creating handler on new empty storages for one test
+type NewHandler func(t *testing.T) *user.Handler
+
+// Run - check routes of handler against specification, then call every route and check requests and answers
+func Run(t *testing.T, newHandler NewHandler) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Routes", func(t *testing.T) {
+		testRoutes(t, spec, newHandler(t))
+	})
+	t.Run("Shapes", func(t *testing.T) {
+		testShapes(t, spec, newHandler(t))
+	})
+}
+
+// testRoutes - every registered route is in specification and every operation of specification is registered
+func testRoutes(t *testing.T, spec *openapi.Spec, h *user.Handler) {
+	h.Register(httprouter.New())
+
+	registered := make(map[string]bool)
+	for _, route := range h.Routes() {
+		registered[routeKey(route.Method, route.Path, route.Deprecated)] = true
+	}
+	specified := make(map[string]bool)
+	for _, route := range spec.Routes() {
+		specified[routeKey(route.Method, route.Path, route.Deprecated)] = true
+	}
+
+	for _, key := range sortedKeys(registered) {
+		if !specified[key] {
+			t.Errorf("route %s is registered but not in specification", key)
+		}
+	}
+	for _, key := range sortedKeys(specified) {
+		if !registered[key] {
+			t.Errorf("route %s is in specification but not registered", key)
+		}
+	}
+}
+
+// routeKey - key of route for comparing handler and specification
+func routeKey(method string, path string, deprecated bool) string {
+	if deprecated {
+		return method + " " + path + " (deprecated)"
+	}
+	return method + " " + path
+}
+
+// sortedKeys - keys of set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// client - calls router and checks every request and answer against specification
+type client struct {
+	t       *testing.T
+	spec    *openapi.Spec
+	router  http.Handler
+	covered map[string]bool
+
+	// authorization - header of every request, empty for anonymous routes
+	authorization string
+}
+
+// do - call route with json body, check status and shapes, return data of answer
+func (c *client) do(method string, path string, body interface{}, wantStatus int) interface{} {
+	c.t.Helper()
+
+	var content []byte
+	if body != nil {
+		var err error
+		if content, err = json.Marshal(body); err != nil {
+			c.t.Fatalf("failed to marshal body of %s %s: %v", method, path, err)
+		}
+	}
+	r := httptest.NewRequest(method, path, bytes.NewReader(content))
+	if c.authorization != "" {
+		r.Header.Set("Authorization", c.authorization)
+	}
+	w := httptest.NewRecorder()
+	c.router.ServeHTTP(w, r)
+	answer, _ := ioutil.ReadAll(w.Result().Body)
+
+	if w.Code != wantStatus {
+		c.t.Fatalf("%s %s status = %d, want %d, answer: %s", method, path, w.Code, wantStatus, answer)
+	}
+	// only requests which are accepted by handlers must match specification
+	if w.Code < http.StatusBadRequest {
+		if err := c.spec.CheckRequest(r, content); err != nil {
+			c.t.Error(err)
+		}
+	}
+	if err := c.spec.CheckResponse(r, w.Code, w.Header(), answer); err != nil {
+		c.t.Errorf("%v, answer: %s", err, answer)
+	}
+	if template, _, err := c.spec.Find(method, r.URL.Path); err == nil {
+		c.covered[strings.ToUpper(method)+" "+template] = true
+	}
+
+	var envelope struct {
+		Data interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(answer, &envelope); err != nil {
+		c.t.Fatalf("%s %s answer is not json: %v", method, path, err)
+	}
+	return envelope.Data
+}
+
+// id - id from data of answer which is one object
+func (c *client) id(data interface{}) string {
+	c.t.Helper()
+
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		c.t.Fatalf("data %v is not object", data)
+	}
+	id, ok := object["id"].(string)
+	if !ok {
+		c.t.Fatalf("data %v has no id", data)
+	}
+	return id
+}
+
+// refreshToken - refresh token from data of answer with tokens
+func (c *client) refreshToken(data interface{}) string {
+	c.t.Helper()
+
+	object, _ := data.(map[string]interface{})
+	token, ok := object["refresh_token"].(string)
+	if !ok {
+		c.t.Fatalf("data %v has no refresh_token", data)
+	}
+	return token
+}
+
+// create - create user through current api and return his id
+func (c *client) create(username string) string {
+	c.t.Helper()
+	return c.id(c.do(http.MethodPost, "/api/v1/users", map[string]string{"username": username}, http.StatusCreated))
+}
+
+// testShapes - call every operation of specification at least once with successful and failing requests,
+// routes are called by admin, so ownership of users doesn't matter
+func testShapes(t *testing.T, spec *openapi.Spec, h *user.Handler) {
+	setAuth(t, h)
+	router := httprouter.New()
+	h.Register(router)
+	admin := "Bearer " + adminToken(t)
+	c := &client{t: t, spec: spec, router: router, covered: make(map[string]bool), authorization: admin}
+
+	// users
+	alice := c.create("alice")
+	bob := c.create("bob")
+	carol := c.create("carol")
+	dave := c.id(c.do(http.MethodPost, "/create", map[string]string{"username": "dave", "age": "40"}, http.StatusCreated))
+	frank := c.create("frank")
+	grace := c.create("grace")
+	heidi := c.create("heidi")
+	c.do(http.MethodPost, "/api/v1/users", map[string]string{"username": "x"}, http.StatusUnprocessableEntity)
+	c.do(http.MethodGet, "/api/v1/users?sort=-username&limit=2&offset=1&username=a&min_age=0&max_age=150", nil, http.StatusOK)
+	c.do(http.MethodGet, "/api/v1/users?sort=name", nil, http.StatusBadRequest)
+	c.do(http.MethodGet, "/users", nil, http.StatusOK)
+	c.do(http.MethodGet, "/api/v1/users/"+alice, nil, http.StatusOK)
+	c.do(http.MethodGet, "/api/v1/users/"+invalidID, nil, http.StatusBadRequest)
+	c.do(http.MethodGet, "/users/"+alice, nil, http.StatusOK)
+	c.do(http.MethodPut, "/api/v1/users/"+alice, map[string]string{"username": "alice", "age": "30"}, http.StatusOK)
+	// legacy PUT changes only age
+	if legacy, _ := c.do(http.MethodPut, "/users/"+alice, map[string]string{"age": "31"}, http.StatusOK).(map[string]interface{}); legacy["username"] != "alice" || legacy["age"] != "31" {
+		t.Errorf("legacy PUT answered %v, want alice of 31", legacy)
+	}
+	c.do(http.MethodPut, "/users/"+alice, map[string]string{"age": "old"}, http.StatusUnprocessableEntity)
+	c.do(http.MethodPatch, "/api/v1/users/"+alice, map[string]interface{}{"age": nil}, http.StatusOK)
+	c.do(http.MethodPatch, "/users/"+alice, map[string]interface{}{"age": "32"}, http.StatusOK)
+
+	send := func(path string, fromID string, toID string) string {
+		t.Helper()
+		return c.id(c.do(http.MethodPost, path+fromID+"/friend_requests", map[string]string{"to_id": toID}, http.StatusCreated))
+	}
+	change := func(path string, userID string, requestID string, action string) {
+		t.Helper()
+		c.do(http.MethodPost, path+userID+"/friend_requests/"+requestID+"/"+action, nil, http.StatusOK)
+	}
+
+	// friends: alice - bob - carol, carol is suggested to alice, users become friends only by accepted requests
+	change("/api/v1/users/", bob, send("/api/v1/users/", alice, bob), "accept")
+	c.do(http.MethodPost, "/api/v1/users/"+alice+"/friend_requests", map[string]string{"to_id": bob}, http.StatusConflict)
+	change("/api/v1/users/", carol, c.id(c.do(http.MethodPost, "/make_friends", map[string]string{"source_id": bob, "target_id": carol}, http.StatusCreated)), "accept")
+	c.do(http.MethodGet, "/api/v1/users/"+alice+"/friends", nil, http.StatusOK)
+	c.do(http.MethodGet, "/friends/"+alice, nil, http.StatusOK)
+	c.do(http.MethodGet, "/api/v1/users/"+alice+"/friends/mutual/"+carol, nil, http.StatusOK)
+	c.do(http.MethodGet, "/users/"+alice+"/friends/mutual/"+carol, nil, http.StatusOK)
+	c.do(http.MethodGet, "/api/v1/users/"+alice+"/suggestions?limit=5", nil, http.StatusOK)
+	c.do(http.MethodGet, "/users/"+alice+"/suggestions", nil, http.StatusOK)
+	c.do(http.MethodGet, "/api/v1/users/"+alice+"/path/"+carol, nil, http.StatusOK)
+	c.do(http.MethodGet, "/users/"+alice+"/path/"+carol, nil, http.StatusOK)
+	c.do(http.MethodGet, "/api/v1/users/"+alice+"/path/"+heidi, nil, http.StatusNotFound)
+	c.do(http.MethodDelete, "/api/v1/users/"+bob+"/friends/"+carol, nil, http.StatusOK)
+	c.do(http.MethodDelete, "/users/"+alice+"/friends/"+bob, nil, http.StatusOK)
+
+	// friend requests, every change is done through current and legacy path
+	change("/api/v1/users/", dave, send("/api/v1/users/", alice, dave), "accept")
+	change("/api/v1/users/", frank, send("/api/v1/users/", alice, frank), "decline")
+	change("/api/v1/users/", alice, send("/api/v1/users/", alice, grace), "cancel")
+	change("/users/", dave, send("/users/", bob, dave), "accept")
+	change("/users/", frank, send("/api/v1/users/", bob, frank), "decline")
+	change("/users/", bob, send("/api/v1/users/", bob, grace), "cancel")
+	c.do(http.MethodPost, "/api/v1/users/"+alice+"/friend_requests", map[string]string{"to_id": dave}, http.StatusConflict)
+	c.do(http.MethodGet, "/api/v1/users/"+alice+"/friend_requests?direction=outgoing&status=accepted", nil, http.StatusOK)
+	c.do(http.MethodGet, "/users/"+dave+"/friend_requests", nil, http.StatusOK)
+
+	// blocks and mutes
+	for _, kind := range []string{"blocks", "mutes"} {
+		c.do(http.MethodPut, "/api/v1/users/"+alice+"/"+kind+"/"+heidi, nil, http.StatusOK)
+		c.do(http.MethodGet, "/api/v1/users/"+alice+"/"+kind, nil, http.StatusOK)
+		c.do(http.MethodDelete, "/api/v1/users/"+alice+"/"+kind+"/"+heidi, nil, http.StatusOK)
+		c.do(http.MethodDelete, "/api/v1/users/"+alice+"/"+kind+"/"+heidi, nil, http.StatusNotFound)
+		c.do(http.MethodPut, "/users/"+alice+"/"+kind+"/"+heidi, nil, http.StatusOK)
+		c.do(http.MethodGet, "/users/"+alice+"/"+kind, nil, http.StatusOK)
+		c.do(http.MethodDelete, "/users/"+alice+"/"+kind+"/"+heidi, nil, http.StatusOK)
+	}
+	c.do(http.MethodPut, "/api/v1/users/"+alice+"/blocks/"+alice, nil, http.StatusUnprocessableEntity)
+
+	// soft delete and restore
+	c.do(http.MethodDelete, "/api/v1/users/"+heidi, nil, http.StatusOK)
+	c.do(http.MethodGet, "/api/v1/users/"+heidi, nil, http.StatusNotFound)
+	c.do(http.MethodPost, "/api/v1/admin/users/"+heidi+"/restore", nil, http.StatusOK)
+	c.do(http.MethodPost, "/api/v1/admin/users/"+heidi+"/restore", nil, http.StatusConflict)
+	c.do(http.MethodDelete, "/users", map[string]string{"target_id": heidi}, http.StatusOK)
+	c.do(http.MethodPost, "/admin/users/"+heidi+"/restore", nil, http.StatusOK)
+
+	// API keys
+	key := c.id(c.do(http.MethodPost, "/api/v1/admin/api_keys", map[string]interface{}{"name": "batch", "scopes": []string{"users:write"}}, http.StatusCreated))
+	c.do(http.MethodPost, "/api/v1/admin/api_keys", map[string]interface{}{"name": "", "scopes": []string{"users:read"}}, http.StatusUnprocessableEntity)
+	c.do(http.MethodPost, "/api/v1/admin/api_keys", map[string]interface{}{"name": "batch", "scopes": []string{"users:delete"}}, http.StatusBadRequest)
+	c.do(http.MethodGet, "/api/v1/admin/api_keys", nil, http.StatusOK)
+	c.do(http.MethodDelete, "/api/v1/admin/api_keys/"+key, nil, http.StatusOK)
+	c.do(http.MethodDelete, "/api/v1/admin/api_keys/"+invalidID, nil, http.StatusNotFound)
+
+	// registration and sessions are anonymous
+	c.authorization = ""
+	registration := map[string]string{"username": "ivan", "email": "ivan@example.com", "password": "correct horse"}
+	c.do(http.MethodPost, "/api/v1/auth/register", registration, http.StatusCreated)
+	c.do(http.MethodPost, "/api/v1/auth/register", registration, http.StatusConflict)
+	c.do(http.MethodPost, "/api/v1/auth/register", map[string]string{"username": "judy", "email": "judy", "password": "short"}, http.StatusUnprocessableEntity)
+	c.do(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": "ivan@example.com", "password": "wrong password"}, http.StatusUnauthorized)
+	login := c.do(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": "ivan@example.com", "password": "correct horse"}, http.StatusOK)
+	refreshed := c.do(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": c.refreshToken(login)}, http.StatusOK)
+	c.do(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": "unknown"}, http.StatusUnauthorized)
+	c.do(http.MethodPost, "/api/v1/auth/logout", map[string]string{}, http.StatusUnprocessableEntity)
+	c.do(http.MethodPost, "/api/v1/auth/logout", map[string]string{"refresh_token": c.refreshToken(refreshed)}, http.StatusOK)
+
+	for _, route := range spec.Routes() {
+		key := route.Method + " " + specPath(route.Path)
+		if !c.covered[key] {
+			t.Errorf("operation %s is not called by test", key)
+		}
+	}
+}
+
+// specPath - change ":param" segments of httprouter to "{param}" of specification
+func specPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = fmt.Sprintf("{%s}", segment[1:])
+		}
+	}
+	return strings.Join(segments, "/")
+}
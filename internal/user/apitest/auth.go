@@ -0,0 +1,578 @@
+package apitest
+
+// file for checking authentication of routes of user.Handler and authorization of changes of users
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/julienschmidt/httprouter"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"project/internal/middleware"
+	"project/internal/user"
+	"strings"
+	"testing"
+	"time"
+)
+
+// authSecret - HS256 secret of handler in tests
+const authSecret = "apitest-secret"
+
+// RunAuth - check that routes of handler from newHandler require bearer token and callers change only their own users
+func RunAuth(t *testing.T, newHandler NewHandler) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, h *user.Handler)
+	}{
+		{"Authentication", testAuthentication},
+		{"Ownership", testOwnership},
+		{"Admin", testAdmin},
+		{"JWKS", testJWKS},
+		{"Sessions", testSessions},
+		{"APIKeys", testAPIKeys},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHandler(t)
+			setAuth(t, h)
+			tt.test(t, h)
+		})
+	}
+	// handler without authentication changes nobody
+	t.Run("FailClosed", func(t *testing.T) {
+		h := newHandler(t)
+		h.Auth = nil
+		testFailClosed(t, h)
+	})
+}
+
+// testAuthentication - request without valid token is answered with 401 before handler is called
+func testAuthentication(t *testing.T, h *user.Handler) {
+	router := authRouter(h)
+	alice := authUser(t, h, "alice")
+	expired := jwt.RegisteredClaims{Subject: alice, ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}
+
+	tests := []struct {
+		name   string
+		header string
+		code   string
+	}{
+		{"no header", "", "unauthorized"},
+		{"not bearer", "Basic YWxpY2U6c2VjcmV0", "unauthorized"},
+		{"malformed", "Bearer not-a-token", "invalid_token"},
+		{"wrong secret", "Bearer " + hs256Token(t, "other-secret", authClaims(alice)), "invalid_token"},
+		{"expired", "Bearer " + hs256Token(t, authSecret, expired), "invalid_token"},
+		{"no subject", "Bearer " + hs256Token(t, authSecret, authClaims("")), "invalid_token"},
+		{"no expiration", "Bearer " + hs256Token(t, authSecret, jwt.RegisteredClaims{Subject: alice}), "invalid_token"},
+		{"unexpected alg", "Bearer " + noneToken(t, authClaims(alice)), "invalid_token"},
+	}
+	for _, tt := range tests {
+		status, code, w := authDo(t, router, http.MethodGet, "/api/v1/users/"+alice, tt.header, nil)
+		if status != http.StatusUnauthorized || code != tt.code || w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: status %d, code %q, WWW-Authenticate %q, want %d with %s", tt.name, status, code, w.Header().Get("WWW-Authenticate"), http.StatusUnauthorized, tt.code)
+		}
+	}
+
+	// legacy alias is protected too
+	if status, _, _ := authDo(t, router, http.MethodGet, "/users/"+alice, "", nil); status != http.StatusUnauthorized {
+		t.Errorf("legacy route without token: status %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status, _, _ := authDo(t, router, http.MethodGet, "/api/v1/users/"+alice, bearer(t, alice), nil); status != http.StatusOK {
+		t.Errorf("valid token: status %d, want %d", status, http.StatusOK)
+	}
+}
+
+// testOwnership - caller changes his own user, changes of other users are forbidden
+func testOwnership(t *testing.T, h *user.Handler) {
+	router := authRouter(h)
+	alice, bob := authUser(t, h, "alice"), authUser(t, h, "bob")
+	token := bearer(t, alice)
+
+	forbidden := []struct {
+		method string
+		path   string
+		body   interface{}
+	}{
+		{http.MethodPut, "/api/v1/users/" + bob, map[string]string{"username": "bobby"}},
+		{http.MethodDelete, "/api/v1/users/" + bob, nil},
+		{http.MethodDelete, "/users", map[string]string{"target_id": bob}},
+		{http.MethodPost, "/make_friends", map[string]string{"source_id": bob, "target_id": alice}},
+		{http.MethodPost, "/api/v1/users/" + bob + "/friend_requests", map[string]string{"to_id": alice}},
+		{http.MethodGet, "/api/v1/users/" + bob + "/friend_requests", nil},
+		{http.MethodPut, "/api/v1/users/" + bob + "/blocks/" + alice, nil},
+		{http.MethodGet, "/api/v1/users/" + bob + "/mutes", nil},
+	}
+	for _, tt := range forbidden {
+		if status, code, _ := authDo(t, router, tt.method, tt.path, token, tt.body); status != http.StatusForbidden || code != "forbidden" {
+			t.Errorf("%s %s of other user: status %d, code %q, want %d with forbidden", tt.method, tt.path, status, code, http.StatusForbidden)
+		}
+	}
+	if u, err := h.UserService.GetUser(context.Background(), bob); err != nil || u.Username != "bob" || len(u.Friends) != 0 {
+		t.Errorf("bob after forbidden requests = %+v, %v, want unchanged", u, err)
+	}
+
+	allowed := []struct {
+		method string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{http.MethodPut, "/api/v1/users/" + alice, map[string]string{"username": "alicia"}, http.StatusOK},
+		{http.MethodPost, "/make_friends", map[string]string{"source_id": alice, "target_id": bob}, http.StatusCreated},
+		{http.MethodGet, "/api/v1/users/" + alice + "/friend_requests", nil, http.StatusOK},
+		{http.MethodGet, "/api/v1/users/" + bob + "/friends", nil, http.StatusOK},
+		{http.MethodDelete, "/users", map[string]string{"target_id": alice}, http.StatusOK},
+	}
+	for _, tt := range allowed {
+		if status, code, _ := authDo(t, router, tt.method, tt.path, token, tt.body); status != tt.status {
+			t.Errorf("%s %s of own user: status %d, code %q, want %d", tt.method, tt.path, status, code, tt.status)
+		}
+	}
+}
+
+// testAdmin - admin changes any user, restore is allowed only for admin
+func testAdmin(t *testing.T, h *user.Handler) {
+	router := authRouter(h)
+	alice, bob := authUser(t, h, "alice"), authUser(t, h, "bob")
+	admin := "Bearer " + adminToken(t)
+
+	if status, code, _ := authDo(t, router, http.MethodDelete, "/api/v1/users/"+bob, admin, nil); status != http.StatusOK {
+		t.Fatalf("admin deletes bob: status %d, code %q, want %d", status, code, http.StatusOK)
+	}
+	restore := "/api/v1/admin/users/" + bob + "/restore"
+	if status, code, _ := authDo(t, router, http.MethodPost, restore, bearer(t, bob), nil); status != http.StatusForbidden || code != "forbidden" {
+		t.Errorf("bob restores himself: status %d, code %q, want %d with forbidden", status, code, http.StatusForbidden)
+	}
+	if status, code, _ := authDo(t, router, http.MethodPost, restore, admin, nil); status != http.StatusOK {
+		t.Errorf("admin restores bob: status %d, code %q, want %d", status, code, http.StatusOK)
+	}
+	if status, code, _ := authDo(t, router, http.MethodPost, "/make_friends", admin, map[string]string{"source_id": alice, "target_id": bob}); status != http.StatusCreated {
+		t.Errorf("admin sends friend request: status %d, code %q, want %d", status, code, http.StatusCreated)
+	}
+}
+
+// testFailClosed - routes of handler without Auth are called without principal, so changes of users and admin routes are unauthorized
+func testFailClosed(t *testing.T, h *user.Handler) {
+	router := authRouter(h)
+	alice, bob := authUser(t, h, "alice"), authUser(t, h, "bob")
+
+	unauthorized := []struct {
+		method string
+		path   string
+		body   interface{}
+	}{
+		{http.MethodPost, "/api/v1/users", map[string]string{"username": "mallory"}},
+		{http.MethodPost, "/create", map[string]string{"username": "mallory"}},
+		{http.MethodPut, "/api/v1/users/" + alice, map[string]string{"username": "alicia"}},
+		{http.MethodPatch, "/api/v1/users/" + alice, map[string]string{"age": "31"}},
+		{http.MethodPut, "/users/" + alice, map[string]string{"age": "31"}},
+		{http.MethodDelete, "/api/v1/users/" + alice, nil},
+		{http.MethodDelete, "/users", map[string]string{"target_id": alice}},
+		{http.MethodPost, "/make_friends", map[string]string{"source_id": alice, "target_id": bob}},
+		{http.MethodPost, "/api/v1/users/" + alice + "/friend_requests", map[string]string{"to_id": bob}},
+		{http.MethodPut, "/api/v1/users/" + alice + "/blocks/" + bob, nil},
+		{http.MethodPost, "/api/v1/admin/users/" + alice + "/restore", nil},
+		{http.MethodGet, "/api/v1/admin/api_keys", nil},
+	}
+	for _, tt := range unauthorized {
+		status, code, w := authDo(t, router, tt.method, tt.path, "", tt.body)
+		if status != http.StatusUnauthorized || code != "unauthorized" || w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s %s without principal: status %d, code %q, want %d with unauthorized", tt.method, tt.path, status, code, http.StatusUnauthorized)
+		}
+	}
+	if u, err := h.UserService.GetUser(context.Background(), alice); err != nil || u.Username != "alice" || u.Age != "" || len(u.Friends) != 0 {
+		t.Errorf("alice after unauthorized requests = %+v, %v, want unchanged", u, err)
+	}
+
+	// API keys are verified without Auth too
+	_, reader, err := h.APIKeys.IssueAPIKey(context.Background(), user.APIKeyInput{Name: "reader", Scopes: []string{"users:read"}})
+	if err != nil {
+		t.Fatalf("IssueAPIKey error: %v", err)
+	}
+	keys := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		body   interface{}
+		status int
+		code   string
+	}{
+		{"unknown key", "uak_unknown", http.MethodGet, "/api/v1/users/" + alice, nil, http.StatusUnauthorized, "invalid_api_key"},
+		{"reader patches user", reader, http.MethodPatch, "/api/v1/users/" + alice, map[string]string{"age": "31"}, http.StatusForbidden, "insufficient_scope"},
+		{"reader gets user", reader, http.MethodGet, "/api/v1/users/" + alice, nil, http.StatusOK, ""},
+	}
+	for _, tt := range keys {
+		if status, code, _ := authDo(t, router, tt.method, tt.path, "ApiKey "+tt.key, tt.body); status != tt.status || code != tt.code {
+			t.Errorf("%s without Auth: status %d, code %q, want %d with %q", tt.name, status, code, tt.status, tt.code)
+		}
+	}
+}
+
+// testJWKS - RS256 tokens are verified with key of JWKS file selected by kid
+func testJWKS(t *testing.T, h *user.Handler) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "key-1",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	content, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err = ioutil.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if h.Auth, err = middleware.NewJWTAuth(middleware.JWTConfig{JWKSFile: path}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := authRouter(h)
+	alice := authUser(t, h, "alice")
+	tests := []struct {
+		name   string
+		kid    string
+		status int
+	}{
+		{"known kid", "key-1", http.StatusOK},
+		{"unknown kid", "key-2", http.StatusUnauthorized},
+		{"no kid", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, authClaims(alice))
+		if tt.kid != "" {
+			token.Header["kid"] = tt.kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status, code, _ := authDo(t, router, http.MethodGet, "/api/v1/users/"+alice, "Bearer "+signed, nil); status != tt.status {
+			t.Errorf("%s: status %d, code %q, want %d", tt.name, status, code, tt.status)
+		}
+	}
+
+	// HS256 is not accepted without secret
+	if status, _, _ := authDo(t, router, http.MethodGet, "/api/v1/users/"+alice, bearer(t, alice), nil); status != http.StatusUnauthorized {
+		t.Errorf("HS256 token without secret: status %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+// testSessions - registered user logs in with email and password, refresh token is single use and logout revokes session
+func testSessions(t *testing.T, h *user.Handler) {
+	router := authRouter(h)
+	bob := authUser(t, h, "bob")
+
+	registration := map[string]string{"username": "alice", "email": "Alice@Example.com", "password": "correct horse"}
+	status, code, w := authDo(t, router, http.MethodPost, "/api/v1/auth/register", "", registration)
+	if status != http.StatusCreated {
+		t.Fatalf("register: status %d, code %q, want %d", status, code, http.StatusCreated)
+	}
+	if body := w.Body.String(); strings.Contains(body, "password") || strings.Contains(body, "example.com") {
+		t.Errorf("registered user has credentials in answer: %s", body)
+	}
+	if status, code, _ = authDo(t, router, http.MethodPost, "/api/v1/auth/register", "", registration); status != http.StatusConflict || code != "email_exists" {
+		t.Errorf("register twice: status %d, code %q, want %d with email_exists", status, code, http.StatusConflict)
+	}
+
+	for _, credentials := range []map[string]string{
+		{"email": "alice@example.com", "password": "wrong password"},
+		{"email": "nobody@example.com", "password": "correct horse"},
+	} {
+		if status, code, _ = authDo(t, router, http.MethodPost, "/api/v1/auth/login", "", credentials); status != http.StatusUnauthorized || code != "invalid_credentials" {
+			t.Errorf("login with %v: status %d, code %q, want %d with invalid_credentials", credentials, status, code, http.StatusUnauthorized)
+		}
+	}
+	first := login(t, router, "ALICE@example.com", "correct horse")
+
+	// access token of session belongs to registered user
+	if status, code, _ = authDo(t, router, http.MethodPatch, "/api/v1/users/"+first.UserID, "Bearer "+first.AccessToken, map[string]string{"age": "30"}); status != http.StatusOK {
+		t.Errorf("alice patches herself: status %d, code %q, want %d", status, code, http.StatusOK)
+	}
+	if status, code, _ = authDo(t, router, http.MethodPatch, "/api/v1/users/"+bob, "Bearer "+first.AccessToken, map[string]string{"age": "30"}); status != http.StatusForbidden {
+		t.Errorf("alice patches bob: status %d, code %q, want %d", status, code, http.StatusForbidden)
+	}
+
+	// reuse of rotated token revokes the whole session
+	second := refresh(t, router, first.RefreshToken, http.StatusOK)
+	if second.UserID != first.UserID || second.RefreshToken == first.RefreshToken {
+		t.Errorf("refresh answered %+v, want new refresh token of %s", second, first.UserID)
+	}
+	refresh(t, router, first.RefreshToken, http.StatusUnauthorized)
+	refresh(t, router, second.RefreshToken, http.StatusUnauthorized)
+
+	// logout revokes session, other sessions are kept
+	third := login(t, router, "alice@example.com", "correct horse")
+	fourth := login(t, router, "alice@example.com", "correct horse")
+	if status, code, _ = authDo(t, router, http.MethodPost, "/api/v1/auth/logout", "", map[string]string{"refresh_token": third.RefreshToken}); status != http.StatusOK {
+		t.Errorf("logout: status %d, code %q, want %d", status, code, http.StatusOK)
+	}
+	refresh(t, router, third.RefreshToken, http.StatusUnauthorized)
+	refresh(t, router, fourth.RefreshToken, http.StatusOK)
+}
+
+// testAPIKeys - API keys are issued by admins, they are limited by scopes, only keys with admin scope change users,
+// their use is recorded and revoked keys are rejected
+func testAPIKeys(t *testing.T, h *user.Handler) {
+	if h.APIKeys == nil {
+		t.Fatal("handler has no APIKeys")
+	}
+	router := authRouter(h)
+	alice, bob := authUser(t, h, "alice"), authUser(t, h, "bob")
+	admin := "Bearer " + adminToken(t)
+
+	if status, code, _ := authDo(t, router, http.MethodPost, "/api/v1/admin/api_keys", bearer(t, alice), map[string]interface{}{"name": "alice", "scopes": []string{"admin"}}); status != http.StatusForbidden {
+		t.Errorf("alice issues api key: status %d, code %q, want %d", status, code, http.StatusForbidden)
+	}
+	reader := issueKey(t, router, admin, "reader", "users:read")
+	writer := issueKey(t, router, admin, "batch", "users:write")
+	keyAdmin := issueKey(t, router, admin, "ops", "admin")
+
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		body   interface{}
+		status int
+		code   string
+	}{
+		{"reader gets user", reader.Key, http.MethodGet, "/api/v1/users/" + alice, nil, http.StatusOK, ""},
+		{"reader patches user", reader.Key, http.MethodPatch, "/api/v1/users/" + alice, map[string]string{"age": "30"}, http.StatusForbidden, "insufficient_scope"},
+		{"writer sends friend request", writer.Key, http.MethodPost, "/make_friends", map[string]string{"source_id": alice, "target_id": bob}, http.StatusForbidden, "forbidden"},
+		{"writer patches user", writer.Key, http.MethodPatch, "/api/v1/users/" + alice, map[string]string{"age": "30"}, http.StatusForbidden, "forbidden"},
+		{"admin key sends friend request", keyAdmin.Key, http.MethodPost, "/make_friends", map[string]string{"source_id": alice, "target_id": bob}, http.StatusCreated, ""},
+		{"writer creates user", writer.Key, http.MethodPost, "/create", map[string]string{"username": "carol"}, http.StatusCreated, ""},
+		{"writer gets user", writer.Key, http.MethodGet, "/api/v1/users/" + bob, nil, http.StatusOK, ""},
+		{"writer lists keys", writer.Key, http.MethodGet, "/api/v1/admin/api_keys", nil, http.StatusForbidden, "forbidden"},
+		{"admin key lists keys", keyAdmin.Key, http.MethodGet, "/api/v1/admin/api_keys", nil, http.StatusOK, ""},
+		{"unknown key", "uak_unknown", http.MethodGet, "/api/v1/users/" + alice, nil, http.StatusUnauthorized, "invalid_api_key"},
+	}
+	for _, tt := range tests {
+		if status, code, _ := authDo(t, router, tt.method, tt.path, "ApiKey "+tt.key, tt.body); status != tt.status || (tt.code != "" && code != tt.code) {
+			t.Errorf("%s: status %d, code %q, want %d with %q", tt.name, status, code, tt.status, tt.code)
+		}
+	}
+
+	// both schemes are offered to anonymous caller
+	_, _, w := authDo(t, router, http.MethodGet, "/api/v1/users/"+alice, "", nil)
+	if challenges := strings.Join(w.Header().Values("WWW-Authenticate"), ", "); !strings.Contains(challenges, "Bearer") || !strings.Contains(challenges, "ApiKey") {
+		t.Errorf("WWW-Authenticate = %q, want Bearer and ApiKey", challenges)
+	}
+
+	// use of key is recorded, value of key is never listed
+	status, code, w := authDo(t, router, http.MethodGet, "/api/v1/admin/api_keys", admin, nil)
+	if status != http.StatusOK {
+		t.Fatalf("list api keys: status %d, code %q, want %d", status, code, http.StatusOK)
+	}
+	if strings.Contains(w.Body.String(), reader.Key) {
+		t.Errorf("list of api keys has value of key: %s", w.Body.String())
+	}
+	var list struct {
+		Data []apiKey `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range list.Data {
+		if key.ID == reader.ID && key.LastUsedAt == nil {
+			t.Errorf("key %s has no time of use", key.Name)
+		}
+	}
+
+	// revoked key is rejected
+	if status, code, _ = authDo(t, router, http.MethodDelete, "/api/v1/admin/api_keys/"+reader.ID, "ApiKey "+keyAdmin.Key, nil); status != http.StatusOK {
+		t.Fatalf("revoke api key: status %d, code %q, want %d", status, code, http.StatusOK)
+	}
+	if status, code, _ = authDo(t, router, http.MethodGet, "/api/v1/users/"+alice, "ApiKey "+reader.Key, nil); status != http.StatusUnauthorized || code != "invalid_api_key" {
+		t.Errorf("revoked key: status %d, code %q, want %d with invalid_api_key", status, code, http.StatusUnauthorized)
+	}
+}
+
+// apiKey - API key of answers of admin routes, value of key is answered only when it is issued
+type apiKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Key        string     `json:"key"`
+}
+
+// issueKey - issue API key with scopes by admin and fail test if it isn't answered with key
+func issueKey(t *testing.T, router http.Handler, admin string, name string, scopes ...string) apiKey {
+	t.Helper()
+
+	status, code, w := authDo(t, router, http.MethodPost, "/api/v1/admin/api_keys", admin, map[string]interface{}{"name": name, "scopes": scopes})
+	if status != http.StatusCreated {
+		t.Fatalf("issue api key %s: status %d, code %q, want %d", name, status, code, http.StatusCreated)
+	}
+	var answer struct {
+		Data apiKey `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &answer); err != nil || answer.Data.Key == "" {
+		t.Fatalf("answer %s has no api key: %v", w.Body.String(), err)
+	}
+	return answer.Data
+}
+
+// sessionTokens - data of answer of login and refresh
+type sessionTokens struct {
+	UserID       string `json:"user_id"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// login - log in with email and password and fail test if it isn't answered with tokens
+func login(t *testing.T, router http.Handler, email string, password string) sessionTokens {
+	t.Helper()
+
+	status, code, w := authDo(t, router, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": email, "password": password})
+	if status != http.StatusOK {
+		t.Fatalf("login of %s: status %d, code %q, want %d", email, status, code, http.StatusOK)
+	}
+	return decodeTokens(t, w)
+}
+
+// refresh - rotate refresh token, answer with wantStatus is required, rejected token is answered with invalid_refresh_token
+func refresh(t *testing.T, router http.Handler, refreshToken string, wantStatus int) sessionTokens {
+	t.Helper()
+
+	status, code, w := authDo(t, router, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{"refresh_token": refreshToken})
+	if status != wantStatus || (status == http.StatusUnauthorized && code != "invalid_refresh_token") {
+		t.Fatalf("refresh: status %d, code %q, want %d", status, code, wantStatus)
+	}
+	if status != http.StatusOK {
+		return sessionTokens{}
+	}
+	return decodeTokens(t, w)
+}
+
+// decodeTokens - tokens from envelope of answer
+func decodeTokens(t *testing.T, w *httptest.ResponseRecorder) sessionTokens {
+	t.Helper()
+
+	var answer struct {
+		Data sessionTokens `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &answer); err != nil {
+		t.Fatalf("answer with tokens is not json: %v", err)
+	}
+	if answer.Data.UserID == "" || answer.Data.AccessToken == "" || answer.Data.RefreshToken == "" {
+		t.Fatalf("answer %s has no tokens", w.Body.String())
+	}
+	return answer.Data
+}
+
+// setAuth - set HS256 authentication with secret of tests, if handler has no authentication
+func setAuth(t *testing.T, h *user.Handler) {
+	t.Helper()
+
+	if h.Auth != nil {
+		return
+	}
+	auth, err := middleware.NewJWTAuth(middleware.JWTConfig{Secret: authSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Auth = auth
+}
+
+// adminToken - HS256 token of admin
+func adminToken(t *testing.T) string {
+	return hs256Token(t, authSecret, authClaims("admin-1", middleware.RoleAdmin))
+}
+
+// authRouter - router with routes of handler
+func authRouter(h *user.Handler) *httprouter.Router {
+	router := httprouter.New()
+	h.Register(router)
+	return router
+}
+
+// authUser - create user through service of handler
+func authUser(t *testing.T, h *user.Handler, username string) string {
+	t.Helper()
+
+	id, err := h.UserService.Create(context.Background(), user.User{Username: username})
+	if err != nil {
+		t.Fatalf("Create(%s) error: %v", username, err)
+	}
+	return id
+}
+
+// authClaims - claims of token of user with roles, token expires in an hour
+func authClaims(subject string, roles ...string) jwt.Claims {
+	return struct {
+		Roles []string `json:"roles,omitempty"`
+		jwt.RegisteredClaims
+	}{roles, jwt.RegisteredClaims{Subject: subject, ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}}
+}
+
+// hs256Token - token signed with secret
+func hs256Token(t *testing.T, secret string, claims jwt.Claims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// noneToken - unsigned token with alg none
+func noneToken(t *testing.T, claims jwt.Claims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// bearer - authorization header of user with HS256 token of tests
+func bearer(t *testing.T, userID string) string {
+	return "Bearer " + hs256Token(t, authSecret, authClaims(userID))
+}
+
+// authDo - call route with authorization header and json body, get status and code of error
+func authDo(t *testing.T, router http.Handler, method string, path string, authorization string, body interface{}) (int, string, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	var content []byte
+	if body != nil {
+		var err error
+		if content, err = json.Marshal(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := httptest.NewRequest(method, path, bytes.NewReader(content))
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	var answer struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &answer)
+	return w.Code, answer.Error.Code, w
+}
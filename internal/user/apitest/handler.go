@@ -0,0 +1,37 @@
+package apitest
+
+// file for building handler of suites and of transport tests of other packages
+
+import (
+	"project/internal/middleware"
+	"project/internal/user"
+	"project/internal/user/memory"
+	"project/pkg/logging"
+	"testing"
+	"time"
+)
+
+// MemoryHandler - handler with services on empty memory storages, its Auth verifies and signs HS256 tokens with secret of suites
+func MemoryHandler(t *testing.T) *user.Handler {
+	logger := logging.GetLogger()
+	users := memory.NewStorage(logger)
+	service, err := user.NewService(users, memory.NewRequestStorage(logger), memory.NewRelationStorage(logger), *logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authService, err := user.NewAuthService(users, memory.NewTokenStorage(logger), time.Hour, *logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := middleware.NewJWTAuth(middleware.JWTConfig{Secret: authSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &user.Handler{
+		Logger:      logger,
+		UserService: service,
+		Auth:        auth,
+		AuthService: authService,
+		APIKeys:     user.NewAPIKeyService(memory.NewAPIKeyStorage(logger), *logger),
+	}
+}
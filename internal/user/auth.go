@@ -0,0 +1,43 @@
+package user
+
+// file for authorization of callers authenticated by middleware.Authenticate or by interceptor of gRPC server
+
+import (
+	"context"
+	"fmt"
+	"project/internal/middleware"
+)
+
+// AuthorizeCaller - ErrUnauthorized if context has no principal, so users are created only by authenticated callers
+func AuthorizeCaller(ctx context.Context) error {
+	if _, ok := middleware.PrincipalFrom(ctx); !ok {
+		return fmt.Errorf("call without principal: %w", ErrUnauthorized)
+	}
+	return nil
+}
+
+// AuthorizeUser - ErrForbidden if caller is neither user with userID nor admin, ErrUnauthorized if context has no principal,
+// so route or call without authentication changes nobody.
+// API keys belong to no user, so only keys with admin scope act on users
+func AuthorizeUser(ctx context.Context, userID string) error {
+	principal, ok := middleware.PrincipalFrom(ctx)
+	if !ok {
+		return fmt.Errorf("change of user %s without principal: %w", userID, ErrUnauthorized)
+	}
+	if principal.Subject == userID || principal.IsAdmin() {
+		return nil
+	}
+	return fmt.Errorf("user %s can't change user %s: %w", principal.Subject, userID, ErrForbidden)
+}
+
+// AuthorizeAdmin - ErrForbidden if caller isn't admin, ErrUnauthorized if context has no principal
+func AuthorizeAdmin(ctx context.Context) error {
+	principal, ok := middleware.PrincipalFrom(ctx)
+	if !ok {
+		return fmt.Errorf("admin call without principal: %w", ErrUnauthorized)
+	}
+	if principal.IsAdmin() {
+		return nil
+	}
+	return fmt.Errorf("user %s is not admin: %w", principal.Subject, ErrForbidden)
+}
@@ -0,0 +1,183 @@
+package user
+
+// file for handle of registration, login and refresh tokens
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"io/ioutil"
+	"net/http"
+	"project/internal/middleware"
+	"project/internal/response"
+	"project/pkg/validator"
+	"time"
+)
+
+// constants for auth path, they have no legacy aliases
+const (
+	registerURL = "/auth/register"
+	loginURL    = "/auth/login"
+	refreshURL  = "/auth/refresh"
+	logoutURL   = "/auth/logout"
+)
+
+// tokens - data of answer with access token and refresh token of session
+type tokens struct {
+	UserID           string    `json:"user_id"`
+	AccessToken      string    `json:"access_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresIn        int64     `json:"expires_in"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// errAuthUnavailable - auth routes of handler without AuthService answer internal error
+var errAuthUnavailable = errors.New("auth service is not configured")
+
+// loggedOut - data of answer for revoked session
+type loggedOut struct {
+	UserID string `json:"user_id"`
+}
+
+// registerAuth - func for init routs of auth, they are called without bearer token
+func (h *Handler) registerAuth(router *httprouter.Router) {
+	h.handleAnonymous(router, http.MethodPost, registerURL, h.RegisterUser)
+	h.handleAnonymous(router, http.MethodPost, loginURL, h.Login)
+	h.handleAnonymous(router, http.MethodPost, refreshURL, h.RefreshToken)
+	h.handleAnonymous(router, http.MethodPost, logoutURL, h.Logout)
+}
+
+// RegisterUser - creating user with email and password from body
+func (h *Handler) RegisterUser(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Register user")
+
+	if h.AuthService == nil {
+		return errAuthUnavailable
+	}
+
+	// registration is validated by service after normalization of email
+	var registration Registration
+	if err := readBody(r, &registration); err != nil {
+		return err
+	}
+
+	user, err := h.AuthService.Register(r.Context(), registration)
+	if err != nil {
+		return err
+	}
+
+	// answer with created user, credentials are never answered
+	return response.JSON(w, http.StatusCreated, user, nil)
+}
+
+// Login - starting session of user with email and password from body
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Login")
+	if h.AuthService == nil {
+		return errAuthUnavailable
+	}
+
+	type body struct {
+		Email    string `json:"email" validate:"required"`
+		Password string `json:"password" validate:"required"`
+	}
+	var message body
+	if err := readBody(r, &message); err != nil {
+		return err
+	}
+	if err := validator.Validate(message); err != nil {
+		return err
+	}
+
+	session, err := h.AuthService.Login(r.Context(), message.Email, message.Password)
+	if err != nil {
+		return err
+	}
+	return h.answerSession(w, session)
+}
+
+// RefreshToken - rotation of refresh token from body, answer has new pair of tokens
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Refresh token")
+	if h.AuthService == nil {
+		return errAuthUnavailable
+	}
+
+	type body struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+	var message body
+	if err := readBody(r, &message); err != nil {
+		return err
+	}
+	if err := validator.Validate(message); err != nil {
+		return err
+	}
+
+	session, err := h.AuthService.Refresh(r.Context(), message.RefreshToken)
+	if err != nil {
+		return err
+	}
+	return h.answerSession(w, session)
+}
+
+// Logout - revoking session of refresh token from body, access tokens live until they expire
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Logout")
+	if h.AuthService == nil {
+		return errAuthUnavailable
+	}
+
+	type body struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+	var message body
+	if err := readBody(r, &message); err != nil {
+		return err
+	}
+	if err := validator.Validate(message); err != nil {
+		return err
+	}
+
+	userID, err := h.AuthService.Logout(r.Context(), message.RefreshToken)
+	if err != nil {
+		return err
+	}
+	return response.JSON(w, http.StatusOK, loggedOut{UserID: userID}, nil)
+}
+
+// answerSession - answer with access token of session user signed by Auth
+func (h *Handler) answerSession(w http.ResponseWriter, session Session) error {
+	if h.Auth == nil {
+		return errors.New("access tokens can't be signed without auth")
+	}
+	accessToken, expiresAt, err := h.Auth.Sign(middleware.Principal{Subject: session.UserID})
+	if err != nil {
+		return err
+	}
+
+	return response.JSON(w, http.StatusOK, tokens{
+		UserID:           session.UserID,
+		AccessToken:      accessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(time.Until(expiresAt).Round(time.Second) / time.Second),
+		RefreshToken:     session.RefreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil)
+}
+
+// readBody - decode json body of request into message
+func readBody(r *http.Request, message interface{}) error {
+	content, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return &ValidationError{Message: fmt.Sprintf("failed to read body: %v", err)}
+	}
+	defer r.Body.Close()
+
+	if err := json.Unmarshal(content, message); err != nil {
+		return &ValidationError{Message: fmt.Sprintf("invalid json: %v", err)}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package user
+
+// file for registration, login and refresh tokens of users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"project/pkg/logging"
+	"project/pkg/validator"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxPasswordBytes - bcrypt uses only the first 72 bytes of password, longer passwords are rejected
+const maxPasswordBytes = 72
+
+// Registration - new user with credentials
+type Registration struct {
+	Username string `json:"username" validate:"required,min=3,max=32"`
+	Age      string `json:"age" validate:"omitempty,numeric,gte=0,lte=150"`
+	Email    string `json:"email" validate:"required,max=254,email"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// Session - refresh token issued to user by login or by rotation of previous token
+type Session struct {
+	UserID       string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// AuthService - interface for registration and login of users, access tokens are signed by caller
+type AuthService interface {
+	// Register - create user with email and hash of password
+	Register(ctx context.Context, registration Registration) (User, error)
+	// Login - new session of user with email and password, ErrInvalidCredentials otherwise
+	Login(ctx context.Context, email string, password string) (Session, error)
+	// Refresh - revoke refresh token and issue new one of the same session, ErrInvalidRefreshToken for unknown,
+	// expired or revoked token, reuse of revoked token revokes the whole session
+	Refresh(ctx context.Context, refreshToken string) (Session, error)
+	// Logout - revoke all refresh tokens of session and get id of its user, ErrInvalidRefreshToken for unknown token
+	Logout(ctx context.Context, refreshToken string) (string, error)
+}
+
+// authService struct with logging
+type authService struct {
+	storage    Storage
+	tokens     TokenStorage
+	refreshTTL time.Duration
+	logger     logging.Logger
+
+	// dummyHash - hash which is compared for unknown email, so login takes the same time for known and unknown users
+	dummyHash []byte
+}
+
+// NewAuthService - func for initialization auth-service, refresh tokens live refreshTTL
+func NewAuthService(userStorage Storage, tokenStorage TokenStorage, refreshTTL time.Duration, logger logging.Logger) (AuthService, error) {
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash dummy password. error: %w", err)
+	}
+	return &authService{
+		storage:    userStorage,
+		tokens:     tokenStorage,
+		refreshTTL: refreshTTL,
+		logger:     logger,
+		dummyHash:  dummyHash,
+	}, nil
+}
+
+// Register - func for creating user with credentials, email is kept in lower case
+func (s authService) Register(ctx context.Context, registration Registration) (User, error) {
+	s.logger.Info("register user")
+	registration.Email = normalizeEmail(registration.Email)
+	if err := validator.Validate(registration); err != nil {
+		return User{}, err
+	}
+	if len(registration.Password) > maxPasswordBytes {
+		return User{}, &ValidationError{Field: "password", Message: fmt.Sprintf("must be at most %d bytes", maxPasswordBytes)}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(registration.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, fmt.Errorf("failed to hash password. error: %w", err)
+	}
+	user := User{Username: registration.Username, Age: registration.Age, Email: registration.Email, PasswordHash: string(hash)}
+	id, err := s.storage.Create(ctx, user)
+	if err != nil {
+		return User{}, fmt.Errorf("failed to register user. error: %w", err)
+	}
+	if user, err = s.storage.FindOne(ctx, id); err != nil {
+		return User{}, fmt.Errorf("failed to register user. error: %w", err)
+	}
+	return user, nil
+}
+
+// Login - func for checking password of user with email and starting new session
+func (s authService) Login(ctx context.Context, email string, password string) (Session, error) {
+	user, err := s.storage.FindByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return Session{}, fmt.Errorf("failed to login. error: %w", err)
+		}
+		_ = bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return Session{}, fmt.Errorf("failed to login. error: %w", ErrInvalidCredentials)
+	}
+	if err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return Session{}, fmt.Errorf("failed to login user %s. error: %w", user.ID, ErrInvalidCredentials)
+	}
+
+	family, err := randomString(16)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to login. error: %w", err)
+	}
+	return s.issue(ctx, user.ID, family)
+}
+
+// Refresh - func for rotation of refresh token, user must still exist
+func (s authService) Refresh(ctx context.Context, refreshToken string) (Session, error) {
+	token, err := s.findToken(ctx, refreshToken)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to refresh token. error: %w", err)
+	}
+
+	now := requestTime()
+	if token.RevokedAt != nil {
+		s.logger.Warnf("revoked refresh token of user %s is reused, session is revoked", token.UserID)
+		return Session{}, s.revokeFamily(ctx, token, now)
+	}
+	if !token.Active(now) {
+		return Session{}, fmt.Errorf("failed to refresh token. error: refresh token is expired: %w", ErrInvalidRefreshToken)
+	}
+	if err = s.tokens.RevokeToken(ctx, token.Hash, now); err != nil {
+		if errors.Is(err, ErrTokenRevoked) {
+			// the same token is refreshed concurrently, only one of callers gets new token
+			s.logger.Warnf("refresh token of user %s is refreshed twice, session is revoked", token.UserID)
+			return Session{}, s.revokeFamily(ctx, token, now)
+		}
+		return Session{}, fmt.Errorf("failed to revoke refresh token. error: %w", err)
+	}
+
+	// deleted user has no sessions
+	if _, err = s.storage.FindOne(ctx, token.UserID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Session{}, s.revokeFamily(ctx, token, now)
+		}
+		return Session{}, fmt.Errorf("failed to refresh token. error: %w", err)
+	}
+	return s.issue(ctx, token.UserID, token.FamilyID)
+}
+
+// Logout - func for revoking session of refresh token, logout of revoked session is not an error
+func (s authService) Logout(ctx context.Context, refreshToken string) (string, error) {
+	token, err := s.findToken(ctx, refreshToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to logout. error: %w", err)
+	}
+	if err = s.tokens.RevokeFamily(ctx, token.FamilyID, requestTime()); err != nil {
+		return "", fmt.Errorf("failed to logout. error: %w", err)
+	}
+	return token.UserID, nil
+}
+
+// issue - save new refresh token of session
+func (s authService) issue(ctx context.Context, userID string, family string) (Session, error) {
+	value, err := randomString(32)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to issue refresh token. error: %w", err)
+	}
+	now := requestTime()
+	token := RefreshToken{
+		Hash:      hashToken(value),
+		UserID:    userID,
+		FamilyID:  family,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.refreshTTL),
+	}
+	if err = s.tokens.SaveToken(ctx, token); err != nil {
+		return Session{}, fmt.Errorf("failed to issue refresh token. error: %w", err)
+	}
+	return Session{UserID: userID, RefreshToken: value, ExpiresAt: token.ExpiresAt}, nil
+}
+
+// findToken - stored token by its value, ErrInvalidRefreshToken for unknown token
+func (s authService) findToken(ctx context.Context, refreshToken string) (RefreshToken, error) {
+	if refreshToken == "" {
+		return RefreshToken{}, &ValidationError{Field: "refresh_token", Message: "is required"}
+	}
+	token, err := s.tokens.FindToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return RefreshToken{}, fmt.Errorf("%v: %w", err, ErrInvalidRefreshToken)
+		}
+		return RefreshToken{}, err
+	}
+	return token, nil
+}
+
+// revokeFamily - revoke session of token, ErrInvalidRefreshToken if it is revoked
+func (s authService) revokeFamily(ctx context.Context, token RefreshToken, now time.Time) error {
+	if err := s.tokens.RevokeFamily(ctx, token.FamilyID, now); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens of user %s. error: %w", token.UserID, err)
+	}
+	return fmt.Errorf("failed to refresh token of user %s. error: %w", token.UserID, ErrInvalidRefreshToken)
+}
+
+// normalizeEmail - emails are compared in lower case without surrounding spaces
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// hashToken - hash of refresh token under which it is stored, token has enough entropy for sha256 without salt
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString - url safe string of n random bytes
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to read random bytes. error: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,202 @@
+package bolt
+
+// file for keeping API keys in BoltDB file
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// buckets of API keys, key of apiKeysBucket is big endian id and value is key in json,
+// apiKeyHashesBucket keeps id of key by hash of its value
+var (
+	apiKeysBucket      = []byte("api_keys")
+	apiKeyHashesBucket = []byte("api_key_hashes")
+)
+
+// apiKeyRecord - API key as it is stored, hash isn't answered in json of user.APIKey
+type apiKeyRecord struct {
+	user.APIKey
+	Hash string `json:"hash"`
+}
+
+// Create API keys storage structure
+type apiKeyStorage struct {
+	client *bbolt.DB
+	logger *logging.Logger
+}
+
+// NewAPIKeyStorage - Initialize new storage of API keys and create buckets if not exist
+func NewAPIKeyStorage(client *bbolt.DB, logger *logging.Logger) (user.APIKeyStorage, error) {
+	err := client.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{apiKeysBucket, apiKeyHashesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create boltDB buckets due to error: %v", err)
+	}
+
+	return &apiKeyStorage{
+		client: client,
+		logger: logger,
+	}, nil
+}
+
+// CreateAPIKey - create new API key with entry of its hash
+func (s *apiKeyStorage) CreateAPIKey(ctx context.Context, k user.APIKey) (user.APIKey, error) {
+	s.logger.Debug("create api key")
+
+	err := s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(apiKeysBucket)
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return fmt.Errorf("failed to create api key due to error: %v", err)
+		}
+		k.ID = strconv.FormatUint(id, 10)
+
+		if err = tx.Bucket(apiKeyHashesBucket).Put([]byte(k.Hash), encodeKey(id)); err != nil {
+			return fmt.Errorf("failed to create api key due to error: %v", err)
+		}
+		return putAPIKey(bucket, id, k)
+	})
+	if err != nil {
+		return user.APIKey{}, err
+	}
+
+	return k, nil
+}
+
+// FindAPIKey - find API key by hash
+func (s *apiKeyStorage) FindAPIKey(ctx context.Context, hash string) (k user.APIKey, err error) {
+	err = s.client.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(apiKeyHashesBucket).Get([]byte(hash))
+		if data == nil {
+			return fmt.Errorf("failed to find api key (hash:%.8s): %w", hash, user.ErrAPIKeyNotFound)
+		}
+		id := binary.BigEndian.Uint64(data)
+		var ok bool
+		if k, ok, err = getAPIKey(tx.Bucket(apiKeysBucket), id); err != nil || !ok {
+			return apiKeyNotFound(strconv.FormatUint(id, 10), err)
+		}
+		return nil
+	})
+
+	return k, err
+}
+
+// FindAPIKeys - find all API keys, newer keys go first
+func (s *apiKeyStorage) FindAPIKeys(ctx context.Context) ([]user.APIKey, error) {
+	keys := []user.APIKey{}
+	err := s.client.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(apiKeysBucket).ForEach(func(k, v []byte) error {
+			key, err := decodeAPIKey(v)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api keys due to error: %v", err)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return user.NewerAPIKey(keys[i], keys[j])
+	})
+
+	return keys, nil
+}
+
+// RevokeAPIKey - revoke API key in one transaction, time of the first revoke is kept
+func (s *apiKeyStorage) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) (k user.APIKey, err error) {
+	err = s.updateAPIKey(id, func(k *user.APIKey) {
+		if k.RevokedAt == nil {
+			k.RevokedAt = &revokedAt
+		}
+	}, &k)
+
+	return k, err
+}
+
+// TouchAPIKey - save time of the last use of API key
+func (s *apiKeyStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	var k user.APIKey
+	return s.updateAPIKey(id, func(k *user.APIKey) {
+		k.LastUsedAt = &usedAt
+	}, &k)
+}
+
+// updateAPIKey - change API key with id in one transaction and get changed key
+func (s *apiKeyStorage) updateAPIKey(id string, change func(k *user.APIKey), changed *user.APIKey) error {
+	key, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return apiKeyNotFound(id, nil)
+	}
+
+	return s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(apiKeysBucket)
+
+		k, ok, err := getAPIKey(bucket, key)
+		if err != nil || !ok {
+			return apiKeyNotFound(id, err)
+		}
+		change(&k)
+		if err = putAPIKey(bucket, key, k); err != nil {
+			return fmt.Errorf("failed to update api key (id:%s) due to error: %v", id, err)
+		}
+		*changed = k
+		return nil
+	})
+}
+
+// getAPIKey - get API key by id, ok is false if there is none
+func getAPIKey(bucket *bbolt.Bucket, id uint64) (user.APIKey, bool, error) {
+	data := bucket.Get(encodeKey(id))
+	if data == nil {
+		return user.APIKey{}, false, nil
+	}
+	k, err := decodeAPIKey(data)
+	return k, err == nil, err
+}
+
+// decodeAPIKey - API key with hash from its record
+func decodeAPIKey(data []byte) (user.APIKey, error) {
+	var record apiKeyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return user.APIKey{}, err
+	}
+	record.APIKey.Hash = record.Hash
+	return record.APIKey, nil
+}
+
+// putAPIKey - save API key with hash by id
+func putAPIKey(bucket *bbolt.Bucket, id uint64, k user.APIKey) error {
+	data, err := json.Marshal(apiKeyRecord{APIKey: k, Hash: k.Hash})
+	if err != nil {
+		return err
+	}
+	return bucket.Put(encodeKey(id), data)
+}
+
+// apiKeyNotFound - error for API key which doesn't exist or can't be decoded
+func apiKeyNotFound(id string, err error) error {
+	if err != nil {
+		return fmt.Errorf("failed to decode api key (id:%s) due to error: %v", id, err)
+	}
+	return fmt.Errorf("failed to find api key (id:%s): %w", id, user.ErrAPIKeyNotFound)
+}
@@ -0,0 +1,602 @@
+// Package bolt for keeping users in embedded BoltDB file (single-node deployments)
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// bucket with users, key is big endian id and value is user in json
+var usersBucket = []byte("users")
+
+// bucket with ids of users by email, it keeps email unique among all users, deleted ones included
+var emailsBucket = []byte("emails")
+
+// Create storage structure
+type storage struct {
+	client *bbolt.DB
+	logger *logging.Logger
+}
+
+// NewStorage - Initialize new storage and create buckets if not exist
+func NewStorage(client *bbolt.DB, logger *logging.Logger) (user.Storage, error) {
+	err := client.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{usersBucket, emailsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create boltDB buckets due to error: %v", err)
+	}
+
+	return &storage{
+		client: client,
+		logger: logger,
+	}, nil
+}
+
+// Create - create new user in file
+func (s *storage) Create(ctx context.Context, u user.User) (string, error) {
+	s.logger.Debug("create user")
+
+	err := s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		u.ID = strconv.FormatUint(id, 10)
+
+		// friends are made only by MakeFriends
+		u.Friends = nil
+
+		if u.Email != "" {
+			emails := tx.Bucket(emailsBucket)
+			if emails.Get([]byte(u.Email)) != nil {
+				return fmt.Errorf("failed to create user with email %s: %w", u.Email, user.ErrEmailExists)
+			}
+			if err = emails.Put([]byte(u.Email), encodeKey(id)); err != nil {
+				return err
+			}
+		}
+		return putUser(bucket, id, u)
+	})
+	if err != nil {
+		if errors.Is(err, user.ErrEmailExists) {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to create user due to error: %v", err)
+	}
+
+	return u.ID, nil
+}
+
+// FindOne - find user by id
+func (s *storage) FindOne(ctx context.Context, id string) (u user.User, err error) {
+	key, err := parseID(id)
+	if err != nil {
+		return u, err
+	}
+
+	err = s.client.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+
+		var ok bool
+		if u, ok, err = getActive(bucket, key); err != nil || !ok {
+			return notFound(id, err)
+		}
+		u, err = activeFriends(bucket, u)
+		return err
+	})
+
+	return u, err
+}
+
+// FindByEmail - find user with credentials by email
+func (s *storage) FindByEmail(ctx context.Context, email string) (u user.User, err error) {
+	err = s.client.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+
+		var ok bool
+		if data := tx.Bucket(emailsBucket).Get([]byte(email)); data != nil {
+			if u, ok, err = getActive(bucket, binary.BigEndian.Uint64(data)); err != nil {
+				return fmt.Errorf("failed to decode user (email:%s) due to error: %v", email, err)
+			}
+		}
+		if !ok {
+			return fmt.Errorf("failed to find user (email:%s): %w", email, user.ErrNotFound)
+		}
+		u, err = activeFriends(bucket, u)
+		return err
+	})
+
+	return u, err
+}
+
+// FindAll - find page of users matched by filter and total number of matched users
+func (s *storage) FindAll(ctx context.Context, filter user.Filter) ([]user.User, int64, error) {
+	var matched []user.User
+	err := s.client.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+		return bucket.ForEach(func(k, v []byte) error {
+			u, err := decodeUser(v)
+			if err != nil {
+				return err
+			}
+			if u.DeletedAt != nil || !filter.Match(u) {
+				return nil
+			}
+			u, err = activeFriends(bucket, u)
+			if err != nil {
+				return err
+			}
+			matched = append(matched, u)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find users due to error: %v", err)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return filter.Less(matched[i], matched[j])
+	})
+
+	return filter.Page(matched), int64(len(matched)), nil
+}
+
+// GetUserFriends - get all friends from one user with usernames resolved by ids
+func (s *storage) GetUserFriends(ctx context.Context, id string) (friends []user.Friend, err error) {
+	key, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.client.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+		u, ok, err := getActive(bucket, key)
+		if err != nil || !ok {
+			return notFound(id, err)
+		}
+
+		friends = make([]user.Friend, 0, len(u.Friends))
+		for _, friendID := range u.Friends {
+			friendKey, err := parseID(friendID)
+			if err != nil {
+				return err
+			}
+			friend, ok, err := getActive(bucket, friendKey)
+			if err != nil {
+				return notFound(friendID, err)
+			}
+			if ok {
+				friends = append(friends, user.Friend{ID: friend.ID, Username: friend.Username})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return friends, nil
+}
+
+// UpdateAge - func update age of one user, returns updated user
+func (s *storage) UpdateAge(ctx context.Context, id string, age string) (u user.User, err error) {
+	key, err := parseID(id)
+	if err != nil {
+		return u, err
+	}
+
+	err = s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+
+		var ok bool
+		if u, ok, err = getActive(bucket, key); err != nil || !ok {
+			return notFound(id, err)
+		}
+		u.Age = age
+		if err = putUser(bucket, key, u); err != nil {
+			return fmt.Errorf("failed to execute update user query. error: %v", err)
+		}
+		u, err = activeFriends(bucket, u)
+		return err
+	})
+
+	return u, err
+}
+
+// Update - replace username and age of one user, friends keep ids so renames need nothing else, returns updated user
+func (s *storage) Update(ctx context.Context, id string, u user.User) (updated user.User, err error) {
+	key, err := parseID(id)
+	if err != nil {
+		return updated, err
+	}
+
+	err = s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+
+		var ok bool
+		if updated, ok, err = getActive(bucket, key); err != nil || !ok {
+			return notFound(id, err)
+		}
+		updated.Username = u.Username
+		updated.Age = u.Age
+		if err = putUser(bucket, key, updated); err != nil {
+			return fmt.Errorf("failed to execute update user query. error: %v", err)
+		}
+		updated, err = activeFriends(bucket, updated)
+		return err
+	})
+
+	return updated, err
+}
+
+// Delete - func for soft delete of user, friends of user and other users are not changed
+func (s *storage) Delete(ctx context.Context, id string, deletedAt time.Time) error {
+	key, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+
+		u, ok, err := getActive(bucket, key)
+		if err != nil || !ok {
+			return notFound(id, err)
+		}
+		u.DeletedAt = &deletedAt
+		if err = putUser(bucket, key, u); err != nil {
+			return fmt.Errorf("failed to execute query. error: %v", err)
+		}
+		s.logger.Tracef("Deleted user %s", id)
+
+		return nil
+	})
+}
+
+// Restore - func for bring soft deleted user back with his friends, returns restored user
+func (s *storage) Restore(ctx context.Context, id string) (u user.User, err error) {
+	key, err := parseID(id)
+	if err != nil {
+		return u, err
+	}
+
+	err = s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+
+		var ok bool
+		if u, ok, err = getUser(bucket, key); err != nil || !ok {
+			return notFound(id, err)
+		}
+		if u.DeletedAt == nil {
+			return fmt.Errorf("failed to restore user (id:%s): %w", id, user.ErrNotDeleted)
+		}
+		u.DeletedAt = nil
+		if err = putUser(bucket, key, u); err != nil {
+			return fmt.Errorf("failed to execute restore user query. error: %v", err)
+		}
+		u, err = activeFriends(bucket, u)
+		return err
+	})
+
+	return u, err
+}
+
+// FindDeleted - find ids of users deleted at or before time
+func (s *storage) FindDeleted(ctx context.Context, before time.Time) ([]string, error) {
+	ids := []string{}
+	err := s.client.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(usersBucket).ForEach(func(k, v []byte) error {
+			u, err := decodeUser(v)
+			if err != nil {
+				return err
+			}
+			if u.DeletedAt != nil && !u.DeletedAt.After(before) {
+				ids = append(ids, u.ID)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find deleted users due to error: %v", err)
+	}
+
+	return ids, nil
+}
+
+// Purge - func for delete soft deleted user from file and from friends of other users in one transaction
+func (s *storage) Purge(ctx context.Context, id string) error {
+	key, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+		u, ok, err := getUser(bucket, key)
+		if err != nil || !ok || u.DeletedAt == nil {
+			return notFound(id, err)
+		}
+		id = strconv.FormatUint(key, 10)
+
+		// find users with id in friends, bucket can't be changed inside ForEach
+		modified := make(map[uint64]user.User)
+		err = bucket.ForEach(func(k, v []byte) error {
+			other, err := decodeUser(v)
+			if err != nil {
+				return err
+			}
+			friends := removeFriend(other.Friends, id)
+			if len(friends) != len(other.Friends) {
+				other.Friends = friends
+				modified[binary.BigEndian.Uint64(k)] = other
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to find other users friends. error: %v", err)
+		}
+
+		// pull id from friends of other users
+		for otherKey, other := range modified {
+			if err = putUser(bucket, otherKey, other); err != nil {
+				return fmt.Errorf("failed delete from other users friends. error: %v", err)
+			}
+		}
+		s.logger.Tracef("Modified %d users", len(modified))
+
+		if err = bucket.Delete(encodeKey(key)); err != nil {
+			return fmt.Errorf("failed to execute query. error: %v", err)
+		}
+		if u.Email != "" {
+			if err = tx.Bucket(emailsBucket).Delete([]byte(u.Email)); err != nil {
+				return fmt.Errorf("failed to delete email of user. error: %v", err)
+			}
+		}
+		s.logger.Tracef("Purged user %s", id)
+
+		return nil
+	})
+}
+
+// MakeFriends - func for add users ids to friends of each other in one transaction, ids are not duplicated, returns updated users
+func (s *storage) MakeFriends(ctx context.Context, firstUserID string, secondUserID string) (firstUser user.User, secondUser user.User, err error) {
+	firstKey, err := parseID(firstUserID)
+	if err != nil {
+		return firstUser, secondUser, err
+	}
+	secondKey, err := parseID(secondUserID)
+	if err != nil {
+		return firstUser, secondUser, err
+	}
+
+	err = s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+
+		var ok bool
+		if firstUser, ok, err = getActive(bucket, firstKey); err != nil || !ok {
+			return notFound(firstUserID, err)
+		}
+		if secondUser, ok, err = getActive(bucket, secondKey); err != nil || !ok {
+			return notFound(secondUserID, err)
+		}
+
+		// friendship in only one direction is completed below, instead of conflict
+		if contains(firstUser.Friends, secondUser.ID) && contains(secondUser.Friends, firstUser.ID) {
+			return fmt.Errorf("failed to make friends %s and %s: %w", firstUserID, secondUserID, user.ErrAlreadyFriends)
+		}
+
+		firstUser.Friends = addFriend(firstUser.Friends, secondUser.ID)
+		if err = putUser(bucket, firstKey, firstUser); err != nil {
+			return fmt.Errorf("failed to add friend to friends. error: %v", err)
+		}
+
+		// re-read second user in case both ids are the same
+		if secondUser, _, err = getUser(bucket, secondKey); err != nil {
+			return err
+		}
+		secondUser.Friends = addFriend(secondUser.Friends, firstUser.ID)
+		if err = putUser(bucket, secondKey, secondUser); err != nil {
+			return fmt.Errorf("failed to add friend to friends. error: %v", err)
+		}
+
+		// first user is read again for the same reason
+		if firstUser, _, err = getUser(bucket, firstKey); err != nil {
+			return err
+		}
+		if firstUser, err = activeFriends(bucket, firstUser); err != nil {
+			return err
+		}
+		secondUser, err = activeFriends(bucket, secondUser)
+		return err
+	})
+
+	return firstUser, secondUser, err
+}
+
+// Unfriend - func for remove users ids from friends of each other in one transaction, friendship in one direction is removed too, returns updated users
+func (s *storage) Unfriend(ctx context.Context, firstUserID string, secondUserID string) (firstUser user.User, secondUser user.User, err error) {
+	firstKey, err := parseID(firstUserID)
+	if err != nil {
+		return firstUser, secondUser, err
+	}
+	secondKey, err := parseID(secondUserID)
+	if err != nil {
+		return firstUser, secondUser, err
+	}
+
+	err = s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+
+		var ok bool
+		if firstUser, ok, err = getActive(bucket, firstKey); err != nil || !ok {
+			return notFound(firstUserID, err)
+		}
+		if secondUser, ok, err = getActive(bucket, secondKey); err != nil || !ok {
+			return notFound(secondUserID, err)
+		}
+
+		if !contains(firstUser.Friends, secondUser.ID) && !contains(secondUser.Friends, firstUser.ID) {
+			return fmt.Errorf("failed to unfriend %s and %s: %w", firstUserID, secondUserID, user.ErrNotFriends)
+		}
+
+		firstUser.Friends = removeFriend(firstUser.Friends, secondUser.ID)
+		if err = putUser(bucket, firstKey, firstUser); err != nil {
+			return fmt.Errorf("failed to remove friend from friends. error: %v", err)
+		}
+		secondUser.Friends = removeFriend(secondUser.Friends, firstUser.ID)
+		if err = putUser(bucket, secondKey, secondUser); err != nil {
+			return fmt.Errorf("failed to remove friend from friends. error: %v", err)
+		}
+
+		if firstUser, err = activeFriends(bucket, firstUser); err != nil {
+			return err
+		}
+		secondUser, err = activeFriends(bucket, secondUser)
+		return err
+	})
+
+	return firstUser, secondUser, err
+}
+
+// getUser - read and decode user by key, ok is false if user not exists
+func getUser(bucket *bbolt.Bucket, key uint64) (u user.User, ok bool, err error) {
+	data := bucket.Get(encodeKey(key))
+	if data == nil {
+		return u, false, nil
+	}
+	if u, err = decodeUser(data); err != nil {
+		return u, false, err
+	}
+	return u, true, nil
+}
+
+// record - user in file with credentials, which are hidden from json of user
+type record struct {
+	user.User
+	Email        string `json:"email,omitempty"`
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// decodeUser - decode user with credentials
+func decodeUser(data []byte) (user.User, error) {
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return user.User{}, err
+	}
+	r.User.Email, r.User.PasswordHash = r.Email, r.PasswordHash
+	return r.User, nil
+}
+
+// getActive - read and decode user by key, ok is false if user not exists or is deleted
+func getActive(bucket *bbolt.Bucket, key uint64) (u user.User, ok bool, err error) {
+	if u, ok, err = getUser(bucket, key); err != nil || !ok {
+		return u, ok, err
+	}
+	return u, u.DeletedAt == nil, nil
+}
+
+// activeFriends - user without deleted friends, they are kept in file for Restore
+func activeFriends(bucket *bbolt.Bucket, u user.User) (user.User, error) {
+	if len(u.Friends) == 0 {
+		return u, nil
+	}
+	friends := make([]string, 0, len(u.Friends))
+	for _, friendID := range u.Friends {
+		friendKey, err := parseID(friendID)
+		if err != nil {
+			return u, err
+		}
+		_, ok, err := getActive(bucket, friendKey)
+		if err != nil {
+			return u, notFound(friendID, err)
+		}
+		if ok {
+			friends = append(friends, friendID)
+		}
+	}
+	u.Friends = friends
+	return u, nil
+}
+
+// putUser - encode and save user by key
+func putUser(bucket *bbolt.Bucket, key uint64, u user.User) error {
+	data, err := json.Marshal(record{User: u, Email: u.Email, PasswordHash: u.PasswordHash})
+	if err != nil {
+		return err
+	}
+	return bucket.Put(encodeKey(key), data)
+}
+
+// notFound - error for user which can't be read
+func notFound(id string, err error) error {
+	if err != nil {
+		return fmt.Errorf("failed to decode user (id:%s) due to error: %v", id, err)
+	}
+	return fmt.Errorf("failed to find user (id:%s): %w", id, user.ErrNotFound)
+}
+
+// addFriend - returns friends with id, if it is not there yet
+func addFriend(friends []string, id string) []string {
+	if contains(friends, id) {
+		return friends
+	}
+	return append(friends[:len(friends):len(friends)], id)
+}
+
+// contains - check that id is in friends
+func contains(friends []string, id string) bool {
+	for _, friend := range friends {
+		if friend == id {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFriend - returns friends without all entries of id
+func removeFriend(friends []string, id string) []string {
+	result := friends[:0:0]
+	for _, friend := range friends {
+		if friend != id {
+			result = append(result, friend)
+		}
+	}
+	if len(result) == len(friends) {
+		return friends
+	}
+	return result
+}
+
+// parseID - convert user ID from API to sequence number
+func parseID(id string) (uint64, error) {
+	key, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || key == 0 {
+		return 0, fmt.Errorf("failed to convert user ID to key. ID=%s: %w", id, user.ErrInvalidID)
+	}
+	return key, nil
+}
+
+// encodeKey - big endian keys keep users sorted by creation order
+func encodeKey(key uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, key)
+	return b
+}
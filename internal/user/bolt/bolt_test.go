@@ -0,0 +1,86 @@
+package bolt
+
+import (
+	"path/filepath"
+	"project/internal/user"
+	"project/internal/user/storagetest"
+	"project/pkg/client/boltdb"
+	"project/pkg/logging"
+	"testing"
+
+	"go.etcd.io/bbolt"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) user.Storage {
+		return newStorage(t, openDB(t))
+	})
+}
+
+func TestGraph(t *testing.T) {
+	storagetest.RunGraph(t, func(t *testing.T) user.Storage {
+		return newStorage(t, openDB(t))
+	})
+}
+
+func TestRequests(t *testing.T) {
+	storagetest.RunRequests(t, func(t *testing.T) (user.Storage, user.RequestStorage) {
+		client := openDB(t)
+		requests, err := NewRequestStorage(client, logging.GetLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return newStorage(t, client), requests
+	})
+}
+
+func TestRelations(t *testing.T) {
+	storagetest.RunRelations(t, func(t *testing.T) (user.Storage, user.RelationStorage) {
+		client := openDB(t)
+		relations, err := NewRelationStorage(client, logging.GetLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return newStorage(t, client), relations
+	})
+}
+
+func TestTokens(t *testing.T) {
+	storagetest.RunTokens(t, func(t *testing.T) (user.Storage, user.TokenStorage) {
+		client := openDB(t)
+		tokens, err := NewTokenStorage(client, logging.GetLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return newStorage(t, client), tokens
+	})
+}
+
+func TestAPIKeys(t *testing.T) {
+	storagetest.RunAPIKeys(t, func(t *testing.T) user.APIKeyStorage {
+		keys, err := NewAPIKeyStorage(openDB(t), logging.GetLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return keys
+	})
+}
+
+// openDB - open new file of test, it is closed after test
+func openDB(t *testing.T) *bbolt.DB {
+	client, err := boltdb.NewClient(filepath.Join(t.TempDir(), "users.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// newStorage - user storage in file of client
+func newStorage(t *testing.T, client *bbolt.DB) user.Storage {
+	s, err := NewStorage(client, logging.GetLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
@@ -0,0 +1,143 @@
+package bolt
+
+// file for keeping blocks and mutes in BoltDB file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"sort"
+
+	"go.etcd.io/bbolt"
+)
+
+// bucket with relations, key is "user:target:kind" and value is relation in json
+var relationsBucket = []byte("relations")
+
+// Create relations storage structure
+type relationStorage struct {
+	client *bbolt.DB
+	logger *logging.Logger
+}
+
+// NewRelationStorage - Initialize new storage of blocks and mutes and create bucket if not exists
+func NewRelationStorage(client *bbolt.DB, logger *logging.Logger) (user.RelationStorage, error) {
+	err := client.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(relationsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create boltDB buckets due to error: %v", err)
+	}
+
+	return &relationStorage{
+		client: client,
+		logger: logger,
+	}, nil
+}
+
+// SaveRelation - save relation in one transaction with check of relation of its kind from user to target
+func (s *relationStorage) SaveRelation(ctx context.Context, r user.Relation) (user.Relation, error) {
+	s.logger.Debug("save relation")
+
+	err := s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(relationsBucket)
+		key := relationKey(r.UserID, r.TargetID, r.Kind)
+
+		if data := bucket.Get(key); data != nil {
+			return json.Unmarshal(data, &r)
+		}
+
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, data)
+	})
+	if err != nil {
+		return user.Relation{}, fmt.Errorf("failed to save relation due to error: %v", err)
+	}
+
+	return r, nil
+}
+
+// DeleteRelation - delete relation of kind from user to target
+func (s *relationStorage) DeleteRelation(ctx context.Context, userID string, targetID string, kind string) error {
+	return s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(relationsBucket)
+		key := relationKey(userID, targetID, kind)
+
+		if bucket.Get(key) == nil {
+			return fmt.Errorf("failed to find %s of %s by %s: %w", kind, targetID, userID, user.ErrRelationNotFound)
+		}
+		if err := bucket.Delete(key); err != nil {
+			return fmt.Errorf("failed to delete relation. error: %v", err)
+		}
+		return nil
+	})
+}
+
+// FindRelations - find relations matched by filter, newer relations go first
+func (s *relationStorage) FindRelations(ctx context.Context, filter user.RelationFilter) ([]user.Relation, error) {
+	matched := []user.Relation{}
+	err := s.client.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(relationsBucket).ForEach(func(k, v []byte) error {
+			var r user.Relation
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			if filter.Match(r) {
+				matched = append(matched, r)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find relations due to error: %v", err)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return user.NewerRelation(matched[i], matched[j])
+	})
+
+	return matched, nil
+}
+
+// DeleteRelations - delete all relations from and to user in one transaction
+func (s *relationStorage) DeleteRelations(ctx context.Context, userID string) error {
+	return s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(relationsBucket)
+
+		// bucket can't be changed inside ForEach
+		var keys [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			var r user.Relation
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			if r.UserID == userID || r.TargetID == userID {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to find relations of user. error: %v", err)
+		}
+
+		for _, k := range keys {
+			if err = bucket.Delete(k); err != nil {
+				return fmt.Errorf("failed to delete relation. error: %v", err)
+			}
+		}
+		s.logger.Tracef("Deleted %d relations", len(keys))
+
+		return nil
+	})
+}
+
+// relationKey - key of relation of kind from user to target
+func relationKey(userID, targetID, kind string) []byte {
+	return []byte(userID + ":" + targetID + ":" + kind)
+}
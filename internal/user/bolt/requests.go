@@ -0,0 +1,218 @@
+package bolt
+
+// file for keeping friend requests in BoltDB file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// bucket with friend requests, key is big endian id and value is request in json
+var requestsBucket = []byte("friend_requests")
+
+// Create friend requests storage structure
+type requestStorage struct {
+	client *bbolt.DB
+	logger *logging.Logger
+}
+
+// NewRequestStorage - Initialize new storage of friend requests and create bucket if not exists
+func NewRequestStorage(client *bbolt.DB, logger *logging.Logger) (user.RequestStorage, error) {
+	err := client.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(requestsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create boltDB buckets due to error: %v", err)
+	}
+
+	return &requestStorage{
+		client: client,
+		logger: logger,
+	}, nil
+}
+
+// CreateRequest - create new friend request in one transaction with check of pending requests between users
+func (s *requestStorage) CreateRequest(ctx context.Context, r user.FriendRequest) (user.FriendRequest, error) {
+	s.logger.Debug("create friend request")
+
+	err := s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(requestsBucket)
+
+		if r.Status == user.RequestPending {
+			err := bucket.ForEach(func(k, v []byte) error {
+				var other user.FriendRequest
+				if err := json.Unmarshal(v, &other); err != nil {
+					return err
+				}
+				if other.Status == user.RequestPending && samePair(other, r) {
+					return fmt.Errorf("failed to create friend request from %s to %s: %w", r.FromID, r.ToID, user.ErrRequestExists)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return fmt.Errorf("failed to create friend request due to error: %v", err)
+		}
+		r.ID = strconv.FormatUint(id, 10)
+
+		return putRequest(bucket, id, r)
+	})
+	if err != nil {
+		return user.FriendRequest{}, err
+	}
+
+	return r, nil
+}
+
+// FindRequest - find friend request by id
+func (s *requestStorage) FindRequest(ctx context.Context, id string) (r user.FriendRequest, err error) {
+	key, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return r, requestNotFound(id, nil)
+	}
+
+	err = s.client.View(func(tx *bbolt.Tx) error {
+		var ok bool
+		if r, ok, err = getRequest(tx.Bucket(requestsBucket), key); err != nil || !ok {
+			return requestNotFound(id, err)
+		}
+		return nil
+	})
+
+	return r, err
+}
+
+// FindRequests - find friend requests matched by filter, newer requests go first
+func (s *requestStorage) FindRequests(ctx context.Context, filter user.RequestFilter) ([]user.FriendRequest, error) {
+	matched := []user.FriendRequest{}
+	err := s.client.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(requestsBucket).ForEach(func(k, v []byte) error {
+			var r user.FriendRequest
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			if filter.Match(r) {
+				matched = append(matched, r)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find friend requests due to error: %v", err)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return user.NewerRequest(matched[i], matched[j])
+	})
+
+	return matched, nil
+}
+
+// UpdateRequestStatus - change status of friend request in one transaction, if it has status from
+func (s *requestStorage) UpdateRequestStatus(ctx context.Context, id string, from, to string, updatedAt time.Time) (r user.FriendRequest, err error) {
+	key, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return r, requestNotFound(id, nil)
+	}
+
+	err = s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(requestsBucket)
+
+		var ok bool
+		if r, ok, err = getRequest(bucket, key); err != nil || !ok {
+			return requestNotFound(id, err)
+		}
+		if r.Status != from {
+			return fmt.Errorf("friend request %s has status %s, not %s: %w", id, r.Status, from, user.ErrRequestState)
+		}
+
+		r.Status = to
+		r.UpdatedAt = updatedAt
+		if err = putRequest(bucket, key, r); err != nil {
+			return fmt.Errorf("failed to execute update friend request query. error: %v", err)
+		}
+		return nil
+	})
+
+	return r, err
+}
+
+// DeleteRequests - delete all friend requests from and to user in one transaction
+func (s *requestStorage) DeleteRequests(ctx context.Context, userID string) error {
+	return s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(requestsBucket)
+
+		// bucket can't be changed inside ForEach
+		var keys [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			var r user.FriendRequest
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			if r.FromID == userID || r.ToID == userID {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to find friend requests of user. error: %v", err)
+		}
+
+		for _, k := range keys {
+			if err = bucket.Delete(k); err != nil {
+				return fmt.Errorf("failed to delete friend request. error: %v", err)
+			}
+		}
+		s.logger.Tracef("Deleted %d friend requests", len(keys))
+
+		return nil
+	})
+}
+
+// getRequest - read and decode friend request by key, ok is false if request not exists
+func getRequest(bucket *bbolt.Bucket, key uint64) (r user.FriendRequest, ok bool, err error) {
+	data := bucket.Get(encodeKey(key))
+	if data == nil {
+		return r, false, nil
+	}
+	if err = json.Unmarshal(data, &r); err != nil {
+		return r, false, err
+	}
+	return r, true, nil
+}
+
+// putRequest - encode and save friend request by key
+func putRequest(bucket *bbolt.Bucket, key uint64, r user.FriendRequest) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(encodeKey(key), data)
+}
+
+// samePair - check that requests are between the same users in any direction
+func samePair(a, b user.FriendRequest) bool {
+	return (a.FromID == b.FromID && a.ToID == b.ToID) || (a.FromID == b.ToID && a.ToID == b.FromID)
+}
+
+// requestNotFound - error for friend request which can't be read
+func requestNotFound(id string, err error) error {
+	if err != nil {
+		return fmt.Errorf("failed to decode friend request (id:%s) due to error: %v", id, err)
+	}
+	return fmt.Errorf("failed to find friend request (id:%s): %w", id, user.ErrRequestNotFound)
+}
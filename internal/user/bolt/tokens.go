@@ -0,0 +1,138 @@
+package bolt
+
+// file for keeping refresh tokens in BoltDB file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// bucket with refresh tokens, key is hash of token and value is token in json
+var tokensBucket = []byte("refresh_tokens")
+
+// Create refresh tokens storage structure
+type tokenStorage struct {
+	client *bbolt.DB
+	logger *logging.Logger
+}
+
+// NewTokenStorage - Initialize new storage of refresh tokens and create bucket if not exists
+func NewTokenStorage(client *bbolt.DB, logger *logging.Logger) (user.TokenStorage, error) {
+	err := client.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(tokensBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create boltDB buckets due to error: %v", err)
+	}
+
+	return &tokenStorage{
+		client: client,
+		logger: logger,
+	}, nil
+}
+
+// SaveToken - save refresh token by its hash
+func (s *tokenStorage) SaveToken(ctx context.Context, t user.RefreshToken) error {
+	s.logger.Debug("save refresh token")
+
+	err := s.client.Update(func(tx *bbolt.Tx) error {
+		return putToken(tx.Bucket(tokensBucket), t)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save refresh token due to error: %v", err)
+	}
+
+	return nil
+}
+
+// FindToken - find refresh token by hash
+func (s *tokenStorage) FindToken(ctx context.Context, hash string) (t user.RefreshToken, err error) {
+	err = s.client.View(func(tx *bbolt.Tx) error {
+		t, err = getToken(tx.Bucket(tokensBucket), hash)
+		return err
+	})
+
+	return t, err
+}
+
+// RevokeToken - revoke refresh token in one transaction with check that it is not revoked yet
+func (s *tokenStorage) RevokeToken(ctx context.Context, hash string, revokedAt time.Time) error {
+	return s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(tokensBucket)
+
+		t, err := getToken(bucket, hash)
+		if err != nil {
+			return err
+		}
+		if t.RevokedAt != nil {
+			return fmt.Errorf("failed to revoke refresh token of user %s: %w", t.UserID, user.ErrTokenRevoked)
+		}
+		t.RevokedAt = &revokedAt
+		if err = putToken(bucket, t); err != nil {
+			return fmt.Errorf("failed to execute revoke refresh token query. error: %v", err)
+		}
+
+		return nil
+	})
+}
+
+// RevokeFamily - revoke all refresh tokens of family which are not revoked yet in one transaction
+func (s *tokenStorage) RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	return s.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(tokensBucket)
+
+		// bucket can't be changed inside ForEach
+		var revoked []user.RefreshToken
+		err := bucket.ForEach(func(k, v []byte) error {
+			var t user.RefreshToken
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+			if t.FamilyID == familyID && t.RevokedAt == nil {
+				t.RevokedAt = &revokedAt
+				revoked = append(revoked, t)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to find refresh tokens of family. error: %v", err)
+		}
+
+		for _, t := range revoked {
+			if err = putToken(bucket, t); err != nil {
+				return fmt.Errorf("failed to revoke refresh tokens. error: %v", err)
+			}
+		}
+		s.logger.Tracef("Revoked %d refresh tokens", len(revoked))
+
+		return nil
+	})
+}
+
+// getToken - read and decode refresh token by hash
+func getToken(bucket *bbolt.Bucket, hash string) (t user.RefreshToken, err error) {
+	data := bucket.Get([]byte(hash))
+	if data == nil {
+		return t, fmt.Errorf("failed to find refresh token (hash:%.8s): %w", hash, user.ErrTokenNotFound)
+	}
+	if err = json.Unmarshal(data, &t); err != nil {
+		return t, fmt.Errorf("failed to decode refresh token due to error: %v", err)
+	}
+	return t, nil
+}
+
+// putToken - encode and save refresh token by hash
+func putToken(bucket *bbolt.Bucket, t user.RefreshToken) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(t.Hash), data)
+}
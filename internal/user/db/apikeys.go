@@ -0,0 +1,151 @@
+package db
+
+// file for API keys in MongoDB database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Create API keys database structure
+type apiKeyDB struct {
+	collection *mongo.Collection
+	logger     *logging.Logger
+}
+
+// NewAPIKeyStorage - Initialize new storage of API keys
+func NewAPIKeyStorage(database *mongo.Database, collection string, logger *logging.Logger) user.APIKeyStorage {
+	return &apiKeyDB{
+		collection: database.Collection(collection),
+		logger:     logger,
+	}
+}
+
+// CreateAPIKeyIndexes - create indexes for API keys, keys are found by unique hash
+func CreateAPIKeyIndexes(ctx context.Context, database *mongo.Database, collection string, logger *logging.Logger) error {
+	names, err := database.Collection(collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create api keys indexes due to error: %v", err)
+	}
+	logger.Tracef("Created indexes %v", names)
+
+	return nil
+}
+
+// CreateAPIKey - create new API key
+func (d *apiKeyDB) CreateAPIKey(ctx context.Context, k user.APIKey) (user.APIKey, error) {
+	d.logger.Debug("create api key")
+
+	result, err := d.collection.InsertOne(ctx, bson.D{
+		{Key: "name", Value: k.Name},
+		{Key: "prefix", Value: k.Prefix},
+		{Key: "hash", Value: k.Hash},
+		{Key: "scopes", Value: k.Scopes},
+		{Key: "created_at", Value: k.CreatedAt},
+	})
+	if err != nil {
+		return user.APIKey{}, fmt.Errorf("failed to create api key due to error: %v", err)
+	}
+
+	oid, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return user.APIKey{}, fmt.Errorf("failed to convert objectid to hex. probably oid: %v", result.InsertedID)
+	}
+	k.ID = oid.Hex()
+
+	return k, nil
+}
+
+// FindAPIKey - find API key by hash
+func (d *apiKeyDB) FindAPIKey(ctx context.Context, hash string) (k user.APIKey, err error) {
+	result := d.collection.FindOne(ctx, bson.M{"hash": hash})
+	if err = result.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return k, fmt.Errorf("failed to find api key (hash:%.8s): %w", hash, user.ErrAPIKeyNotFound)
+		}
+		return k, fmt.Errorf("failed to find api key due to error: %v", err)
+	}
+	if err = result.Decode(&k); err != nil {
+		return k, fmt.Errorf("failed to decode api key from DB due to error: %v", err)
+	}
+
+	return k, nil
+}
+
+// FindAPIKeys - find all API keys, newer keys go first
+func (d *apiKeyDB) FindAPIKeys(ctx context.Context) ([]user.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := d.collection.Find(ctx, bson.D{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api keys due to error: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	keys := []user.APIKey{}
+	if err = cursor.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode api keys from DB due to error: %v", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey - revoke API key, if it is not revoked yet, and find it
+func (d *apiKeyDB) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) (k user.APIKey, err error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return k, apiKeyNotFound(id)
+	}
+
+	filter := bson.M{"_id": oid, "revoked_at": bson.M{"$exists": false}}
+	if _, err = d.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": revokedAt}}); err != nil {
+		return k, fmt.Errorf("failed to execute revoke api key query. error: %v", err)
+	}
+
+	result := d.collection.FindOne(ctx, bson.M{"_id": oid})
+	if err = result.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return k, apiKeyNotFound(id)
+		}
+		return k, fmt.Errorf("failed to find api key (id:%s) due to error: %v", id, err)
+	}
+	if err = result.Decode(&k); err != nil {
+		return k, fmt.Errorf("failed to decode api key (id:%s) from DB due to error: %v", id, err)
+	}
+
+	return k, nil
+}
+
+// TouchAPIKey - save time of the last use of API key
+func (d *apiKeyDB) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return apiKeyNotFound(id)
+	}
+
+	result, err := d.collection.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$set": bson.M{"last_used_at": usedAt}})
+	if err != nil {
+		return fmt.Errorf("failed to execute touch api key query. error: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return apiKeyNotFound(id)
+	}
+
+	return nil
+}
+
+// apiKeyNotFound - error for API key which doesn't exist
+func apiKeyNotFound(id string) error {
+	return fmt.Errorf("failed to find api key (id:%s): %w", id, user.ErrAPIKeyNotFound)
+}
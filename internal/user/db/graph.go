@@ -0,0 +1,253 @@
+package db
+
+// file for social graph queries in MongoDB, database walks friends instead of application
+
+import (
+	"context"
+	"fmt"
+	"project/internal/user"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MutualFriends - intersection of friends of both users, usernames are resolved by one query
+func (d *db) MutualFriends(ctx context.Context, id string, otherID string) ([]user.Friend, error) {
+	oid, err := objectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	otherOID, err := objectIDFromHex(otherID)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := d.findOne(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	other, err := d.findOne(ctx, otherOID)
+	if err != nil {
+		return nil, err
+	}
+
+	var mutual []string
+	for _, friendID := range u.Friends {
+		if contains(other.Friends, friendID) {
+			mutual = append(mutual, friendID)
+		}
+	}
+
+	friends, _, err := d.resolveFriends(ctx, mutual)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find mutual friends of users %s and %s due to error: %v", id, otherID, err)
+	}
+	return friends, nil
+}
+
+// Suggestions - friends of friends are counted and ranked by one aggregation
+func (d *db) Suggestions(ctx context.Context, id string, limit int) ([]user.Suggestion, error) {
+	oid, err := objectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// user is checked first, aggregation has no results for unknown user and for user without friends
+	if _, err = d.findOne(ctx, oid); err != nil {
+		return nil, err
+	}
+
+	collection := d.collection.Name()
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "_id", Value: oid}}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: collection},
+			{Key: "localField", Value: "friends"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "direct"},
+		}}},
+		{{Key: "$unwind", Value: "$direct"}},
+		// friends of deleted friend are not suggested through him
+		{{Key: "$match", Value: bson.D{{Key: "direct.deleted_at", Value: notDeleted}}}},
+		{{Key: "$unwind", Value: "$direct.friends"}},
+		// every friend of friend is counted once for every mutual friend
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$direct.friends"},
+			{Key: "mutual_count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "friends", Value: bson.D{{Key: "$first", Value: "$friends"}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "$expr", Value: bson.D{{Key: "$and", Value: bson.A{
+			bson.D{{Key: "$ne", Value: bson.A{"$_id", oid}}},
+			bson.D{{Key: "$not", Value: bson.A{bson.D{{Key: "$in", Value: bson.A{"$_id", "$friends"}}}}}},
+		}}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "mutual_count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+	// ids of deleted users can be left in friends, so page is cut after they are dropped
+	pipeline = append(pipeline,
+		bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: collection},
+			{Key: "localField", Value: "_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "user"},
+		}}},
+		bson.D{{Key: "$unwind", Value: "$user"}},
+		bson.D{{Key: "$match", Value: bson.D{{Key: "user.deleted_at", Value: notDeleted}}}},
+	)
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: int64(limit)}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$project", Value: bson.D{
+		{Key: "username", Value: "$user.username"},
+		{Key: "mutual_count", Value: 1},
+	}}})
+
+	cursor, err := d.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find suggestions due to error: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var found []struct {
+		ID          primitive.ObjectID `bson:"_id"`
+		Username    string             `bson:"username"`
+		MutualCount int                `bson:"mutual_count"`
+	}
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, fmt.Errorf("failed to decode suggestions from DB due to error: %v", err)
+	}
+
+	suggestions := make([]user.Suggestion, 0, len(found))
+	for _, s := range found {
+		suggestions = append(suggestions, user.Suggestion{
+			Friend:      user.Friend{ID: s.ID.Hex(), Username: s.Username},
+			MutualCount: s.MutualCount,
+		})
+	}
+	return suggestions, nil
+}
+
+// ShortestPath - $graphLookup finds all users in maxDepth steps from first user with their depth,
+// path is built back from second user through friends with smaller depth
+func (d *db) ShortestPath(ctx context.Context, fromID string, toID string, maxDepth int) ([]user.Friend, error) {
+	fromOID, err := objectIDFromHex(fromID)
+	if err != nil {
+		return nil, err
+	}
+	toOID, err := objectIDFromHex(toID)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := d.findOne(ctx, fromOID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := d.findOne(ctx, toOID)
+	if err != nil {
+		return nil, err
+	}
+	if from.ID == to.ID {
+		return []user.Friend{{ID: from.ID, Username: from.Username}}, nil
+	}
+
+	// depth of direct friends is 0, so users in maxDepth steps have depth maxDepth-1
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "_id", Value: fromOID}}}},
+		{{Key: "$graphLookup", Value: bson.D{
+			{Key: "from", Value: d.collection.Name()},
+			{Key: "startWith", Value: "$friends"},
+			{Key: "connectFromField", Value: "friends"},
+			{Key: "connectToField", Value: "_id"},
+			{Key: "as", Value: "network"},
+			{Key: "maxDepth", Value: maxDepth - 1},
+			{Key: "depthField", Value: "depth"},
+			// first user is reached again through every friend, he must keep depth -1
+			{Key: "restrictSearchWithMatch", Value: bson.D{
+				{Key: "_id", Value: bson.D{{Key: "$ne", Value: fromOID}}},
+				{Key: "deleted_at", Value: notDeleted},
+			}},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "network._id", Value: 1},
+			{Key: "network.username", Value: 1},
+			{Key: "network.friends", Value: 1},
+			{Key: "network.depth", Value: 1},
+		}}},
+	}
+
+	cursor, err := d.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find path between users due to error: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var found []struct {
+		Network []networkUser `bson:"network"`
+	}
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, fmt.Errorf("failed to decode path between users from DB due to error: %v", err)
+	}
+
+	var network []networkUser
+	if len(found) > 0 {
+		network = found[0].Network
+	}
+	path, err := pathThrough(from, to, network)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build path from %s to %s in %d steps: %w", fromID, toID, maxDepth, err)
+	}
+	return path, nil
+}
+
+// networkUser - user found by $graphLookup with number of steps from first user, direct friends have depth 0
+type networkUser struct {
+	user.User `bson:",inline"`
+	Depth     int `bson:"depth"`
+}
+
+// pathThrough - path from first user to second one through users of network, ErrNotConnected if second user isn't in network.
+// First user has depth -1 even if network has him, so he is never a step of path
+func pathThrough(from user.User, to user.User, network []networkUser) ([]user.Friend, error) {
+	depths := map[string]int{from.ID: -1}
+	users := map[string]user.User{from.ID: from}
+	for _, u := range network {
+		if u.ID == from.ID {
+			continue
+		}
+		depths[u.ID] = u.Depth
+		users[u.ID] = u.User
+	}
+	depth, ok := depths[to.ID]
+	if !ok {
+		return nil, user.ErrNotConnected
+	}
+
+	// every user at depth n is a friend of some user at depth n-1, friendship is kept in both directions
+	path := []user.Friend{{ID: to.ID, Username: to.Username}}
+	current := users[to.ID]
+	for ; depth >= 0; depth-- {
+		next, ok := closerFriend(current, depths, depth-1)
+		if !ok {
+			return nil, fmt.Errorf("friendship of %s is one-sided", current.ID)
+		}
+		current = users[next]
+		path = append(path, user.Friend{ID: current.ID, Username: current.Username})
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, nil
+}
+
+// closerFriend - friend of user with depth, the one with smaller id if there are many
+func closerFriend(u user.User, depths map[string]int, depth int) (string, bool) {
+	var closer string
+	for _, friendID := range u.Friends {
+		if d, ok := depths[friendID]; ok && d == depth && (closer == "" || friendID < closer) {
+			closer = friendID
+		}
+	}
+	return closer, closer != ""
+}
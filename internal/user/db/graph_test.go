@@ -0,0 +1,50 @@
+package db
+
+import (
+	"errors"
+	"project/internal/user"
+	"testing"
+)
+
+// TestPathThrough - path is built back through friends with smaller depth, first user found again in network is not a step of path
+func TestPathThrough(t *testing.T) {
+	first := user.User{ID: "1", Username: "first", Friends: []string{"2"}}
+	second := user.User{ID: "2", Username: "second", Friends: []string{"1", "3"}}
+	third := user.User{ID: "3", Username: "third", Friends: []string{"2"}}
+	alone := user.User{ID: "4", Username: "alone"}
+
+	tests := []struct {
+		name    string
+		to      user.User
+		network []networkUser
+		want    []string
+		err     error
+	}{
+		{"friend", second, []networkUser{{second, 0}, {third, 1}}, []string{"first", "second"}, nil},
+		{"friend of friend", third, []networkUser{{second, 0}, {third, 1}}, []string{"first", "second", "third"}, nil},
+		// $graphLookup reaches first user again through his friends
+		{"first user in network", third, []networkUser{{second, 0}, {first, 1}, {third, 1}}, []string{"first", "second", "third"}, nil},
+		{"not connected", alone, []networkUser{{second, 0}, {third, 1}}, nil, user.ErrNotConnected},
+	}
+	for _, tt := range tests {
+		path, err := pathThrough(first, tt.to, tt.network)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		var names []string
+		for _, friend := range path {
+			names = append(names, friend.Username)
+		}
+		if len(names) != len(tt.want) {
+			t.Errorf("%s: path = %v, want %v", tt.name, names, tt.want)
+			continue
+		}
+		for i := range names {
+			if names[i] != tt.want[i] {
+				t.Errorf("%s: path = %v, want %v", tt.name, names, tt.want)
+				break
+			}
+		}
+	}
+}
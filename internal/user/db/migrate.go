@@ -0,0 +1,135 @@
+package db
+
+// file for one-time migration of friends from usernames to ObjectIDs
+
+import (
+	"context"
+	"fmt"
+	"project/pkg/logging"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FriendsMigration - result of friends migration
+type FriendsMigration struct {
+	// Migrated - number of users with rewritten friends
+	Migrated int
+	// Dropped - number of usernames which can't be resolved to one user, they are removed from friends
+	Dropped int
+}
+
+// legacyUser - user document with friends as usernames, ObjectIDs or both
+type legacyUser struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	Username string             `bson:"username"`
+	Friends  []interface{}      `bson:"friends"`
+}
+
+// MigrateFriends - rewrite usernames in friends of all users to ObjectIDs, users already migrated are not changed,
+// so migration can be run again. Username of several users is resolved to the ones who have the user in friends.
+// With dryRun nothing is written.
+func MigrateFriends(ctx context.Context, database *mongo.Database, collection string, dryRun bool, logger *logging.Logger) (result FriendsMigration, err error) {
+	users := database.Collection(collection)
+
+	// usernames of friends are resolved against all users, so all of them are read once
+	opts := options.Find().SetProjection(bson.D{{Key: "username", Value: 1}, {Key: "friends", Value: 1}})
+	cursor, err := users.Find(ctx, bson.D{}, opts)
+	if err != nil {
+		return result, fmt.Errorf("failed to find users due to error: %v", err)
+	}
+	var all []legacyUser
+	if err = cursor.All(ctx, &all); err != nil {
+		return result, fmt.Errorf("failed to decode users from DB due to error: %v", err)
+	}
+
+	byUsername := make(map[string][]legacyUser)
+	for _, u := range all {
+		byUsername[u.Username] = append(byUsername[u.Username], u)
+	}
+
+	for _, u := range all {
+		if !needsMigration(u) {
+			continue
+		}
+
+		friends := make([]primitive.ObjectID, 0, len(u.Friends))
+		for _, friend := range u.Friends {
+			var ids []primitive.ObjectID
+			switch friend := friend.(type) {
+			case primitive.ObjectID:
+				ids = []primitive.ObjectID{friend}
+			case string:
+				ids = resolveFriend(u, byUsername[friend])
+				if len(ids) == 0 {
+					logger.Warnf("friend %q of user %s can't be resolved, it is dropped", friend, u.ID.Hex())
+					result.Dropped++
+				}
+			default:
+				logger.Warnf("friend %v of user %s has unknown type %T, it is dropped", friend, u.ID.Hex(), friend)
+				result.Dropped++
+			}
+			for _, id := range ids {
+				friends = addObjectID(friends, id)
+			}
+		}
+
+		result.Migrated++
+		if dryRun {
+			logger.Infof("user %s: friends %v would be rewritten to %v", u.ID.Hex(), u.Friends, friends)
+			continue
+		}
+		_, err = users.UpdateOne(ctx, bson.M{"_id": u.ID}, bson.D{
+			{Key: "$set", Value: bson.D{{Key: "friends", Value: friends}}},
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to rewrite friends of user %s due to error: %v", u.ID.Hex(), err)
+		}
+		logger.Tracef("user %s: friends %v are rewritten to %v", u.ID.Hex(), u.Friends, friends)
+	}
+
+	return result, nil
+}
+
+// needsMigration - friends of user are null or have usernames
+func needsMigration(u legacyUser) bool {
+	if u.Friends == nil {
+		return true
+	}
+	for _, friend := range u.Friends {
+		if _, ok := friend.(primitive.ObjectID); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFriend - ids of users with username of friend, several users are narrowed to those who have u in friends
+func resolveFriend(u legacyUser, candidates []legacyUser) []primitive.ObjectID {
+	if len(candidates) == 1 {
+		return []primitive.ObjectID{candidates[0].ID}
+	}
+
+	var ids []primitive.ObjectID
+	for _, candidate := range candidates {
+		for _, friend := range candidate.Friends {
+			if friend == u.Username || friend == u.ID {
+				ids = append(ids, candidate.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// addObjectID - returns ids with id, if it is not there yet
+func addObjectID(ids []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"project/pkg/logging"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestResolveFriend - username of one user is resolved to him, username of several users to the ones who have user in friends
+func TestResolveFriend(t *testing.T) {
+	alice := legacyUser{ID: primitive.NewObjectID(), Username: "alice", Friends: []interface{}{"bob"}}
+	bob := legacyUser{ID: primitive.NewObjectID(), Username: "bob", Friends: []interface{}{"alice"}}
+	otherBob := legacyUser{ID: primitive.NewObjectID(), Username: "bob"}
+	migratedBob := legacyUser{ID: primitive.NewObjectID(), Username: "bob", Friends: []interface{}{alice.ID}}
+
+	tests := []struct {
+		name       string
+		candidates []legacyUser
+		want       []primitive.ObjectID
+	}{
+		{"one user", []legacyUser{otherBob}, []primitive.ObjectID{otherBob.ID}},
+		{"several users", []legacyUser{otherBob, bob}, []primitive.ObjectID{bob.ID}},
+		{"several users, one is migrated", []legacyUser{otherBob, migratedBob}, []primitive.ObjectID{migratedBob.ID}},
+		{"several users without friendship", []legacyUser{otherBob, {ID: primitive.NewObjectID(), Username: "bob"}}, nil},
+		{"unknown user", nil, nil},
+	}
+	for _, tt := range tests {
+		if got := resolveFriend(alice, tt.candidates); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: resolveFriend = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestNeedsMigration - users with null friends or with any username in friends are migrated
+func TestNeedsMigration(t *testing.T) {
+	id := primitive.NewObjectID()
+	tests := []struct {
+		friends []interface{}
+		want    bool
+	}{
+		{nil, true},
+		{[]interface{}{}, false},
+		{[]interface{}{id}, false},
+		{[]interface{}{id, "bob"}, true},
+	}
+	for _, tt := range tests {
+		if got := needsMigration(legacyUser{Friends: tt.friends}); got != tt.want {
+			t.Errorf("needsMigration(%v) = %t, want %t", tt.friends, got, tt.want)
+		}
+	}
+}
+
+// TestMigrateFriends - friends of users with the same username are rewritten to ids, dry run writes nothing
+// and second run changes nobody
+func TestMigrateFriends(t *testing.T) {
+	ctx := context.Background()
+	database := openDB(t)
+	users := database.Collection(usersCollection)
+	logger := logging.GetLogger()
+
+	alice, bob, otherBob, carol := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	_, err := users.InsertMany(ctx, []interface{}{
+		bson.M{"_id": alice, "username": "alice", "friends": bson.A{"bob", "carol", "dave"}},
+		bson.M{"_id": bob, "username": "bob", "friends": bson.A{"alice"}},
+		bson.M{"_id": otherBob, "username": "bob", "friends": nil},
+		bson.M{"_id": carol, "username": "carol", "friends": bson.A{alice}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result, err := MigrateFriends(ctx, database, usersCollection, true, logger); err != nil || result.Migrated != 3 || result.Dropped != 1 {
+		t.Errorf("dry run = %+v, %v, want 3 migrated and 1 dropped", result, err)
+	}
+	friends := func(id primitive.ObjectID) bson.A {
+		var document struct {
+			Friends bson.A `bson:"friends"`
+		}
+		if err := users.FindOne(ctx, bson.M{"_id": id}).Decode(&document); err != nil {
+			t.Fatal(err)
+		}
+		return document.Friends
+	}
+	if got := friends(alice); !reflect.DeepEqual(got, bson.A{"bob", "carol", "dave"}) {
+		t.Errorf("friends of alice after dry run = %v, want usernames", got)
+	}
+
+	if result, err := MigrateFriends(ctx, database, usersCollection, false, logger); err != nil || result.Migrated != 3 || result.Dropped != 1 {
+		t.Errorf("migration = %+v, %v, want 3 migrated and 1 dropped", result, err)
+	}
+	tests := []struct {
+		id   primitive.ObjectID
+		want bson.A
+	}{
+		{alice, bson.A{bob, carol}},
+		{bob, bson.A{alice}},
+		{otherBob, bson.A{}},
+		{carol, bson.A{alice}},
+	}
+	for _, tt := range tests {
+		if got := friends(tt.id); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("friends of %s = %v, want %v", tt.id.Hex(), got, tt.want)
+		}
+	}
+
+	if result, err := MigrateFriends(ctx, database, usersCollection, false, logger); err != nil || result.Migrated != 0 {
+		t.Errorf("second migration = %+v, %v, want nobody migrated", result, err)
+	}
+}
@@ -7,16 +7,27 @@ import (
 	"fmt"
 	"project/internal/user"
 	"project/pkg/logging"
+	"regexp"
+	"sync"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // Create database structure
 type db struct {
 	collection *mongo.Collection
 	logger     *logging.Logger
+
+	// mu guards transactions, nil until deployment is checked
+	mu           sync.Mutex
+	transactions *bool
+
+	// failpoint - hook of tests, it is called between writes of multi-document operations and its error fails them
+	failpoint func(operation string) error
 }
 
 // NewStorage - Initialize new storage
@@ -27,13 +38,43 @@ func NewStorage(database *mongo.Database, collection string, logger *logging.Log
 	}
 }
 
+// CreateIndexes - create indexes for searching users by username and friends
+func CreateIndexes(ctx context.Context, database *mongo.Database, collection string, logger *logging.Logger) error {
+	names, err := database.Collection(collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// prefix search and sorting by username, _id keeps order of equal usernames
+		{Keys: bson.D{{Key: "username", Value: 1}, {Key: "_id", Value: 1}}},
+		// users with deleted friend are found by friends
+		{Keys: bson.D{{Key: "friends", Value: 1}}},
+		// purger finds deleted users by time of deletion, users who are not deleted are not indexed
+		{Keys: bson.D{{Key: "deleted_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+		// login finds users by email, email is unique among registered users, users without email are not indexed
+		{
+			Keys: bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.D{{Key: "email", Value: bson.D{{Key: "$exists", Value: true}}}}),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes due to error: %v", err)
+	}
+	logger.Tracef("Created indexes %v", names)
+
+	return nil
+}
+
 // Create - create new user in database
-func (d *db) Create(ctx context.Context, user user.User) (string, error) {
+func (d *db) Create(ctx context.Context, u user.User) (string, error) {
+
+	// friends are made only by MakeFriends, empty array is needed for $addToSet
+	u.Friends = []string{}
 
 	// Push user to collection
 	d.logger.Debug("create user")
-	result, err := d.collection.InsertOne(ctx, user)
+	result, err := d.collection.InsertOne(ctx, u)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", fmt.Errorf("failed to create user with email %s: %w", u.Email, user.ErrEmailExists)
+		}
 		return "", fmt.Errorf("failed to create user due to error: %v", err)
 	}
 
@@ -45,163 +86,623 @@ func (d *db) Create(ctx context.Context, user user.User) (string, error) {
 	if ok {
 		return oid.Hex(), nil
 	}
-	d.logger.Trace(user)
+	d.logger.Trace(result.InsertedID)
 	return "", fmt.Errorf("failed to convert objectid to hex. probably oid: %s", oid)
 }
 
-// GetUserFriends - get all friends from one user
-func (d *db) GetUserFriends(ctx context.Context, id string) ([]string, error) {
-	var u user.User
-	oid, err := primitive.ObjectIDFromHex(id)
+// FindOne - find user by id
+func (d *db) FindOne(ctx context.Context, id string) (user.User, error) {
+	oid, err := objectIDFromHex(id)
 	if err != nil {
-		return u.Friends, fmt.Errorf("failed to convert hex to objectid. hex: %s", id)
+		return user.User{}, err
 	}
 
-	// filter for searching user in MongoDB
-	filter := bson.M{"_id": oid}
+	u, err := d.findOne(ctx, oid)
+	if err != nil {
+		return u, err
+	}
+	return u, d.activeFriends(ctx, &u)
+}
 
-	// find user in database
-	result := d.collection.FindOne(ctx, filter)
-	if result.Err() != nil {
-		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
-			return u.Friends, err
+// FindByEmail - find user with credentials by email
+func (d *db) FindByEmail(ctx context.Context, email string) (u user.User, err error) {
+	result := d.collection.FindOne(ctx, bson.M{"email": email, "deleted_at": notDeleted})
+	if err = result.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return u, fmt.Errorf("failed to find user (email:%s): %w", email, user.ErrNotFound)
 		}
-		return u.Friends, fmt.Errorf("failed to find one user by id: %s due to error: %v", id, err)
+		return u, fmt.Errorf("failed to find one user by email: %s due to error: %v", email, err)
 	}
-
-	// decoding data to go struct
 	if err = result.Decode(&u); err != nil {
-		return u.Friends, fmt.Errorf("failed to decode user (id:%s) from DB due to error: %v", id, err)
+		return u, fmt.Errorf("failed to decode user (email:%s) from DB due to error: %v", email, err)
 	}
 
-	return u.Friends, nil
+	return u, d.activeFriends(ctx, &u)
 }
 
-// UpdateAge - func update age of one user
-func (d *db) UpdateAge(ctx context.Context, id string, age string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+// FindAll - find page of users matched by filter and total number of matched users
+func (d *db) FindAll(ctx context.Context, filter user.Filter) (users []user.User, total int64, err error) {
+	query := listQuery(filter)
+
+	total, err = d.collection.CountDocuments(ctx, query)
 	if err != nil {
-		return fmt.Errorf("failed to convert user ID to ObjectID. ID=%s", id)
+		return nil, 0, fmt.Errorf("failed to count users due to error: %v", err)
 	}
 
-	// filter for searching user in MongoDB
-	filter := bson.M{"_id": objectID}
+	// age is a string, so it is sorted by converted value
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: query}}}
+	sortField := "_id"
+	switch filter.SortBy {
+	case user.SortByUsername:
+		sortField = "username"
+	case user.SortByAge:
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.D{{Key: "ageNumber", Value: ageNumber}}}})
+		sortField = "ageNumber"
+	}
+
+	order := 1
+	if filter.Desc {
+		order = -1
+	}
+	sort := bson.D{{Key: sortField, Value: order}}
+	if sortField != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: order})
+	}
+
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: sort}},
+		bson.D{{Key: "$skip", Value: int64(filter.Offset)}},
+	)
+	if filter.Limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: int64(filter.Limit)}})
+	}
+
+	cursor, err := d.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find users due to error: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	users = []user.User{}
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode users from DB due to error: %v", err)
+	}
+
+	// friends of all users of page are checked by one query
+	page := make([]*user.User, 0, len(users))
+	for i := range users {
+		page = append(page, &users[i])
+	}
+	if err = d.activeFriends(ctx, page...); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// GetUserFriends - get all friends from one user with usernames resolved by ids
+func (d *db) GetUserFriends(ctx context.Context, id string) ([]user.Friend, error) {
+	oid, err := objectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// find user in database
+	u, err := d.findOne(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+
+	friends, missing, err := d.resolveFriends(ctx, u.Friends)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find friends of user (id:%s) due to error: %v", id, err)
+	}
+
+	// ids of purged users are left by Purge without transaction, failure to pull them is only logged
+	if len(missing) > 0 {
+		_, err = d.collection.UpdateOne(ctx, bson.M{"_id": oid}, bson.D{
+			{Key: "$pull", Value: bson.D{{Key: "friends", Value: bson.M{"$in": missing}}}},
+		})
+		if err != nil {
+			d.logger.Errorf("failed to pull deleted friends of user %s. error: %v", id, err)
+		}
+	}
+
+	return friends, nil
+}
+
+// UpdateAge - func update age of one user, returns updated user
+func (d *db) UpdateAge(ctx context.Context, id string, age string) (u user.User, err error) {
+	objectID, err := objectIDFromHex(id)
+	if err != nil {
+		return u, err
+	}
 
 	// create a message for mongoDB for change age
 	updateAge := bson.D{
-		{"$set", bson.D{{"age", age}}},
+		{Key: "$set", Value: bson.D{{Key: "age", Value: age}}},
 	}
 
 	// updating user in database
-	result, err := d.collection.UpdateOne(ctx, filter, updateAge)
+	if u, err = d.findOneAndUpdate(ctx, objectID, updateAge); err != nil {
+		return u, err
+	}
+	return u, d.activeFriends(ctx, &u)
+}
+
+// Update - replace username and age of one user, friends keep ids so renames need nothing else, returns updated user
+func (d *db) Update(ctx context.Context, id string, u user.User) (user.User, error) {
+	objectID, err := objectIDFromHex(id)
 	if err != nil {
-		return fmt.Errorf("failed to execute update user query. error: %v", err)
+		return user.User{}, err
 	}
 
-	// check for match
-	if result.MatchedCount == 0 {
+	// create a message for mongoDB for replace user fields
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "username", Value: u.Username},
+			{Key: "age", Value: u.Age},
+		}},
+	}
+
+	updated, err := d.findOneAndUpdate(ctx, objectID, update)
+	if err != nil {
+		return updated, err
+	}
+	return updated, d.activeFriends(ctx, &updated)
+}
+
+// Delete - func for soft delete of user, friends of user and other users are not changed
+func (d *db) Delete(ctx context.Context, id string, deletedAt time.Time) error {
+	objectID, err := objectIDFromHex(id)
+	if err != nil {
 		return err
 	}
 
-	d.logger.Tracef("Matched %d documents and Modified %d documents", result.MatchedCount, result.ModifiedCount)
+	result, err := d.collection.UpdateOne(ctx, bson.M{"_id": objectID, "deleted_at": notDeleted}, bson.D{
+		{Key: "$set", Value: bson.D{{Key: "deleted_at", Value: deletedAt}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("failed to find user (id:%s): %w", id, user.ErrNotFound)
+	}
+	d.logger.Tracef("Deleted %d documents", result.ModifiedCount)
 
 	return nil
 }
 
-// Delete - func for delete user from database
-func (d *db) Delete(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+// Restore - func for bring soft deleted user back with his friends, returns restored user
+func (d *db) Restore(ctx context.Context, id string) (u user.User, err error) {
+	objectID, err := objectIDFromHex(id)
 	if err != nil {
-		return fmt.Errorf("failed to convert user ID to ObjectID. ID=%s", id)
+		return u, err
 	}
 
-	// filter for searching user in MongoDB
-	filter := bson.M{"_id": objectID}
+	filter := bson.M{"_id": objectID, "deleted_at": bson.M{"$exists": true}}
+	update := bson.D{{Key: "$unset", Value: bson.D{{Key: "deleted_at", Value: ""}}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
-	var u user.User
+	result := d.collection.FindOneAndUpdate(ctx, filter, update, opts)
+	if err = result.Err(); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return u, fmt.Errorf("failed to execute restore user query. error: %v", err)
+		}
 
-	// find user in a database
-	res := d.collection.FindOne(ctx, filter)
-	if err = res.Decode(&u); err != nil {
-		return fmt.Errorf("failed to decode user (id:%s) from DB due to error: %v", id, err)
+		// nothing is found for unknown user and for user who is not deleted
+		if u, err = d.findOne(ctx, objectID); err != nil {
+			return u, err
+		}
+		return user.User{}, fmt.Errorf("failed to restore user (id:%s): %w", id, user.ErrNotDeleted)
+	}
+	if err = result.Decode(&u); err != nil {
+		return u, fmt.Errorf("failed to decode user (id:%s) from DB due to error: %v", id, err)
 	}
 
-	updateFilter := bson.M{"friends": u.Username}
-	updateResult, err := d.collection.UpdateMany(ctx, updateFilter, bson.D{
-		{"$pull", bson.D{{"friends", u.Username}}},
-	})
+	return u, d.activeFriends(ctx, &u)
+}
+
+// FindDeleted - find ids of users deleted at or before time
+func (d *db) FindDeleted(ctx context.Context, before time.Time) ([]string, error) {
+	opts := options.Find().
+		SetProjection(bson.D{{Key: "_id", Value: 1}}).
+		SetSort(bson.D{{Key: "deleted_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := d.collection.Find(ctx, bson.M{"deleted_at": bson.M{"$lte": before}}, opts)
 	if err != nil {
-		return fmt.Errorf("failed delete from other users friends. error: %v", err)
+		return nil, fmt.Errorf("failed to find deleted users due to error: %v", err)
 	}
-	d.logger.Tracef("Modified %d documents", updateResult.ModifiedCount)
+	defer cursor.Close(ctx)
 
-	result, err := d.collection.DeleteOne(ctx, filter)
-	if err != nil {
-		return fmt.Errorf("failed to execute query. error: %v", err)
+	var deleted []user.User
+	if err = cursor.All(ctx, &deleted); err != nil {
+		return nil, fmt.Errorf("failed to decode deleted users from DB due to error: %v", err)
+	}
+	ids := make([]string, 0, len(deleted))
+	for _, u := range deleted {
+		ids = append(ids, u.ID)
 	}
-	if result.DeletedCount == 0 {
+
+	return ids, nil
+}
+
+// Purge - func for delete soft deleted user from database and from friends of other users in one transaction
+func (d *db) Purge(ctx context.Context, id string) error {
+	objectID, err := objectIDFromHex(id)
+	if err != nil {
 		return err
 	}
-	d.logger.Tracef("Deleted %d documents", result.DeletedCount)
 
-	return nil
+	// filter for searching deleted user in MongoDB
+	filter := bson.M{"_id": objectID, "deleted_at": bson.M{"$exists": true}}
+
+	return d.withTransaction(ctx, func(ctx context.Context) error {
+		// user is deleted before its id is pulled, so without transaction crash leaves only
+		// ids of purged user in friends, GetUserFriends skips and pulls them
+		var purged bson.Raw
+		err := d.collection.FindOneAndDelete(ctx, filter).Decode(&purged)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("failed to find deleted user (id:%s): %w", id, user.ErrNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to execute query. error: %v", err)
+		}
+		d.logger.Tracef("Purged user %s", id)
+
+		var updateResult *mongo.UpdateResult
+		if err = d.failBetweenWrites("Purge"); err == nil {
+			updateResult, err = d.collection.UpdateMany(ctx, bson.M{"friends": objectID}, bson.D{
+				{Key: "$pull", Value: bson.D{{Key: "friends", Value: objectID}}},
+			})
+		}
+		if err != nil {
+			// without transaction user is inserted back, so he is purged with his friendships next time
+			if !inTransaction(ctx) {
+				d.undoPurge(ctx, purged)
+			}
+			return fmt.Errorf("failed delete from other users friends. error: %w", err)
+		}
+		d.logger.Tracef("Modified %d documents", updateResult.ModifiedCount)
+
+		return nil
+	})
 }
 
+// MakeFriends - func for add users ObjectIDs to friends of each other in one transaction, $addToSet doesn't duplicate ids, returns updated users
 func (d *db) MakeFriends(ctx context.Context, firstUserID string, secondUserID string) (firstUser user.User, secondUser user.User, err error) {
-	firstObjectID, err := primitive.ObjectIDFromHex(firstUserID)
+	firstObjectID, err := objectIDFromHex(firstUserID)
 	if err != nil {
-		return firstUser, secondUser, fmt.Errorf("failed to convert user ID to ObjectID. ID=%s", firstUserID)
+		return firstUser, secondUser, err
 	}
-	secondObjectID, err := primitive.ObjectIDFromHex(secondUserID)
+	secondObjectID, err := objectIDFromHex(secondUserID)
 	if err != nil {
-		return firstUser, secondUser, fmt.Errorf("failed to convert user ID to ObjectID. ID=%s", secondUserID)
+		return firstUser, secondUser, err
 	}
 
-	// filter for mongoDB for first user
-	filter := bson.M{"_id": firstObjectID}
+	err = d.withTransaction(ctx, func(ctx context.Context) error {
+		// find first user in database
+		if firstUser, err = d.findOne(ctx, firstObjectID); err != nil {
+			return err
+		}
 
-	// find first user in database
-	res := d.collection.FindOne(ctx, filter)
+		// find second user in database
+		if secondUser, err = d.findOne(ctx, secondObjectID); err != nil {
+			return err
+		}
 
-	// decoding first user
-	if err = res.Decode(&firstUser); err != nil {
-		return firstUser, secondUser, fmt.Errorf("failed to decode user (id:%s) from DB due to error: %v", firstUserID, err)
-	}
+		// friendship in only one direction is completed below, instead of conflict
+		if contains(firstUser.Friends, secondUser.ID) && contains(secondUser.Friends, firstUser.ID) {
+			return fmt.Errorf("failed to make friends %s and %s: %w", firstUserID, secondUserID, user.ErrAlreadyFriends)
+		}
+		firstHadFriend := contains(firstUser.Friends, secondUser.ID)
+
+		// updating first user in database
+		firstUser, err = d.findOneAndUpdate(ctx, firstObjectID, bson.D{
+			{Key: "$addToSet", Value: bson.D{{Key: "friends", Value: secondObjectID}}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add friend to friends. error: %w", err)
+		}
 
-	// filter for mongoDB for second user
-	filter = bson.M{"_id": secondObjectID}
+		// updating second user in database
+		if err = d.failBetweenWrites("MakeFriends"); err == nil {
+			secondUser, err = d.findOneAndUpdate(ctx, secondObjectID, bson.D{
+				{Key: "$addToSet", Value: bson.D{{Key: "friends", Value: firstObjectID}}},
+			})
+		}
+		if err != nil {
+			// without transaction first update is undone, so friendship is not left in one direction
+			if !inTransaction(ctx) && !firstHadFriend {
+				d.undoAddFriend(ctx, firstObjectID, secondObjectID)
+			}
+			return fmt.Errorf("failed to add friend to friends. error: %w", err)
+		}
 
-	// // find second user in database
-	res = d.collection.FindOne(ctx, filter)
+		return nil
+	})
+	if err != nil {
+		return firstUser, secondUser, err
+	}
 
-	// decoding second user
-	if err = res.Decode(&secondUser); err != nil {
-		return firstUser, secondUser, fmt.Errorf("failed to decode user (id:%s) from DB due to error: %v", secondUserID, err)
+	return firstUser, secondUser, d.activeFriends(ctx, &firstUser, &secondUser)
+}
+
+// Unfriend - func for pull users ObjectIDs from friends of each other in one transaction, friendship in one direction is removed too, returns updated users
+func (d *db) Unfriend(ctx context.Context, firstUserID string, secondUserID string) (firstUser user.User, secondUser user.User, err error) {
+	firstObjectID, err := objectIDFromHex(firstUserID)
+	if err != nil {
+		return firstUser, secondUser, err
+	}
+	secondObjectID, err := objectIDFromHex(secondUserID)
+	if err != nil {
+		return firstUser, secondUser, err
 	}
 
-	// filter for updating first user
-	updateFilter := bson.M{"_id": firstObjectID}
+	err = d.withTransaction(ctx, func(ctx context.Context) error {
+		if firstUser, err = d.findOne(ctx, firstObjectID); err != nil {
+			return err
+		}
+		if secondUser, err = d.findOne(ctx, secondObjectID); err != nil {
+			return err
+		}
+
+		if !contains(firstUser.Friends, secondUser.ID) && !contains(secondUser.Friends, firstUser.ID) {
+			return fmt.Errorf("failed to unfriend %s and %s: %w", firstUserID, secondUserID, user.ErrNotFriends)
+		}
+		firstHadFriend := contains(firstUser.Friends, secondUser.ID)
+
+		// without transaction crash between updates leaves friendship in one direction, repeated Unfriend removes it
+		firstUser, err = d.findOneAndUpdate(ctx, firstObjectID, bson.D{
+			{Key: "$pull", Value: bson.D{{Key: "friends", Value: secondObjectID}}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove friend from friends. error: %w", err)
+		}
+		if err = d.failBetweenWrites("Unfriend"); err == nil {
+			secondUser, err = d.findOneAndUpdate(ctx, secondObjectID, bson.D{
+				{Key: "$pull", Value: bson.D{{Key: "friends", Value: firstObjectID}}},
+			})
+		}
+		if err != nil {
+			// without transaction first update is undone, so friendship is kept in both directions
+			if !inTransaction(ctx) && firstHadFriend {
+				d.undoRemoveFriend(ctx, firstObjectID, secondObjectID)
+			}
+			return fmt.Errorf("failed to remove friend from friends. error: %w", err)
+		}
 
-	// updating first user in database
-	updateResult, err := d.collection.UpdateOne(ctx, updateFilter, bson.D{
-		{"$push", bson.D{{"friends", secondUser.Username}}},
+		return nil
 	})
 	if err != nil {
-		return firstUser, secondUser, fmt.Errorf("failed to add friend to friends. error: %v", err)
+		return firstUser, secondUser, err
 	}
-	d.logger.Tracef("Modified %d documents", updateResult.ModifiedCount)
 
-	// filter for updating second user
-	updateFilter = bson.M{"_id": secondObjectID}
+	return firstUser, secondUser, d.activeFriends(ctx, &firstUser, &secondUser)
+}
+
+// undoAddFriend - pull friend added without transaction, failure is only logged,
+// friendship in one direction is completed by repeated MakeFriends
+func (d *db) undoAddFriend(ctx context.Context, oid, friendOID primitive.ObjectID) {
+	_, err := d.collection.UpdateOne(ctx, bson.M{"_id": oid}, bson.D{
+		{Key: "$pull", Value: bson.D{{Key: "friends", Value: friendOID}}},
+	})
+	if err != nil {
+		d.logger.Errorf("failed to undo friend %s of user %s. error: %v", friendOID.Hex(), oid.Hex(), err)
+	}
+}
 
-	// updating second user in database
-	updateResult, err = d.collection.UpdateOne(ctx, updateFilter, bson.D{
-		{"$push", bson.D{{"friends", firstUser.Username}}},
+// undoRemoveFriend - add back friend removed without transaction, failure is only logged,
+// friendship in one direction is removed by repeated Unfriend
+func (d *db) undoRemoveFriend(ctx context.Context, oid, friendOID primitive.ObjectID) {
+	_, err := d.collection.UpdateOne(ctx, bson.M{"_id": oid}, bson.D{
+		{Key: "$addToSet", Value: bson.D{{Key: "friends", Value: friendOID}}},
 	})
 	if err != nil {
-		return firstUser, secondUser, fmt.Errorf("failed to add friend to friends. error: %v", err)
+		d.logger.Errorf("failed to undo removal of friend %s of user %s. error: %v", friendOID.Hex(), oid.Hex(), err)
+	}
+}
+
+// undoPurge - insert back user purged without transaction, failure is only logged,
+// ids of purged user left in friends are skipped and pulled by GetUserFriends
+func (d *db) undoPurge(ctx context.Context, purged bson.Raw) {
+	if _, err := d.collection.InsertOne(ctx, purged); err != nil {
+		d.logger.Errorf("failed to undo purge of user %v. error: %v", purged.Lookup("_id"), err)
+	}
+}
+
+// ageNumber - age converted to int, null for not numeric age
+var ageNumber = bson.D{{Key: "$convert", Value: bson.D{
+	{Key: "input", Value: "$age"},
+	{Key: "to", Value: "int"},
+	{Key: "onError", Value: nil},
+	{Key: "onNull", Value: nil},
+}}}
+
+// notDeleted - condition on deleted_at of users who are not deleted
+var notDeleted = bson.M{"$exists": false}
+
+// listQuery - query for users matched by filter, deleted users are never matched
+func listQuery(filter user.Filter) bson.D {
+	query := bson.D{{Key: "deleted_at", Value: notDeleted}}
+
+	// ids of wrong format can't be in collection
+	if filter.IDs != nil {
+		oids := bson.A{}
+		for _, id := range filter.IDs {
+			if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+				oids = append(oids, oid)
+			}
+		}
+		query = append(query, bson.E{Key: "_id", Value: bson.D{{Key: "$in", Value: oids}}})
+	}
+
+	// anchored regex uses username index
+	if filter.UsernamePrefix != "" {
+		query = append(query, bson.E{Key: "username", Value: bson.D{
+			{Key: "$regex", Value: "^" + regexp.QuoteMeta(filter.UsernamePrefix)},
+		}})
+	}
+
+	if filter.MinAge != nil || filter.MaxAge != nil {
+		conditions := bson.A{bson.D{{Key: "$ne", Value: bson.A{ageNumber, nil}}}}
+		if filter.MinAge != nil {
+			conditions = append(conditions, bson.D{{Key: "$gte", Value: bson.A{ageNumber, *filter.MinAge}}})
+		}
+		if filter.MaxAge != nil {
+			conditions = append(conditions, bson.D{{Key: "$lte", Value: bson.A{ageNumber, *filter.MaxAge}}})
+		}
+		query = append(query, bson.E{Key: "$expr", Value: bson.D{{Key: "$and", Value: conditions}}})
 	}
-	d.logger.Tracef("Modified %d documents", updateResult.ModifiedCount)
 
-	return firstUser, secondUser, nil
+	return query
+}
+
+// resolveFriends - usernames of friends by one query in order of ids, deleted friends are skipped,
+// ids of users which don't exist are returned as missing
+func (d *db) resolveFriends(ctx context.Context, ids []string) (friends []user.Friend, missing []primitive.ObjectID, err error) {
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		oid, err := objectIDFromHex(id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("friend is not an ObjectID, run friends migration: %v", err)
+		}
+		oids = append(oids, oid)
+	}
+
+	opts := options.Find().SetProjection(bson.D{{Key: "username", Value: 1}, {Key: "deleted_at", Value: 1}})
+	cursor, err := d.collection.Find(ctx, bson.M{"_id": bson.M{"$in": oids}}, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var found []user.User
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode friends from DB due to error: %v", err)
+	}
+	byID := make(map[string]user.User, len(found))
+	for _, friend := range found {
+		byID[friend.ID] = friend
+	}
+
+	friends = make([]user.Friend, 0, len(ids))
+	for i, id := range ids {
+		friend, ok := byID[id]
+		switch {
+		case !ok:
+			missing = append(missing, oids[i])
+		case friend.DeletedAt == nil:
+			friends = append(friends, user.Friend{ID: id, Username: friend.Username})
+		}
+	}
+
+	return friends, missing, nil
+}
+
+// findOne - find and decode user by ObjectID, deleted user is not found
+func (d *db) findOne(ctx context.Context, oid primitive.ObjectID) (u user.User, err error) {
+	// filter for searching user in MongoDB
+	filter := bson.M{"_id": oid, "deleted_at": notDeleted}
+
+	result := d.collection.FindOne(ctx, filter)
+	if err = result.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return u, fmt.Errorf("failed to find user (id:%s): %w", oid.Hex(), user.ErrNotFound)
+		}
+		return u, fmt.Errorf("failed to find one user by id: %s due to error: %v", oid.Hex(), err)
+	}
+
+	// decoding data to go struct
+	if err = result.Decode(&u); err != nil {
+		return u, fmt.Errorf("failed to decode user (id:%s) from DB due to error: %v", oid.Hex(), err)
+	}
+
+	return u, nil
+}
+
+// findOneAndUpdate - update user by ObjectID and decode it after update, deleted user is not found
+func (d *db) findOneAndUpdate(ctx context.Context, oid primitive.ObjectID, update interface{}) (u user.User, err error) {
+	filter := bson.M{"_id": oid, "deleted_at": notDeleted}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	result := d.collection.FindOneAndUpdate(ctx, filter, update, opts)
+	if err = result.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return u, fmt.Errorf("failed to find user (id:%s): %w", oid.Hex(), user.ErrNotFound)
+		}
+		return u, fmt.Errorf("failed to execute update user query. error: %v", err)
+	}
+
+	if err = result.Decode(&u); err != nil {
+		return u, fmt.Errorf("failed to decode user (id:%s) from DB due to error: %v", oid.Hex(), err)
+	}
+
+	return u, nil
+}
+
+// activeFriends - remove deleted friends from users by one query, they are kept in database for Restore
+func (d *db) activeFriends(ctx context.Context, users ...*user.User) error {
+	var oids []primitive.ObjectID
+	for _, u := range users {
+		for _, id := range u.Friends {
+			oid, err := objectIDFromHex(id)
+			if err != nil {
+				return fmt.Errorf("friend is not an ObjectID, run friends migration: %v", err)
+			}
+			oids = append(oids, oid)
+		}
+	}
+	if len(oids) == 0 {
+		return nil
+	}
+
+	opts := options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := d.collection.Find(ctx, bson.M{"_id": bson.M{"$in": oids}, "deleted_at": bson.M{"$exists": true}}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to find deleted friends due to error: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var deleted []user.User
+	if err = cursor.All(ctx, &deleted); err != nil {
+		return fmt.Errorf("failed to decode deleted friends from DB due to error: %v", err)
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+
+	isDeleted := make(map[string]bool, len(deleted))
+	for _, friend := range deleted {
+		isDeleted[friend.ID] = true
+	}
+	for _, u := range users {
+		friends := make([]string, 0, len(u.Friends))
+		for _, id := range u.Friends {
+			if !isDeleted[id] {
+				friends = append(friends, id)
+			}
+		}
+		u.Friends = friends
+	}
+
+	return nil
+}
+
+// objectIDFromHex - convert user ID to ObjectID
+func objectIDFromHex(id string) (primitive.ObjectID, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return oid, fmt.Errorf("failed to convert user ID to ObjectID. ID=%s: %w", id, user.ErrInvalidID)
+	}
+	return oid, nil
+}
+
+// contains - check that id is in friends
+func contains(friends []string, id string) bool {
+	for _, friend := range friends {
+		if friend == id {
+			return true
+		}
+	}
+	return false
 }
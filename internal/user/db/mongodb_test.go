@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"project/internal/user"
+	"project/internal/user/storagetest"
+	"project/pkg/logging"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// uriEnv - environment variable with URI of MongoDB for tests, tests are skipped without it.
+// Every test gets its own database, which is dropped after test
+const uriEnv = "MONGODB_TEST_URI"
+
+// collections of tests
+const (
+	usersCollection     = "users"
+	requestsCollection  = "friend_requests"
+	relationsCollection = "relations"
+	tokensCollection    = "refresh_tokens"
+	apiKeysCollection   = "api_keys"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) user.Storage {
+		return NewStorage(openDB(t), usersCollection, logging.GetLogger())
+	})
+}
+
+func TestGraph(t *testing.T) {
+	storagetest.RunGraph(t, func(t *testing.T) user.Storage {
+		return NewStorage(openDB(t), usersCollection, logging.GetLogger())
+	})
+}
+
+func TestRequests(t *testing.T) {
+	storagetest.RunRequests(t, func(t *testing.T) (user.Storage, user.RequestStorage) {
+		database := openDB(t)
+		return NewStorage(database, usersCollection, logging.GetLogger()), NewRequestStorage(database, requestsCollection, logging.GetLogger())
+	})
+}
+
+func TestRelations(t *testing.T) {
+	storagetest.RunRelations(t, func(t *testing.T) (user.Storage, user.RelationStorage) {
+		database := openDB(t)
+		return NewStorage(database, usersCollection, logging.GetLogger()), NewRelationStorage(database, relationsCollection, logging.GetLogger())
+	})
+}
+
+func TestTokens(t *testing.T) {
+	storagetest.RunTokens(t, func(t *testing.T) (user.Storage, user.TokenStorage) {
+		database := openDB(t)
+		return NewStorage(database, usersCollection, logging.GetLogger()), NewTokenStorage(database, tokensCollection, logging.GetLogger())
+	})
+}
+
+func TestAPIKeys(t *testing.T) {
+	storagetest.RunAPIKeys(t, func(t *testing.T) user.APIKeyStorage {
+		return NewAPIKeyStorage(openDB(t), apiKeysCollection, logging.GetLogger())
+	})
+}
+
+// openDB - connect to MongoDB of uriEnv and create new database with indexes of all collections,
+// database is dropped and connection is closed after test
+func openDB(t *testing.T) *mongo.Database {
+	uri := os.Getenv(uriEnv)
+	if uri == "" {
+		t.Skipf("%s is not set", uriEnv)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	if err = client.Ping(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	database := client.Database(fmt.Sprintf("users_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() { database.Drop(context.Background()) })
+
+	logger := logging.GetLogger()
+	for _, createIndexes := range []func() error{
+		func() error { return CreateIndexes(ctx, database, usersCollection, logger) },
+		func() error { return CreateRequestIndexes(ctx, database, requestsCollection, logger) },
+		func() error { return CreateRelationIndexes(ctx, database, relationsCollection, logger) },
+		func() error { return CreateTokenIndexes(ctx, database, tokensCollection, logger) },
+		func() error { return CreateAPIKeyIndexes(ctx, database, apiKeysCollection, logger) },
+	} {
+		if err = createIndexes(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return database
+}
@@ -0,0 +1,157 @@
+package db
+
+// file for blocks and mutes in MongoDB database
+
+import (
+	"context"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Create relations database structure
+type relationDB struct {
+	collection *mongo.Collection
+	logger     *logging.Logger
+}
+
+// NewRelationStorage - Initialize new storage of blocks and mutes
+func NewRelationStorage(database *mongo.Database, collection string, logger *logging.Logger) user.RelationStorage {
+	return &relationDB{
+		collection: database.Collection(collection),
+		logger:     logger,
+	}
+}
+
+// CreateRelationIndexes - create indexes for relations, unique key keeps one relation of every kind from user to target
+func CreateRelationIndexes(ctx context.Context, database *mongo.Database, collection string, logger *logging.Logger) error {
+	names, err := database.Collection(collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "target_id", Value: 1}, {Key: "kind", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "target_id", Value: 1}, {Key: "kind", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create relations indexes due to error: %v", err)
+	}
+	logger.Tracef("Created indexes %v", names)
+
+	return nil
+}
+
+// SaveRelation - upsert relation, existing relation is not changed and is returned
+func (d *relationDB) SaveRelation(ctx context.Context, r user.Relation) (saved user.Relation, err error) {
+	d.logger.Debug("save relation")
+
+	key, err := relationKey(r.UserID, r.TargetID, r.Kind)
+	if err != nil {
+		return saved, err
+	}
+
+	// users are stored as ObjectIDs like in friends
+	update := bson.D{{Key: "$setOnInsert", Value: bson.D{{Key: "created_at", Value: r.CreatedAt}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	result := d.collection.FindOneAndUpdate(ctx, key, update, opts)
+	if err = result.Err(); err != nil {
+		return saved, fmt.Errorf("failed to save relation due to error: %v", err)
+	}
+	if err = result.Decode(&saved); err != nil {
+		return saved, fmt.Errorf("failed to decode relation from DB due to error: %v", err)
+	}
+
+	return saved, nil
+}
+
+// DeleteRelation - delete relation of kind from user to target
+func (d *relationDB) DeleteRelation(ctx context.Context, userID string, targetID string, kind string) error {
+	key, err := relationKey(userID, targetID, kind)
+	if err != nil {
+		return err
+	}
+
+	result, err := d.collection.DeleteOne(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete relation. error: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("failed to find %s of %s by %s: %w", kind, targetID, userID, user.ErrRelationNotFound)
+	}
+
+	return nil
+}
+
+// FindRelations - find relations matched by filter, newer relations go first
+func (d *relationDB) FindRelations(ctx context.Context, filter user.RelationFilter) ([]user.Relation, error) {
+	query := bson.D{}
+	for _, field := range []struct {
+		key   string
+		value string
+	}{
+		{"user_id", filter.UserID},
+		{"target_id", filter.TargetID},
+	} {
+		if field.value == "" {
+			continue
+		}
+		oid, err := objectIDFromHex(field.value)
+		if err != nil {
+			return nil, err
+		}
+		query = append(query, bson.E{Key: field.key, Value: oid})
+	}
+	if filter.Kind != "" {
+		query = append(query, bson.E{Key: "kind", Value: filter.Kind})
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "target_id", Value: -1}, {Key: "user_id", Value: -1}})
+	cursor, err := d.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find relations due to error: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	relations := []user.Relation{}
+	if err = cursor.All(ctx, &relations); err != nil {
+		return nil, fmt.Errorf("failed to decode relations from DB due to error: %v", err)
+	}
+
+	return relations, nil
+}
+
+// DeleteRelations - delete all relations from and to user
+func (d *relationDB) DeleteRelations(ctx context.Context, userID string) error {
+	oid, err := objectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+
+	result, err := d.collection.DeleteMany(ctx, bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "user_id", Value: oid}},
+		bson.D{{Key: "target_id", Value: oid}},
+	}}})
+	if err != nil {
+		return fmt.Errorf("failed to delete relations. error: %v", err)
+	}
+	d.logger.Tracef("Deleted %d relations", result.DeletedCount)
+
+	return nil
+}
+
+// relationKey - filter of the only relation of kind from user to target
+func relationKey(userID, targetID, kind string) (bson.D, error) {
+	userOID, err := objectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	targetOID, err := objectIDFromHex(targetID)
+	if err != nil {
+		return nil, err
+	}
+	return bson.D{{Key: "user_id", Value: userOID}, {Key: "target_id", Value: targetOID}, {Key: "kind", Value: kind}}, nil
+}
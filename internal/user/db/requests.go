@@ -0,0 +1,212 @@
+package db
+
+// file for friend requests in MongoDB database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Create friend requests database structure
+type requestDB struct {
+	collection *mongo.Collection
+	logger     *logging.Logger
+}
+
+// NewRequestStorage - Initialize new storage of friend requests
+func NewRequestStorage(database *mongo.Database, collection string, logger *logging.Logger) user.RequestStorage {
+	return &requestDB{
+		collection: database.Collection(collection),
+		logger:     logger,
+	}
+}
+
+// CreateRequestIndexes - create indexes for friend requests, unique pair keeps one pending request between users
+func CreateRequestIndexes(ctx context.Context, database *mongo.Database, collection string, logger *logging.Logger) error {
+	names, err := database.Collection(collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "pair", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.D{{Key: "status", Value: user.RequestPending}}),
+		},
+		{Keys: bson.D{{Key: "from_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "to_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create friend requests indexes due to error: %v", err)
+	}
+	logger.Tracef("Created indexes %v", names)
+
+	return nil
+}
+
+// CreateRequest - create new friend request, unique index of pair keeps one pending request between users
+func (d *requestDB) CreateRequest(ctx context.Context, r user.FriendRequest) (user.FriendRequest, error) {
+	d.logger.Debug("create friend request")
+
+	fromID, err := objectIDFromHex(r.FromID)
+	if err != nil {
+		return user.FriendRequest{}, err
+	}
+	toID, err := objectIDFromHex(r.ToID)
+	if err != nil {
+		return user.FriendRequest{}, err
+	}
+
+	// users are stored as ObjectIDs like in friends
+	result, err := d.collection.InsertOne(ctx, bson.D{
+		{Key: "from_id", Value: fromID},
+		{Key: "to_id", Value: toID},
+		{Key: "pair", Value: requestPair(fromID, toID)},
+		{Key: "status", Value: r.Status},
+		{Key: "created_at", Value: r.CreatedAt},
+		{Key: "updated_at", Value: r.UpdatedAt},
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return user.FriendRequest{}, fmt.Errorf("failed to create friend request from %s to %s: %w", r.FromID, r.ToID, user.ErrRequestExists)
+		}
+		return user.FriendRequest{}, fmt.Errorf("failed to create friend request due to error: %v", err)
+	}
+
+	oid, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return user.FriendRequest{}, fmt.Errorf("failed to convert objectid to hex. probably oid: %v", result.InsertedID)
+	}
+	r.ID = oid.Hex()
+
+	return r, nil
+}
+
+// FindRequest - find friend request by id
+func (d *requestDB) FindRequest(ctx context.Context, id string) (r user.FriendRequest, err error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return r, requestNotFound(id)
+	}
+
+	result := d.collection.FindOne(ctx, bson.M{"_id": oid})
+	if err = result.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return r, requestNotFound(id)
+		}
+		return r, fmt.Errorf("failed to find friend request (id:%s) due to error: %v", id, err)
+	}
+	if err = result.Decode(&r); err != nil {
+		return r, fmt.Errorf("failed to decode friend request (id:%s) from DB due to error: %v", id, err)
+	}
+
+	return r, nil
+}
+
+// FindRequests - find friend requests matched by filter, newer requests go first
+func (d *requestDB) FindRequests(ctx context.Context, filter user.RequestFilter) ([]user.FriendRequest, error) {
+	query := bson.D{}
+	for _, field := range []struct {
+		key   string
+		value string
+	}{
+		{"from_id", filter.FromID},
+		{"to_id", filter.ToID},
+	} {
+		if field.value == "" {
+			continue
+		}
+		oid, err := objectIDFromHex(field.value)
+		if err != nil {
+			return nil, err
+		}
+		query = append(query, bson.E{Key: field.key, Value: oid})
+	}
+	if filter.Status != "" {
+		query = append(query, bson.E{Key: "status", Value: filter.Status})
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := d.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find friend requests due to error: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	requests := []user.FriendRequest{}
+	if err = cursor.All(ctx, &requests); err != nil {
+		return nil, fmt.Errorf("failed to decode friend requests from DB due to error: %v", err)
+	}
+
+	return requests, nil
+}
+
+// UpdateRequestStatus - change status of friend request, if it has status from
+func (d *requestDB) UpdateRequestStatus(ctx context.Context, id string, from, to string, updatedAt time.Time) (r user.FriendRequest, err error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return r, requestNotFound(id)
+	}
+
+	filter := bson.D{{Key: "_id", Value: oid}, {Key: "status", Value: from}}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "status", Value: to},
+		{Key: "updated_at", Value: updatedAt},
+	}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	result := d.collection.FindOneAndUpdate(ctx, filter, update, opts)
+	if err = result.Err(); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return r, fmt.Errorf("failed to execute update friend request query. error: %v", err)
+		}
+
+		// nothing is found for unknown request and for request with other status
+		if r, err = d.FindRequest(ctx, id); err != nil {
+			return r, err
+		}
+		return r, fmt.Errorf("friend request %s has status %s, not %s: %w", id, r.Status, from, user.ErrRequestState)
+	}
+	if err = result.Decode(&r); err != nil {
+		return r, fmt.Errorf("failed to decode friend request (id:%s) from DB due to error: %v", id, err)
+	}
+
+	return r, nil
+}
+
+// DeleteRequests - delete all friend requests from and to user
+func (d *requestDB) DeleteRequests(ctx context.Context, userID string) error {
+	oid, err := objectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+
+	result, err := d.collection.DeleteMany(ctx, bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "from_id", Value: oid}},
+		bson.D{{Key: "to_id", Value: oid}},
+	}}})
+	if err != nil {
+		return fmt.Errorf("failed to delete friend requests. error: %v", err)
+	}
+	d.logger.Tracef("Deleted %d friend requests", result.DeletedCount)
+
+	return nil
+}
+
+// requestPair - the same key for requests between users in both directions
+func requestPair(a, b primitive.ObjectID) string {
+	if a.Hex() > b.Hex() {
+		a, b = b, a
+	}
+	return a.Hex() + ":" + b.Hex()
+}
+
+// requestNotFound - error for friend request which doesn't exist
+func requestNotFound(id string) error {
+	return fmt.Errorf("failed to find friend request (id:%s): %w", id, user.ErrRequestNotFound)
+}
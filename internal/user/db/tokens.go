@@ -0,0 +1,123 @@
+package db
+
+// file for refresh tokens in MongoDB database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Create refresh tokens database structure
+type tokenDB struct {
+	collection *mongo.Collection
+	logger     *logging.Logger
+}
+
+// NewTokenStorage - Initialize new storage of refresh tokens
+func NewTokenStorage(database *mongo.Database, collection string, logger *logging.Logger) user.TokenStorage {
+	return &tokenDB{
+		collection: database.Collection(collection),
+		logger:     logger,
+	}
+}
+
+// CreateTokenIndexes - create indexes for refresh tokens, MongoDB deletes expired tokens by TTL index itself
+func CreateTokenIndexes(ctx context.Context, database *mongo.Database, collection string, logger *logging.Logger) error {
+	names, err := database.Collection(collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create refresh tokens indexes due to error: %v", err)
+	}
+	logger.Tracef("Created indexes %v", names)
+
+	return nil
+}
+
+// SaveToken - save refresh token, hash of token is its _id
+func (d *tokenDB) SaveToken(ctx context.Context, t user.RefreshToken) error {
+	d.logger.Debug("save refresh token")
+
+	// users are stored as ObjectIDs like in friends
+	userID, err := objectIDFromHex(t.UserID)
+	if err != nil {
+		return err
+	}
+	_, err = d.collection.InsertOne(ctx, bson.D{
+		{Key: "_id", Value: t.Hash},
+		{Key: "user_id", Value: userID},
+		{Key: "family_id", Value: t.FamilyID},
+		{Key: "created_at", Value: t.CreatedAt},
+		{Key: "expires_at", Value: t.ExpiresAt},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save refresh token due to error: %v", err)
+	}
+
+	return nil
+}
+
+// FindToken - find refresh token by hash
+func (d *tokenDB) FindToken(ctx context.Context, hash string) (t user.RefreshToken, err error) {
+	result := d.collection.FindOne(ctx, bson.M{"_id": hash})
+	if err = result.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return t, tokenNotFound(hash)
+		}
+		return t, fmt.Errorf("failed to find refresh token due to error: %v", err)
+	}
+	if err = result.Decode(&t); err != nil {
+		return t, fmt.Errorf("failed to decode refresh token from DB due to error: %v", err)
+	}
+
+	return t, nil
+}
+
+// RevokeToken - revoke refresh token, if it is not revoked yet
+func (d *tokenDB) RevokeToken(ctx context.Context, hash string, revokedAt time.Time) error {
+	filter := bson.M{"_id": hash, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": revokedAt}}
+
+	result, err := d.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to execute revoke refresh token query. error: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		// nothing is matched for unknown token and for revoked token
+		t, err := d.FindToken(ctx, hash)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("failed to revoke refresh token of user %s: %w", t.UserID, user.ErrTokenRevoked)
+	}
+
+	return nil
+}
+
+// RevokeFamily - revoke all refresh tokens of family which are not revoked yet
+func (d *tokenDB) RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	filter := bson.M{"family_id": familyID, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": revokedAt}}
+
+	result, err := d.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens. error: %v", err)
+	}
+	d.logger.Tracef("Revoked %d refresh tokens", result.ModifiedCount)
+
+	return nil
+}
+
+// tokenNotFound - error for refresh token which doesn't exist, only beginning of hash is logged
+func tokenNotFound(hash string) error {
+	return fmt.Errorf("failed to find refresh token (hash:%.8s): %w", hash, user.ErrTokenNotFound)
+}
@@ -0,0 +1,77 @@
+package db
+
+// file for transactions of multi-document operations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// withTransaction - run fn in session transaction, so all its writes are applied together or not at all.
+// Standalone MongoDB has no transactions, there fn runs without them and must order its writes
+// and undo them on error itself, see inTransaction.
+func (d *db) withTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	transactions, err := d.supportsTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	if !transactions {
+		return fn(ctx)
+	}
+
+	session, err := d.collection.Database().Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session due to error: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	// transaction is retried by driver on transient errors, so fn reads everything it needs inside
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+// failBetweenWrites - error of failpoint for operation, tests inject it between writes to check that nothing is left half done
+func (d *db) failBetweenWrites(operation string) error {
+	if d.failpoint == nil {
+		return nil
+	}
+	return d.failpoint(operation)
+}
+
+// inTransaction - check that ctx belongs to transaction of withTransaction
+func inTransaction(ctx context.Context) bool {
+	return mongo.SessionFromContext(ctx) != nil
+}
+
+// supportsTransactions - replica sets and sharded clusters support transactions, result is cached after success
+func (d *db) supportsTransactions(ctx context.Context) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.transactions != nil {
+		return *d.transactions, nil
+	}
+
+	var reply struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := d.collection.Database().RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&reply)
+	if err != nil {
+		return false, fmt.Errorf("failed to check MongoDB deployment due to error: %v", err)
+	}
+
+	// mongos answers with msg isdbgrid
+	transactions := reply.SetName != "" || reply.Msg == "isdbgrid"
+	if !transactions {
+		d.logger.Warn("MongoDB is not a replica set, multi-document operations run without transactions")
+	}
+	d.transactions = &transactions
+
+	return transactions, nil
+}
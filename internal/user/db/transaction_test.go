@@ -0,0 +1,186 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"project/internal/user"
+	"project/pkg/logging"
+	"sort"
+	"testing"
+	"time"
+)
+
+// errInjected - failure injected between writes
+var errInjected = errors.New("injected failure")
+
+// TestFailureBetweenWrites - failure between writes of multi-document operation leaves no partial friendship or request state,
+// with transaction of replica set and with undo of standalone server
+func TestFailureBetweenWrites(t *testing.T) {
+	modes := []struct {
+		name         string
+		transactions bool
+	}{
+		{"Transaction", true},
+		{"Standalone", false},
+	}
+	tests := []struct {
+		name string
+		test func(t *testing.T, s *db, fail func())
+	}{
+		{"MakeFriends", testFailMakeFriends},
+		{"Unfriend", testFailUnfriend},
+		{"Purge", testFailPurge},
+		{"AcceptFriendRequest", testFailAcceptRequest},
+	}
+
+	for _, mode := range modes {
+		t.Run(mode.name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					s := failingStorage(t, mode.transactions)
+					tt.test(t, s, func() {
+						s.failpoint = func(string) error { return errInjected }
+					})
+				})
+			}
+		})
+	}
+}
+
+// failingStorage - storage of new database without failure, transactions are required or turned off.
+// Test is skipped if transactions are required and deployment is standalone
+func failingStorage(t *testing.T, transactions bool) *db {
+	s := NewStorage(openDB(t), usersCollection, logging.GetLogger()).(*db)
+	if !transactions {
+		// replica set runs the fallback of standalone server too
+		s.transactions = &transactions
+		return s
+	}
+
+	supported, err := s.supportsTransactions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !supported {
+		t.Skip("MongoDB of tests is not a replica set")
+	}
+	return s
+}
+
+// testFailMakeFriends - failed MakeFriends adds friend to none of users
+func testFailMakeFriends(t *testing.T, s *db, fail func()) {
+	ctx := context.Background()
+	alice, bob := createUser(t, s, "alice"), createUser(t, s, "bob")
+
+	fail()
+	if _, _, err := s.MakeFriends(ctx, alice, bob); !errors.Is(err, errInjected) {
+		t.Fatalf("MakeFriends error = %v, want %v", err, errInjected)
+	}
+	checkFriends(t, s, alice)
+	checkFriends(t, s, bob)
+}
+
+// testFailUnfriend - failed Unfriend keeps friendship in both directions
+func testFailUnfriend(t *testing.T, s *db, fail func()) {
+	ctx := context.Background()
+	alice, bob := createUser(t, s, "alice"), createUser(t, s, "bob")
+	if _, _, err := s.MakeFriends(ctx, alice, bob); err != nil {
+		t.Fatal(err)
+	}
+
+	fail()
+	if _, _, err := s.Unfriend(ctx, alice, bob); !errors.Is(err, errInjected) {
+		t.Fatalf("Unfriend error = %v, want %v", err, errInjected)
+	}
+	checkFriends(t, s, alice, bob)
+	checkFriends(t, s, bob, alice)
+}
+
+// testFailPurge - failed Purge keeps deleted user with his friendships, so he can be restored or purged again
+func testFailPurge(t *testing.T, s *db, fail func()) {
+	ctx := context.Background()
+	alice, bob := createUser(t, s, "alice"), createUser(t, s, "bob")
+	if _, _, err := s.MakeFriends(ctx, alice, bob); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, alice, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	fail()
+	if err := s.Purge(ctx, alice); !errors.Is(err, errInjected) {
+		t.Fatalf("Purge error = %v, want %v", err, errInjected)
+	}
+	if deleted, err := s.FindDeleted(ctx, time.Now()); err != nil || len(deleted) != 1 || deleted[0] != alice {
+		t.Fatalf("FindDeleted = %v, %v, want [%s]", deleted, err, alice)
+	}
+	restored, err := s.Restore(ctx, alice)
+	if err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	if len(restored.Friends) != 1 || restored.Friends[0] != bob {
+		t.Errorf("friends of restored user = %v, want [%s]", restored.Friends, bob)
+	}
+	checkFriends(t, s, bob, alice)
+}
+
+// testFailAcceptRequest - request whose friendship failed is pending again and users are not friends
+func testFailAcceptRequest(t *testing.T, s *db, fail func()) {
+	ctx := context.Background()
+	requests := NewRequestStorage(s.collection.Database(), requestsCollection, logging.GetLogger())
+	relations := NewRelationStorage(s.collection.Database(), relationsCollection, logging.GetLogger())
+	service, err := user.NewService(s, requests, relations, *logging.GetLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, bob := createUser(t, s, "alice"), createUser(t, s, "bob")
+	request, err := service.SendFriendRequest(ctx, alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fail()
+	if _, err = service.AcceptFriendRequest(ctx, bob, request.ID); !errors.Is(err, errInjected) {
+		t.Fatalf("AcceptFriendRequest error = %v, want %v", err, errInjected)
+	}
+	if request, err = requests.FindRequest(ctx, request.ID); err != nil || request.Status != user.RequestPending {
+		t.Errorf("request after failure = %+v, %v, want pending", request, err)
+	}
+	checkFriends(t, s, alice)
+	checkFriends(t, s, bob)
+}
+
+// createUser - create user and return his id
+func createUser(t *testing.T, s user.Storage, username string) string {
+	t.Helper()
+
+	id, err := s.Create(context.Background(), user.User{Username: username})
+	if err != nil {
+		t.Fatalf("Create(%s) error: %v", username, err)
+	}
+	return id
+}
+
+// checkFriends - stored friends of user are exactly want, failure is turned off before reading
+func checkFriends(t *testing.T, s *db, id string, want ...string) {
+	t.Helper()
+
+	s.failpoint = nil
+	u, err := s.FindOne(context.Background(), id)
+	if err != nil {
+		t.Fatalf("FindOne(%s) error: %v", id, err)
+	}
+	got := append([]string(nil), u.Friends...)
+	sort.Strings(got)
+	sort.Strings(want)
+	if len(got) != len(want) {
+		t.Errorf("friends of %s = %v, want %v", u.Username, got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("friends of %s = %v, want %v", u.Username, got, want)
+			return
+		}
+	}
+}
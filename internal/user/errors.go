@@ -0,0 +1,84 @@
+package user
+
+// file for user errors, storages and service wrap them with %w, so callers check them with errors.Is and errors.As
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound - user with such id doesn't exist
+	ErrNotFound = errors.New("user not found")
+
+	// ErrInvalidID - id has wrong format for storage
+	ErrInvalidID = errors.New("invalid user id")
+
+	// ErrAlreadyFriends - users are friends already
+	ErrAlreadyFriends = errors.New("users are already friends")
+
+	// ErrNotFriends - users are not friends in any direction
+	ErrNotFriends = errors.New("users are not friends")
+
+	// ErrNotDeleted - user can't be restored, because he is not deleted
+	ErrNotDeleted = errors.New("user is not deleted")
+
+	// ErrNotConnected - there is no path of friendships between users
+	ErrNotConnected = errors.New("users are not connected")
+
+	// ErrRequestNotFound - friend request with such id doesn't exist or doesn't belong to user
+	ErrRequestNotFound = errors.New("friend request not found")
+
+	// ErrRequestExists - pending friend request between users exists in any direction
+	ErrRequestExists = errors.New("friend request already exists")
+
+	// ErrRequestState - friend request can't get new status from its current status
+	ErrRequestState = errors.New("friend request is not pending")
+
+	// ErrRequestForbidden - user can't change friend request in such way, only recipient accepts and declines, only sender cancels
+	ErrRequestForbidden = errors.New("friend request can't be changed by user")
+
+	// ErrBlocked - one of users blocked another, so they can't become friends
+	ErrBlocked = errors.New("user is blocked")
+
+	// ErrRelationNotFound - user has no block or mute of such kind for target
+	ErrRelationNotFound = errors.New("relation not found")
+
+	// ErrEmailExists - email belongs to another user
+	ErrEmailExists = errors.New("email is already registered")
+
+	// ErrInvalidCredentials - there is no user with such email and password
+	ErrInvalidCredentials = errors.New("invalid email or password")
+
+	// ErrInvalidRefreshToken - refresh token is unknown, expired or revoked
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+	// ErrTokenNotFound - refresh token with such hash doesn't exist
+	ErrTokenNotFound = errors.New("refresh token not found")
+
+	// ErrTokenRevoked - refresh token is revoked already
+	ErrTokenRevoked = errors.New("refresh token is revoked")
+
+	// ErrAPIKeyNotFound - API key with such id or hash doesn't exist
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	// ErrForbidden - caller is not allowed to change other users or to call admin routes
+	ErrForbidden = errors.New("operation is not allowed for caller")
+
+	// ErrUnauthorized - caller isn't authenticated, so he can't change users or call admin routes
+	ErrUnauthorized = errors.New("caller is not authenticated")
+)
+
+// ValidationError - error for malformed request or query, payloads are checked by validate tags of project/pkg/validator
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+// Error - text of validation error
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
@@ -0,0 +1,85 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"project/internal/middleware"
+	"project/pkg/validator"
+	"testing"
+)
+
+// TestAppError - errors of storages and service wrapped with %w get status and code of their kind, other errors are internal
+func TestAppError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{ErrInvalidID, http.StatusBadRequest, "invalid_id"},
+		{&ValidationError{Field: "limit", Message: "must be a number"}, http.StatusBadRequest, "validation_error"},
+		{validator.Errors{{Field: "username", Message: "is required"}}, http.StatusUnprocessableEntity, "validation_failed"},
+		{ErrNotFound, http.StatusNotFound, "not_found"},
+		{ErrAlreadyFriends, http.StatusConflict, "already_friends"},
+		{ErrNotFriends, http.StatusNotFound, "not_friends"},
+		{ErrNotDeleted, http.StatusConflict, "not_deleted"},
+		{ErrRequestExists, http.StatusConflict, "request_exists"},
+		{ErrRequestState, http.StatusConflict, "request_not_pending"},
+		{ErrBlocked, http.StatusForbidden, "blocked"},
+		{ErrForbidden, http.StatusForbidden, "forbidden"},
+		{ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+		{errors.New("connection refused"), http.StatusInternalServerError, "internal_error"},
+	}
+	for _, tt := range tests {
+		wrapped := fmt.Errorf("failed to call storage. error: %w", fmt.Errorf("user 1: %w", tt.err))
+		appErr := appError(wrapped)
+		if appErr.Status != tt.status || appErr.Code != tt.code {
+			t.Errorf("appError(%v) = %d %s, want %d %s", wrapped, appErr.Status, appErr.Code, tt.status, tt.code)
+		}
+		if appErr.Err != wrapped {
+			t.Errorf("appError(%v) wraps %v, error for logs is lost", wrapped, appErr.Err)
+		}
+	}
+}
+
+// TestTranslateErrors - error of handler is answered with its status and json error, internal details are not answered
+func TestTranslateErrors(t *testing.T) {
+	tests := []struct {
+		err     error
+		status  int
+		code    string
+		message string
+		field   string
+	}{
+		{fmt.Errorf("user 1: %w", ErrNotFound), http.StatusNotFound, "not_found", ErrNotFound.Error(), ""},
+		{&ValidationError{Field: "limit", Message: "must be a number"}, http.StatusBadRequest, "validation_error", "must be a number", "limit"},
+		{fmt.Errorf("secret dsn: %w", errors.New("connection refused")), http.StatusInternalServerError, "internal_error", http.StatusText(http.StatusInternalServerError), ""},
+	}
+	for _, tt := range tests {
+		handler := middleware.Errors(translateErrors(func(w http.ResponseWriter, r *http.Request) error {
+			return tt.err
+		}))
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil))
+
+		var envelope struct {
+			Data  interface{} `json:"data"`
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+				Field   string `json:"field"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+			t.Fatalf("answer %s is not json: %v", w.Body.String(), err)
+		}
+		if w.Code != tt.status || w.Header().Get("Content-Type") != "application/json" || envelope.Data != nil {
+			t.Errorf("%v: status %d, content type %q, data %v, want %d with json error", tt.err, w.Code, w.Header().Get("Content-Type"), envelope.Data, tt.status)
+		}
+		if envelope.Error.Code != tt.code || envelope.Error.Message != tt.message || envelope.Error.Field != tt.field {
+			t.Errorf("%v: error %+v, want %s %q of field %q", tt.err, envelope.Error, tt.code, tt.message, tt.field)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package user
+
+// file for description of users list filter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// fields for sorting list of users
+const (
+	SortByID       = "id"
+	SortByUsername = "username"
+	SortByAge      = "age"
+)
+
+// default and max size of one page of users
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Filter - filter, sorting and pagination for list of users
+type Filter struct {
+	// IDs - user has one of ids, nil matches all users, ids of wrong format match nobody
+	IDs []string
+
+	// UsernamePrefix - username starts with it, empty prefix matches all users
+	UsernamePrefix string
+
+	// MinAge and MaxAge - inclusive age range, users with not numeric age don't match range
+	MinAge *int
+	MaxAge *int
+
+	// SortBy - one of SortBy constants, users with equal field are sorted by id
+	SortBy string
+	Desc   bool
+
+	Limit  int
+	Offset int
+}
+
+// NumericAge - age as number, ok is false if age is not a number
+func NumericAge(age string) (n int, ok bool) {
+	value, err := strconv.ParseInt(age, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int(value), true
+}
+
+// MatchAge - check that age is in age range of filter
+func (f Filter) MatchAge(age string) bool {
+	if f.MinAge == nil && f.MaxAge == nil {
+		return true
+	}
+	n, ok := NumericAge(age)
+	if !ok {
+		return false
+	}
+	if f.MinAge != nil && n < *f.MinAge {
+		return false
+	}
+	if f.MaxAge != nil && n > *f.MaxAge {
+		return false
+	}
+	return true
+}
+
+// MatchID - check that id is one of ids of filter
+func (f Filter) MatchID(id string) bool {
+	if f.IDs == nil {
+		return true
+	}
+	for _, filterID := range f.IDs {
+		if filterID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Match - check that user matches ids, username prefix and age range of filter
+func (f Filter) Match(u User) bool {
+	return f.MatchID(u.ID) && strings.HasPrefix(u.Username, f.UsernamePrefix) && f.MatchAge(u.Age)
+}
+
+// Less - order of users for storages which sort users themselves, not numeric ages go before numeric
+func (f Filter) Less(a, b User) bool {
+	var cmp int
+	switch f.SortBy {
+	case SortByUsername:
+		cmp = strings.Compare(a.Username, b.Username)
+	case SortByAge:
+		cmp = compareAges(a.Age, b.Age)
+	}
+	if cmp == 0 {
+		cmp = compareIDs(a.ID, b.ID)
+	}
+	if f.Desc {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+// Page - users of page of filter from all sorted users
+func (f Filter) Page(users []User) []User {
+	if f.Offset >= len(users) {
+		return []User{}
+	}
+	users = users[f.Offset:]
+	if f.Limit > 0 && f.Limit < len(users) {
+		users = users[:f.Limit]
+	}
+	return users
+}
+
+// compareAges - compare ages as numbers
+func compareAges(a, b string) int {
+	x, okA := NumericAge(a)
+	y, okB := NumericAge(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// compareIDs - ids of one storage have the same alphabet, so shorter id is less (numeric ids)
+func compareIDs(a, b string) int {
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
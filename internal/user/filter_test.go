@@ -0,0 +1,111 @@
+package user
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestParseFilter - query of list of users is parsed to filter, wrong params are validation errors of their field
+func TestParseFilter(t *testing.T) {
+	age := func(n int) *int { return &n }
+
+	tests := []struct {
+		query string
+		want  Filter
+		field string
+	}{
+		{"", Filter{SortBy: SortByID}, ""},
+		{"username=al&min_age=18&max_age=30", Filter{UsernamePrefix: "al", MinAge: age(18), MaxAge: age(30), SortBy: SortByID}, ""},
+		{"min_age=0", Filter{MinAge: age(0), SortBy: SortByID}, ""},
+		{"sort=-username&limit=2&offset=4", Filter{SortBy: SortByUsername, Desc: true, Limit: 2, Offset: 4}, ""},
+		{"sort=age", Filter{SortBy: SortByAge}, ""},
+		{"sort=-id", Filter{SortBy: SortByID, Desc: true}, ""},
+		{"sort=name", Filter{}, "sort"},
+		{"limit=ten", Filter{}, "limit"},
+		{"offset=1.5", Filter{}, "offset"},
+		{"min_age=old", Filter{}, "min_age"},
+		{"max_age=", Filter{SortBy: SortByID}, ""},
+	}
+	for _, tt := range tests {
+		query, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filter, err := parseFilter(query)
+		var validationErr *ValidationError
+		switch {
+		case tt.field != "" && (!errors.As(err, &validationErr) || validationErr.Field != tt.field):
+			t.Errorf("parseFilter(%q) error = %v, want validation error of %s", tt.query, err, tt.field)
+		case tt.field == "" && (err != nil || !reflect.DeepEqual(filter, tt.want)):
+			t.Errorf("parseFilter(%q) = %+v, %v, want %+v", tt.query, filter, err, tt.want)
+		}
+	}
+}
+
+// TestFilterMatch - username prefix and inclusive age range, users without numeric age are out of any range
+func TestFilterMatch(t *testing.T) {
+	age := func(n int) *int { return &n }
+	alice := User{ID: "1", Username: "alice", Age: "30"}
+	noAge := User{ID: "2", Username: "albert"}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		user   User
+		want   bool
+	}{
+		{"empty filter", Filter{}, noAge, true},
+		{"prefix", Filter{UsernamePrefix: "al"}, alice, true},
+		{"other prefix", Filter{UsernamePrefix: "bo"}, alice, false},
+		{"prefix is case sensitive", Filter{UsernamePrefix: "Al"}, alice, false},
+		{"min age is inclusive", Filter{MinAge: age(30)}, alice, true},
+		{"max age is inclusive", Filter{MaxAge: age(30)}, alice, true},
+		{"older than max", Filter{MaxAge: age(29)}, alice, false},
+		{"younger than min", Filter{MinAge: age(31)}, alice, false},
+		{"no age in range", Filter{MinAge: age(0)}, noAge, false},
+		{"id of filter", Filter{IDs: []string{"3", "1"}}, alice, true},
+		{"no id of filter", Filter{IDs: []string{}}, alice, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(tt.user); got != tt.want {
+			t.Errorf("%s: Match(%+v) = %t, want %t", tt.name, tt.user, got, tt.want)
+		}
+	}
+}
+
+// TestFilterSortAndPage - users are sorted by field of filter and then by id, page is cut by offset and limit
+func TestFilterSortAndPage(t *testing.T) {
+	users := []User{
+		{ID: "10", Username: "bob", Age: "20"},
+		{ID: "2", Username: "alice", Age: "30"},
+		{ID: "3", Username: "bob"},
+		{ID: "1", Username: "carol", Age: "20"},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"id", Filter{SortBy: SortByID}, []string{"1", "2", "3", "10"}},
+		{"username then id", Filter{SortBy: SortByUsername}, []string{"2", "3", "10", "1"}},
+		{"descending username", Filter{SortBy: SortByUsername, Desc: true}, []string{"1", "10", "3", "2"}},
+		{"age, no age first", Filter{SortBy: SortByAge}, []string{"3", "1", "10", "2"}},
+		{"page", Filter{SortBy: SortByID, Limit: 2, Offset: 1}, []string{"2", "3"}},
+		{"page after last user", Filter{SortBy: SortByID, Offset: 4}, []string{}},
+	}
+	for _, tt := range tests {
+		sorted := append([]User(nil), users...)
+		sort.Slice(sorted, func(i, j int) bool { return tt.filter.Less(sorted[i], sorted[j]) })
+		ids := []string{}
+		for _, u := range tt.filter.Page(sorted) {
+			ids = append(ids, u.ID)
+		}
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("%s: ids %v, want %v", tt.name, ids, tt.want)
+		}
+	}
+}
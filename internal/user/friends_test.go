@@ -0,0 +1,135 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"project/internal/user"
+	"project/internal/user/apitest"
+	"project/internal/user/memory"
+	"project/pkg/logging"
+	"project/pkg/validator"
+	"reflect"
+	"testing"
+)
+
+// TestFriendsByID - friends are kept by ids, so users with the same username and renames don't break friendships
+func TestFriendsByID(t *testing.T) {
+	ctx := context.Background()
+	s := apitest.MemoryHandler(t).UserService
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	otherBob := createUser(t, s, "bob")
+	befriend(t, s, alice, bob)
+
+	if _, err := s.Update(ctx, bob, user.User{Username: "robert"}); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+
+	tests := []struct {
+		id   string
+		want []user.Friend
+	}{
+		{alice, []user.Friend{{ID: bob, Username: "robert"}}},
+		{bob, []user.Friend{{ID: alice, Username: "alice"}}},
+		{otherBob, []user.Friend{}},
+	}
+	for _, tt := range tests {
+		if friends, err := s.GetUserFriends(ctx, tt.id); err != nil || !reflect.DeepEqual(friends, tt.want) {
+			t.Errorf("GetUserFriends(%s) = %+v, %v, want %+v", tt.id, friends, err, tt.want)
+		}
+	}
+	if u, err := s.GetUser(ctx, alice); err != nil || !reflect.DeepEqual(u.Friends, []string{bob}) {
+		t.Errorf("friends of alice = %v, %v, want id of bob", u.Friends, err)
+	}
+}
+
+// TestFriendshipIdempotent - friendship is made once, repeated link is ErrAlreadyFriends and friends are not duplicated
+func TestFriendshipIdempotent(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.GetLogger()
+	storage := memory.NewStorage(logger)
+	s, err := user.NewService(storage, memory.NewRequestStorage(logger), memory.NewRelationStorage(logger), *logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	befriend(t, s, alice, bob)
+
+	if _, err := s.SendFriendRequest(ctx, bob, alice); !errors.Is(err, user.ErrAlreadyFriends) {
+		t.Errorf("SendFriendRequest to friend error = %v, want %v", err, user.ErrAlreadyFriends)
+	}
+	if _, _, err := storage.MakeFriends(ctx, alice, bob); !errors.Is(err, user.ErrAlreadyFriends) {
+		t.Errorf("second MakeFriends of storage error = %v, want %v", err, user.ErrAlreadyFriends)
+	}
+	for _, id := range []string{alice, bob} {
+		if u, err := s.GetUser(ctx, id); err != nil || len(u.Friends) != 1 {
+			t.Errorf("friends of %s = %v, %v, want one friend", id, u.Friends, err)
+		}
+	}
+}
+
+// TestUnfriend - friendship is removed in both directions, removal of missing friendship is ErrNotFriends
+func TestUnfriend(t *testing.T) {
+	ctx := context.Background()
+	s := apitest.MemoryHandler(t).UserService
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	carol := createUser(t, s, "carol")
+	befriend(t, s, alice, bob)
+	befriend(t, s, alice, carol)
+
+	first, second, err := s.Unfriend(ctx, bob, alice)
+	if err != nil || first.ID != bob || second.ID != alice {
+		t.Fatalf("Unfriend = %+v, %+v, %v, want bob and alice", first, second, err)
+	}
+	if len(first.Friends) != 0 || !reflect.DeepEqual(second.Friends, []string{carol}) {
+		t.Errorf("friends after Unfriend = %v and %v, want none and carol", first.Friends, second.Friends)
+	}
+
+	var fieldErrs validator.Errors
+	tests := []struct {
+		name     string
+		id       string
+		friendID string
+		check    func(err error) bool
+	}{
+		{"second time", alice, bob, func(err error) bool { return errors.Is(err, user.ErrNotFriends) }},
+		{"never friends", bob, carol, func(err error) bool { return errors.Is(err, user.ErrNotFriends) }},
+		{"himself", alice, alice, func(err error) bool { return errors.As(err, &fieldErrs) }},
+		{"invalid id", alice, "not-an-id", func(err error) bool { return errors.Is(err, user.ErrInvalidID) || errors.Is(err, user.ErrNotFound) }},
+	}
+	for _, tt := range tests {
+		if _, _, err := s.Unfriend(ctx, tt.id, tt.friendID); !tt.check(err) {
+			t.Errorf("Unfriend %s error = %v", tt.name, err)
+		}
+	}
+	if friends, err := s.GetUserFriends(ctx, carol); err != nil || len(friends) != 1 || friends[0].ID != alice {
+		t.Errorf("friends of carol = %+v, %v, want alice", friends, err)
+	}
+}
+
+// createUser - create user through service and return his id
+func createUser(t *testing.T, s user.Service, username string) string {
+	t.Helper()
+
+	id, err := s.Create(context.Background(), user.User{Username: username})
+	if err != nil {
+		t.Fatalf("Create(%s) error: %v", username, err)
+	}
+	return id
+}
+
+// befriend - make users friends through friend request of service which second user accepts
+func befriend(t *testing.T, s user.Service, firstID string, secondID string) {
+	t.Helper()
+
+	ctx := context.Background()
+	request, err := s.SendFriendRequest(ctx, firstID, secondID)
+	if err != nil {
+		t.Fatalf("SendFriendRequest(%s, %s) error: %v", firstID, secondID, err)
+	}
+	if _, err = s.AcceptFriendRequest(ctx, secondID, request.ID); err != nil {
+		t.Fatalf("AcceptFriendRequest(%s, %s) error: %v", secondID, request.ID, err)
+	}
+}
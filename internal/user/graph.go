@@ -0,0 +1,179 @@
+package user
+
+// file for social graph queries, generic implementation works over any Storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// MaxPathDepth - max degrees of separation which are searched between users
+const MaxPathDepth = 6
+
+// Suggestion - friend of friends who is not a friend of user yet
+type Suggestion struct {
+	Friend
+	// MutualCount - number of friends of user who are friends of suggested user
+	MutualCount int `json:"mutual_count"`
+}
+
+// GraphStorage - social graph queries, storages implement it when they can do them better than generic implementation
+type GraphStorage interface {
+	// MutualFriends - friends of both users in order of friends of the first user
+	MutualFriends(ctx context.Context, id string, otherID string) ([]Friend, error)
+	// Suggestions - friends of friends who are not friends of user, more mutual friends go first, then smaller id
+	Suggestions(ctx context.Context, id string, limit int) ([]Suggestion, error)
+	// ShortestPath - users from first to second user, both included, ErrNotConnected if path is longer than maxDepth
+	ShortestPath(ctx context.Context, fromID string, toID string, maxDepth int) ([]Friend, error)
+}
+
+// Graph - graph queries of storage, generic implementation over Storage if storage doesn't implement GraphStorage
+func Graph(storage Storage) GraphStorage {
+	if graph, ok := storage.(GraphStorage); ok {
+		return graph
+	}
+	return graph{storage: storage}
+}
+
+// graph - generic implementation of GraphStorage, it reads one user per query
+type graph struct {
+	storage Storage
+}
+
+// MutualFriends - friends of first user which are in friends of second user
+func (g graph) MutualFriends(ctx context.Context, id string, otherID string) ([]Friend, error) {
+	friends, err := g.storage.GetUserFriends(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	other, err := g.storage.FindOne(ctx, otherID)
+	if err != nil {
+		return nil, err
+	}
+
+	mutual := []Friend{}
+	for _, friend := range friends {
+		if contains(other.Friends, friend.ID) {
+			mutual = append(mutual, friend)
+		}
+	}
+	return mutual, nil
+}
+
+// Suggestions - count friends of every friend of user, then resolve usernames of the best ones
+func (g graph) Suggestions(ctx context.Context, id string, limit int) ([]Suggestion, error) {
+	u, err := g.storage.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, friendID := range u.Friends {
+		friend, err := g.storage.FindOne(ctx, friendID)
+		if err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, candidate := range friend.Friends {
+			if candidate != u.ID && !contains(u.Friends, candidate) {
+				counts[candidate]++
+			}
+		}
+	}
+
+	candidates := make([]Suggestion, 0, len(counts))
+	for candidate, count := range counts {
+		candidates = append(candidates, Suggestion{Friend: Friend{ID: candidate}, MutualCount: count})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].MutualCount != candidates[j].MutualCount {
+			return candidates[i].MutualCount > candidates[j].MutualCount
+		}
+		return compareIDs(candidates[i].ID, candidates[j].ID) < 0
+	})
+
+	suggestions := []Suggestion{}
+	for _, candidate := range candidates {
+		if limit > 0 && len(suggestions) == limit {
+			break
+		}
+		suggested, err := g.storage.FindOne(ctx, candidate.ID)
+		if err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		candidate.Username = suggested.Username
+		suggestions = append(suggestions, candidate)
+	}
+	return suggestions, nil
+}
+
+// ShortestPath - breadth-first search from first user, level by level up to maxDepth
+func (g graph) ShortestPath(ctx context.Context, fromID string, toID string, maxDepth int) ([]Friend, error) {
+	from, err := g.storage.FindOne(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := g.storage.FindOne(ctx, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	if from.ID == to.ID {
+		return []Friend{{ID: from.ID, Username: from.Username}}, nil
+	}
+
+	// parents keeps the user through which every visited user was reached
+	parents := map[string]string{from.ID: ""}
+	visited := map[string]User{from.ID: from}
+	level := []User{from}
+	for depth := 0; depth < maxDepth && len(level) > 0; depth++ {
+		var next []User
+		for _, u := range level {
+			for _, friendID := range u.Friends {
+				if _, ok := parents[friendID]; ok {
+					continue
+				}
+				friend, err := g.storage.FindOne(ctx, friendID)
+				if err != nil {
+					if isNotFound(err) {
+						continue
+					}
+					return nil, err
+				}
+				parents[friend.ID] = u.ID
+				visited[friend.ID] = friend
+				if friend.ID == to.ID {
+					return buildPath(parents, visited, to.ID), nil
+				}
+				next = append(next, friend)
+			}
+		}
+		level = next
+	}
+
+	return nil, fmt.Errorf("no path from %s to %s in %d steps: %w", fromID, toID, maxDepth, ErrNotConnected)
+}
+
+// buildPath - path from root of parents to user with id
+func buildPath(parents map[string]string, users map[string]User, id string) []Friend {
+	var path []Friend
+	for ; id != ""; id = parents[id] {
+		path = append(path, Friend{ID: id, Username: users[id].Username})
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// isNotFound - friend ids of deleted users can be left by storages without transactions, such friends are skipped
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
@@ -0,0 +1,80 @@
+package graphqlapi
+
+// file for translating errors of user service to GraphQL errors with codes of HTTP API in extensions
+
+import (
+	"errors"
+	"net/http"
+	"project/internal/user"
+)
+
+// codeInternal - code of internal errors, the same as in HTTP API
+const codeInternal = "internal_error"
+
+// codedError - error for client with code in extensions, Err is only logged
+type codedError struct {
+	Err     error
+	Code    string
+	Message string
+	Field   string
+}
+
+// Error - message for client
+func (e *codedError) Error() string {
+	return e.Message
+}
+
+// Unwrap - wrapped error for errors.Is and errors.As
+func (e *codedError) Unwrap() error {
+	return e.Err
+}
+
+// Extensions - code and field of error, graphql-go adds them to answer
+func (e *codedError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.Code}
+	if e.Field != "" {
+		extensions["field"] = e.Field
+	}
+	return extensions
+}
+
+// limitError - query is too deep or too complex, it is not executed
+type limitError struct {
+	message string
+}
+
+// Error - message for client
+func (e *limitError) Error() string {
+	return e.message
+}
+
+// Extensions - code of error
+func (e *limitError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": "query_too_complex"}
+}
+
+// queryError - log error and get error for client, details of internal errors are only logged
+func (r resolver) queryError(err error) error {
+	coded := toCodedError(err)
+	if coded.Code == codeInternal {
+		r.logger.Errorf("GraphQL query failed: %v", err)
+	} else {
+		r.logger.Debugf("GraphQL query failed: %v", err)
+	}
+	return coded
+}
+
+// toCodedError - get error with code and message for client from error of user service
+func toCodedError(err error) *codedError {
+	var validationErr *user.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return &codedError{Err: err, Code: "validation_error", Message: validationErr.Message, Field: validationErr.Field}
+	case errors.Is(err, user.ErrInvalidID):
+		return &codedError{Err: err, Code: "invalid_id", Message: user.ErrInvalidID.Error()}
+	case errors.Is(err, user.ErrNotFound):
+		return &codedError{Err: err, Code: "not_found", Message: user.ErrNotFound.Error()}
+	default:
+		return &codedError{Err: err, Code: codeInternal, Message: http.StatusText(http.StatusInternalServerError)}
+	}
+}
@@ -0,0 +1,123 @@
+// Package graphqlapi - GraphQL endpoint of users and their friend graph, resolvers call the same user.Service as http handlers
+package graphqlapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/julienschmidt/httprouter"
+	"io/ioutil"
+	"net/http"
+	"project/internal/middleware"
+	"project/internal/user"
+	"project/pkg/logging"
+)
+
+// graphqlURL - path of endpoint, it is outside of /api/v1 like other GraphQL endpoints
+const graphqlURL = "/graphql"
+
+// Handler - handler of GraphQL queries with limits of their depth and complexity,
+// callers are authenticated by Auth and APIKeys the same way as callers of user.Handler
+type Handler struct {
+	Auth    *middleware.JWTAuth
+	APIKeys middleware.KeyVerifier
+
+	logger *logging.Logger
+	schema graphql.Schema
+	limits Limits
+	users  user.Service
+}
+
+// request - body of GraphQL request
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// NewHandler - create handler with schema of users
+func NewHandler(logger *logging.Logger, userService user.Service, limits Limits) (*Handler, error) {
+	schema, err := newSchema(resolver{logger: logger, service: userService})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create graphql schema. error: %w", err)
+	}
+	return &Handler{logger: logger, schema: schema, limits: limits, users: userService}, nil
+}
+
+// Register - func for init route of GraphQL endpoint, it requires bearer token or API key if handler has Auth
+func (h *Handler) Register(router *httprouter.Router) {
+	query := func(w http.ResponseWriter, r *http.Request) error {
+		h.Query(w, r)
+		return nil
+	}
+	// queries only read users, so API keys with read scope post them
+	router.HandlerFunc(http.MethodPost, graphqlURL, middleware.PanicRecovery(
+		middleware.Errors(middleware.AuthenticateScope(h.Auth, h.APIKeys, middleware.ScopeUsersRead, query)),
+	))
+}
+
+// Query - execute query of body, answer is in form of GraphQL with data and errors, not in envelope.
+// Query which can't be parsed, is invalid or exceeds limits isn't executed and is answered with 400
+func (h *Handler) Query(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("GraphQL query")
+
+	content, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		h.writeErrors(w, http.StatusBadRequest, fmt.Errorf("failed to read body: %v", err))
+		return
+	}
+	defer r.Body.Close()
+
+	var req request
+	if err = json.Unmarshal(content, &req); err != nil {
+		h.writeErrors(w, http.StatusBadRequest, fmt.Errorf("invalid json: %v", err))
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		h.writeErrors(w, http.StatusBadRequest, err)
+		return
+	}
+	if validation := graphql.ValidateDocument(&h.schema, doc, nil); !validation.IsValid {
+		h.writeResult(w, http.StatusBadRequest, &graphql.Result{Errors: validation.Errors})
+		return
+	}
+	if err = h.limits.check(doc, req.Variables); err != nil {
+		h.logger.Debugf("GraphQL query rejected: %v", err)
+		h.writeErrors(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withLoader(r.Context(), h.users),
+	})
+	h.writeResult(w, http.StatusOK, result)
+}
+
+// writeErrors - answer with errors without data, codes of errors are kept in extensions
+func (h *Handler) writeErrors(w http.ResponseWriter, status int, errs ...error) {
+	formatted := gqlerrors.FormatErrors(errs...)
+	for i, err := range errs {
+		if extended, ok := err.(gqlerrors.ExtendedError); ok {
+			formatted[i].Extensions = extended.Extensions()
+		}
+	}
+	h.writeResult(w, status, &graphql.Result{Errors: formatted})
+}
+
+// writeResult - answer with result of query
+func (h *Handler) writeResult(w http.ResponseWriter, status int, result *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.logger.Errorf("failed to write graphql answer. error: %v", err)
+	}
+}
@@ -0,0 +1,397 @@
+package graphqlapi_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"net/http/httptest"
+	"project/internal/middleware"
+	"project/internal/user"
+	"project/internal/user/apitest"
+	"project/internal/user/graphqlapi"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// invalidID - id which can't be produced by any storage
+const invalidID = "not-an-id"
+
+// graphQLLimits - limits of endpoint in tests, the same as defaults of config
+var graphQLLimits = graphqlapi.Limits{MaxDepth: 5, MaxComplexity: 1000}
+
+// TestGraphQL - query users and their friend graph through GraphQL endpoint with service on memory storages,
+// queries are authenticated with token of user, query without token is unauthorized
+func TestGraphQL(t *testing.T) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, router http.Handler, s *countingService)
+	}{
+		{"FriendsOfFriends", testGraphQLFriendsOfFriends},
+		{"Batching", testGraphQLBatching},
+		{"Users", testGraphQLUsers},
+		{"Limits", testGraphQLLimits},
+		{"Errors", testGraphQLErrors},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := apitest.MemoryHandler(t)
+			s := &countingService{Service: h.UserService}
+			router := graphQLRouter(t, h, s)
+			tt.test(t, withAuthorization(router, bearer(t, h.Auth, "graphql-reader")), s)
+		})
+	}
+	t.Run("Auth", func(t *testing.T) {
+		h := apitest.MemoryHandler(t)
+		testGraphQLAuth(t, h, graphQLRouter(t, h, h.UserService))
+	})
+}
+
+// graphQLRouter - router with GraphQL endpoint of service s, it authenticates callers the same way as handler
+func graphQLRouter(t *testing.T, h *user.Handler, s user.Service) *httprouter.Router {
+	t.Helper()
+
+	graphQLHandler, err := graphqlapi.NewHandler(h.Logger, s, graphQLLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graphQLHandler.Auth = h.Auth
+	graphQLHandler.APIKeys = h.APIKeys
+	router := httprouter.New()
+	graphQLHandler.Register(router)
+	return router
+}
+
+// withAuthorization - handler which calls router with authorization header
+func withAuthorization(router http.Handler, authorization string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("Authorization", authorization)
+		router.ServeHTTP(w, r)
+	})
+}
+
+// countingService - user service which counts loads of users
+type countingService struct {
+	user.Service
+	getUser  int
+	getUsers int
+}
+
+// GetUser - count call and get user
+func (s *countingService) GetUser(ctx context.Context, userID string) (user.User, error) {
+	s.getUser++
+	return s.Service.GetUser(ctx, userID)
+}
+
+// GetUsers - count call and get users
+func (s *countingService) GetUsers(ctx context.Context, ids []string) ([]user.User, error) {
+	s.getUsers++
+	return s.Service.GetUsers(ctx, ids)
+}
+
+// graphQLAnswer - answer of GraphQL endpoint
+type graphQLAnswer struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Path       []interface{}          `json:"path"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+// graphQLUser - user of answer with friends of any depth
+type graphQLUser struct {
+	ID       string        `json:"id"`
+	Username string        `json:"username"`
+	Friends  []graphQLUser `json:"friends"`
+}
+
+// testGraphQLFriendsOfFriends - user, his friends and friends of friends come in one answer
+func testGraphQLFriendsOfFriends(t *testing.T, router http.Handler, s *countingService) {
+	ids := graphQLUsers(t, s, "alice", "bob", "carol", "dave")
+	graphQLFriends(t, s, ids["alice"], ids["bob"])
+	graphQLFriends(t, s, ids["alice"], ids["carol"])
+	graphQLFriends(t, s, ids["carol"], ids["dave"])
+
+	var data struct {
+		User graphQLUser `json:"user"`
+	}
+	query := `query($id: ID!) { user(id: $id) { username friends { username friends { username } } } }`
+	graphQLData(t, router, query, map[string]interface{}{"id": ids["alice"]}, &data)
+
+	if data.User.Username != "alice" {
+		t.Fatalf("user = %+v, want alice", data.User)
+	}
+	friends := map[string][]string{}
+	for _, friend := range data.User.Friends {
+		friends[friend.Username] = graphQLUsernames(friend.Friends)
+	}
+	want := map[string][]string{"bob": {"alice"}, "carol": {"alice", "dave"}}
+	if len(friends) != len(want) || !equalStrings(friends["bob"], want["bob"]) || !equalStrings(friends["carol"], want["carol"]) {
+		t.Errorf("friends of friends of alice = %v, want %v", friends, want)
+	}
+}
+
+// testGraphQLBatching - every level of friends is loaded by one call of service, whatever number of users is on level
+func testGraphQLBatching(t *testing.T, router http.Handler, s *countingService) {
+	ids := graphQLUsers(t, s, "alice", "bob", "carol", "dave", "eve", "frank")
+	for _, friend := range []string{"bob", "carol", "dave"} {
+		graphQLFriends(t, s, ids["alice"], ids[friend])
+	}
+	graphQLFriends(t, s, ids["bob"], ids["eve"])
+	graphQLFriends(t, s, ids["carol"], ids["frank"])
+	graphQLFriends(t, s, ids["dave"], ids["eve"])
+	s.getUser, s.getUsers = 0, 0
+
+	var data struct {
+		User graphQLUser `json:"user"`
+	}
+	query := `query($id: ID!) { user(id: $id) { friends { id friends { id username } } } }`
+	graphQLData(t, router, query, map[string]interface{}{"id": ids["alice"]}, &data)
+
+	if len(data.User.Friends) != 3 {
+		t.Fatalf("friends of alice = %+v, want bob, carol and dave", data.User.Friends)
+	}
+	// friends of alice, then eve and frank, alice is loaded already
+	if s.getUser != 1 || s.getUsers != 2 {
+		t.Errorf("calls of service: GetUser %d, GetUsers %d, want 1 and 2", s.getUser, s.getUsers)
+	}
+}
+
+// testGraphQLUsers - page of users is filtered like in HTTP API, friends of page are loaded together
+func testGraphQLUsers(t *testing.T, router http.Handler, s *countingService) {
+	ids := graphQLUsers(t, s, "alice", "albert", "bob")
+	graphQLFriends(t, s, ids["alice"], ids["bob"])
+	graphQLFriends(t, s, ids["albert"], ids["bob"])
+	s.getUsers = 0
+
+	var data struct {
+		Users struct {
+			Users []graphQLUser `json:"users"`
+			Total int           `json:"total"`
+		} `json:"users"`
+	}
+	query := `{ users(usernamePrefix: "al", sortBy: USERNAME, desc: true, limit: 1) { total users { username friends { username } } } }`
+	graphQLData(t, router, query, nil, &data)
+
+	page := data.Users
+	if page.Total != 2 || len(page.Users) != 1 || page.Users[0].Username != "alice" || !equalStrings(graphQLUsernames(page.Users[0].Friends), []string{"bob"}) {
+		t.Errorf("users = %+v, want page with alice and her friend bob of 2 users", page)
+	}
+	if s.getUsers != 1 {
+		t.Errorf("calls of GetUsers = %d, want 1", s.getUsers)
+	}
+}
+
+// testGraphQLLimits - too deep and too complex queries are rejected before service is called
+func testGraphQLLimits(t *testing.T, router http.Handler, s *countingService) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"depth", `query($id: ID!) { user(id: $id) { friends { friends { friends { friends { id } } } } } }`},
+		{"complexity", `{ users(limit: 100) { users { friends { friends { id username } } } } }`},
+		{"complexity with fragment", `{ users(limit: 50) { users { ...deep } } } fragment deep on User { friends { friends { id } } }`},
+	}
+	for _, tt := range tests {
+		s.getUser, s.getUsers = 0, 0
+		status, answer := graphQLQuery(t, router, tt.query, map[string]interface{}{"id": "1"})
+		if status != http.StatusBadRequest || len(answer.Errors) != 1 || answer.Errors[0].Extensions["code"] != "query_too_complex" {
+			t.Errorf("%s: status %d, errors %+v, want %d with query_too_complex", tt.name, status, answer.Errors, http.StatusBadRequest)
+		}
+		if s.getUser != 0 || s.getUsers != 0 {
+			t.Errorf("%s: service is called for rejected query", tt.name)
+		}
+	}
+
+	// introspection is not limited
+	if status, answer := graphQLQuery(t, router, `{ __schema { types { name fields { name type { name ofType { name ofType { name } } } } } } }`, nil); status != http.StatusOK || len(answer.Errors) != 0 {
+		t.Errorf("introspection: status %d, errors %+v, want %d", status, answer.Errors, http.StatusOK)
+	}
+}
+
+// testGraphQLErrors - errors of service have codes of HTTP API, invalid queries are answered with 400
+func testGraphQLErrors(t *testing.T, router http.Handler, s *countingService) {
+	ids := graphQLUsers(t, s, "alice")
+	s.getUser = 0
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		code   interface{}
+	}{
+		{"unknown user", `{ user(id: "` + ids["unknown"] + `") { id } }`, http.StatusOK, "not_found"},
+		{"invalid id", `{ user(id: "` + invalidID + `") { id } }`, http.StatusOK, "invalid_id"},
+		{"invalid filter", `{ users(limit: 101) { total } }`, http.StatusOK, "validation_error"},
+		{"unknown field", `{ user(id: "1") { email } }`, http.StatusBadRequest, nil},
+		{"syntax", `{ user(id: `, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		status, answer := graphQLQuery(t, router, tt.query, nil)
+		if status != tt.status || len(answer.Errors) != 1 || answer.Errors[0].Extensions["code"] != tt.code {
+			t.Errorf("%s: status %d, errors %+v, want %d with code %v", tt.name, status, answer.Errors, tt.status, tt.code)
+		}
+	}
+}
+
+// testGraphQLAuth - query without valid token is answered with 401 before it is executed, API key with read scope is enough for queries
+func testGraphQLAuth(t *testing.T, h *user.Handler, router http.Handler) {
+	alice := createUser(t, h, "alice")
+	query := `{ user(id: "` + alice + `") { username } }`
+	other, err := middleware.NewJWTAuth(middleware.JWTConfig{Secret: "other-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		code   string
+	}{
+		{"no header", "", "unauthorized"},
+		{"malformed", "Bearer not-a-token", "invalid_token"},
+		{"wrong secret", bearer(t, other, alice), "invalid_token"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ users { total } }"}`))
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		router.ServeHTTP(w, r)
+
+		var answer struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &answer)
+		if w.Code != http.StatusUnauthorized || answer.Error.Code != tt.code || w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: status %d, code %q, want %d with %s", tt.name, w.Code, answer.Error.Code, http.StatusUnauthorized, tt.code)
+		}
+	}
+
+	if status, answer := graphQLQuery(t, withAuthorization(router, bearer(t, h.Auth, alice)), query, nil); status != http.StatusOK || len(answer.Errors) != 0 {
+		t.Errorf("valid token: status %d, errors %+v, want %d", status, answer.Errors, http.StatusOK)
+	}
+	_, readKey, err := h.APIKeys.IssueAPIKey(context.Background(), user.APIKeyInput{Name: "reader", Scopes: []string{middleware.ScopeUsersRead}})
+	if err != nil {
+		t.Fatalf("IssueAPIKey error: %v", err)
+	}
+	if status, answer := graphQLQuery(t, withAuthorization(router, "ApiKey "+readKey), query, nil); status != http.StatusOK || len(answer.Errors) != 0 {
+		t.Errorf("API key with read scope: status %d, errors %+v, want %d", status, answer.Errors, http.StatusOK)
+	}
+}
+
+// graphQLQuery - post query with variables and decode answer
+func graphQLQuery(t *testing.T, router http.Handler, query string, variables map[string]interface{}) (int, graphQLAnswer) {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+
+	var answer graphQLAnswer
+	if err = json.Unmarshal(w.Body.Bytes(), &answer); err != nil {
+		t.Fatalf("invalid answer %s: %v", w.Body.String(), err)
+	}
+	return w.Code, answer
+}
+
+// graphQLData - post query which must succeed and decode its data
+func graphQLData(t *testing.T, router http.Handler, query string, variables map[string]interface{}, data interface{}) {
+	t.Helper()
+
+	status, answer := graphQLQuery(t, router, query, variables)
+	if status != http.StatusOK || len(answer.Errors) != 0 {
+		t.Fatalf("query %s: status %d, errors %+v", query, status, answer.Errors)
+	}
+	if err := json.Unmarshal(answer.Data, data); err != nil {
+		t.Fatalf("invalid data %s: %v", answer.Data, err)
+	}
+}
+
+// graphQLUsers - create users with usernames through service, id of unknown user is under "unknown"
+func graphQLUsers(t *testing.T, s user.Service, usernames ...string) map[string]string {
+	t.Helper()
+
+	ctx := context.Background()
+	ids := make(map[string]string)
+	for _, username := range append(usernames, "unknown") {
+		id, err := s.Create(ctx, user.User{Username: username})
+		if err != nil {
+			t.Fatalf("Create(%s) error: %v", username, err)
+		}
+		ids[username] = id
+	}
+	if err := s.Delete(ctx, ids["unknown"]); err != nil {
+		t.Fatalf("Delete(%s) error: %v", ids["unknown"], err)
+	}
+	return ids
+}
+
+// graphQLFriends - make users friends through friend request of service which second user accepts
+func graphQLFriends(t *testing.T, s user.Service, firstID, secondID string) {
+	t.Helper()
+
+	ctx := context.Background()
+	request, err := s.SendFriendRequest(ctx, firstID, secondID)
+	if err != nil {
+		t.Fatalf("SendFriendRequest(%s, %s) error: %v", firstID, secondID, err)
+	}
+	if _, err = s.AcceptFriendRequest(ctx, secondID, request.ID); err != nil {
+		t.Fatalf("AcceptFriendRequest(%s, %s) error: %v", secondID, request.ID, err)
+	}
+}
+
+// graphQLUsernames - sorted usernames of users
+func graphQLUsernames(users []graphQLUser) []string {
+	names := make([]string, 0, len(users))
+	for _, u := range users {
+		names = append(names, u.Username)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// equalStrings - slices have the same strings in the same order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// createUser - create user through service of handler
+func createUser(t *testing.T, h *user.Handler, username string) string {
+	t.Helper()
+
+	id, err := h.UserService.Create(context.Background(), user.User{Username: username})
+	if err != nil {
+		t.Fatalf("Create(%s) error: %v", username, err)
+	}
+	return id
+}
+
+// bearer - authorization header with token of subject signed by auth
+func bearer(t *testing.T, auth *middleware.JWTAuth, subject string) string {
+	t.Helper()
+
+	token, _, err := auth.Sign(middleware.Principal{Subject: subject})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
@@ -0,0 +1,130 @@
+package graphqlapi
+
+// file for limits of query depth and complexity, they are checked before query touches storage
+
+import (
+	"fmt"
+	"github.com/graphql-go/graphql/language/ast"
+	"project/internal/user"
+	"strconv"
+	"strings"
+)
+
+// friendsCost - expected number of friends of one user, selection of friends costs this many times more
+const friendsCost = 10
+
+// Limits - max depth and complexity of one query, zero value turns limit off
+type Limits struct {
+	// MaxDepth - max nesting of fields, user { friends { username } } has depth 3
+	MaxDepth int
+	// MaxComplexity - max estimated number of resolved fields, lists multiply cost of their fields by expected length
+	MaxComplexity int
+}
+
+// check - error if one of operations of document is deeper or more complex than limits
+func (l Limits) check(doc *ast.Document, variables map[string]interface{}) error {
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		m := measure{fragments: fragments, variables: variables}
+		depth, complexity := m.selectionSet(operation.SelectionSet, true)
+		if l.MaxDepth > 0 && depth > l.MaxDepth {
+			return &limitError{message: fmt.Sprintf("query depth %d exceeds limit %d", depth, l.MaxDepth)}
+		}
+		if l.MaxComplexity > 0 && complexity > l.MaxComplexity {
+			return &limitError{message: fmt.Sprintf("query complexity %d exceeds limit %d", complexity, l.MaxComplexity)}
+		}
+	}
+	return nil
+}
+
+// measure - depth and complexity of selection sets with fragments of document, document must be validated,
+// so fragments have no cycles
+type measure struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// selectionSet - depth and complexity of fields of selection set, fragments are counted as their fields
+func (m measure) selectionSet(set *ast.SelectionSet, root bool) (depth int, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var d, c int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			d, c = m.field(selection, root)
+		case *ast.InlineFragment:
+			d, c = m.selectionSet(selection.SelectionSet, root)
+		case *ast.FragmentSpread:
+			if fragment, ok := m.fragments[selection.Name.Value]; ok {
+				d, c = m.selectionSet(fragment.SelectionSet, root)
+			}
+		}
+		if d > depth {
+			depth = d
+		}
+		complexity += c
+	}
+	return depth, complexity
+}
+
+// field - depth and complexity of field with its selection set, introspection fields are free
+func (m measure) field(field *ast.Field, root bool) (depth int, complexity int) {
+	if strings.HasPrefix(field.Name.Value, "__") {
+		return 0, 0
+	}
+	depth, complexity = m.selectionSet(field.SelectionSet, false)
+	return depth + 1, 1 + m.multiplier(field, root)*complexity
+}
+
+// multiplier - expected length of list returned by field, page of users of query is as long as its limit
+func (m measure) multiplier(field *ast.Field, root bool) int {
+	switch {
+	case field.Name.Value == "friends":
+		return friendsCost
+	case field.Name.Value == "users" && root:
+		limit := m.intArgument(field, "limit")
+		if limit <= 0 {
+			return user.DefaultLimit
+		}
+		if limit > user.MaxLimit {
+			return user.MaxLimit
+		}
+		return limit
+	}
+	return 1
+}
+
+// intArgument - value of int argument given as literal or variable, zero if it is not set
+func (m measure) intArgument(field *ast.Field, name string) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != name {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			n, _ := strconv.Atoi(value.Value)
+			return n
+		case *ast.Variable:
+			// numbers of JSON variables are float64
+			switch n := m.variables[value.Name.Value].(type) {
+			case float64:
+				return int(n)
+			case int:
+				return n
+			}
+		}
+	}
+	return 0
+}
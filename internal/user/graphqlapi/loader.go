@@ -0,0 +1,81 @@
+package graphqlapi
+
+// file for batch loading of users, friends of all users of one level of query are got by one call of service
+
+import (
+	"context"
+	"project/internal/user"
+)
+
+// loader - users of one query by id, ids are collected while level of query is resolved and loaded together
+// by the first thunk of next level. Executor of graphql-go resolves fields in one goroutine, so there is no lock
+type loader struct {
+	service user.Service
+
+	// pending - ids which are requested but not loaded yet
+	pending map[string]bool
+	// users - loaded users, unknown and deleted users are stored as nil
+	users map[string]*user.User
+}
+
+// newLoader - loader for one query
+func newLoader(service user.Service) *loader {
+	return &loader{
+		service: service,
+		pending: make(map[string]bool),
+		users:   make(map[string]*user.User),
+	}
+}
+
+// prime - save user got by other call of service, so he isn't loaded again
+func (l *loader) prime(u user.User) {
+	l.users[u.ID] = &u
+	delete(l.pending, u.ID)
+}
+
+// load - thunk of users with ids in the same order, unknown and deleted users are skipped
+func (l *loader) load(ctx context.Context, ids []string) func() (interface{}, error) {
+	for _, id := range ids {
+		if _, ok := l.users[id]; !ok {
+			l.pending[id] = true
+		}
+	}
+
+	return func() (interface{}, error) {
+		if err := l.flush(ctx); err != nil {
+			return nil, err
+		}
+		users := make([]user.User, 0, len(ids))
+		for _, id := range ids {
+			if u := l.users[id]; u != nil {
+				users = append(users, *u)
+			}
+		}
+		return users, nil
+	}
+}
+
+// flush - load all pending users by one call of service
+func (l *loader) flush(ctx context.Context) error {
+	if len(l.pending) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(l.pending))
+	for id := range l.pending {
+		ids = append(ids, id)
+	}
+	l.pending = make(map[string]bool)
+
+	users, err := l.service.GetUsers(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		l.users[id] = nil
+	}
+	for _, u := range users {
+		l.prime(u)
+	}
+	return nil
+}
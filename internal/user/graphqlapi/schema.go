@@ -0,0 +1,159 @@
+package graphqlapi
+
+// file for GraphQL schema of users and their friends, resolvers call user.Service
+
+import (
+	"context"
+	"errors"
+	"github.com/graphql-go/graphql"
+	"project/internal/user"
+	"project/pkg/logging"
+)
+
+// loaderKey - key of loader of query in context
+type loaderKey struct{}
+
+// withLoader - context with new loader of query
+func withLoader(ctx context.Context, service user.Service) context.Context {
+	return context.WithValue(ctx, loaderKey{}, newLoader(service))
+}
+
+// loaderFrom - loader of query from context
+func loaderFrom(ctx context.Context) (*loader, error) {
+	l, ok := ctx.Value(loaderKey{}).(*loader)
+	if !ok {
+		return nil, errors.New("loader of users is not in context")
+	}
+	return l, nil
+}
+
+// resolver - resolvers of schema with logger
+type resolver struct {
+	logger  *logging.Logger
+	service user.Service
+}
+
+// newSchema - schema with query of one user and page of users, friends of users are users too
+func newSchema(r resolver) (graphql.Schema, error) {
+	// fields of user.User are resolved by their json tags
+	var userType *graphql.Object
+	userType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "User",
+		Description: "User with friends, blocked users are never friends",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+				"username": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"age":      &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "Numeric string from 0 to 150 or empty"},
+				"friends": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+					Description: "Friends of user, they are loaded together with friends of other users of the same level",
+					Resolve:     r.friends,
+				},
+			}
+		}),
+	})
+
+	userPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UserPage",
+		Fields: graphql.Fields{
+			"users": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType)))},
+			"total": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Number of users matched by filter"},
+		},
+	})
+
+	sortByType := graphql.NewEnum(graphql.EnumConfig{
+		Name: "UserSort",
+		Values: graphql.EnumValueConfigMap{
+			"ID":       &graphql.EnumValueConfig{Value: user.SortByID},
+			"USERNAME": &graphql.EnumValueConfig{Value: user.SortByUsername},
+			"AGE":      &graphql.EnumValueConfig{Value: user.SortByAge},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: r.user,
+			},
+			"users": &graphql.Field{
+				Type: graphql.NewNonNull(userPageType),
+				Args: graphql.FieldConfigArgument{
+					"usernamePrefix": &graphql.ArgumentConfig{Type: graphql.String},
+					"minAge":         &graphql.ArgumentConfig{Type: graphql.Int},
+					"maxAge":         &graphql.ArgumentConfig{Type: graphql.Int},
+					"sortBy":         &graphql.ArgumentConfig{Type: sortByType, DefaultValue: user.SortByID},
+					"desc":           &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+					"limit":          &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: user.DefaultLimit},
+					"offset":         &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: r.users,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// user - one user by id, he is saved in loader for his friends of friends
+func (r resolver) user(p graphql.ResolveParams) (interface{}, error) {
+	u, err := r.service.GetUser(p.Context, p.Args["id"].(string))
+	if err != nil {
+		return nil, r.queryError(err)
+	}
+	if l, err := loaderFrom(p.Context); err == nil {
+		l.prime(u)
+	}
+	return u, nil
+}
+
+// users - page of users by filter, users are saved in loader
+func (r resolver) users(p graphql.ResolveParams) (interface{}, error) {
+	filter := user.Filter{
+		SortBy: p.Args["sortBy"].(string),
+		Desc:   p.Args["desc"].(bool),
+		Limit:  p.Args["limit"].(int),
+		Offset: p.Args["offset"].(int),
+	}
+	if prefix, ok := p.Args["usernamePrefix"].(string); ok {
+		filter.UsernamePrefix = prefix
+	}
+	if minAge, ok := p.Args["minAge"].(int); ok {
+		filter.MinAge = &minAge
+	}
+	if maxAge, ok := p.Args["maxAge"].(int); ok {
+		filter.MaxAge = &maxAge
+	}
+
+	users, total, err := r.service.ListUsers(p.Context, filter)
+	if err != nil {
+		return nil, r.queryError(err)
+	}
+	if l, err := loaderFrom(p.Context); err == nil {
+		for _, u := range users {
+			l.prime(u)
+		}
+	}
+	return map[string]interface{}{"users": users, "total": total}, nil
+}
+
+// friends - thunk of friends of user, it is called after friends of all users of level are requested
+func (r resolver) friends(p graphql.ResolveParams) (interface{}, error) {
+	l, err := loaderFrom(p.Context)
+	if err != nil {
+		return nil, r.queryError(err)
+	}
+	load := l.load(p.Context, p.Source.(user.User).Friends)
+	return func() (interface{}, error) {
+		friends, err := load()
+		if err != nil {
+			return nil, r.queryError(err)
+		}
+		return friends, nil
+	}, nil
+}
@@ -0,0 +1,73 @@
+package grpcapi
+
+// file for authentication of gRPC callers by bearer token or API key of metadata "authorization", the same as for http handlers
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"project/internal/middleware"
+	"project/pkg/grpc/userspb"
+	"strings"
+)
+
+// authorizationKey - key of metadata with "Bearer <token>" or "ApiKey <key>"
+const authorizationKey = "authorization"
+
+// readMethods - methods which only read users, API keys with read scope call them, other methods require write scope
+var readMethods = map[string]bool{
+	userspb.Users_GetUser_FullMethodName:            true,
+	userspb.Users_ListUsers_FullMethodName:          true,
+	userspb.Users_GetUserFriends_FullMethodName:     true,
+	userspb.Users_MutualFriends_FullMethodName:      true,
+	userspb.Users_Suggestions_FullMethodName:        true,
+	userspb.Users_ShortestPath_FullMethodName:       true,
+	userspb.Users_ListFriendRequests_FullMethodName: true,
+	userspb.Users_ListRelations_FullMethodName:      true,
+}
+
+// Authenticate - interceptor for unary calls which answers with Unauthenticated status to call without valid bearer token or API key
+// and puts principal of caller to context of call. API key is accepted only if keys is not nil and it must have scope of method,
+// nil auth lets call without API key through, so methods which change users fail without principal
+func Authenticate(auth *middleware.JWTAuth, keys middleware.KeyVerifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var header string
+		if values := metadata.ValueFromIncomingContext(ctx, authorizationKey); len(values) > 0 {
+			header = values[0]
+		}
+		if key := strings.TrimSpace(strings.TrimPrefix(header, "ApiKey ")); keys != nil && key != header {
+			principal, err := keys.VerifyKey(ctx, key)
+			if err != nil {
+				return nil, status.Error(codes.Unauthenticated, "invalid api key")
+			}
+			if scope := methodScope(info.FullMethod); !principal.HasScope(scope) {
+				return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("api key has no scope %s", scope))
+			}
+			return handler(middleware.WithPrincipal(ctx, principal), req)
+		}
+		if auth == nil {
+			return handler(ctx, req)
+		}
+
+		token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+		if header == "" || token == header {
+			return nil, status.Error(codes.Unauthenticated, "bearer token is required")
+		}
+		principal, err := auth.Verify(token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return handler(middleware.WithPrincipal(ctx, principal), req)
+	}
+}
+
+// methodScope - scope which is required for call of method
+func methodScope(fullMethod string) string {
+	if readMethods[fullMethod] {
+		return middleware.ScopeUsersRead
+	}
+	return middleware.ScopeUsersWrite
+}
@@ -0,0 +1,99 @@
+package grpcapi
+
+// file for translating errors of user service to gRPC statuses, the only place where it is done
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"project/internal/user"
+	"project/pkg/logging"
+	"project/pkg/validator"
+	"runtime/debug"
+)
+
+// statusError - log error and get status error for client, details of internal errors are only logged
+func (s *Server) statusError(err error) error {
+	st := toStatus(err)
+	if st.Code() == codes.Internal {
+		s.Logger.Errorf("gRPC call failed: %v", err)
+	} else {
+		s.Logger.Debugf("gRPC call failed: %v", err)
+	}
+	return st.Err()
+}
+
+// toStatus - get gRPC status with code and message for client from error, fields of failed validation are in BadRequest details
+func toStatus(err error) *status.Status {
+	var validationErr *user.ValidationError
+	var fieldErrs validator.Errors
+	switch {
+	case errors.As(err, &fieldErrs):
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(fieldErrs))
+		for _, fieldErr := range fieldErrs {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: fieldErr.Field, Description: fieldErr.Message})
+		}
+		return withDetails(status.New(codes.InvalidArgument, "request validation failed"), &errdetails.BadRequest{FieldViolations: violations})
+	case errors.As(err, &validationErr):
+		st := status.New(codes.InvalidArgument, validationErr.Error())
+		if validationErr.Field == "" {
+			return st
+		}
+		return withDetails(st, &errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: validationErr.Field, Description: validationErr.Message},
+		}})
+	case errors.Is(err, user.ErrInvalidID):
+		return status.New(codes.InvalidArgument, user.ErrInvalidID.Error())
+	case errors.Is(err, user.ErrNotFound):
+		return status.New(codes.NotFound, user.ErrNotFound.Error())
+	case errors.Is(err, user.ErrAlreadyFriends):
+		return status.New(codes.AlreadyExists, user.ErrAlreadyFriends.Error())
+	case errors.Is(err, user.ErrNotDeleted):
+		return status.New(codes.FailedPrecondition, user.ErrNotDeleted.Error())
+	case errors.Is(err, user.ErrNotFriends):
+		return status.New(codes.NotFound, user.ErrNotFriends.Error())
+	case errors.Is(err, user.ErrNotConnected):
+		return status.New(codes.NotFound, user.ErrNotConnected.Error())
+	case errors.Is(err, user.ErrRequestNotFound):
+		return status.New(codes.NotFound, user.ErrRequestNotFound.Error())
+	case errors.Is(err, user.ErrRequestExists):
+		return status.New(codes.AlreadyExists, user.ErrRequestExists.Error())
+	case errors.Is(err, user.ErrRequestState):
+		return status.New(codes.FailedPrecondition, user.ErrRequestState.Error())
+	case errors.Is(err, user.ErrRequestForbidden):
+		return status.New(codes.PermissionDenied, user.ErrRequestForbidden.Error())
+	case errors.Is(err, user.ErrBlocked):
+		return status.New(codes.PermissionDenied, user.ErrBlocked.Error())
+	case errors.Is(err, user.ErrRelationNotFound):
+		return status.New(codes.NotFound, user.ErrRelationNotFound.Error())
+	case errors.Is(err, user.ErrUnauthorized):
+		return status.New(codes.Unauthenticated, user.ErrUnauthorized.Error())
+	case errors.Is(err, user.ErrForbidden):
+		return status.New(codes.PermissionDenied, user.ErrForbidden.Error())
+	default:
+		return status.New(codes.Internal, "internal server error")
+	}
+}
+
+// withDetails - add details to status, status without details is still usable if they can't be added
+func withDetails(st *status.Status, details *errdetails.BadRequest) *status.Status {
+	if detailed, err := st.WithDetails(details); err == nil {
+		return detailed
+	}
+	return st
+}
+
+// PanicRecovery - recovery interceptor for unary calls, panic is logged and answered with Internal status
+func PanicRecovery(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			logging.GetLogger().Errorf("panic in %s: %v\n%s", info.FullMethod, p, debug.Stack())
+			err = toStatus(fmt.Errorf("panic: %v", p)).Err()
+		}
+	}()
+	return handler(ctx, req)
+}
@@ -0,0 +1,283 @@
+// Package grpcapi - gRPC server of users API, it calls the same user.Service as http handlers
+// and authorizes callers authenticated by Authenticate interceptor the same way
+package grpcapi
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"project/internal/user"
+	"project/pkg/grpc/userspb"
+	"project/pkg/logging"
+)
+
+// Server - implementation of userspb.UsersServer with logger
+type Server struct {
+	userspb.UnimplementedUsersServer
+
+	Logger      *logging.Logger
+	UserService user.Service
+}
+
+// Register - register server in gRPC server
+func (s *Server) Register(server *grpc.Server) {
+	userspb.RegisterUsersServer(server, s)
+}
+
+// Create - creating user, answer with created user
+func (s *Server) Create(ctx context.Context, r *userspb.CreateRequest) (*userspb.User, error) {
+	s.Logger.Info("gRPC create user")
+
+	if err := user.AuthorizeCaller(ctx); err != nil {
+		return nil, s.statusError(err)
+	}
+
+	u := user.User{Username: r.GetUsername(), Age: r.GetAge()}
+	id, err := s.UserService.Create(ctx, u)
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	u.ID = id
+	return toUser(u), nil
+}
+
+// GetUser - getting one user
+func (s *Server) GetUser(ctx context.Context, r *userspb.GetUserRequest) (*userspb.User, error) {
+	s.Logger.Info("gRPC get user")
+
+	u, err := s.UserService.GetUser(ctx, r.GetId())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	return toUser(u), nil
+}
+
+// ListUsers - getting page of users by filter, sorting and pagination of request
+func (s *Server) ListUsers(ctx context.Context, r *userspb.ListUsersRequest) (*userspb.ListUsersResponse, error) {
+	s.Logger.Info("gRPC list users")
+
+	filter := user.Filter{
+		UsernamePrefix: r.GetUsernamePrefix(),
+		SortBy:         r.GetSortBy(),
+		Desc:           r.GetDesc(),
+		Limit:          int(r.GetLimit()),
+		Offset:         int(r.GetOffset()),
+	}
+	switch filter.SortBy {
+	case "":
+		filter.SortBy = user.SortByID
+	case user.SortByID, user.SortByUsername, user.SortByAge:
+	default:
+		return nil, s.statusError(&user.ValidationError{Field: "sort_by", Message: "must be one of id, username, age"})
+	}
+	if r.MinAge != nil {
+		minAge := int(r.GetMinAge())
+		filter.MinAge = &minAge
+	}
+	if r.MaxAge != nil {
+		maxAge := int(r.GetMaxAge())
+		filter.MaxAge = &maxAge
+	}
+
+	users, total, err := s.UserService.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	answer := &userspb.ListUsersResponse{Total: total}
+	for _, u := range users {
+		answer.Users = append(answer.Users, toUser(u))
+	}
+	return answer, nil
+}
+
+// Update - replacing username and age of user
+func (s *Server) Update(ctx context.Context, r *userspb.UpdateRequest) (*userspb.User, error) {
+	s.Logger.Info("gRPC update user")
+
+	if err := user.AuthorizeUser(ctx, r.GetId()); err != nil {
+		return nil, s.statusError(err)
+	}
+	u, err := s.UserService.Update(ctx, r.GetId(), user.User{Username: r.GetUsername(), Age: r.GetAge()})
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	return toUser(u), nil
+}
+
+// UpdateAge - changing only age of user
+func (s *Server) UpdateAge(ctx context.Context, r *userspb.UpdateAgeRequest) (*userspb.User, error) {
+	s.Logger.Info("gRPC update age")
+
+	if err := user.AuthorizeUser(ctx, r.GetId()); err != nil {
+		return nil, s.statusError(err)
+	}
+	u, err := s.UserService.UpdateAge(ctx, r.GetId(), r.GetAge())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	return toUser(u), nil
+}
+
+// Delete - soft deleting user, answer with his id
+func (s *Server) Delete(ctx context.Context, r *userspb.DeleteRequest) (*userspb.DeleteResponse, error) {
+	s.Logger.Info("gRPC delete user")
+
+	if err := user.AuthorizeUser(ctx, r.GetId()); err != nil {
+		return nil, s.statusError(err)
+	}
+	if err := s.UserService.Delete(ctx, r.GetId()); err != nil {
+		return nil, s.statusError(err)
+	}
+	return &userspb.DeleteResponse{Id: r.GetId()}, nil
+}
+
+// Restore - restoring soft deleted user with his friendships, only admin restores users
+func (s *Server) Restore(ctx context.Context, r *userspb.RestoreRequest) (*userspb.User, error) {
+	s.Logger.Info("gRPC restore user")
+
+	if err := user.AuthorizeAdmin(ctx); err != nil {
+		return nil, s.statusError(err)
+	}
+
+	u, err := s.UserService.Restore(ctx, r.GetId())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	return toUser(u), nil
+}
+
+// GetUserFriends - getting friends of user
+func (s *Server) GetUserFriends(ctx context.Context, r *userspb.GetUserFriendsRequest) (*userspb.FriendsResponse, error) {
+	s.Logger.Info("gRPC get user friends")
+
+	friends, err := s.UserService.GetUserFriends(ctx, r.GetId())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	return toFriends(friends), nil
+}
+
+// Unfriend - removing friendship of user and friend in both directions, answer with both users
+func (s *Server) Unfriend(ctx context.Context, r *userspb.UnfriendRequest) (*userspb.UserPair, error) {
+	s.Logger.Info("gRPC unfriend")
+
+	if err := user.AuthorizeUser(ctx, r.GetId()); err != nil {
+		return nil, s.statusError(err)
+	}
+	first, second, err := s.UserService.Unfriend(ctx, r.GetId(), r.GetFriendId())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	return &userspb.UserPair{First: toUser(first), Second: toUser(second)}, nil
+}
+
+// MutualFriends - getting friends of both users
+func (s *Server) MutualFriends(ctx context.Context, r *userspb.MutualFriendsRequest) (*userspb.FriendsResponse, error) {
+	s.Logger.Info("gRPC mutual friends")
+
+	friends, err := s.UserService.MutualFriends(ctx, r.GetId(), r.GetOtherId())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	return toFriends(friends), nil
+}
+
+// Suggestions - getting friends of friends ranked by mutual friends
+func (s *Server) Suggestions(ctx context.Context, r *userspb.SuggestionsRequest) (*userspb.SuggestionsResponse, error) {
+	s.Logger.Info("gRPC friend suggestions")
+
+	suggestions, err := s.UserService.Suggestions(ctx, r.GetId(), int(r.GetLimit()))
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	answer := &userspb.SuggestionsResponse{}
+	for _, suggestion := range suggestions {
+		answer.Suggestions = append(answer.Suggestions, &userspb.Suggestion{
+			Friend:      toFriend(suggestion.Friend),
+			MutualCount: int32(suggestion.MutualCount),
+		})
+	}
+	return answer, nil
+}
+
+// ShortestPath - getting the shortest chain of friends between users, both included
+func (s *Server) ShortestPath(ctx context.Context, r *userspb.ShortestPathRequest) (*userspb.FriendsResponse, error) {
+	s.Logger.Info("gRPC shortest path")
+
+	path, err := s.UserService.ShortestPath(ctx, r.GetFromId(), r.GetToId())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	return toFriends(path), nil
+}
+
+// ListFriendRequests - getting friend requests of user by direction and status
+func (s *Server) ListFriendRequests(ctx context.Context, r *userspb.ListFriendRequestsRequest) (*userspb.ListFriendRequestsResponse, error) {
+	s.Logger.Info("gRPC list friend requests")
+
+	if err := user.AuthorizeUser(ctx, r.GetId()); err != nil {
+		return nil, s.statusError(err)
+	}
+	direction := r.GetDirection()
+	if direction == "" {
+		direction = user.RequestsIncoming
+	}
+	requests, err := s.UserService.ListFriendRequests(ctx, r.GetId(), direction, r.GetStatus())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	answer := &userspb.ListFriendRequestsResponse{}
+	for _, request := range requests {
+		answer.Requests = append(answer.Requests, &userspb.FriendRequest{
+			Id:        request.ID,
+			FromId:    request.FromID,
+			ToId:      request.ToID,
+			Status:    request.Status,
+			CreatedAt: timestamppb.New(request.CreatedAt),
+			UpdatedAt: timestamppb.New(request.UpdatedAt),
+		})
+	}
+	return answer, nil
+}
+
+// ListRelations - getting blocks or mutes of user
+func (s *Server) ListRelations(ctx context.Context, r *userspb.ListRelationsRequest) (*userspb.ListRelationsResponse, error) {
+	s.Logger.Info("gRPC list relations")
+
+	if err := user.AuthorizeUser(ctx, r.GetId()); err != nil {
+		return nil, s.statusError(err)
+	}
+	relations, err := s.UserService.ListRelations(ctx, r.GetId(), r.GetKind())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	answer := &userspb.ListRelationsResponse{}
+	for _, relation := range relations {
+		answer.Relations = append(answer.Relations, &userspb.Relation{
+			UserId:    relation.UserID,
+			TargetId:  relation.TargetID,
+			Kind:      relation.Kind,
+			CreatedAt: timestamppb.New(relation.CreatedAt),
+		})
+	}
+	return answer, nil
+}
+
+// toUser - message of user
+func toUser(u user.User) *userspb.User {
+	return &userspb.User{Id: u.ID, Username: u.Username, Age: u.Age, Friends: u.Friends}
+}
+
+// toFriend - message of friend
+func toFriend(f user.Friend) *userspb.Friend {
+	return &userspb.Friend{Id: f.ID, Username: f.Username}
+}
+
+// toFriends - message with list of friends
+func toFriends(friends []user.Friend) *userspb.FriendsResponse {
+	answer := &userspb.FriendsResponse{}
+	for _, f := range friends {
+		answer.Friends = append(answer.Friends, toFriend(f))
+	}
+	return answer
+}
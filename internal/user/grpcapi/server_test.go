@@ -0,0 +1,407 @@
+package grpcapi_test
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"project/internal/middleware"
+	"project/internal/user"
+	"project/internal/user/apitest"
+	"project/internal/user/grpcapi"
+	"project/pkg/grpc/userspb"
+	"testing"
+)
+
+// invalidID - id which can't be produced by any storage
+const invalidID = "not-an-id"
+
+// bufSize - size of buffer of in-process connection
+const bufSize = 1 << 20
+
+// TestGRPC - call every method of gRPC server with service on memory storages as admin,
+// check that calls without valid token or of other users are rejected
+func TestGRPC(t *testing.T) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, c userspb.UsersClient)
+	}{
+		{"Users", testGRPCUsers},
+		{"Lists", testGRPCLists},
+		{"Errors", testGRPCErrors},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := apitest.MemoryHandler(t)
+			tt.test(t, newGRPCClient(t, h, bearer(t, h.Auth, "admin-1", middleware.RoleAdmin)))
+		})
+	}
+	t.Run("Friends", func(t *testing.T) {
+		h := apitest.MemoryHandler(t)
+		testGRPCFriends(t, h)
+	})
+	t.Run("Auth", func(t *testing.T) {
+		h := apitest.MemoryHandler(t)
+		testGRPCAuth(t, h)
+	})
+	// server without authentication changes nobody
+	t.Run("FailClosed", func(t *testing.T) {
+		h := apitest.MemoryHandler(t)
+		h.Auth = nil
+		testGRPCFailClosed(t, h)
+	})
+}
+
+// newGRPCClient - start gRPC server with service and authentication of handler on bufconn listener and connect to it,
+// every call of client has metadata with authorization if it is not empty, server and connection are closed after test
+func newGRPCClient(t *testing.T, h *user.Handler, authorization string) userspb.UsersClient {
+	listener := bufconn.Listen(bufSize)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(grpcapi.PanicRecovery, grpcapi.Authenticate(h.Auth, h.APIKeys)))
+	usersServer := grpcapi.Server{Logger: h.Logger, UserService: h.UserService}
+	usersServer.Register(server)
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			t.Errorf("gRPC server error: %v", err)
+		}
+	}()
+	t.Cleanup(server.Stop)
+
+	dial := func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}
+	authorize := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if authorization != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", authorization)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(dial),
+		grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithUnaryInterceptor(authorize))
+	if err != nil {
+		t.Fatalf("failed to dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return userspb.NewUsersClient(conn)
+}
+
+// testGRPCUsers - create, read, update, delete and restore user
+func testGRPCUsers(t *testing.T, c userspb.UsersClient) {
+	ctx := context.Background()
+
+	alice, err := c.Create(ctx, &userspb.CreateRequest{Username: "alice", Age: "30"})
+	if err != nil || alice.GetId() == "" || alice.GetUsername() != "alice" {
+		t.Fatalf("Create = %v, %v, want alice with id", alice, err)
+	}
+	if got, err := c.GetUser(ctx, &userspb.GetUserRequest{Id: alice.GetId()}); err != nil || got.GetAge() != "30" {
+		t.Errorf("GetUser = %v, %v, want alice of 30", got, err)
+	}
+	if got, err := c.Update(ctx, &userspb.UpdateRequest{Id: alice.GetId(), Username: "alice2", Age: "31"}); err != nil || got.GetUsername() != "alice2" {
+		t.Errorf("Update = %v, %v, want alice2", got, err)
+	}
+	if got, err := c.UpdateAge(ctx, &userspb.UpdateAgeRequest{Id: alice.GetId(), Age: "32"}); err != nil || got.GetUsername() != "alice2" || got.GetAge() != "32" {
+		t.Errorf("UpdateAge = %v, %v, want alice2 of 32", got, err)
+	}
+
+	if _, err := c.Delete(ctx, &userspb.DeleteRequest{Id: alice.GetId()}); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, err := c.GetUser(ctx, &userspb.GetUserRequest{Id: alice.GetId()}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetUser of deleted user error = %v, want %v", err, codes.NotFound)
+	}
+	if got, err := c.Restore(ctx, &userspb.RestoreRequest{Id: alice.GetId()}); err != nil || got.GetId() != alice.GetId() {
+		t.Errorf("Restore = %v, %v, want alice", got, err)
+	}
+	if _, err := c.Restore(ctx, &userspb.RestoreRequest{Id: alice.GetId()}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Restore of not deleted user error = %v, want %v", err, codes.FailedPrecondition)
+	}
+}
+
+// testGRPCFriends - friendships made by accepted friend requests of service and graph queries,
+// users can't become friends by MakeFriends at once
+func testGRPCFriends(t *testing.T, h *user.Handler) {
+	ctx := context.Background()
+	c := newGRPCClient(t, h, bearer(t, h.Auth, "admin-1", middleware.RoleAdmin))
+	alice := createGRPC(t, c, "alice")
+	bob := createGRPC(t, c, "bob")
+	carol := createGRPC(t, c, "carol")
+
+	if _, err := c.MakeFriends(ctx, &userspb.MakeFriendsRequest{Id: alice, FriendId: bob}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("MakeFriends error = %v, want %v", err, codes.Unimplemented)
+	}
+	befriend(t, h, alice, bob)
+	befriend(t, h, bob, carol)
+
+	if friends, err := c.GetUserFriends(ctx, &userspb.GetUserFriendsRequest{Id: bob}); err != nil || len(friends.GetFriends()) != 2 {
+		t.Errorf("GetUserFriends = %v, %v, want alice and carol", friends, err)
+	}
+	if mutual, err := c.MutualFriends(ctx, &userspb.MutualFriendsRequest{Id: alice, OtherId: carol}); err != nil || len(mutual.GetFriends()) != 1 {
+		t.Errorf("MutualFriends = %v, %v, want bob", mutual, err)
+	}
+	suggestions, err := c.Suggestions(ctx, &userspb.SuggestionsRequest{Id: alice, Limit: 5})
+	if err != nil || len(suggestions.GetSuggestions()) != 1 || suggestions.GetSuggestions()[0].GetFriend().GetId() != carol || suggestions.GetSuggestions()[0].GetMutualCount() != 1 {
+		t.Errorf("Suggestions = %v, %v, want carol with one mutual friend", suggestions, err)
+	}
+	if path, err := c.ShortestPath(ctx, &userspb.ShortestPathRequest{FromId: alice, ToId: carol}); err != nil || len(path.GetFriends()) != 3 {
+		t.Errorf("ShortestPath = %v, %v, want alice, bob, carol", path, err)
+	}
+
+	if _, err := c.Unfriend(ctx, &userspb.UnfriendRequest{Id: alice, FriendId: bob}); err != nil {
+		t.Fatalf("Unfriend error: %v", err)
+	}
+	if _, err := c.Unfriend(ctx, &userspb.UnfriendRequest{Id: alice, FriendId: bob}); status.Code(err) != codes.NotFound {
+		t.Errorf("second Unfriend error = %v, want %v", err, codes.NotFound)
+	}
+}
+
+// testGRPCLists - users, friend requests and relations are listed with the same service as http handlers
+func testGRPCLists(t *testing.T, c userspb.UsersClient) {
+	ctx := context.Background()
+	alice := createGRPC(t, c, "alice")
+	bob := createGRPC(t, c, "bob")
+	if _, err := c.UpdateAge(ctx, &userspb.UpdateAgeRequest{Id: bob, Age: "40"}); err != nil {
+		t.Fatalf("UpdateAge error: %v", err)
+	}
+
+	minAge := int32(35)
+	page, err := c.ListUsers(ctx, &userspb.ListUsersRequest{MinAge: &minAge, SortBy: user.SortByUsername, Desc: true})
+	if err != nil || page.GetTotal() != 1 || len(page.GetUsers()) != 1 || page.GetUsers()[0].GetId() != bob {
+		t.Errorf("ListUsers = %v, %v, want page with bob", page, err)
+	}
+
+	requests, err := c.ListFriendRequests(ctx, &userspb.ListFriendRequestsRequest{Id: alice})
+	if err != nil || len(requests.GetRequests()) != 0 {
+		t.Errorf("ListFriendRequests = %v, %v, want no requests", requests, err)
+	}
+	relations, err := c.ListRelations(ctx, &userspb.ListRelationsRequest{Id: alice, Kind: user.RelationBlock})
+	if err != nil || len(relations.GetRelations()) != 0 {
+		t.Errorf("ListRelations = %v, %v, want no relations", relations, err)
+	}
+}
+
+// testGRPCErrors - errors of service are answered with codes and fields of failed validation
+func testGRPCErrors(t *testing.T, c userspb.UsersClient) {
+	ctx := context.Background()
+
+	_, err := c.Create(ctx, &userspb.CreateRequest{Username: "x"})
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("Create with short username error = %v, want %v", err, codes.InvalidArgument)
+	}
+	var fields []string
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				fields = append(fields, violation.GetField())
+			}
+		}
+	}
+	if len(fields) != 1 || fields[0] != "username" {
+		t.Errorf("field violations of Create with short username = %v, want [username]", fields)
+	}
+
+	alice := createGRPC(t, c, "alice")
+	for _, age := range []string{"abc", "-5", "151"} {
+		if _, err := c.UpdateAge(ctx, &userspb.UpdateAgeRequest{Id: alice, Age: age}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("UpdateAge with age %q error = %v, want %v", age, err, codes.InvalidArgument)
+		}
+	}
+	if u, err := c.GetUser(ctx, &userspb.GetUserRequest{Id: alice}); err != nil || u.GetAge() != "" {
+		t.Errorf("GetUser after invalid ages = %v, %v, want alice without age", u, err)
+	}
+
+	if _, err := c.GetUser(ctx, &userspb.GetUserRequest{Id: invalidID}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetUser with invalid id error = %v, want %v", err, codes.InvalidArgument)
+	}
+	if _, err := c.ListUsers(ctx, &userspb.ListUsersRequest{SortBy: "name"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ListUsers with unknown sort error = %v, want %v", err, codes.InvalidArgument)
+	}
+	if _, err := c.ListRelations(ctx, &userspb.ListRelationsRequest{Id: alice, Kind: "friend"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ListRelations of unknown kind error = %v, want %v", err, codes.InvalidArgument)
+	}
+}
+
+// createGRPC - create user through gRPC and return his id
+func createGRPC(t *testing.T, c userspb.UsersClient, username string) string {
+	t.Helper()
+
+	u, err := c.Create(context.Background(), &userspb.CreateRequest{Username: username})
+	if err != nil {
+		t.Fatalf("Create(%s) error: %v", username, err)
+	}
+	return u.GetId()
+}
+
+// testGRPCAuth - calls without valid token are unauthenticated, caller changes only his own user, API key is limited by its scopes
+func testGRPCAuth(t *testing.T, h *user.Handler) {
+	ctx := context.Background()
+	alice, bob := createUser(t, h, "alice"), createUser(t, h, "bob")
+
+	anonymous := newGRPCClient(t, h, "")
+	if _, err := anonymous.GetUser(ctx, &userspb.GetUserRequest{Id: alice}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("GetUser without token error = %v, want %v", err, codes.Unauthenticated)
+	}
+	for name, call := range grpcMutations(bob, alice) {
+		if err := call(anonymous); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s without token error = %v, want %v", name, err, codes.Unauthenticated)
+		}
+	}
+	other, err := middleware.NewJWTAuth(middleware.JWTConfig{Secret: "other-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := newGRPCClient(t, h, bearer(t, other, alice))
+	if _, err := invalid.Update(ctx, &userspb.UpdateRequest{Id: alice, Username: "alicia"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Update with token of other secret error = %v, want %v", err, codes.Unauthenticated)
+	}
+
+	c := newGRPCClient(t, h, bearer(t, h.Auth, alice))
+	for name, call := range grpcMutations(bob, alice) {
+		if name == "Create" {
+			continue
+		}
+		if err := call(c); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s of other user error = %v, want %v", name, err, codes.PermissionDenied)
+		}
+	}
+	if _, err := c.ListFriendRequests(ctx, &userspb.ListFriendRequestsRequest{Id: bob}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("ListFriendRequests of other user error = %v, want %v", err, codes.PermissionDenied)
+	}
+	if u, err := h.UserService.GetUser(ctx, bob); err != nil || u.Username != "bob" || u.Age != "" || len(u.Friends) != 0 {
+		t.Errorf("bob after forbidden calls = %+v, %v, want unchanged", u, err)
+	}
+
+	bobClient := newGRPCClient(t, h, bearer(t, h.Auth, bob))
+	if _, err := bobClient.Delete(ctx, &userspb.DeleteRequest{Id: bob}); err != nil {
+		t.Fatalf("Delete of own user error: %v", err)
+	}
+	if _, err := bobClient.Restore(ctx, &userspb.RestoreRequest{Id: bob}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Restore by not admin error = %v, want %v", err, codes.PermissionDenied)
+	}
+	if u, err := newGRPCClient(t, h, bearer(t, h.Auth, "admin-1", middleware.RoleAdmin)).Restore(ctx, &userspb.RestoreRequest{Id: bob}); err != nil || u.GetUsername() != "bob" {
+		t.Errorf("Restore by admin = %v, %v, want bob", u, err)
+	}
+
+	if u, err := c.Update(ctx, &userspb.UpdateRequest{Id: alice, Username: "alicia", Age: "30"}); err != nil || u.GetUsername() != "alicia" {
+		t.Errorf("Update of own user = %v, %v, want alicia", u, err)
+	}
+	befriend(t, h, alice, bob)
+	if pair, err := c.Unfriend(ctx, &userspb.UnfriendRequest{Id: alice, FriendId: bob}); err != nil || len(pair.GetFirst().GetFriends()) != 0 {
+		t.Errorf("Unfriend of own user = %v, %v, want no friends", pair, err)
+	}
+
+	_, readKey, err := h.APIKeys.IssueAPIKey(ctx, user.APIKeyInput{Name: "reader", Scopes: []string{middleware.ScopeUsersRead}})
+	if err != nil {
+		t.Fatalf("IssueAPIKey error: %v", err)
+	}
+	reader := newGRPCClient(t, h, "ApiKey "+readKey)
+	if u, err := reader.GetUser(ctx, &userspb.GetUserRequest{Id: bob}); err != nil || u.GetUsername() != "bob" {
+		t.Errorf("GetUser with read key = %v, %v, want bob", u, err)
+	}
+	if _, err := reader.Delete(ctx, &userspb.DeleteRequest{Id: bob}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Delete with read key error = %v, want %v", err, codes.PermissionDenied)
+	}
+	if _, err := newGRPCClient(t, h, "ApiKey uak_unknown").GetUser(ctx, &userspb.GetUserRequest{Id: bob}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("GetUser with unknown key error = %v, want %v", err, codes.Unauthenticated)
+	}
+}
+
+// testGRPCFailClosed - server without authentication calls methods without principal, so they change nobody
+func testGRPCFailClosed(t *testing.T, h *user.Handler) {
+	alice, bob := createUser(t, h, "alice"), createUser(t, h, "bob")
+
+	c := newGRPCClient(t, h, "")
+	for name, call := range grpcMutations(alice, bob) {
+		if err := call(c); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s without principal error = %v, want %v", name, err, codes.Unauthenticated)
+		}
+	}
+	if u, err := h.UserService.GetUser(context.Background(), alice); err != nil || u.Username != "alice" || u.Age != "" || len(u.Friends) != 0 {
+		t.Errorf("alice after calls without principal = %+v, %v, want unchanged", u, err)
+	}
+
+	// API keys are verified without Auth too
+	_, reader, err := h.APIKeys.IssueAPIKey(context.Background(), user.APIKeyInput{Name: "reader", Scopes: []string{middleware.ScopeUsersRead}})
+	if err != nil {
+		t.Fatalf("IssueAPIKey error: %v", err)
+	}
+	if _, err := newGRPCClient(t, h, "ApiKey uak_unknown").GetUser(context.Background(), &userspb.GetUserRequest{Id: alice}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("GetUser with unknown key error = %v, want %v", err, codes.Unauthenticated)
+	}
+	if _, err := newGRPCClient(t, h, "ApiKey "+reader).UpdateAge(context.Background(), &userspb.UpdateAgeRequest{Id: alice, Age: "31"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("UpdateAge with read key error = %v, want %v", err, codes.PermissionDenied)
+	}
+}
+
+// grpcMutations - calls of methods which change user with id, other is his friend
+func grpcMutations(id string, other string) map[string]func(c userspb.UsersClient) error {
+	ctx := context.Background()
+	return map[string]func(c userspb.UsersClient) error{
+		"Create": func(c userspb.UsersClient) error {
+			_, err := c.Create(ctx, &userspb.CreateRequest{Username: "mallory"})
+			return err
+		},
+		"Update": func(c userspb.UsersClient) error {
+			_, err := c.Update(ctx, &userspb.UpdateRequest{Id: id, Username: "mallory"})
+			return err
+		},
+		"UpdateAge": func(c userspb.UsersClient) error {
+			_, err := c.UpdateAge(ctx, &userspb.UpdateAgeRequest{Id: id, Age: "99"})
+			return err
+		},
+		"Delete": func(c userspb.UsersClient) error {
+			_, err := c.Delete(ctx, &userspb.DeleteRequest{Id: id})
+			return err
+		},
+		"Unfriend": func(c userspb.UsersClient) error {
+			_, err := c.Unfriend(ctx, &userspb.UnfriendRequest{Id: id, FriendId: other})
+			return err
+		},
+		"Restore": func(c userspb.UsersClient) error {
+			_, err := c.Restore(ctx, &userspb.RestoreRequest{Id: id})
+			return err
+		},
+	}
+}
+
+// befriend - make users friends through friend request of service which second user accepts
+func befriend(t *testing.T, h *user.Handler, firstID string, secondID string) {
+	t.Helper()
+
+	ctx := context.Background()
+	request, err := h.UserService.SendFriendRequest(ctx, firstID, secondID)
+	if err != nil {
+		t.Fatalf("SendFriendRequest(%s, %s) error: %v", firstID, secondID, err)
+	}
+	if _, err = h.UserService.AcceptFriendRequest(ctx, secondID, request.ID); err != nil {
+		t.Fatalf("AcceptFriendRequest(%s, %s) error: %v", secondID, request.ID, err)
+	}
+}
+
+// createUser - create user through service of handler
+func createUser(t *testing.T, h *user.Handler, username string) string {
+	t.Helper()
+
+	id, err := h.UserService.Create(context.Background(), user.User{Username: username})
+	if err != nil {
+		t.Fatalf("Create(%s) error: %v", username, err)
+	}
+	return id
+}
+
+// bearer - authorization metadata with token of subject with roles signed by auth
+func bearer(t *testing.T, auth *middleware.JWTAuth, subject string, roles ...string) string {
+	t.Helper()
+
+	token, _, err := auth.Sign(middleware.Principal{Subject: subject, Roles: roles})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
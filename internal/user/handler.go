@@ -4,73 +4,237 @@ package user
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/julienschmidt/httprouter"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"project/internal/apperror"
 	"project/internal/middleware"
+	"project/internal/response"
 	"project/pkg/logging"
+	"project/pkg/validator"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// constants for standart path
+// apiPrefix - prefix of all paths of current api version
+const apiPrefix = "/api/v1"
+
+// constants for standart path, they are registered under apiPrefix
 const (
-	usersURL = "/users"
-	userURL  = "/users/:id"
+	usersURL         = "/users"
+	userURL          = "/users/:id"
+	userFriendsURL   = "/users/:id/friends"
+	userFriendURL    = "/users/:id/friends/:friendId"
+	mutualFriendsURL = "/users/:id/friends/mutual/:other"
+	suggestionsURL   = "/users/:id/suggestions"
+	shortestPathURL  = "/users/:id/path/:other"
+	restoreUserURL   = "/admin/users/:id/restore"
+)
+
+// Paths without apiPrefix are deprecated since legacyDeprecatedAt and are removed at legacySunset
+var (
+	legacyDeprecatedAt = time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+	legacySunset       = time.Date(2027, time.May, 1, 0, 0, 0, 0, time.UTC)
 )
 
+// pathMeta - meta of answer with path between users
+type pathMeta struct {
+	Degrees int `json:"degrees"`
+}
+
+// listMeta - meta of answer with list
+type listMeta struct {
+	Count int `json:"count"`
+}
+
+// pageMeta - meta of answer with page of list
+type pageMeta struct {
+	Count  int   `json:"count"`
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+}
+
+// deletedUser - data of answer for deleted user
+type deletedUser struct {
+	ID string `json:"id"`
+}
+
 // Handler - structure for handlers with logger
 type Handler struct {
 	Logger      *logging.Logger
 	UserService Service
+
+	// Auth - verifier of bearer tokens of every route, routes are anonymous without it
+	Auth *middleware.JWTAuth
+	// AuthService - registration and login of users, access tokens of its sessions are signed by Auth
+	AuthService AuthService
+	// APIKeys - API keys of services, they are accepted alongside bearer tokens if Auth is set
+	APIKeys APIKeyService
+
+	routes []Route
 }
 
-// Register - func for init routs
+// Route - route registered by Handler, path is in form of httprouter
+type Route struct {
+	Method     string
+	Path       string
+	Deprecated bool
+}
+
+// Register - func for init routs, every route is under apiPrefix and old paths are kept as deprecated aliases
 func (h *Handler) Register(router *httprouter.Router) {
-	router.HandlerFunc(http.MethodPost, "/create", middleware.PanicRecovery(middleware.Logging(h.CreateUser)))
-	router.HandlerFunc(http.MethodGet, "/friends/:id", middleware.PanicRecovery(middleware.Logging(h.GetUserFriends)))
-	router.HandlerFunc(http.MethodPut, userURL, middleware.PanicRecovery(middleware.Logging(h.UpdateUserAge)))
-	router.HandlerFunc(http.MethodPost, "/make_friends", middleware.PanicRecovery(middleware.Logging(h.MakeFriends)))
-	router.HandlerFunc(http.MethodDelete, usersURL, middleware.PanicRecovery(middleware.Logging(h.DeleteUser)))
+	h.handle(router, http.MethodPost, usersURL, h.CreateUser, "/create")
+	h.handle(router, http.MethodGet, usersURL, h.ListUsers, usersURL)
+	h.handle(router, http.MethodGet, userURL, h.GetUser, userURL)
+	h.handle(router, http.MethodPut, userURL, h.UpdateUser)
+	h.handle(router, http.MethodPatch, userURL, h.PatchUser, userURL)
+	h.handle(router, http.MethodDelete, userURL, h.DeleteUser)
+	h.handle(router, http.MethodGet, userFriendsURL, h.GetUserFriends, "/friends/:id")
+	h.handle(router, http.MethodDelete, userFriendURL, h.Unfriend, userFriendURL)
+	h.handle(router, http.MethodGet, mutualFriendsURL, h.MutualFriends, mutualFriendsURL)
+	h.handle(router, http.MethodGet, suggestionsURL, h.Suggestions, suggestionsURL)
+	h.handle(router, http.MethodGet, shortestPathURL, h.ShortestPath, shortestPathURL)
+	h.handle(router, http.MethodPost, restoreUserURL, h.RestoreUser, restoreUserURL)
+	h.registerRequests(router)
+	h.registerRelations(router)
+	h.registerAuth(router)
+	h.registerAPIKeys(router)
+
+	// old paths with ids in body, they have no alias in apiPrefix
+	h.handleDeprecated(router, http.MethodPost, "/make_friends", friendRequestsURL, h.MakeFriends)
+	h.handleDeprecated(router, http.MethodDelete, usersURL, userURL, h.DeleteUserByBody)
+
+	// old PUT changed only age, full replace is only under apiPrefix
+	h.handleDeprecated(router, http.MethodPut, userURL, userURL, h.UpdateUserAge)
+}
+
+// Routes - routes registered by Register, for checking them against OpenAPI specification
+func (h *Handler) Routes() []Route {
+	return append([]Route(nil), h.routes...)
+}
+
+// handle - register handler at path under apiPrefix and at deprecated legacy paths
+func (h *Handler) handle(router *httprouter.Router, method string, path string, handler func(w http.ResponseWriter, r *http.Request) error, legacy ...string) {
+	router.HandlerFunc(method, apiPrefix+path, h.wrap(handler))
+	h.routes = append(h.routes, Route{Method: method, Path: apiPrefix + path})
+	for _, legacyPath := range legacy {
+		h.handleDeprecated(router, method, legacyPath, path, handler)
+	}
+}
+
+// handleAnonymous - register handler at path under apiPrefix, which is called without bearer token
+func (h *Handler) handleAnonymous(router *httprouter.Router, method string, path string, handler func(w http.ResponseWriter, r *http.Request) error) {
+	router.HandlerFunc(method, apiPrefix+path, middleware.PanicRecovery(middleware.Errors(translateErrors(handler))))
+	h.routes = append(h.routes, Route{Method: method, Path: apiPrefix + path})
+}
+
+// handleDeprecated - register handler at deprecated legacy path, successor is path of the same resource under apiPrefix
+func (h *Handler) handleDeprecated(router *httprouter.Router, method string, legacyPath string, successor string, handler func(w http.ResponseWriter, r *http.Request) error) {
+	router.HandlerFunc(method, legacyPath, middleware.Deprecated(h.wrap(handler), legacyDeprecatedAt, legacySunset, successorURL(apiPrefix+successor)))
+	h.routes = append(h.routes, Route{Method: method, Path: legacyPath, Deprecated: true})
+}
+
+// wrap - chain of middleware of every route, caller is authenticated before handler is called
+func (h *Handler) wrap(handler func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return middleware.PanicRecovery(middleware.Errors(middleware.Authenticate(h.Auth, h.APIKeys, translateErrors(handler))))
+}
+
+// successorURL - get func which fills params of path from params of request, params which request doesn't have are kept
+func successorURL(path string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		params, _ := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			if !strings.HasPrefix(segment, ":") {
+				continue
+			}
+			if value := params.ByName(segment[1:]); value != "" {
+				segments[i] = url.PathEscape(value)
+			}
+		}
+		return strings.Join(segments, "/")
+	}
 }
 
 // CreateUser - creating user by http-request
 func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) error {
 	h.Logger.Info("Create user")
-	w.Header().Set("Content-Type", "application/json")
+
+	if err := AuthorizeCaller(r.Context()); err != nil {
+		return err
+	}
 
 	// get data from http`s body
 	content, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-		return err
+		return &ValidationError{Message: fmt.Sprintf("failed to read body: %v", err)}
 	}
 	defer r.Body.Close()
 	var u User
 
 	// unmarshalling json to user struct
 	if err := json.Unmarshal(content, &u); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-		return err
+		return &ValidationError{Message: fmt.Sprintf("invalid json: %v", err)}
 	}
 
-	// call user-service for create user in database
+	// call user-service for create user in database, it validates user
 	u.ID, err = h.UserService.Create(r.Context(), u)
 	if err != nil {
-		fmt.Println(err)
+		return err
+	}
+
+	// answer with created user
+	return response.JSON(w, http.StatusCreated, u, nil)
+
+}
+
+// GetUser - getting one user by http-request
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Get user")
+
+	// getting id from url params
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	userID := params.ByName("id")
+
+	u, err := h.UserService.GetUser(r.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return response.JSON(w, http.StatusOK, u, nil)
+}
+
+// ListUsers - getting page of users by http-request, filter, sorting and pagination are in query:
+// username (prefix), min_age, max_age, sort (id, username, age, "-" for descending), limit, offset
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("List users")
+
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		return err
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(fmt.Sprintf("From 2 instance: User with id:%v was created", u.ID)))
-	return nil
+	users, total, err := h.UserService.ListUsers(r.Context(), filter)
+	if err != nil {
+		return err
+	}
 
+	// limit of answer is the one used by service, not the one from query
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return response.JSON(w, http.StatusOK, users, pageMeta{Count: len(users), Total: total, Limit: limit, Offset: filter.Offset})
 }
 
 // GetUserFriends - getting friends from one user by http-request
 func (h *Handler) GetUserFriends(w http.ResponseWriter, r *http.Request) error {
 	h.Logger.Info("Find User's Friends")
-	w.Header().Set("Content-Type", "application/json")
 
 	h.Logger.Debug("get userID from context")
 
@@ -83,30 +247,109 @@ func (h *Handler) GetUserFriends(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
-	w.WriteHeader(http.StatusOK)
 
-	// creating message for answer
-	message := fmt.Sprintf("From 2 instance: Друзья Пользователя c id:%s, это:%s", userID, friends)
-	w.Write([]byte(message))
-	return nil
+	// empty friends are answered with empty array, not null
+	if friends == nil {
+		friends = []Friend{}
+	}
+	return response.JSON(w, http.StatusOK, friends, listMeta{Count: len(friends)})
+}
+
+// MutualFriends - getting friends of both users from url
+func (h *Handler) MutualFriends(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Mutual friends")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	friends, err := h.UserService.MutualFriends(r.Context(), params.ByName("id"), params.ByName("other"))
+	if err != nil {
+		return err
+	}
+
+	return response.JSON(w, http.StatusOK, friends, listMeta{Count: len(friends)})
+}
+
+// Suggestions - getting friends of friends of user ranked by mutual friends, size of list is limit from query
+func (h *Handler) Suggestions(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Friend suggestions")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	limit, err := intParam(r.URL.Query(), "limit")
+	if err != nil {
+		return err
+	}
+
+	suggestions, err := h.UserService.Suggestions(r.Context(), params.ByName("id"), limit)
+	if err != nil {
+		return err
+	}
+
+	return response.JSON(w, http.StatusOK, suggestions, listMeta{Count: len(suggestions)})
 }
 
-// UpdateUserAge - func for update user`s age in database by http-request
+// ShortestPath - getting the shortest chain of friends between users from url, degrees of separation are in meta
+func (h *Handler) ShortestPath(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Shortest path")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	path, err := h.UserService.ShortestPath(r.Context(), params.ByName("id"), params.ByName("other"))
+	if err != nil {
+		return err
+	}
+
+	return response.JSON(w, http.StatusOK, path, pathMeta{Degrees: len(path) - 1})
+}
+
+// UpdateUser - func for replacing all mutable fields of user in database by http-request, id and friends from body are ignored
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Update user")
+
+	// getting id from url params
+	h.Logger.Debug("get userID from context")
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	userID := params.ByName("id")
+	if err := AuthorizeUser(r.Context(), userID); err != nil {
+		return err
+	}
+
+	// getting new user from http message body
+	content, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return &ValidationError{Message: fmt.Sprintf("failed to read body: %v", err)}
+	}
+	defer r.Body.Close()
+	var u User
+
+	// unmarshalling data from json message
+	if err := json.Unmarshal(content, &u); err != nil {
+		return &ValidationError{Message: fmt.Sprintf("invalid json: %v", err)}
+	}
+
+	// call user-service for replace user in database, it validates user
+	u, err = h.UserService.Update(r.Context(), userID, u)
+	if err != nil {
+		return err
+	}
+
+	// answer with updated user
+	return response.JSON(w, http.StatusOK, u, nil)
+}
+
+// UpdateUserAge - deprecated func for changing only age of user in database by http-request, other fields of body are ignored
 func (h *Handler) UpdateUserAge(w http.ResponseWriter, r *http.Request) error {
 	h.Logger.Info("Update user age")
-	w.Header().Set("Content-Type", "application/json")
 
 	// getting id from url params
 	h.Logger.Debug("get userID from context")
 	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
 	userID := params.ByName("id")
+	if err := AuthorizeUser(r.Context(), userID); err != nil {
+		return err
+	}
 
 	// getting new age of user from http message body
 	content, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-		return err
+		return &ValidationError{Message: fmt.Sprintf("failed to read body: %v", err)}
 	}
 	defer r.Body.Close()
 
@@ -114,94 +357,160 @@ func (h *Handler) UpdateUserAge(w http.ResponseWriter, r *http.Request) error {
 	type body struct {
 		Age string `json:"age"`
 	}
-	var age body
+	var message body
 
 	// unmarshalling data from json message
-	if err := json.Unmarshal(content, &age); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+	if err := json.Unmarshal(content, &message); err != nil {
+		return &ValidationError{Message: fmt.Sprintf("invalid json: %v", err)}
+	}
+
+	// call user-service for change age of user in database, age is validated by service
+	u, err := h.UserService.UpdateAge(r.Context(), userID, message.Age)
+	if err != nil {
 		return err
 	}
 
-	// call user-service for change age of user in database
-	err = h.UserService.UpdateAge(r.Context(), userID, age.Age)
+	// answer with updated user
+	return response.JSON(w, http.StatusOK, u, nil)
+}
+
+// PatchUser - func for changing user in database by JSON Merge Patch (RFC 7396) from http-request,
+// only fields from body are changed, null removes field
+func (h *Handler) PatchUser(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Patch user")
+
+	// getting id from url params
+	h.Logger.Debug("get userID from context")
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	userID := params.ByName("id")
+	if err := AuthorizeUser(r.Context(), userID); err != nil {
+		return err
+	}
+
+	// getting merge patch from http message body
+	content, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return &ValidationError{Message: fmt.Sprintf("failed to read body: %v", err)}
+	}
+	defer r.Body.Close()
+
+	// call user-service for patch user in database
+	u, err := h.UserService.Patch(r.Context(), userID, content)
 	if err != nil {
 		return err
 	}
-	w.WriteHeader(http.StatusOK)
 
-	// create message for answer
-	message := fmt.Sprintf("From 2 instance: Пользователь c id:%s, обновлен", userID)
-	w.Write([]byte(message))
-	return nil
+	// answer with updated user
+	return response.JSON(w, http.StatusOK, u, nil)
 }
 
-// MakeFriends - func that making friends two users, data getting from http-request
+// MakeFriends - deprecated func that sends friend request from source user to target user, data getting from http-request.
+// Users become friends only when target accepts the request
 func (h *Handler) MakeFriends(w http.ResponseWriter, r *http.Request) error {
 	h.Logger.Info("Make friends")
-	w.Header().Set("Content-Type", "application/json")
 
 	// getting data from http`s body
 	content, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-		return err
+		return &ValidationError{Message: fmt.Sprintf("failed to read body: %v", err)}
 	}
 	defer r.Body.Close()
 
 	// creating struct for unmarshalling data
 	type body struct {
-		SourceID string `json:"source_id"`
-		TargetID string `json:"target_id"`
+		SourceID string `json:"source_id" validate:"required"`
+		TargetID string `json:"target_id" validate:"required,nefield=SourceID"`
 	}
 	var message body
 
 	// unmarshalling data from json
 	if err := json.Unmarshal(content, &message); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		return &ValidationError{Message: fmt.Sprintf("invalid json: %v", err)}
+	}
+	if err := validator.Validate(message); err != nil {
+		return err
+	}
+	if err := AuthorizeUser(r.Context(), message.SourceID); err != nil {
+		return err
+	}
+
+	// calling user-service to send friend request to target user
+	request, err := h.UserService.SendFriendRequest(r.Context(), message.SourceID, message.TargetID)
+	if err != nil {
+		return err
+	}
+
+	// answer with created request
+	return response.JSON(w, http.StatusCreated, request, nil)
+}
+
+// Unfriend - func that removes friendship of user and friend from url in both directions
+func (h *Handler) Unfriend(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Unfriend")
+
+	// getting ids from url params
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	userID := params.ByName("id")
+	friendID := params.ByName("friendId")
+	if err := AuthorizeUser(r.Context(), userID); err != nil {
 		return err
 	}
-	var firstUser, secondUser User
 
-	// calling user-service to make friends from to users in database
-	firstUser, secondUser, err = h.UserService.MakeFriends(r.Context(), message.SourceID, message.TargetID)
+	// calling user-service to remove friendship in database
+	firstUser, secondUser, err := h.UserService.Unfriend(r.Context(), userID, friendID)
 	if err != nil {
 		return err
 	}
-	w.WriteHeader(http.StatusOK)
 
-	// creating message for answer
-	answer := fmt.Sprintf("From 2 instance: Пользователи %s и %s теперь друзья", firstUser.Username, secondUser.Username)
-	w.Write([]byte(answer))
-	return nil
+	// answer with both users after removing friendship
+	return response.JSON(w, http.StatusOK, []User{firstUser, secondUser}, nil)
 }
 
-// DeleteUser - func that delete user from database and delete from friends arrays of all users
+// DeleteUser - func that soft deletes user from path, he is purged with his friendships after retention period
 func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Delete user")
+
+	// getting id from url params
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	userID := params.ByName("id")
+	if err := AuthorizeUser(r.Context(), userID); err != nil {
+		return err
+	}
+
+	// call user-service to delete user from database
+	if err := h.UserService.Delete(r.Context(), userID); err != nil {
+		return err
+	}
+
+	// answer with id of deleted user
+	return response.JSON(w, http.StatusOK, deletedUser{ID: userID}, nil)
+}
+
+// DeleteUserByBody - deprecated func that soft deletes user with id from body
+func (h *Handler) DeleteUserByBody(w http.ResponseWriter, r *http.Request) error {
 	h.Logger.Info("DELETE USER")
-	w.Header().Set("Content-Type", "application/json")
 
 	// getting data from http-request body
 	content, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-		return err
+		return &ValidationError{Message: fmt.Sprintf("failed to read body: %v", err)}
 	}
 	defer r.Body.Close()
 
 	// create struct for unmarshalling
 	type body struct {
-		TargetID string `json:"target_id"`
+		TargetID string `json:"target_id" validate:"required"`
 	}
 	var message body
 
 	// unmarshall data from json
 	if err := json.Unmarshal(content, &message); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		return &ValidationError{Message: fmt.Sprintf("invalid json: %v", err)}
+	}
+	if err := validator.Validate(message); err != nil {
+		return err
+	}
+	if err := AuthorizeUser(r.Context(), message.TargetID); err != nil {
 		return err
 	}
 
@@ -210,9 +519,156 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
-	w.WriteHeader(http.StatusOK)
-	text := fmt.Sprintf("From 2 instance: Пользователь c id:%s - удален", message.TargetID)
-	w.Write([]byte(text))
 
-	return nil
+	// answer with id of deleted user
+	return response.JSON(w, http.StatusOK, deletedUser{ID: message.TargetID}, nil)
+}
+
+// RestoreUser - admin func that brings soft deleted user from url back with his friendships
+func (h *Handler) RestoreUser(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Restore user")
+
+	// getting id from url params
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	userID := params.ByName("id")
+	if err := AuthorizeAdmin(r.Context()); err != nil {
+		return err
+	}
+
+	u, err := h.UserService.Restore(r.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	// answer with restored user
+	return response.JSON(w, http.StatusOK, u, nil)
+}
+
+// parseFilter - get users filter from query of request
+func parseFilter(query url.Values) (filter Filter, err error) {
+	filter.UsernamePrefix = query.Get("username")
+
+	if filter.Limit, err = intParam(query, "limit"); err != nil {
+		return filter, err
+	}
+	if filter.Offset, err = intParam(query, "offset"); err != nil {
+		return filter, err
+	}
+	if filter.MinAge, err = optionalIntParam(query, "min_age"); err != nil {
+		return filter, err
+	}
+	if filter.MaxAge, err = optionalIntParam(query, "max_age"); err != nil {
+		return filter, err
+	}
+
+	// "-" before field is descending order
+	sortBy := query.Get("sort")
+	if strings.HasPrefix(sortBy, "-") {
+		filter.Desc = true
+		sortBy = sortBy[1:]
+	}
+	switch sortBy {
+	case "", SortByID:
+		filter.SortBy = SortByID
+	case SortByUsername, SortByAge:
+		filter.SortBy = sortBy
+	default:
+		return filter, &ValidationError{Field: "sort", Message: "must be one of id, username, age"}
+	}
+
+	return filter, nil
+}
+
+// intParam - get integer from query, empty param is zero
+func intParam(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, &ValidationError{Field: name, Message: "must be an integer"}
+	}
+	return n, nil
+}
+
+// optionalIntParam - get integer from query, nil if param is empty
+func optionalIntParam(query url.Values, name string) (*int, error) {
+	if query.Get(name) == "" {
+		return nil, nil
+	}
+	n, err := intParam(query, name)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// translateErrors - translate errors of user service to application errors, the only place where it is done
+func translateErrors(handler func(w http.ResponseWriter, r *http.Request) error) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		err := handler(w, r)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+		}
+		return appError(err)
+	}
+}
+
+// appError - get application error with status code and message for client from error
+func appError(err error) *apperror.AppError {
+	var validationErr *ValidationError
+	var fieldErrs validator.Errors
+	switch {
+	case errors.As(err, &fieldErrs):
+		appErr := apperror.New(http.StatusUnprocessableEntity, "validation_failed", "request validation failed", err)
+		appErr.Details = fieldErrs
+		return appErr
+	case errors.As(err, &validationErr):
+		appErr := apperror.New(http.StatusBadRequest, "validation_error", validationErr.Message, err)
+		appErr.Field = validationErr.Field
+		return appErr
+	case errors.Is(err, ErrInvalidID):
+		return apperror.New(http.StatusBadRequest, "invalid_id", ErrInvalidID.Error(), err)
+	case errors.Is(err, ErrNotFound):
+		return apperror.New(http.StatusNotFound, "not_found", ErrNotFound.Error(), err)
+	case errors.Is(err, ErrAlreadyFriends):
+		return apperror.New(http.StatusConflict, "already_friends", ErrAlreadyFriends.Error(), err)
+	case errors.Is(err, ErrNotDeleted):
+		return apperror.New(http.StatusConflict, "not_deleted", ErrNotDeleted.Error(), err)
+	case errors.Is(err, ErrNotFriends):
+		return apperror.New(http.StatusNotFound, "not_friends", ErrNotFriends.Error(), err)
+	case errors.Is(err, ErrNotConnected):
+		return apperror.New(http.StatusNotFound, "not_connected", ErrNotConnected.Error(), err)
+	case errors.Is(err, ErrRequestNotFound):
+		return apperror.New(http.StatusNotFound, "request_not_found", ErrRequestNotFound.Error(), err)
+	case errors.Is(err, ErrRequestExists):
+		return apperror.New(http.StatusConflict, "request_exists", ErrRequestExists.Error(), err)
+	case errors.Is(err, ErrRequestState):
+		return apperror.New(http.StatusConflict, "request_not_pending", ErrRequestState.Error(), err)
+	case errors.Is(err, ErrRequestForbidden):
+		return apperror.New(http.StatusForbidden, "request_forbidden", ErrRequestForbidden.Error(), err)
+	case errors.Is(err, ErrBlocked):
+		return apperror.New(http.StatusForbidden, "blocked", ErrBlocked.Error(), err)
+	case errors.Is(err, ErrForbidden):
+		return apperror.New(http.StatusForbidden, "forbidden", ErrForbidden.Error(), err)
+	case errors.Is(err, ErrUnauthorized):
+		return apperror.New(http.StatusUnauthorized, "unauthorized", ErrUnauthorized.Error(), err)
+	case errors.Is(err, ErrEmailExists):
+		return apperror.New(http.StatusConflict, "email_exists", ErrEmailExists.Error(), err)
+	case errors.Is(err, ErrInvalidCredentials):
+		return apperror.New(http.StatusUnauthorized, "invalid_credentials", ErrInvalidCredentials.Error(), err)
+	case errors.Is(err, ErrInvalidRefreshToken):
+		return apperror.New(http.StatusUnauthorized, "invalid_refresh_token", ErrInvalidRefreshToken.Error(), err)
+	case errors.Is(err, ErrAPIKeyNotFound):
+		return apperror.New(http.StatusNotFound, "api_key_not_found", ErrAPIKeyNotFound.Error(), err)
+	case errors.Is(err, ErrRelationNotFound):
+		return apperror.New(http.StatusNotFound, "relation_not_found", ErrRelationNotFound.Error(), err)
+	default:
+		// details of internal errors are only logged
+		return apperror.SystemError(err)
+	}
 }
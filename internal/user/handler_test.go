@@ -0,0 +1,14 @@
+package user_test
+
+import (
+	"project/internal/user/apitest"
+	"testing"
+)
+
+func TestAPI(t *testing.T) {
+	apitest.Run(t, apitest.MemoryHandler)
+}
+
+func TestAuth(t *testing.T) {
+	apitest.RunAuth(t, apitest.MemoryHandler)
+}
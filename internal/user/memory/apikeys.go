@@ -0,0 +1,124 @@
+package memory
+
+// file for keeping API keys in process memory
+
+import (
+	"context"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Create API keys storage structure
+type apiKeyStorage struct {
+	mu     sync.RWMutex
+	keys   map[string]user.APIKey
+	logger *logging.Logger
+}
+
+// NewAPIKeyStorage - Initialize new in-memory storage of API keys
+func NewAPIKeyStorage(logger *logging.Logger) user.APIKeyStorage {
+	return &apiKeyStorage{
+		keys:   make(map[string]user.APIKey),
+		logger: logger,
+	}
+}
+
+// CreateAPIKey - create new API key
+func (s *apiKeyStorage) CreateAPIKey(ctx context.Context, k user.APIKey) (user.APIKey, error) {
+	s.logger.Debug("create api key")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k.ID = primitive.NewObjectID().Hex()
+	s.keys[k.ID] = copyAPIKey(k)
+
+	return copyAPIKey(k), nil
+}
+
+// FindAPIKey - find API key by hash
+func (s *apiKeyStorage) FindAPIKey(ctx context.Context, hash string) (user.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.keys {
+		if k.Hash == hash {
+			return copyAPIKey(k), nil
+		}
+	}
+
+	return user.APIKey{}, fmt.Errorf("failed to find api key (hash:%.8s): %w", hash, user.ErrAPIKeyNotFound)
+}
+
+// FindAPIKeys - find all API keys, newer keys go first
+func (s *apiKeyStorage) FindAPIKeys(ctx context.Context) ([]user.APIKey, error) {
+	s.mu.RLock()
+	keys := make([]user.APIKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, copyAPIKey(k))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return user.NewerAPIKey(keys[i], keys[j])
+	})
+
+	return keys, nil
+}
+
+// RevokeAPIKey - revoke API key, time of the first revoke is kept
+func (s *apiKeyStorage) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) (user.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[id]
+	if !ok {
+		return user.APIKey{}, apiKeyNotFound(id)
+	}
+	if k.RevokedAt == nil {
+		k.RevokedAt = &revokedAt
+		s.keys[id] = k
+	}
+
+	return copyAPIKey(k), nil
+}
+
+// TouchAPIKey - save time of the last use of API key
+func (s *apiKeyStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[id]
+	if !ok {
+		return apiKeyNotFound(id)
+	}
+	k.LastUsedAt = &usedAt
+	s.keys[id] = k
+
+	return nil
+}
+
+// copyAPIKey - copy of API key, so scopes and times of stored key can't be changed by caller
+func copyAPIKey(k user.APIKey) user.APIKey {
+	k.Scopes = append([]string(nil), k.Scopes...)
+	if k.LastUsedAt != nil {
+		at := *k.LastUsedAt
+		k.LastUsedAt = &at
+	}
+	if k.RevokedAt != nil {
+		at := *k.RevokedAt
+		k.RevokedAt = &at
+	}
+	return k
+}
+
+// apiKeyNotFound - error for API key which doesn't exist
+func apiKeyNotFound(id string) error {
+	return fmt.Errorf("failed to find api key (id:%s): %w", id, user.ErrAPIKeyNotFound)
+}
@@ -0,0 +1,404 @@
+// Package memory for keeping users in process memory (tests and local runs without database)
+package memory
+
+import (
+	"context"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Create storage structure
+type storage struct {
+	mu     sync.RWMutex
+	users  map[string]user.User
+	logger *logging.Logger
+}
+
+// NewStorage - Initialize new in-memory storage
+func NewStorage(logger *logging.Logger) user.Storage {
+	return &storage{
+		users:  make(map[string]user.User),
+		logger: logger,
+	}
+}
+
+// Create - create new user in memory
+func (s *storage) Create(ctx context.Context, u user.User) (string, error) {
+	s.logger.Debug("create user")
+
+	// ids are generated the same way as in MongoDB, so clients can't tell storages apart
+	// friends are made only by MakeFriends
+	u.ID = primitive.NewObjectID().Hex()
+	u.Friends = nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// email of deleted user is taken too, he may be restored
+	if u.Email != "" {
+		for _, other := range s.users {
+			if other.Email == u.Email {
+				return "", fmt.Errorf("failed to create user with email %s: %w", u.Email, user.ErrEmailExists)
+			}
+		}
+	}
+	s.users[u.ID] = u
+
+	return u.ID, nil
+}
+
+// FindOne - find user by id
+func (s *storage) FindOne(ctx context.Context, id string) (user.User, error) {
+	if err := validateID(id); err != nil {
+		return user.User{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.active(id)
+	if !ok {
+		return user.User{}, notFound(id)
+	}
+
+	return s.copyUser(u), nil
+}
+
+// FindByEmail - find user with credentials by email
+func (s *storage) FindByEmail(ctx context.Context, email string) (user.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if email != "" && u.Email == email && u.DeletedAt == nil {
+			return s.copyUser(u), nil
+		}
+	}
+
+	return user.User{}, fmt.Errorf("failed to find user (email:%s): %w", email, user.ErrNotFound)
+}
+
+// FindAll - find page of users matched by filter and total number of matched users
+func (s *storage) FindAll(ctx context.Context, filter user.Filter) ([]user.User, int64, error) {
+	s.mu.RLock()
+	matched := make([]user.User, 0, len(s.users))
+	for _, u := range s.users {
+		if u.DeletedAt == nil && filter.Match(u) {
+			matched = append(matched, s.copyUser(u))
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return filter.Less(matched[i], matched[j])
+	})
+
+	return filter.Page(matched), int64(len(matched)), nil
+}
+
+// GetUserFriends - get all friends from one user with usernames resolved by ids
+func (s *storage) GetUserFriends(ctx context.Context, id string) ([]user.Friend, error) {
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.active(id)
+	if !ok {
+		return nil, notFound(id)
+	}
+
+	friends := make([]user.Friend, 0, len(u.Friends))
+	for _, friendID := range u.Friends {
+		if friend, ok := s.active(friendID); ok {
+			friends = append(friends, user.Friend{ID: friend.ID, Username: friend.Username})
+		}
+	}
+
+	return friends, nil
+}
+
+// UpdateAge - func update age of one user, returns updated user
+func (s *storage) UpdateAge(ctx context.Context, id string, age string) (user.User, error) {
+	if err := validateID(id); err != nil {
+		return user.User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.active(id)
+	if !ok {
+		return user.User{}, notFound(id)
+	}
+	u.Age = age
+	s.users[id] = u
+
+	return s.copyUser(u), nil
+}
+
+// Update - replace username and age of one user, friends keep ids so renames need nothing else, returns updated user
+func (s *storage) Update(ctx context.Context, id string, u user.User) (user.User, error) {
+	if err := validateID(id); err != nil {
+		return user.User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.active(id)
+	if !ok {
+		return user.User{}, notFound(id)
+	}
+	stored.Username = u.Username
+	stored.Age = u.Age
+	s.users[id] = stored
+
+	return s.copyUser(stored), nil
+}
+
+// Delete - func for soft delete of user, friends of user and other users are not changed
+func (s *storage) Delete(ctx context.Context, id string, deletedAt time.Time) error {
+	if err := validateID(id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.active(id)
+	if !ok {
+		return notFound(id)
+	}
+	u.DeletedAt = &deletedAt
+	s.users[id] = u
+	s.logger.Tracef("Deleted user %s", id)
+
+	return nil
+}
+
+// Restore - func for bring soft deleted user back with his friends, returns restored user
+func (s *storage) Restore(ctx context.Context, id string) (user.User, error) {
+	if err := validateID(id); err != nil {
+		return user.User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return user.User{}, notFound(id)
+	}
+	if u.DeletedAt == nil {
+		return user.User{}, fmt.Errorf("failed to restore user (id:%s): %w", id, user.ErrNotDeleted)
+	}
+	u.DeletedAt = nil
+	s.users[id] = u
+
+	return s.copyUser(u), nil
+}
+
+// FindDeleted - find ids of users deleted at or before time
+func (s *storage) FindDeleted(ctx context.Context, before time.Time) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := []string{}
+	for id, u := range s.users {
+		if u.DeletedAt != nil && !u.DeletedAt.After(before) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// Purge - func for delete soft deleted user from memory and from friends of other users
+func (s *storage) Purge(ctx context.Context, id string) error {
+	if err := validateID(id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[id]; !ok || u.DeletedAt == nil {
+		return notFound(id)
+	}
+
+	// pull id from friends of other users
+	var modified int
+	for otherID, other := range s.users {
+		friends := removeFriend(other.Friends, id)
+		if len(friends) != len(other.Friends) {
+			other.Friends = friends
+			s.users[otherID] = other
+			modified++
+		}
+	}
+	s.logger.Tracef("Modified %d users", modified)
+
+	delete(s.users, id)
+	s.logger.Tracef("Purged user %s", id)
+
+	return nil
+}
+
+// MakeFriends - func for add users ids to friends of each other, ids already in friends are not duplicated, returns updated users
+func (s *storage) MakeFriends(ctx context.Context, firstUserID string, secondUserID string) (firstUser user.User, secondUser user.User, err error) {
+	if err = validateID(firstUserID); err != nil {
+		return firstUser, secondUser, err
+	}
+	if err = validateID(secondUserID); err != nil {
+		return firstUser, secondUser, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first, ok := s.active(firstUserID)
+	if !ok {
+		return firstUser, secondUser, notFound(firstUserID)
+	}
+	second, ok := s.active(secondUserID)
+	if !ok {
+		return firstUser, secondUser, notFound(secondUserID)
+	}
+
+	// friendship in only one direction is completed below, instead of conflict
+	if contains(first.Friends, secondUserID) && contains(second.Friends, firstUserID) {
+		return firstUser, secondUser, fmt.Errorf("failed to make friends %s and %s: %w", firstUserID, secondUserID, user.ErrAlreadyFriends)
+	}
+
+	first.Friends = addFriend(first.Friends, secondUserID)
+	s.users[firstUserID] = first
+
+	// re-read second user in case both ids are the same
+	second = s.users[secondUserID]
+	second.Friends = addFriend(second.Friends, firstUserID)
+	s.users[secondUserID] = second
+
+	return s.copyUser(s.users[firstUserID]), s.copyUser(second), nil
+}
+
+// Unfriend - func for remove users ids from friends of each other, friendship in one direction is removed too, returns updated users
+func (s *storage) Unfriend(ctx context.Context, firstUserID string, secondUserID string) (firstUser user.User, secondUser user.User, err error) {
+	if err = validateID(firstUserID); err != nil {
+		return firstUser, secondUser, err
+	}
+	if err = validateID(secondUserID); err != nil {
+		return firstUser, secondUser, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first, ok := s.active(firstUserID)
+	if !ok {
+		return firstUser, secondUser, notFound(firstUserID)
+	}
+	second, ok := s.active(secondUserID)
+	if !ok {
+		return firstUser, secondUser, notFound(secondUserID)
+	}
+
+	if !contains(first.Friends, secondUserID) && !contains(second.Friends, firstUserID) {
+		return firstUser, secondUser, fmt.Errorf("failed to unfriend %s and %s: %w", firstUserID, secondUserID, user.ErrNotFriends)
+	}
+
+	first.Friends = removeFriend(first.Friends, secondUserID)
+	s.users[firstUserID] = first
+	second.Friends = removeFriend(second.Friends, firstUserID)
+	s.users[secondUserID] = second
+
+	return s.copyUser(first), s.copyUser(second), nil
+}
+
+// active - user by id, ok is false if user not exists or is deleted
+func (s *storage) active(id string) (user.User, bool) {
+	u, ok := s.users[id]
+	return u, ok && u.DeletedAt == nil
+}
+
+// copyUser - copy user without deleted friends, so callers can't change stored users
+func (s *storage) copyUser(u user.User) user.User {
+	if u.Friends != nil {
+		friends := make([]string, 0, len(u.Friends))
+		for _, friendID := range u.Friends {
+			if _, ok := s.active(friendID); ok {
+				friends = append(friends, friendID)
+			}
+		}
+		u.Friends = friends
+	}
+	if u.DeletedAt != nil {
+		deletedAt := *u.DeletedAt
+		u.DeletedAt = &deletedAt
+	}
+	return u
+}
+
+// copyFriends - copy friends slice, so callers can't change stored users
+func copyFriends(friends []string) []string {
+	if friends == nil {
+		return nil
+	}
+	result := make([]string, len(friends))
+	copy(result, friends)
+	return result
+}
+
+// addFriend - returns copy of friends with id, if it is not there yet
+func addFriend(friends []string, id string) []string {
+	result := copyFriends(friends)
+	if contains(friends, id) {
+		return result
+	}
+	return append(result, id)
+}
+
+// contains - check that id is in friends
+func contains(friends []string, id string) bool {
+	for _, friend := range friends {
+		if friend == id {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFriend - returns friends without all entries of id
+func removeFriend(friends []string, id string) []string {
+	result := friends[:0:0]
+	for _, friend := range friends {
+		if friend != id {
+			result = append(result, friend)
+		}
+	}
+	if len(result) == len(friends) {
+		return friends
+	}
+	return result
+}
+
+// validateID - ids have the same format as MongoDB ObjectID
+func validateID(id string) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return fmt.Errorf("failed to convert user ID to ObjectID. ID=%s: %w", id, user.ErrInvalidID)
+	}
+	return nil
+}
+
+// notFound - error for user which doesn't exist
+func notFound(id string) error {
+	return fmt.Errorf("failed to find user (id:%s): %w", id, user.ErrNotFound)
+}
@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"project/internal/user"
+	"project/internal/user/storagetest"
+	"project/pkg/logging"
+	"testing"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) user.Storage {
+		return NewStorage(logging.GetLogger())
+	})
+}
+
+func TestGraph(t *testing.T) {
+	storagetest.RunGraph(t, func(t *testing.T) user.Storage {
+		return NewStorage(logging.GetLogger())
+	})
+}
+
+func TestRequests(t *testing.T) {
+	storagetest.RunRequests(t, func(t *testing.T) (user.Storage, user.RequestStorage) {
+		logger := logging.GetLogger()
+		return NewStorage(logger), NewRequestStorage(logger)
+	})
+}
+
+func TestRelations(t *testing.T) {
+	storagetest.RunRelations(t, func(t *testing.T) (user.Storage, user.RelationStorage) {
+		logger := logging.GetLogger()
+		return NewStorage(logger), NewRelationStorage(logger)
+	})
+}
+
+func TestTokens(t *testing.T) {
+	storagetest.RunTokens(t, func(t *testing.T) (user.Storage, user.TokenStorage) {
+		logger := logging.GetLogger()
+		return NewStorage(logger), NewTokenStorage(logger)
+	})
+}
+
+func TestAPIKeys(t *testing.T) {
+	storagetest.RunAPIKeys(t, func(t *testing.T) user.APIKeyStorage {
+		return NewAPIKeyStorage(logging.GetLogger())
+	})
+}
@@ -0,0 +1,104 @@
+package memory
+
+// file for keeping blocks and mutes in process memory
+
+import (
+	"context"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"sort"
+	"sync"
+)
+
+// relationKey - user can have one relation of every kind to target
+type relationKey struct {
+	userID   string
+	targetID string
+	kind     string
+}
+
+// Create relations storage structure
+type relationStorage struct {
+	mu        sync.RWMutex
+	relations map[relationKey]user.Relation
+	logger    *logging.Logger
+}
+
+// NewRelationStorage - Initialize new in-memory storage of blocks and mutes
+func NewRelationStorage(logger *logging.Logger) user.RelationStorage {
+	return &relationStorage{
+		relations: make(map[relationKey]user.Relation),
+		logger:    logger,
+	}
+}
+
+// SaveRelation - save relation if user has no relation of its kind to target yet
+func (s *relationStorage) SaveRelation(ctx context.Context, r user.Relation) (user.Relation, error) {
+	s.logger.Debug("save relation")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := relationKey{userID: r.UserID, targetID: r.TargetID, kind: r.Kind}
+	if existing, ok := s.relations[key]; ok {
+		return existing, nil
+	}
+	s.relations[key] = r
+
+	return r, nil
+}
+
+// DeleteRelation - delete relation of kind from user to target
+func (s *relationStorage) DeleteRelation(ctx context.Context, userID string, targetID string, kind string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := relationKey{userID: userID, targetID: targetID, kind: kind}
+	if _, ok := s.relations[key]; !ok {
+		return relationNotFound(userID, targetID, kind)
+	}
+	delete(s.relations, key)
+
+	return nil
+}
+
+// FindRelations - find relations matched by filter, newer relations go first
+func (s *relationStorage) FindRelations(ctx context.Context, filter user.RelationFilter) ([]user.Relation, error) {
+	s.mu.RLock()
+	matched := []user.Relation{}
+	for _, r := range s.relations {
+		if filter.Match(r) {
+			matched = append(matched, r)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return user.NewerRelation(matched[i], matched[j])
+	})
+
+	return matched, nil
+}
+
+// DeleteRelations - delete all relations from and to user
+func (s *relationStorage) DeleteRelations(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int
+	for key := range s.relations {
+		if key.userID == userID || key.targetID == userID {
+			delete(s.relations, key)
+			deleted++
+		}
+	}
+	s.logger.Tracef("Deleted %d relations", deleted)
+
+	return nil
+}
+
+// relationNotFound - error for relation which doesn't exist
+func relationNotFound(userID, targetID, kind string) error {
+	return fmt.Errorf("failed to find %s of %s by %s: %w", kind, targetID, userID, user.ErrRelationNotFound)
+}
@@ -0,0 +1,129 @@
+package memory
+
+// file for keeping friend requests in process memory
+
+import (
+	"context"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Create friend requests storage structure
+type requestStorage struct {
+	mu       sync.RWMutex
+	requests map[string]user.FriendRequest
+	logger   *logging.Logger
+}
+
+// NewRequestStorage - Initialize new in-memory storage of friend requests
+func NewRequestStorage(logger *logging.Logger) user.RequestStorage {
+	return &requestStorage{
+		requests: make(map[string]user.FriendRequest),
+		logger:   logger,
+	}
+}
+
+// CreateRequest - create new friend request, only one pending request between users can exist
+func (s *requestStorage) CreateRequest(ctx context.Context, r user.FriendRequest) (user.FriendRequest, error) {
+	s.logger.Debug("create friend request")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Status == user.RequestPending {
+		for _, other := range s.requests {
+			if other.Status == user.RequestPending && samePair(other, r) {
+				return user.FriendRequest{}, fmt.Errorf("failed to create friend request from %s to %s: %w", r.FromID, r.ToID, user.ErrRequestExists)
+			}
+		}
+	}
+
+	r.ID = primitive.NewObjectID().Hex()
+	s.requests[r.ID] = r
+
+	return r, nil
+}
+
+// FindRequest - find friend request by id
+func (s *requestStorage) FindRequest(ctx context.Context, id string) (user.FriendRequest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.requests[id]
+	if !ok {
+		return user.FriendRequest{}, requestNotFound(id)
+	}
+
+	return r, nil
+}
+
+// FindRequests - find friend requests matched by filter, newer requests go first
+func (s *requestStorage) FindRequests(ctx context.Context, filter user.RequestFilter) ([]user.FriendRequest, error) {
+	s.mu.RLock()
+	matched := []user.FriendRequest{}
+	for _, r := range s.requests {
+		if filter.Match(r) {
+			matched = append(matched, r)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return user.NewerRequest(matched[i], matched[j])
+	})
+
+	return matched, nil
+}
+
+// UpdateRequestStatus - change status of friend request, if it has status from
+func (s *requestStorage) UpdateRequestStatus(ctx context.Context, id string, from, to string, updatedAt time.Time) (user.FriendRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.requests[id]
+	if !ok {
+		return user.FriendRequest{}, requestNotFound(id)
+	}
+	if r.Status != from {
+		return r, fmt.Errorf("friend request %s has status %s, not %s: %w", id, r.Status, from, user.ErrRequestState)
+	}
+
+	r.Status = to
+	r.UpdatedAt = updatedAt
+	s.requests[id] = r
+
+	return r, nil
+}
+
+// DeleteRequests - delete all friend requests from and to user
+func (s *requestStorage) DeleteRequests(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int
+	for id, r := range s.requests {
+		if r.FromID == userID || r.ToID == userID {
+			delete(s.requests, id)
+			deleted++
+		}
+	}
+	s.logger.Tracef("Deleted %d friend requests", deleted)
+
+	return nil
+}
+
+// samePair - check that requests are between the same users in any direction
+func samePair(a, b user.FriendRequest) bool {
+	return (a.FromID == b.FromID && a.ToID == b.ToID) || (a.FromID == b.ToID && a.ToID == b.FromID)
+}
+
+// requestNotFound - error for friend request which doesn't exist
+func requestNotFound(id string) error {
+	return fmt.Errorf("failed to find friend request (id:%s): %w", id, user.ErrRequestNotFound)
+}
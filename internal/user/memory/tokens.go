@@ -0,0 +1,101 @@
+package memory
+
+// file for keeping refresh tokens in process memory
+
+import (
+	"context"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"sync"
+	"time"
+)
+
+// Create refresh tokens storage structure
+type tokenStorage struct {
+	mu     sync.RWMutex
+	tokens map[string]user.RefreshToken
+	logger *logging.Logger
+}
+
+// NewTokenStorage - Initialize new in-memory storage of refresh tokens
+func NewTokenStorage(logger *logging.Logger) user.TokenStorage {
+	return &tokenStorage{
+		tokens: make(map[string]user.RefreshToken),
+		logger: logger,
+	}
+}
+
+// SaveToken - save refresh token by its hash
+func (s *tokenStorage) SaveToken(ctx context.Context, t user.RefreshToken) error {
+	s.logger.Debug("save refresh token")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[t.Hash] = copyToken(t)
+
+	return nil
+}
+
+// FindToken - find refresh token by hash
+func (s *tokenStorage) FindToken(ctx context.Context, hash string) (user.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tokens[hash]
+	if !ok {
+		return user.RefreshToken{}, tokenNotFound(hash)
+	}
+
+	return copyToken(t), nil
+}
+
+// RevokeToken - revoke refresh token, if it is not revoked yet
+func (s *tokenStorage) RevokeToken(ctx context.Context, hash string, revokedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[hash]
+	if !ok {
+		return tokenNotFound(hash)
+	}
+	if t.RevokedAt != nil {
+		return fmt.Errorf("failed to revoke refresh token of user %s: %w", t.UserID, user.ErrTokenRevoked)
+	}
+	t.RevokedAt = &revokedAt
+	s.tokens[hash] = t
+
+	return nil
+}
+
+// RevokeFamily - revoke all refresh tokens of family which are not revoked yet
+func (s *tokenStorage) RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var revoked int
+	for hash, t := range s.tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &revokedAt
+			s.tokens[hash] = t
+			revoked++
+		}
+	}
+	s.logger.Tracef("Revoked %d refresh tokens", revoked)
+
+	return nil
+}
+
+// copyToken - copy token, so callers can't change stored tokens
+func copyToken(t user.RefreshToken) user.RefreshToken {
+	if t.RevokedAt != nil {
+		revokedAt := *t.RevokedAt
+		t.RevokedAt = &revokedAt
+	}
+	return t
+}
+
+// tokenNotFound - error for refresh token which doesn't exist, only beginning of hash is logged
+func tokenNotFound(hash string) error {
+	return fmt.Errorf("failed to find refresh token (hash:%.8s): %w", hash, user.ErrTokenNotFound)
+}
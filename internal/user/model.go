@@ -2,9 +2,28 @@ package user
 
 // file for user struct description
 
+import (
+	"time"
+)
+
+// User - user with friends, validate tags are checked before saving by project/pkg/validator
 type User struct {
 	ID       string   `json:"id" bson:"_id,omitempty"`
-	Username string   `json:"username" bson:"username"`
-	Age      string   `json:"age" bson:"age"`
-	Friends  []string `json:"friends" bson:"friends"`
+	Username string   `json:"username" bson:"username" validate:"required,min=3,max=32"`
+	Age      string   `json:"age" bson:"age" validate:"omitempty,numeric,gte=0,lte=150"`
+	Friends  []string `json:"friends" bson:"friends"` // ids of friends
+
+	// Email and PasswordHash - credentials of registered user, they are never answered in json.
+	// Email is unique among all users, deleted ones included
+	Email        string `json:"-" bson:"email,omitempty"`
+	PasswordHash string `json:"-" bson:"password_hash,omitempty"`
+
+	// DeletedAt - time of soft deletion, deleted users are hidden from all reads until they are restored or purged
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+}
+
+// Friend - friend of user resolved by id
+type Friend struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
 }
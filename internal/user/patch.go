@@ -0,0 +1,80 @@
+package user
+
+// file for JSON Merge Patch (RFC 7396) of users
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// readOnlyFields - json fields of user which can't be changed by patch
+var readOnlyFields = []string{"id", "friends", "deleted_at"}
+
+// applyMergePatch - apply merge patch to json view of user, so new fields of user are patched without new code
+func applyMergePatch(u User, patch []byte) (User, error) {
+	var patchDocument interface{}
+	if err := json.Unmarshal(patch, &patchDocument); err != nil {
+		return u, &ValidationError{Message: fmt.Sprintf("invalid json: %v", err)}
+	}
+
+	// patch which is not an object replaces whole user, that is never a valid user
+	patchObject, ok := patchDocument.(map[string]interface{})
+	if !ok {
+		return u, &ValidationError{Message: "merge patch must be a json object"}
+	}
+	for _, field := range readOnlyFields {
+		if _, ok := patchObject[field]; ok {
+			return u, &ValidationError{Field: field, Message: "can't be changed"}
+		}
+	}
+
+	// user is patched as generic json document
+	document, err := json.Marshal(u)
+	if err != nil {
+		return u, fmt.Errorf("failed to encode user. error: %v", err)
+	}
+	var target interface{}
+	if err = json.Unmarshal(document, &target); err != nil {
+		return u, fmt.Errorf("failed to decode user. error: %v", err)
+	}
+	if document, err = json.Marshal(mergePatch(target, patchObject)); err != nil {
+		return u, fmt.Errorf("failed to encode patched user. error: %v", err)
+	}
+
+	// unknown fields and wrong types are errors of client
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.DisallowUnknownFields()
+	var patched User
+	if err = decoder.Decode(&patched); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return u, &ValidationError{Field: typeErr.Field, Message: fmt.Sprintf("must be %s", typeErr.Type)}
+		}
+		return u, &ValidationError{Message: fmt.Sprintf("invalid patch: %v", err)}
+	}
+
+	return patched, nil
+}
+
+// mergePatch - MergePatch function of RFC 7396, null in patch removes member of target
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
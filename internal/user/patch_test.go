@@ -0,0 +1,46 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"project/internal/user"
+	"project/internal/user/apitest"
+	"testing"
+)
+
+// TestPatchReadOnlyFields - patch of fields which are kept by storage is rejected before user is changed
+func TestPatchReadOnlyFields(t *testing.T) {
+	ctx := context.Background()
+	s := apitest.MemoryHandler(t).UserService
+	id, err := s.Create(ctx, user.User{Username: "alice"})
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		patch string
+		field string
+	}{
+		{"id", `{"id": "other"}`, "id"},
+		{"friends", `{"friends": []}`, "friends"},
+		{"deleted_at", `{"deleted_at": "2020-01-01T00:00:00Z"}`, "deleted_at"},
+		{"null deleted_at", `{"deleted_at": null}`, "deleted_at"},
+		{"age", `{"age": "30"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Patch(ctx, id, []byte(tt.patch))
+			var validationErr *user.ValidationError
+			switch {
+			case tt.field == "" && err != nil:
+				t.Errorf("Patch(%s) error: %v", tt.patch, err)
+			case tt.field != "" && (!errors.As(err, &validationErr) || validationErr.Field != tt.field):
+				t.Errorf("Patch(%s) error = %v, want validation error of %s", tt.patch, err, tt.field)
+			}
+		})
+	}
+	if u, err := s.GetUser(ctx, id); err != nil || u.DeletedAt != nil || u.Age != "30" {
+		t.Errorf("user after patches = %+v, %v, want alice of 30 which is not deleted", u, err)
+	}
+}
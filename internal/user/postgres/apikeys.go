@@ -0,0 +1,151 @@
+package postgres
+
+// file for API keys in PostgreSQL database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// apiKeyColumns - columns of API key in order of scanAPIKey
+const apiKeyColumns = "id, name, prefix, hash, scopes, created_at, last_used_at, revoked_at"
+
+// Create API keys database structure
+type apiKeyDB struct {
+	client *sql.DB
+	logger *logging.Logger
+}
+
+// NewAPIKeyStorage - Initialize new storage of API keys, schema must be migrated with Migrate before
+func NewAPIKeyStorage(client *sql.DB, logger *logging.Logger) user.APIKeyStorage {
+	return &apiKeyDB{
+		client: client,
+		logger: logger,
+	}
+}
+
+// CreateAPIKey - create new API key
+func (d *apiKeyDB) CreateAPIKey(ctx context.Context, k user.APIKey) (user.APIKey, error) {
+	d.logger.Debug("create api key")
+
+	row := d.client.QueryRowContext(ctx,
+		"INSERT INTO api_keys (name, prefix, hash, scopes, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING "+apiKeyColumns,
+		k.Name, k.Prefix, k.Hash, pq.Array(k.Scopes), k.CreatedAt,
+	)
+	created, err := scanAPIKey(row)
+	if err != nil {
+		return user.APIKey{}, fmt.Errorf("failed to create api key due to error: %v", err)
+	}
+
+	return created, nil
+}
+
+// FindAPIKey - find API key by hash
+func (d *apiKeyDB) FindAPIKey(ctx context.Context, hash string) (user.APIKey, error) {
+	k, err := scanAPIKey(d.client.QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE hash = $1", hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return k, fmt.Errorf("failed to find api key (hash:%.8s): %w", hash, user.ErrAPIKeyNotFound)
+		}
+		return k, fmt.Errorf("failed to find api key due to error: %v", err)
+	}
+
+	return k, nil
+}
+
+// FindAPIKeys - find all API keys, newer keys go first
+func (d *apiKeyDB) FindAPIKeys(ctx context.Context) ([]user.APIKey, error) {
+	rows, err := d.client.QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY created_at DESC, id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api keys due to error: %v", err)
+	}
+	defer rows.Close()
+
+	keys := []user.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key due to error: %v", err)
+		}
+		keys = append(keys, k)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find api keys due to error: %v", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey - revoke API key, time of the first revoke is kept
+func (d *apiKeyDB) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) (user.APIKey, error) {
+	keyID, err := parseID(id)
+	if err != nil {
+		return user.APIKey{}, apiKeyNotFound(id)
+	}
+
+	k, err := scanAPIKey(d.client.QueryRowContext(ctx,
+		"UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $1) WHERE id = $2 RETURNING "+apiKeyColumns, revokedAt, keyID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return k, apiKeyNotFound(id)
+		}
+		return k, fmt.Errorf("failed to revoke api key (id:%s) due to error: %v", id, err)
+	}
+
+	return k, nil
+}
+
+// TouchAPIKey - save time of the last use of API key
+func (d *apiKeyDB) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	keyID, err := parseID(id)
+	if err != nil {
+		return apiKeyNotFound(id)
+	}
+
+	result, err := d.client.ExecContext(ctx, "UPDATE api_keys SET last_used_at = $1 WHERE id = $2", usedAt, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to execute touch api key query. error: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get updated rows. error: %v", err)
+	}
+	if affected == 0 {
+		return apiKeyNotFound(id)
+	}
+
+	return nil
+}
+
+// scanAPIKey - scan API key from columns of apiKeyColumns
+func scanAPIKey(row scanner) (k user.APIKey, err error) {
+	var id int64
+	var lastUsedAt, revokedAt sql.NullTime
+	if err = row.Scan(&id, &k.Name, &k.Prefix, &k.Hash, pq.Array(&k.Scopes), &k.CreatedAt, &lastUsedAt, &revokedAt); err != nil {
+		return k, err
+	}
+	k.ID = formatID(id)
+	k.CreatedAt = k.CreatedAt.UTC()
+	if lastUsedAt.Valid {
+		at := lastUsedAt.Time.UTC()
+		k.LastUsedAt = &at
+	}
+	if revokedAt.Valid {
+		at := revokedAt.Time.UTC()
+		k.RevokedAt = &at
+	}
+	return k, nil
+}
+
+// apiKeyNotFound - error for API key which doesn't exist
+func apiKeyNotFound(id string) error {
+	return fmt.Errorf("failed to find api key (id:%s): %w", id, user.ErrAPIKeyNotFound)
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"project/pkg/logging"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrations are applied in order of version - number before "_" in file name
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// key of advisory lock, so only one instance applies migrations at the same time
+const migrationLockID = 7351082264
+
+// migration - one versioned schema change
+type migration struct {
+	version int64
+	name    string
+	query   string
+}
+
+// Migrate - apply all migrations which are not applied to database yet
+func Migrate(ctx context.Context, client *sql.DB, logger *logging.Logger) error {
+	list, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	// use one connection, because advisory lock belongs to session
+	conn, err := client.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection for migrations. error: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to lock migrations. error: %v", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table. error: %v", err)
+	}
+
+	// get versions which are already applied
+	applied := make(map[int64]bool)
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations. error: %v", err)
+	}
+	for rows.Next() {
+		var version int64
+		if err = rows.Scan(&version); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan migration version. error: %v", err)
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to get applied migrations. error: %v", err)
+	}
+
+	for _, m := range list {
+		if applied[m.version] {
+			continue
+		}
+		logger.Infof("apply migration %s", m.name)
+		if err = applyMigration(ctx, conn, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyMigration - execute migration and save its version in one transaction
+func applyMigration(ctx context.Context, conn *sql.Conn, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for migration %s. error: %v", m.name, err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, m.query); err != nil {
+		return fmt.Errorf("failed to apply migration %s. error: %v", m.name, err)
+	}
+	if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", m.version); err != nil {
+		return fmt.Errorf("failed to save migration %s. error: %v", m.name, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %s. error: %v", m.name, err)
+	}
+	return nil
+}
+
+// loadMigrations - read embedded migrations sorted by version
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations. error: %v", err)
+	}
+
+	list := make([]migration, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		version, err := strconv.ParseInt(strings.SplitN(name, "_", 2)[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse version of migration %s. error: %v", name, err)
+		}
+
+		query, err := fs.ReadFile(migrations, "migrations/"+name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s. error: %v", name, err)
+		}
+
+		list = append(list, migration{version: version, name: name, query: string(query)})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].version < list[j].version })
+	for i := 1; i < len(list); i++ {
+		if list[i].version == list[i-1].version {
+			return nil, fmt.Errorf("duplicate migration version %d", list[i].version)
+		}
+	}
+
+	return list, nil
+}
@@ -0,0 +1,5 @@
+CREATE TABLE users (
+    id       BIGSERIAL PRIMARY KEY,
+    username TEXT NOT NULL,
+    age      TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,9 @@
+-- friendship is stored in both directions, so friends of a user are found by user_id only
+CREATE TABLE friendships (
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    friend_id  BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, friend_id)
+);
+
+CREATE INDEX friendships_friend_id_idx ON friendships (friend_id);
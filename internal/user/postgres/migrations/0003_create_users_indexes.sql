@@ -0,0 +1,5 @@
+-- "C" collation sorts usernames by bytes like other storages do and lets LIKE 'prefix%' use index
+CREATE INDEX users_username_idx ON users ((username COLLATE "C"), id);
+
+-- age is text, so it is indexed by the same expression which is used in queries
+CREATE INDEX users_age_idx ON users ((CASE WHEN age ~ '^[-+]?[0-9]{1,9}$' THEN age::int END), id);
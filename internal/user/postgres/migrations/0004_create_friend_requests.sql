@@ -0,0 +1,16 @@
+-- requests of deleted users are deleted with them
+CREATE TABLE friend_requests (
+    id         BIGSERIAL PRIMARY KEY,
+    from_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    to_id      BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    status     TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- only one pending request between two users in any direction
+CREATE UNIQUE INDEX friend_requests_pending_pair_idx ON friend_requests (LEAST(from_id, to_id), GREATEST(from_id, to_id))
+    WHERE status = 'pending';
+
+CREATE INDEX friend_requests_from_id_idx ON friend_requests (from_id, created_at);
+CREATE INDEX friend_requests_to_id_idx ON friend_requests (to_id, created_at);
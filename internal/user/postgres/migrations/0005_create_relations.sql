@@ -0,0 +1,10 @@
+-- blocks and mutes of deleted users are deleted with them
+CREATE TABLE relations (
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    target_id  BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, target_id, kind)
+);
+
+CREATE INDEX relations_target_id_idx ON relations (target_id, kind);
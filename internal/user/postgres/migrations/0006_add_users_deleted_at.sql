@@ -0,0 +1,5 @@
+-- soft deleted users are kept with friendships until they are purged
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- purger searches only deleted users
+CREATE INDEX users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
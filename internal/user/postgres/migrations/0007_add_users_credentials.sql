@@ -0,0 +1,6 @@
+-- credentials of registered users, users created without registration have none
+ALTER TABLE users ADD COLUMN email TEXT;
+ALTER TABLE users ADD COLUMN password_hash TEXT;
+
+-- email is unique among all users, deleted ones included
+CREATE UNIQUE INDEX users_email_key ON users (email) WHERE email IS NOT NULL;
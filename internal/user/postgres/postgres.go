@@ -0,0 +1,588 @@
+// Package postgres for working with PostgreSQL database
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// constraint which keeps email unique
+const emailIndex = "users_email_key"
+
+// Create database structure
+type db struct {
+	client *sql.DB
+	logger *logging.Logger
+}
+
+// NewStorage - Initialize new storage, schema must be migrated with Migrate before
+func NewStorage(client *sql.DB, logger *logging.Logger) user.Storage {
+	return &db{
+		client: client,
+		logger: logger,
+	}
+}
+
+// Create - create new user in database
+func (d *db) Create(ctx context.Context, u user.User) (string, error) {
+	d.logger.Debug("create user")
+
+	// friends are created only by MakeFriends, so they are not inserted here, users without credentials have NULL ones
+	var id int64
+	err := d.client.QueryRowContext(ctx,
+		"INSERT INTO users (username, age, email, password_hash) VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, '')) RETURNING id",
+		u.Username, u.Age, u.Email, u.PasswordHash,
+	).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Constraint == emailIndex {
+			return "", fmt.Errorf("failed to create user with email %s: %w", u.Email, user.ErrEmailExists)
+		}
+		return "", fmt.Errorf("failed to create user due to error: %v", err)
+	}
+
+	return formatID(id), nil
+}
+
+// FindOne - find user by id
+func (d *db) FindOne(ctx context.Context, id string) (user.User, error) {
+	userID, err := parseID(id)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	return d.findOne(ctx, d.client, userID)
+}
+
+// FindByEmail - find user with credentials by email
+func (d *db) FindByEmail(ctx context.Context, email string) (u user.User, err error) {
+	var id int64
+	err = d.client.QueryRowContext(ctx,
+		"SELECT id, email, password_hash FROM users WHERE email = $1 AND deleted_at IS NULL", email,
+	).Scan(&id, &u.Email, &u.PasswordHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return u, fmt.Errorf("failed to find user (email:%s): %w", email, user.ErrNotFound)
+		}
+		return u, fmt.Errorf("failed to find user (email:%s) due to error: %v", email, err)
+	}
+
+	found, err := d.findOne(ctx, d.client, id)
+	if err != nil {
+		return u, err
+	}
+	found.Email, found.PasswordHash = u.Email, u.PasswordHash
+	return found, nil
+}
+
+// FindAll - find page of users matched by filter and total number of matched users
+func (d *db) FindAll(ctx context.Context, filter user.Filter) (users []user.User, total int64, err error) {
+	where, args := listWhere(filter)
+
+	if err = d.client.QueryRowContext(ctx, "SELECT count(*) FROM users"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users due to error: %v", err)
+	}
+
+	// without limit postgres returns all rows
+	var limit interface{}
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+	args = append(args, limit, filter.Offset)
+	query := fmt.Sprintf("SELECT id, username, age FROM users%s ORDER BY %s LIMIT $%d OFFSET $%d",
+		where, listOrder(filter), len(args)-1, len(args))
+
+	rows, err := d.client.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find users due to error: %v", err)
+	}
+	defer rows.Close()
+
+	users = []user.User{}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var u user.User
+		if err = rows.Scan(&id, &u.Username, &u.Age); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user due to error: %v", err)
+		}
+		u.ID = formatID(id)
+		users = append(users, u)
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to find users due to error: %v", err)
+	}
+
+	// friends of all users of page are found by one query
+	friends, err := d.friendsOf(ctx, ids)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find friends of users due to error: %v", err)
+	}
+	for i := range users {
+		users[i].Friends = friends[ids[i]]
+	}
+
+	return users, total, nil
+}
+
+// GetUserFriends - get all friends from one user with usernames resolved by ids
+func (d *db) GetUserFriends(ctx context.Context, id string) ([]user.Friend, error) {
+	userID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// user without friends and unknown user both have no rows
+	var exists bool
+	if err = d.client.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)", userID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to find user (id:%d) due to error: %v", userID, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("failed to find user (id:%d): %w", userID, user.ErrNotFound)
+	}
+
+	rows, err := d.client.QueryContext(ctx, `
+		SELECT u.id, u.username
+		FROM friendships f
+		JOIN users u ON u.id = f.friend_id
+		WHERE f.user_id = $1 AND u.deleted_at IS NULL
+		ORDER BY f.created_at, f.friend_id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find friends of user (id:%d) due to error: %v", userID, err)
+	}
+	defer rows.Close()
+
+	friends := []user.Friend{}
+	for rows.Next() {
+		var friendID int64
+		var friend user.Friend
+		if err = rows.Scan(&friendID, &friend.Username); err != nil {
+			return nil, fmt.Errorf("failed to scan friend due to error: %v", err)
+		}
+		friend.ID = formatID(friendID)
+		friends = append(friends, friend)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find friends of user (id:%d) due to error: %v", userID, err)
+	}
+
+	return friends, nil
+}
+
+// UpdateAge - func update age of one user, returns updated user
+func (d *db) UpdateAge(ctx context.Context, id string, age string) (u user.User, err error) {
+	userID, err := parseID(id)
+	if err != nil {
+		return u, err
+	}
+
+	result, err := d.client.ExecContext(ctx, "UPDATE users SET age = $1 WHERE id = $2 AND deleted_at IS NULL", age, userID)
+	if err != nil {
+		return u, fmt.Errorf("failed to execute update user query. error: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return u, fmt.Errorf("failed to get updated rows. error: %v", err)
+	}
+	if affected == 0 {
+		return u, fmt.Errorf("failed to find user (id:%s): %w", id, user.ErrNotFound)
+	}
+	d.logger.Tracef("Updated %d rows", affected)
+
+	return d.findOne(ctx, d.client, userID)
+}
+
+// Update - replace username and age of one user, friends keep ids so renames need nothing else, returns updated user
+func (d *db) Update(ctx context.Context, id string, u user.User) (updated user.User, err error) {
+	userID, err := parseID(id)
+	if err != nil {
+		return updated, err
+	}
+
+	result, err := d.client.ExecContext(ctx, "UPDATE users SET username = $1, age = $2 WHERE id = $3 AND deleted_at IS NULL", u.Username, u.Age, userID)
+	if err != nil {
+		return updated, fmt.Errorf("failed to execute update user query. error: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return updated, fmt.Errorf("failed to get updated rows. error: %v", err)
+	}
+	if affected == 0 {
+		return updated, fmt.Errorf("failed to find user (id:%s): %w", id, user.ErrNotFound)
+	}
+	d.logger.Tracef("Updated %d rows", affected)
+
+	return d.findOne(ctx, d.client, userID)
+}
+
+// Delete - func for soft delete of user, friendships are kept for Restore
+func (d *db) Delete(ctx context.Context, id string, deletedAt time.Time) error {
+	userID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := d.client.ExecContext(ctx, "UPDATE users SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL", deletedAt, userID)
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get deleted rows. error: %v", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("failed to find user (id:%s): %w", id, user.ErrNotFound)
+	}
+	d.logger.Tracef("Deleted %d rows", affected)
+
+	return nil
+}
+
+// Restore - func for bring soft deleted user back with his friendships, returns restored user
+func (d *db) Restore(ctx context.Context, id string) (user.User, error) {
+	userID, err := parseID(id)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	result, err := d.client.ExecContext(ctx, "UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL", userID)
+	if err != nil {
+		return user.User{}, fmt.Errorf("failed to execute restore user query. error: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return user.User{}, fmt.Errorf("failed to get restored rows. error: %v", err)
+	}
+
+	// nothing is restored for unknown user and for user who is not deleted
+	if affected == 0 {
+		var exists bool
+		if err = d.client.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists); err != nil {
+			return user.User{}, fmt.Errorf("failed to find user (id:%d) due to error: %v", userID, err)
+		}
+		if !exists {
+			return user.User{}, fmt.Errorf("failed to find user (id:%s): %w", id, user.ErrNotFound)
+		}
+		return user.User{}, fmt.Errorf("failed to restore user (id:%s): %w", id, user.ErrNotDeleted)
+	}
+
+	return d.findOne(ctx, d.client, userID)
+}
+
+// FindDeleted - find ids of users deleted at or before time
+func (d *db) FindDeleted(ctx context.Context, before time.Time) ([]string, error) {
+	rows, err := d.client.QueryContext(ctx, "SELECT id FROM users WHERE deleted_at <= $1 ORDER BY deleted_at, id", before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find deleted users due to error: %v", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan deleted user due to error: %v", err)
+		}
+		ids = append(ids, formatID(id))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find deleted users due to error: %v", err)
+	}
+
+	return ids, nil
+}
+
+// Purge - func for delete soft deleted user from database, friendships are deleted by foreign key cascade
+func (d *db) Purge(ctx context.Context, id string) error {
+	userID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := d.client.ExecContext(ctx, "DELETE FROM users WHERE id = $1 AND deleted_at IS NOT NULL", userID)
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get purged rows. error: %v", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("failed to find deleted user (id:%s): %w", id, user.ErrNotFound)
+	}
+	d.logger.Tracef("Purged %d rows", affected)
+
+	return nil
+}
+
+// MakeFriends - func for creating friendship between two users in both directions, friendship in only one direction is completed, returns updated users
+func (d *db) MakeFriends(ctx context.Context, firstUserID string, secondUserID string) (firstUser user.User, secondUser user.User, err error) {
+	firstID, err := parseID(firstUserID)
+	if err != nil {
+		return firstUser, secondUser, err
+	}
+	secondID, err := parseID(secondUserID)
+	if err != nil {
+		return firstUser, secondUser, err
+	}
+
+	tx, err := d.client.BeginTx(ctx, nil)
+	if err != nil {
+		return firstUser, secondUser, fmt.Errorf("failed to begin transaction. error: %v", err)
+	}
+	defer tx.Rollback()
+
+	if firstUser, err = d.findOne(ctx, tx, firstID); err != nil {
+		return firstUser, secondUser, err
+	}
+	if secondUser, err = d.findOne(ctx, tx, secondID); err != nil {
+		return firstUser, secondUser, err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO friendships (user_id, friend_id) VALUES ($1, $2), ($2, $1) ON CONFLICT DO NOTHING",
+		firstID, secondID,
+	)
+	if err != nil {
+		return firstUser, secondUser, fmt.Errorf("failed to add friend to friends. error: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return firstUser, secondUser, fmt.Errorf("failed to get inserted rows. error: %v", err)
+	}
+	if affected == 0 {
+		return firstUser, secondUser, fmt.Errorf("failed to make friends %s and %s: %w", firstUserID, secondUserID, user.ErrAlreadyFriends)
+	}
+	d.logger.Tracef("Inserted %d friendships", affected)
+
+	// read users again to return them with new friends
+	if firstUser, err = d.findOne(ctx, tx, firstID); err != nil {
+		return firstUser, secondUser, err
+	}
+	if secondUser, err = d.findOne(ctx, tx, secondID); err != nil {
+		return firstUser, secondUser, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return firstUser, secondUser, fmt.Errorf("failed to commit friendship. error: %v", err)
+	}
+
+	return firstUser, secondUser, nil
+}
+
+// Unfriend - func for deleting friendship between two users in both directions, friendship in one direction is deleted too, returns updated users
+func (d *db) Unfriend(ctx context.Context, firstUserID string, secondUserID string) (firstUser user.User, secondUser user.User, err error) {
+	firstID, err := parseID(firstUserID)
+	if err != nil {
+		return firstUser, secondUser, err
+	}
+	secondID, err := parseID(secondUserID)
+	if err != nil {
+		return firstUser, secondUser, err
+	}
+
+	tx, err := d.client.BeginTx(ctx, nil)
+	if err != nil {
+		return firstUser, secondUser, fmt.Errorf("failed to begin transaction. error: %v", err)
+	}
+	defer tx.Rollback()
+
+	// users are found first, so unknown user is not answered as not friends
+	if _, err = d.findOne(ctx, tx, firstID); err != nil {
+		return firstUser, secondUser, err
+	}
+	if _, err = d.findOne(ctx, tx, secondID); err != nil {
+		return firstUser, secondUser, err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"DELETE FROM friendships WHERE (user_id = $1 AND friend_id = $2) OR (user_id = $2 AND friend_id = $1)",
+		firstID, secondID,
+	)
+	if err != nil {
+		return firstUser, secondUser, fmt.Errorf("failed to remove friend from friends. error: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return firstUser, secondUser, fmt.Errorf("failed to get deleted rows. error: %v", err)
+	}
+	if affected == 0 {
+		return firstUser, secondUser, fmt.Errorf("failed to unfriend %s and %s: %w", firstUserID, secondUserID, user.ErrNotFriends)
+	}
+	d.logger.Tracef("Deleted %d friendships", affected)
+
+	// read users again to return them without friendship
+	if firstUser, err = d.findOne(ctx, tx, firstID); err != nil {
+		return firstUser, secondUser, err
+	}
+	if secondUser, err = d.findOne(ctx, tx, secondID); err != nil {
+		return firstUser, secondUser, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return firstUser, secondUser, fmt.Errorf("failed to commit unfriend. error: %v", err)
+	}
+
+	return firstUser, secondUser, nil
+}
+
+// queryer - common methods of sql.DB and sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// findOne - find user with friends by id
+func (d *db) findOne(ctx context.Context, q queryer, id int64) (u user.User, err error) {
+	err = q.QueryRowContext(ctx, "SELECT id, username, age FROM users WHERE id = $1 AND deleted_at IS NULL", id).Scan(&id, &u.Username, &u.Age)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return u, fmt.Errorf("failed to find user (id:%d): %w", id, user.ErrNotFound)
+		}
+		return u, fmt.Errorf("failed to find user (id:%d) due to error: %v", id, err)
+	}
+	u.ID = formatID(id)
+
+	if u.Friends, err = d.friends(ctx, q, id); err != nil {
+		return u, fmt.Errorf("failed to find friends of user (id:%d) due to error: %v", id, err)
+	}
+
+	return u, nil
+}
+
+// friends - get ids of user friends in order of making friends, deleted friends are skipped
+func (d *db) friends(ctx context.Context, q queryer, id int64) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT f.friend_id
+		FROM friendships f
+		JOIN users u ON u.id = f.friend_id
+		WHERE f.user_id = $1 AND u.deleted_at IS NULL
+		ORDER BY f.created_at, f.friend_id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var friends []string
+	for rows.Next() {
+		var friendID int64
+		if err = rows.Scan(&friendID); err != nil {
+			return nil, err
+		}
+		friends = append(friends, formatID(friendID))
+	}
+
+	return friends, rows.Err()
+}
+
+// friendsOf - get ids of friends of many users, deleted friends are skipped
+func (d *db) friendsOf(ctx context.Context, ids []int64) (map[int64][]string, error) {
+	friends := make(map[int64][]string, len(ids))
+	if len(ids) == 0 {
+		return friends, nil
+	}
+
+	rows, err := d.client.QueryContext(ctx, `
+		SELECT f.user_id, f.friend_id
+		FROM friendships f
+		JOIN users u ON u.id = f.friend_id
+		WHERE f.user_id = ANY($1) AND u.deleted_at IS NULL
+		ORDER BY f.created_at, f.friend_id`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, friendID int64
+		if err = rows.Scan(&id, &friendID); err != nil {
+			return nil, err
+		}
+		friends[id] = append(friends[id], formatID(friendID))
+	}
+
+	return friends, rows.Err()
+}
+
+// ageNumber - age converted to int, null for not numeric age, the same expression is indexed
+const ageNumber = `(CASE WHEN age ~ '^[-+]?[0-9]{1,9}$' THEN age::int END)`
+
+// listWhere - where clause and its arguments for users matched by filter, deleted users are never matched
+func listWhere(filter user.Filter) (string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+
+	// ids of wrong format can't be in table
+	if filter.IDs != nil {
+		ids := make([]int64, 0, len(filter.IDs))
+		for _, id := range filter.IDs {
+			if userID, err := parseID(id); err == nil {
+				ids = append(ids, userID)
+			}
+		}
+		args = append(args, pq.Array(ids))
+		conditions = append(conditions, fmt.Sprintf("id = ANY($%d)", len(args)))
+	}
+	if filter.UsernamePrefix != "" {
+		args = append(args, likeEscaper.Replace(filter.UsernamePrefix)+"%")
+		conditions = append(conditions, fmt.Sprintf(`(username COLLATE "C") LIKE $%d`, len(args)))
+	}
+	if filter.MinAge != nil {
+		args = append(args, *filter.MinAge)
+		conditions = append(conditions, fmt.Sprintf("%s >= $%d", ageNumber, len(args)))
+	}
+	if filter.MaxAge != nil {
+		args = append(args, *filter.MaxAge)
+		conditions = append(conditions, fmt.Sprintf("%s <= $%d", ageNumber, len(args)))
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// listOrder - order by clause for sorting of filter, not numeric ages go first like in MongoDB
+func listOrder(filter user.Filter) string {
+	direction, nulls := "ASC", "NULLS FIRST"
+	if filter.Desc {
+		direction, nulls = "DESC", "NULLS LAST"
+	}
+
+	switch filter.SortBy {
+	case user.SortByUsername:
+		return fmt.Sprintf(`(username COLLATE "C") %s, id %s`, direction, direction)
+	case user.SortByAge:
+		return fmt.Sprintf("%s %s %s, id %s", ageNumber, direction, nulls, direction)
+	default:
+		return "id " + direction
+	}
+}
+
+// likeEscaper - escape special characters of LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// parseID - convert user ID from API to database id
+func parseID(id string) (int64, error) {
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || userID <= 0 {
+		return 0, fmt.Errorf("failed to convert user ID to database id. ID=%s: %w", id, user.ErrInvalidID)
+	}
+	return userID, nil
+}
+
+// formatID - convert database id to user ID for API
+func formatID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"project/internal/user"
+	"project/internal/user/storagetest"
+	"project/pkg/logging"
+	"testing"
+
+	// register postgres driver for database/sql
+	_ "github.com/lib/pq"
+)
+
+// dsnEnv - environment variable with DSN of database for tests, tests are skipped without it.
+// Tables of database are truncated before every test
+const dsnEnv = "POSTGRES_TEST_DSN"
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) user.Storage {
+		return NewStorage(openDB(t), logging.GetLogger())
+	})
+}
+
+func TestGraph(t *testing.T) {
+	storagetest.RunGraph(t, func(t *testing.T) user.Storage {
+		return NewStorage(openDB(t), logging.GetLogger())
+	})
+}
+
+func TestRequests(t *testing.T) {
+	storagetest.RunRequests(t, func(t *testing.T) (user.Storage, user.RequestStorage) {
+		client := openDB(t)
+		return NewStorage(client, logging.GetLogger()), NewRequestStorage(client, logging.GetLogger())
+	})
+}
+
+func TestRelations(t *testing.T) {
+	storagetest.RunRelations(t, func(t *testing.T) (user.Storage, user.RelationStorage) {
+		client := openDB(t)
+		return NewStorage(client, logging.GetLogger()), NewRelationStorage(client, logging.GetLogger())
+	})
+}
+
+func TestTokens(t *testing.T) {
+	storagetest.RunTokens(t, func(t *testing.T) (user.Storage, user.TokenStorage) {
+		client := openDB(t)
+		return NewStorage(client, logging.GetLogger()), NewTokenStorage(client, logging.GetLogger())
+	})
+}
+
+func TestAPIKeys(t *testing.T) {
+	storagetest.RunAPIKeys(t, func(t *testing.T) user.APIKeyStorage {
+		return NewAPIKeyStorage(openDB(t), logging.GetLogger())
+	})
+}
+
+// openDB - connect to database of dsnEnv, migrate it and remove all rows, connection is closed after test
+func openDB(t *testing.T) *sql.DB {
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", dsnEnv)
+	}
+
+	ctx := context.Background()
+	client, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if err = Migrate(ctx, client, logging.GetLogger()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.ExecContext(ctx, `TRUNCATE users, friendships, friend_requests, relations, refresh_tokens, api_keys RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
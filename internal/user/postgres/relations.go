@@ -0,0 +1,168 @@
+package postgres
+
+// file for blocks and mutes in PostgreSQL database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"strings"
+)
+
+// columns of relation in order of scanRelation
+const relationColumns = "user_id, target_id, kind, created_at"
+
+// Create relations database structure
+type relationDB struct {
+	client *sql.DB
+	logger *logging.Logger
+}
+
+// NewRelationStorage - Initialize new storage of blocks and mutes, schema must be migrated with Migrate before
+func NewRelationStorage(client *sql.DB, logger *logging.Logger) user.RelationStorage {
+	return &relationDB{
+		client: client,
+		logger: logger,
+	}
+}
+
+// SaveRelation - save relation, primary key keeps one relation of every kind from user to target
+func (d *relationDB) SaveRelation(ctx context.Context, r user.Relation) (user.Relation, error) {
+	d.logger.Debug("save relation")
+
+	userID, err := parseID(r.UserID)
+	if err != nil {
+		return user.Relation{}, err
+	}
+	targetID, err := parseID(r.TargetID)
+	if err != nil {
+		return user.Relation{}, err
+	}
+
+	// existing relation is updated by no-op, so RETURNING gives it back
+	saved, err := scanRelation(d.client.QueryRowContext(ctx,
+		"INSERT INTO relations (user_id, target_id, kind, created_at) VALUES ($1, $2, $3, $4) "+
+			"ON CONFLICT (user_id, target_id, kind) DO UPDATE SET kind = EXCLUDED.kind RETURNING "+relationColumns,
+		userID, targetID, r.Kind, r.CreatedAt,
+	))
+	if err != nil {
+		return user.Relation{}, fmt.Errorf("failed to save relation due to error: %v", err)
+	}
+
+	return saved, nil
+}
+
+// DeleteRelation - delete relation of kind from user to target
+func (d *relationDB) DeleteRelation(ctx context.Context, userID string, targetID string, kind string) error {
+	id, err := parseID(userID)
+	if err != nil {
+		return err
+	}
+	target, err := parseID(targetID)
+	if err != nil {
+		return err
+	}
+
+	result, err := d.client.ExecContext(ctx, "DELETE FROM relations WHERE user_id = $1 AND target_id = $2 AND kind = $3", id, target, kind)
+	if err != nil {
+		return fmt.Errorf("failed to delete relation. error: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get deleted rows. error: %v", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("failed to find %s of %s by %s: %w", kind, targetID, userID, user.ErrRelationNotFound)
+	}
+
+	return nil
+}
+
+// FindRelations - find relations matched by filter, newer relations go first
+func (d *relationDB) FindRelations(ctx context.Context, filter user.RelationFilter) ([]user.Relation, error) {
+	var conditions []string
+	var args []interface{}
+	for _, field := range []struct {
+		column string
+		value  string
+		id     bool
+	}{
+		{"user_id", filter.UserID, true},
+		{"target_id", filter.TargetID, true},
+		{"kind", filter.Kind, false},
+	} {
+		if field.value == "" {
+			continue
+		}
+		var value interface{} = field.value
+		if field.id {
+			id, err := parseID(field.value)
+			if err != nil {
+				return nil, err
+			}
+			value = id
+		}
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", field.column, len(args)))
+	}
+
+	query := "SELECT " + relationColumns + " FROM relations"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, target_id DESC, user_id DESC"
+
+	rows, err := d.client.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find relations due to error: %v", err)
+	}
+	defer rows.Close()
+
+	relations := []user.Relation{}
+	for rows.Next() {
+		r, err := scanRelation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan relation due to error: %v", err)
+		}
+		relations = append(relations, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find relations due to error: %v", err)
+	}
+
+	return relations, nil
+}
+
+// DeleteRelations - delete all relations from and to user, deleted users lose them by foreign key cascade too
+func (d *relationDB) DeleteRelations(ctx context.Context, userID string) error {
+	id, err := parseID(userID)
+	if err != nil {
+		return err
+	}
+
+	result, err := d.client.ExecContext(ctx, "DELETE FROM relations WHERE user_id = $1 OR target_id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete relations. error: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get deleted rows. error: %v", err)
+	}
+	d.logger.Tracef("Deleted %d relations", affected)
+
+	return nil
+}
+
+// scanRelation - scan relation from columns of relationColumns
+func scanRelation(row scanner) (r user.Relation, err error) {
+	var userID, targetID int64
+	if err = row.Scan(&userID, &targetID, &r.Kind, &r.CreatedAt); err != nil {
+		return r, err
+	}
+	r.UserID = formatID(userID)
+	r.TargetID = formatID(targetID)
+	r.CreatedAt = r.CreatedAt.UTC()
+	return r, nil
+}
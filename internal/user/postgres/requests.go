@@ -0,0 +1,207 @@
+package postgres
+
+// file for friend requests in PostgreSQL database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// constraint which keeps one pending request between users
+const pendingPairIndex = "friend_requests_pending_pair_idx"
+
+// columns of friend request in order of scanRequest
+const requestColumns = "id, from_id, to_id, status, created_at, updated_at"
+
+// Create friend requests database structure
+type requestDB struct {
+	client *sql.DB
+	logger *logging.Logger
+}
+
+// NewRequestStorage - Initialize new storage of friend requests, schema must be migrated with Migrate before
+func NewRequestStorage(client *sql.DB, logger *logging.Logger) user.RequestStorage {
+	return &requestDB{
+		client: client,
+		logger: logger,
+	}
+}
+
+// CreateRequest - create new friend request, unique index keeps one pending request between users
+func (d *requestDB) CreateRequest(ctx context.Context, r user.FriendRequest) (user.FriendRequest, error) {
+	d.logger.Debug("create friend request")
+
+	fromID, err := parseID(r.FromID)
+	if err != nil {
+		return user.FriendRequest{}, err
+	}
+	toID, err := parseID(r.ToID)
+	if err != nil {
+		return user.FriendRequest{}, err
+	}
+
+	row := d.client.QueryRowContext(ctx,
+		"INSERT INTO friend_requests (from_id, to_id, status, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING "+requestColumns,
+		fromID, toID, r.Status, r.CreatedAt, r.UpdatedAt,
+	)
+	created, err := scanRequest(row)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Constraint == pendingPairIndex {
+			return user.FriendRequest{}, fmt.Errorf("failed to create friend request from %s to %s: %w", r.FromID, r.ToID, user.ErrRequestExists)
+		}
+		return user.FriendRequest{}, fmt.Errorf("failed to create friend request due to error: %v", err)
+	}
+
+	return created, nil
+}
+
+// FindRequest - find friend request by id
+func (d *requestDB) FindRequest(ctx context.Context, id string) (user.FriendRequest, error) {
+	requestID, err := parseID(id)
+	if err != nil {
+		return user.FriendRequest{}, requestNotFound(id)
+	}
+
+	r, err := scanRequest(d.client.QueryRowContext(ctx, "SELECT "+requestColumns+" FROM friend_requests WHERE id = $1", requestID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return r, requestNotFound(id)
+		}
+		return r, fmt.Errorf("failed to find friend request (id:%s) due to error: %v", id, err)
+	}
+
+	return r, nil
+}
+
+// FindRequests - find friend requests matched by filter, newer requests go first
+func (d *requestDB) FindRequests(ctx context.Context, filter user.RequestFilter) ([]user.FriendRequest, error) {
+	var conditions []string
+	var args []interface{}
+	for _, field := range []struct {
+		column string
+		value  string
+		id     bool
+	}{
+		{"from_id", filter.FromID, true},
+		{"to_id", filter.ToID, true},
+		{"status", filter.Status, false},
+	} {
+		if field.value == "" {
+			continue
+		}
+		var value interface{} = field.value
+		if field.id {
+			id, err := parseID(field.value)
+			if err != nil {
+				return nil, err
+			}
+			value = id
+		}
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", field.column, len(args)))
+	}
+
+	query := "SELECT " + requestColumns + " FROM friend_requests"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := d.client.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find friend requests due to error: %v", err)
+	}
+	defer rows.Close()
+
+	requests := []user.FriendRequest{}
+	for rows.Next() {
+		r, err := scanRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan friend request due to error: %v", err)
+		}
+		requests = append(requests, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find friend requests due to error: %v", err)
+	}
+
+	return requests, nil
+}
+
+// UpdateRequestStatus - change status of friend request, if it has status from
+func (d *requestDB) UpdateRequestStatus(ctx context.Context, id string, from, to string, updatedAt time.Time) (user.FriendRequest, error) {
+	requestID, err := parseID(id)
+	if err != nil {
+		return user.FriendRequest{}, requestNotFound(id)
+	}
+
+	r, err := scanRequest(d.client.QueryRowContext(ctx,
+		"UPDATE friend_requests SET status = $1, updated_at = $2 WHERE id = $3 AND status = $4 RETURNING "+requestColumns,
+		to, updatedAt, requestID, from,
+	))
+	if err == nil {
+		return r, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return r, fmt.Errorf("failed to execute update friend request query. error: %v", err)
+	}
+
+	// no rows are updated for unknown request and for request with other status
+	if r, err = d.FindRequest(ctx, id); err != nil {
+		return r, err
+	}
+	return r, fmt.Errorf("friend request %s has status %s, not %s: %w", id, r.Status, from, user.ErrRequestState)
+}
+
+// DeleteRequests - delete all friend requests from and to user, deleted users lose them by foreign key cascade too
+func (d *requestDB) DeleteRequests(ctx context.Context, userID string) error {
+	id, err := parseID(userID)
+	if err != nil {
+		return err
+	}
+
+	result, err := d.client.ExecContext(ctx, "DELETE FROM friend_requests WHERE from_id = $1 OR to_id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete friend requests. error: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get deleted rows. error: %v", err)
+	}
+	d.logger.Tracef("Deleted %d friend requests", affected)
+
+	return nil
+}
+
+// scanner - common method of sql.Row and sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRequest - scan friend request from columns of requestColumns
+func scanRequest(row scanner) (r user.FriendRequest, err error) {
+	var id, fromID, toID int64
+	if err = row.Scan(&id, &fromID, &toID, &r.Status, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return r, err
+	}
+	r.ID = formatID(id)
+	r.FromID = formatID(fromID)
+	r.ToID = formatID(toID)
+	r.CreatedAt = r.CreatedAt.UTC()
+	r.UpdatedAt = r.UpdatedAt.UTC()
+	return r, nil
+}
+
+// requestNotFound - error for friend request which doesn't exist
+func requestNotFound(id string) error {
+	return fmt.Errorf("failed to find friend request (id:%s): %w", id, user.ErrRequestNotFound)
+}
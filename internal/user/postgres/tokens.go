@@ -0,0 +1,109 @@
+package postgres
+
+// file for refresh tokens in PostgreSQL database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"project/internal/user"
+	"project/pkg/logging"
+	"time"
+)
+
+// Create refresh tokens database structure
+type tokenDB struct {
+	client *sql.DB
+	logger *logging.Logger
+}
+
+// NewTokenStorage - Initialize new storage of refresh tokens, schema must be migrated with Migrate before
+func NewTokenStorage(client *sql.DB, logger *logging.Logger) user.TokenStorage {
+	return &tokenDB{
+		client: client,
+		logger: logger,
+	}
+}
+
+// SaveToken - save refresh token by its hash
+func (d *tokenDB) SaveToken(ctx context.Context, t user.RefreshToken) error {
+	d.logger.Debug("save refresh token")
+
+	userID, err := parseID(t.UserID)
+	if err != nil {
+		return err
+	}
+	_, err = d.client.ExecContext(ctx,
+		"INSERT INTO refresh_tokens (hash, user_id, family_id, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)",
+		t.Hash, userID, t.FamilyID, t.CreatedAt, t.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save refresh token due to error: %v", err)
+	}
+
+	return nil
+}
+
+// FindToken - find refresh token by hash
+func (d *tokenDB) FindToken(ctx context.Context, hash string) (t user.RefreshToken, err error) {
+	var userID int64
+	var revokedAt sql.NullTime
+	err = d.client.QueryRowContext(ctx,
+		"SELECT hash, user_id, family_id, created_at, expires_at, revoked_at FROM refresh_tokens WHERE hash = $1", hash,
+	).Scan(&t.Hash, &userID, &t.FamilyID, &t.CreatedAt, &t.ExpiresAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return t, tokenNotFound(hash)
+		}
+		return t, fmt.Errorf("failed to find refresh token due to error: %v", err)
+	}
+	t.UserID = formatID(userID)
+	t.CreatedAt, t.ExpiresAt = t.CreatedAt.UTC(), t.ExpiresAt.UTC()
+	if revokedAt.Valid {
+		at := revokedAt.Time.UTC()
+		t.RevokedAt = &at
+	}
+
+	return t, nil
+}
+
+// RevokeToken - revoke refresh token, if it is not revoked yet
+func (d *tokenDB) RevokeToken(ctx context.Context, hash string, revokedAt time.Time) error {
+	result, err := d.client.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = $1 WHERE hash = $2 AND revoked_at IS NULL", revokedAt, hash)
+	if err != nil {
+		return fmt.Errorf("failed to execute revoke refresh token query. error: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get updated rows. error: %v", err)
+	}
+	if affected == 0 {
+		// nothing is updated for unknown token and for revoked token
+		t, err := d.FindToken(ctx, hash)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("failed to revoke refresh token of user %s: %w", t.UserID, user.ErrTokenRevoked)
+	}
+
+	return nil
+}
+
+// RevokeFamily - revoke all refresh tokens of family which are not revoked yet
+func (d *tokenDB) RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	result, err := d.client.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL", revokedAt, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens. error: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil {
+		d.logger.Tracef("Revoked %d refresh tokens", affected)
+	}
+
+	return nil
+}
+
+// tokenNotFound - error for refresh token which doesn't exist, only beginning of hash is logged
+func tokenNotFound(hash string) error {
+	return fmt.Errorf("failed to find refresh token (hash:%.8s): %w", hash, user.ErrTokenNotFound)
+}
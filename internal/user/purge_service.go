@@ -0,0 +1,69 @@
+package user
+
+// file for restore and purge of soft deleted users in user-service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Restore - func for bringing soft deleted user back with his friendships, returns restored user
+func (s service) Restore(ctx context.Context, userID string) (User, error) {
+	user, err := s.storage.Restore(ctx, userID)
+	if err != nil {
+		return user, fmt.Errorf("failed to restore user. error: %w", err)
+	}
+	return user, nil
+}
+
+// PurgeDeleted - func for deleting for good users deleted at or before time with friend requests, blocks and mutes from and to them
+func (s service) PurgeDeleted(ctx context.Context, before time.Time) (purged int, err error) {
+	ids, err := s.storage.FindDeleted(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find deleted users. error: %w", err)
+	}
+
+	for _, id := range ids {
+		// user restored after he was found is not purged
+		if err = s.storage.Purge(ctx, id); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return purged, fmt.Errorf("failed to purge user %s. error: %w", id, err)
+		}
+		if err = s.requests.DeleteRequests(ctx, id); err != nil {
+			return purged, fmt.Errorf("failed to delete friend requests of user %s. error: %w", id, err)
+		}
+		if err = s.relations.DeleteRelations(ctx, id); err != nil {
+			return purged, fmt.Errorf("failed to delete relations of user %s. error: %w", id, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// RunPurger - func for purging users deleted longer than retention ago every interval, returns when ctx is done
+func (s service) RunPurger(ctx context.Context, interval time.Duration, retention time.Duration) {
+	s.logger.Infof("purge users deleted %s ago every %s", retention, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// errors are only logged, the next run purges the rest
+		purged, err := s.PurgeDeleted(ctx, time.Now().Add(-retention))
+		if err != nil {
+			s.logger.Errorf("failed to purge deleted users. error: %v", err)
+		}
+		if purged > 0 {
+			s.logger.Infof("purged %d deleted users", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
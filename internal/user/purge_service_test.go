@@ -0,0 +1,93 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"project/internal/user"
+	"project/internal/user/apitest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestDeleteRestore - deleted user is hidden with his friendships until he is restored, only deleted user can be restored
+func TestDeleteRestore(t *testing.T) {
+	ctx := context.Background()
+	s := apitest.MemoryHandler(t).UserService
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	befriend(t, s, alice, bob)
+
+	if err := s.Delete(ctx, bob); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, err := s.GetUser(ctx, bob); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("GetUser of deleted user error = %v, want %v", err, user.ErrNotFound)
+	}
+	if err := s.Delete(ctx, bob); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("second Delete error = %v, want %v", err, user.ErrNotFound)
+	}
+	if friends, err := s.GetUserFriends(ctx, alice); err != nil || len(friends) != 0 {
+		t.Errorf("friends of alice = %+v, %v, want deleted bob hidden", friends, err)
+	}
+
+	restored, err := s.Restore(ctx, bob)
+	if err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	if restored.DeletedAt != nil || !reflect.DeepEqual(restored.Friends, []string{alice}) {
+		t.Errorf("restored user = %+v, want alice in friends without deleted_at", restored)
+	}
+	if friends, err := s.GetUserFriends(ctx, alice); err != nil || !sameFriends(friends, []string{bob}) {
+		t.Errorf("friends of alice after Restore = %+v, %v, want bob", friends, err)
+	}
+	if _, err = s.Restore(ctx, bob); !errors.Is(err, user.ErrNotDeleted) {
+		t.Errorf("second Restore error = %v, want %v", err, user.ErrNotDeleted)
+	}
+	if _, err = s.Restore(ctx, alice); !errors.Is(err, user.ErrNotDeleted) {
+		t.Errorf("Restore of active user error = %v, want %v", err, user.ErrNotDeleted)
+	}
+}
+
+// TestPurgeDeleted - only users deleted at or before time are purged, with their friendships, requests and relations
+func TestPurgeDeleted(t *testing.T) {
+	ctx := context.Background()
+	s := apitest.MemoryHandler(t).UserService
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	carol := createUser(t, s, "carol")
+	befriend(t, s, alice, bob)
+	if _, err := s.SendFriendRequest(ctx, bob, carol); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Mute(ctx, carol, bob); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now().Add(-time.Second)
+	if err := s.Delete(ctx, bob); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if purged, err := s.PurgeDeleted(ctx, before); err != nil || purged != 0 {
+		t.Errorf("PurgeDeleted before delete = %d, %v, want nobody purged", purged, err)
+	}
+	if purged, err := s.PurgeDeleted(ctx, time.Now()); err != nil || purged != 1 {
+		t.Errorf("PurgeDeleted = %d, %v, want one purged", purged, err)
+	}
+
+	if _, err := s.Restore(ctx, bob); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("Restore of purged user error = %v, want %v", err, user.ErrNotFound)
+	}
+	if u, err := s.GetUser(ctx, alice); err != nil || len(u.Friends) != 0 {
+		t.Errorf("friends of alice = %v, %v, want purged bob pulled", u.Friends, err)
+	}
+	if requests, err := s.ListFriendRequests(ctx, carol, user.RequestsIncoming, ""); err != nil || len(requests) != 0 {
+		t.Errorf("requests of carol = %+v, %v, want requests of purged bob deleted", requests, err)
+	}
+	if relations, err := s.ListRelations(ctx, carol, user.RelationMute); err != nil || len(relations) != 0 {
+		t.Errorf("mutes of carol = %+v, %v, want mute of purged bob deleted", relations, err)
+	}
+	if purged, err := s.PurgeDeleted(ctx, time.Now()); err != nil || purged != 0 {
+		t.Errorf("second PurgeDeleted = %d, %v, want nobody purged", purged, err)
+	}
+}
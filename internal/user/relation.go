@@ -0,0 +1,57 @@
+package user
+
+// file for block and mute relations between users
+
+import (
+	"time"
+)
+
+// kinds of relation from one user to another
+const (
+	// RelationBlock - users can't be friends, are hidden from each other's friend lists and suggestions
+	RelationBlock = "block"
+	// RelationMute - target is hidden only from suggestions and incoming friend requests of user, friendship stays
+	RelationMute = "mute"
+)
+
+// Relation - block or mute of target by user, user can have one relation of every kind to target
+type Relation struct {
+	UserID    string    `json:"user_id" bson:"user_id"`
+	TargetID  string    `json:"target_id" bson:"target_id"`
+	Kind      string    `json:"kind" bson:"kind"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// RelationFilter - filter for list of relations, empty fields match all relations
+type RelationFilter struct {
+	UserID   string
+	TargetID string
+	Kind     string
+}
+
+// Match - check that relation matches filter, for storages which filter relations themselves
+func (f RelationFilter) Match(r Relation) bool {
+	return (f.UserID == "" || f.UserID == r.UserID) &&
+		(f.TargetID == "" || f.TargetID == r.TargetID) &&
+		(f.Kind == "" || f.Kind == r.Kind)
+}
+
+// NewerRelation - order of relations for storages which sort relations themselves, newer relations go first
+func NewerRelation(a, b Relation) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	if a.TargetID != b.TargetID {
+		return compareIDs(a.TargetID, b.TargetID) > 0
+	}
+	return compareIDs(a.UserID, b.UserID) > 0
+}
+
+// validRelationKind - check that kind is one of relation kinds
+func validRelationKind(kind string) bool {
+	switch kind {
+	case RelationBlock, RelationMute:
+		return true
+	}
+	return false
+}
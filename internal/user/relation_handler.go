@@ -0,0 +1,117 @@
+package user
+
+// file for handle of blocks and mutes
+
+import (
+	"context"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"project/internal/response"
+)
+
+// constants for blocks and mutes path
+const (
+	blocksURL = "/users/:id/blocks"
+	blockURL  = "/users/:id/blocks/:targetId"
+	mutesURL  = "/users/:id/mutes"
+	muteURL   = "/users/:id/mutes/:targetId"
+)
+
+// deletedRelation - data of answer for deleted block or mute
+type deletedRelation struct {
+	UserID   string `json:"user_id"`
+	TargetID string `json:"target_id"`
+	Kind     string `json:"kind"`
+}
+
+// registerRelations - func for init routs of blocks and mutes, user from path is the one who blocks or mutes
+func (h *Handler) registerRelations(router *httprouter.Router) {
+	h.handle(router, http.MethodGet, blocksURL, h.ListBlocks, blocksURL)
+	h.handle(router, http.MethodPut, blockURL, h.Block, blockURL)
+	h.handle(router, http.MethodDelete, blockURL, h.Unblock, blockURL)
+	h.handle(router, http.MethodGet, mutesURL, h.ListMutes, mutesURL)
+	h.handle(router, http.MethodPut, muteURL, h.Mute, muteURL)
+	h.handle(router, http.MethodDelete, muteURL, h.Unmute, muteURL)
+}
+
+// Block - blocking user from url by user of path, blocking twice is not an error
+func (h *Handler) Block(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Block user")
+	return h.relate(w, r, h.UserService.Block)
+}
+
+// Unblock - removing block of user from url by user of path
+func (h *Handler) Unblock(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Unblock user")
+	return h.unrelate(w, r, RelationBlock, h.UserService.Unblock)
+}
+
+// ListBlocks - getting users blocked by user of path
+func (h *Handler) ListBlocks(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("List blocks")
+	return h.listRelations(w, r, RelationBlock)
+}
+
+// Mute - muting user from url by user of path, muting twice is not an error
+func (h *Handler) Mute(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Mute user")
+	return h.relate(w, r, h.UserService.Mute)
+}
+
+// Unmute - removing mute of user from url by user of path
+func (h *Handler) Unmute(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Unmute user")
+	return h.unrelate(w, r, RelationMute, h.UserService.Unmute)
+}
+
+// ListMutes - getting users muted by user of path
+func (h *Handler) ListMutes(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("List mutes")
+	return h.listRelations(w, r, RelationMute)
+}
+
+// relate - call user-service to save relation with users from path, answer with saved relation
+func (h *Handler) relate(w http.ResponseWriter, r *http.Request, relate func(ctx context.Context, userID string, targetID string) (Relation, error)) error {
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	if err := AuthorizeUser(r.Context(), params.ByName("id")); err != nil {
+		return err
+	}
+
+	relation, err := relate(r.Context(), params.ByName("id"), params.ByName("targetId"))
+	if err != nil {
+		return err
+	}
+
+	return response.JSON(w, http.StatusOK, relation, nil)
+}
+
+// unrelate - call user-service to delete relation with users from path, answer with deleted relation
+func (h *Handler) unrelate(w http.ResponseWriter, r *http.Request, kind string, unrelate func(ctx context.Context, userID string, targetID string) error) error {
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	userID := params.ByName("id")
+	targetID := params.ByName("targetId")
+	if err := AuthorizeUser(r.Context(), userID); err != nil {
+		return err
+	}
+
+	if err := unrelate(r.Context(), userID, targetID); err != nil {
+		return err
+	}
+
+	return response.JSON(w, http.StatusOK, deletedRelation{UserID: userID, TargetID: targetID, Kind: kind}, nil)
+}
+
+// listRelations - call user-service to get relations of kind of user from path
+func (h *Handler) listRelations(w http.ResponseWriter, r *http.Request, kind string) error {
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	if err := AuthorizeUser(r.Context(), params.ByName("id")); err != nil {
+		return err
+	}
+
+	relations, err := h.UserService.ListRelations(r.Context(), params.ByName("id"), kind)
+	if err != nil {
+		return err
+	}
+
+	return response.JSON(w, http.StatusOK, relations, listMeta{Count: len(relations)})
+}
@@ -0,0 +1,178 @@
+package user
+
+// file for blocks and mutes functions of user-service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"project/pkg/validator"
+)
+
+// Block - func for blocking target by user, friendship and pending friend requests between them are removed
+func (s service) Block(ctx context.Context, userID string, targetID string) (Relation, error) {
+	relation, err := s.relate(ctx, userID, targetID, RelationBlock)
+	if err != nil {
+		return relation, fmt.Errorf("failed to block user. error: %w", err)
+	}
+
+	_, _, err = s.storage.Unfriend(ctx, relation.UserID, relation.TargetID)
+	if err != nil && !errors.Is(err, ErrNotFriends) {
+		return relation, fmt.Errorf("failed to unfriend blocked user. error: %w", err)
+	}
+
+	for _, filter := range []RequestFilter{
+		{FromID: relation.UserID, ToID: relation.TargetID, Status: RequestPending},
+		{FromID: relation.TargetID, ToID: relation.UserID, Status: RequestPending},
+	} {
+		requests, err := s.requests.FindRequests(ctx, filter)
+		if err != nil {
+			return relation, fmt.Errorf("failed to find friend requests of blocked user. error: %w", err)
+		}
+		for _, request := range requests {
+			// request answered at the same time is left as it is
+			_, err = s.requests.UpdateRequestStatus(ctx, request.ID, RequestPending, RequestCanceled, requestTime())
+			if err != nil && !errors.Is(err, ErrRequestState) {
+				return relation, fmt.Errorf("failed to cancel friend request of blocked user. error: %w", err)
+			}
+		}
+	}
+	return relation, nil
+}
+
+// Unblock - func for removing block of target by user, users don't become friends again
+func (s service) Unblock(ctx context.Context, userID string, targetID string) error {
+	if err := s.unrelate(ctx, userID, targetID, RelationBlock); err != nil {
+		return fmt.Errorf("failed to unblock user. error: %w", err)
+	}
+	return nil
+}
+
+// Mute - func for muting target by user, target is not suggested to user and his friend requests are not listed for user
+func (s service) Mute(ctx context.Context, userID string, targetID string) (Relation, error) {
+	relation, err := s.relate(ctx, userID, targetID, RelationMute)
+	if err != nil {
+		return relation, fmt.Errorf("failed to mute user. error: %w", err)
+	}
+	return relation, nil
+}
+
+// Unmute - func for removing mute of target by user
+func (s service) Unmute(ctx context.Context, userID string, targetID string) error {
+	if err := s.unrelate(ctx, userID, targetID, RelationMute); err != nil {
+		return fmt.Errorf("failed to unmute user. error: %w", err)
+	}
+	return nil
+}
+
+// ListRelations - func for get users blocked or muted by user, users who blocked user are not listed
+func (s service) ListRelations(ctx context.Context, userID string, kind string) ([]Relation, error) {
+	if !validRelationKind(kind) {
+		return nil, &ValidationError{Field: "kind", Message: "must be one of block, mute"}
+	}
+
+	user, err := s.storage.FindOne(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relations. error: %w", err)
+	}
+	relations, err := s.relations.FindRelations(ctx, RelationFilter{UserID: user.ID, Kind: kind})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relations. error: %w", err)
+	}
+	return relations, nil
+}
+
+// relate - save relation of kind from user to target, both users must exist
+func (s service) relate(ctx context.Context, userID string, targetID string, kind string) (Relation, error) {
+	if userID == targetID {
+		return Relation{}, validator.Errors{{Field: "targetId", Message: "must be different from id"}}
+	}
+
+	// relations keep ids in form of storage
+	user, err := s.storage.FindOne(ctx, userID)
+	if err != nil {
+		return Relation{}, err
+	}
+	target, err := s.storage.FindOne(ctx, targetID)
+	if err != nil {
+		return Relation{}, err
+	}
+
+	return s.relations.SaveRelation(ctx, Relation{
+		UserID:    user.ID,
+		TargetID:  target.ID,
+		Kind:      kind,
+		CreatedAt: requestTime(),
+	})
+}
+
+// unrelate - delete relation of kind from user to target
+func (s service) unrelate(ctx context.Context, userID string, targetID string, kind string) error {
+	user, err := s.storage.FindOne(ctx, userID)
+	if err != nil {
+		return err
+	}
+	target, err := s.storage.FindOne(ctx, targetID)
+	if err != nil {
+		return err
+	}
+	return s.relations.DeleteRelation(ctx, user.ID, target.ID, kind)
+}
+
+// checkNotBlocked - ErrBlocked if one of users blocked another, ids must be in form of storage
+func (s service) checkNotBlocked(ctx context.Context, firstID string, secondID string) error {
+	for _, filter := range []RelationFilter{
+		{UserID: firstID, TargetID: secondID, Kind: RelationBlock},
+		{UserID: secondID, TargetID: firstID, Kind: RelationBlock},
+	} {
+		relations, err := s.relations.FindRelations(ctx, filter)
+		if err != nil {
+			return err
+		}
+		if len(relations) > 0 {
+			return fmt.Errorf("user %s blocked user %s: %w", filter.UserID, filter.TargetID, ErrBlocked)
+		}
+	}
+	return nil
+}
+
+// hiddenFrom - ids of users hidden from user: blocked by user and who blocked user, with mutes also muted by user
+func (s service) hiddenFrom(ctx context.Context, userID string, withMutes bool) (map[string]bool, error) {
+	user, err := s.storage.FindOne(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden := make(map[string]bool)
+	relations, err := s.relations.FindRelations(ctx, RelationFilter{UserID: user.ID})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range relations {
+		if r.Kind == RelationBlock || withMutes {
+			hidden[r.TargetID] = true
+		}
+	}
+	relations, err = s.relations.FindRelations(ctx, RelationFilter{TargetID: user.ID, Kind: RelationBlock})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range relations {
+		hidden[r.UserID] = true
+	}
+	return hidden, nil
+}
+
+// visibleFriends - friends without hidden users
+func visibleFriends(friends []Friend, hidden map[string]bool) []Friend {
+	if len(hidden) == 0 {
+		return friends
+	}
+	visible := make([]Friend, 0, len(friends))
+	for _, friend := range friends {
+		if !hidden[friend.ID] {
+			visible = append(visible, friend)
+		}
+	}
+	return visible
+}
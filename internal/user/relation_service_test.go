@@ -0,0 +1,155 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"project/internal/user"
+	"project/internal/user/apitest"
+	"project/pkg/validator"
+	"testing"
+)
+
+// TestBlock - block removes friendship and pending requests of both users, blocked users can't send requests
+// to each other and are not suggested, other friendships are kept
+func TestBlock(t *testing.T) {
+	ctx := context.Background()
+	s := apitest.MemoryHandler(t).UserService
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	carol := createUser(t, s, "carol")
+	befriend(t, s, alice, bob)
+	befriend(t, s, alice, carol)
+	befriend(t, s, bob, carol)
+
+	if _, err := s.Block(ctx, alice, alice); !errors.As(err, &validator.Errors{}) {
+		t.Errorf("Block of himself error = %v, want validation errors", err)
+	}
+
+	dave := createUser(t, s, "dave")
+	request, err := s.SendFriendRequest(ctx, dave, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Block(ctx, alice, bob); err != nil {
+		t.Fatalf("Block error: %v", err)
+	}
+	if _, err = s.Block(ctx, dave, alice); err != nil {
+		t.Fatalf("Block error: %v", err)
+	}
+
+	for _, pair := range [][2]string{{alice, bob}, {bob, alice}, {alice, dave}, {dave, alice}} {
+		if _, err = s.SendFriendRequest(ctx, pair[0], pair[1]); !errors.Is(err, user.ErrBlocked) {
+			t.Errorf("SendFriendRequest(%s, %s) error = %v, want %v", pair[0], pair[1], err, user.ErrBlocked)
+		}
+	}
+	if _, err = s.AcceptFriendRequest(ctx, alice, request.ID); !errors.Is(err, user.ErrRequestState) {
+		t.Errorf("AcceptFriendRequest of blocked user error = %v, want %v", err, user.ErrRequestState)
+	}
+	if requests, err := s.ListFriendRequests(ctx, dave, user.RequestsOutgoing, user.RequestCanceled); err != nil || len(requests) != 1 {
+		t.Errorf("canceled requests of dave = %+v, %v, want request to alice", requests, err)
+	}
+
+	tests := []struct {
+		id   string
+		want []string
+	}{
+		{alice, []string{carol}},
+		{bob, []string{carol}},
+		{carol, []string{alice, bob}},
+	}
+	for _, tt := range tests {
+		friends, err := s.GetUserFriends(ctx, tt.id)
+		if err != nil || !sameFriends(friends, tt.want) {
+			t.Errorf("friends of %s = %+v, %v, want %v", tt.id, friends, err, tt.want)
+		}
+	}
+
+	// bob is friend of carol, but he is blocked by alice and alice is hidden from him
+	for _, pair := range [][2]string{{alice, bob}, {bob, alice}} {
+		if suggestions, err := s.Suggestions(ctx, pair[0], 0); err != nil || suggested(suggestions, pair[1]) {
+			t.Errorf("suggestions of %s = %+v, %v, want without %s", pair[0], suggestions, err, pair[1])
+		}
+	}
+
+	if relations, err := s.ListRelations(ctx, bob, user.RelationBlock); err != nil || len(relations) != 0 {
+		t.Errorf("blocks of bob = %+v, %v, want none, blocks of other users are not listed", relations, err)
+	}
+	if err = s.Unblock(ctx, alice, bob); err != nil {
+		t.Fatalf("Unblock error: %v", err)
+	}
+	if friends, err := s.GetUserFriends(ctx, bob); err != nil || !sameFriends(friends, []string{carol}) {
+		t.Errorf("friends of bob after Unblock = %+v, %v, want only carol", friends, err)
+	}
+	if _, err = s.SendFriendRequest(ctx, bob, alice); err != nil {
+		t.Errorf("SendFriendRequest after Unblock error: %v", err)
+	}
+}
+
+// TestMute - muted user is not suggested and his incoming requests are not listed, but they can still be accepted
+func TestMute(t *testing.T) {
+	ctx := context.Background()
+	s := apitest.MemoryHandler(t).UserService
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	carol := createUser(t, s, "carol")
+	befriend(t, s, alice, carol)
+	befriend(t, s, bob, carol)
+
+	request, err := s.SendFriendRequest(ctx, bob, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Mute(ctx, alice, bob); err != nil {
+		t.Fatalf("Mute error: %v", err)
+	}
+
+	if requests, err := s.ListFriendRequests(ctx, alice, user.RequestsIncoming, user.RequestPending); err != nil || len(requests) != 0 {
+		t.Errorf("incoming requests of alice = %+v, %v, want none", requests, err)
+	}
+	if requests, err := s.ListFriendRequests(ctx, bob, user.RequestsOutgoing, user.RequestPending); err != nil || len(requests) != 1 {
+		t.Errorf("outgoing requests of bob = %+v, %v, want request to alice", requests, err)
+	}
+	if suggestions, err := s.Suggestions(ctx, alice, 0); err != nil || suggested(suggestions, bob) {
+		t.Errorf("suggestions of alice = %+v, %v, want without bob", suggestions, err)
+	}
+	if suggestions, err := s.Suggestions(ctx, bob, 0); err != nil || !suggested(suggestions, alice) {
+		t.Errorf("suggestions of bob = %+v, %v, want alice, mute is one-way", suggestions, err)
+	}
+
+	if err = s.Unmute(ctx, alice, bob); err != nil {
+		t.Fatalf("Unmute error: %v", err)
+	}
+	if requests, err := s.ListFriendRequests(ctx, alice, user.RequestsIncoming, user.RequestPending); err != nil || len(requests) != 1 || requests[0].ID != request.ID {
+		t.Errorf("incoming requests of alice after Unmute = %+v, %v, want request of bob", requests, err)
+	}
+	if _, err = s.ListRelations(ctx, alice, "ignore"); !errors.As(err, new(*user.ValidationError)) {
+		t.Errorf("ListRelations of unknown kind error = %v, want validation error", err)
+	}
+}
+
+// sameFriends - friends are exactly users with ids in any order
+func sameFriends(friends []user.Friend, ids []string) bool {
+	if len(friends) != len(ids) {
+		return false
+	}
+	for _, id := range ids {
+		found := false
+		for _, friend := range friends {
+			found = found || friend.ID == id
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// suggested - user with id is in suggestions
+func suggested(suggestions []user.Suggestion, id string) bool {
+	for _, suggestion := range suggestions {
+		if suggestion.ID == id {
+			return true
+		}
+	}
+	return false
+}
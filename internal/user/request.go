@@ -0,0 +1,77 @@
+package user
+
+// file for friend request description and its state machine
+
+import (
+	"time"
+)
+
+// statuses of friend request, only pending request can be changed
+const (
+	RequestPending  = "pending"
+	RequestAccepted = "accepted"
+	RequestDeclined = "declined"
+	RequestCanceled = "canceled"
+)
+
+// directions of friend requests for user
+const (
+	RequestsIncoming = "incoming"
+	RequestsOutgoing = "outgoing"
+)
+
+// FriendRequest - request of one user to become friend of another, friends are made only after it is accepted
+type FriendRequest struct {
+	ID        string    `json:"id" bson:"_id,omitempty"`
+	FromID    string    `json:"from_id" bson:"from_id"`
+	ToID      string    `json:"to_id" bson:"to_id"`
+	Status    string    `json:"status" bson:"status"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// RequestFilter - filter for list of friend requests, empty fields match all requests
+type RequestFilter struct {
+	FromID string
+	ToID   string
+	Status string
+}
+
+// Match - check that request matches filter, for storages which filter requests themselves
+func (f RequestFilter) Match(r FriendRequest) bool {
+	return (f.FromID == "" || f.FromID == r.FromID) &&
+		(f.ToID == "" || f.ToID == r.ToID) &&
+		(f.Status == "" || f.Status == r.Status)
+}
+
+// NewerRequest - order of requests for storages which sort requests themselves, newer requests go first
+func NewerRequest(a, b FriendRequest) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return compareIDs(a.ID, b.ID) > 0
+}
+
+// transitions - statuses which request can get from its status, accepted, declined and canceled requests are final
+var transitions = map[string][]string{
+	RequestPending: {RequestAccepted, RequestDeclined, RequestCanceled},
+}
+
+// CanTransition - check that request with status from can get status to
+func CanTransition(from, to string) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// validRequestStatus - check that status is one of request statuses
+func validRequestStatus(status string) bool {
+	switch status {
+	case RequestPending, RequestAccepted, RequestDeclined, RequestCanceled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package user
+
+// file for handle of friend requests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"io/ioutil"
+	"net/http"
+	"project/internal/response"
+	"project/pkg/validator"
+)
+
+// constants for friend requests path
+const (
+	friendRequestsURL = "/users/:id/friend_requests"
+	friendRequestURL  = "/users/:id/friend_requests/:requestId"
+)
+
+// registerRequests - func for init routs of friend requests, user from path is the one who acts
+func (h *Handler) registerRequests(router *httprouter.Router) {
+	h.handle(router, http.MethodPost, friendRequestsURL, h.SendFriendRequest, friendRequestsURL)
+	h.handle(router, http.MethodGet, friendRequestsURL, h.ListFriendRequests, friendRequestsURL)
+	h.handle(router, http.MethodPost, friendRequestURL+"/accept", h.AcceptFriendRequest, friendRequestURL+"/accept")
+	h.handle(router, http.MethodPost, friendRequestURL+"/decline", h.DeclineFriendRequest, friendRequestURL+"/decline")
+	h.handle(router, http.MethodPost, friendRequestURL+"/cancel", h.CancelFriendRequest, friendRequestURL+"/cancel")
+}
+
+// SendFriendRequest - sending friend request from user of path to user from body
+func (h *Handler) SendFriendRequest(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Send friend request")
+
+	// getting id from url params
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	userID := params.ByName("id")
+	if err := AuthorizeUser(r.Context(), userID); err != nil {
+		return err
+	}
+
+	// getting recipient from http`s body
+	content, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return &ValidationError{Message: fmt.Sprintf("failed to read body: %v", err)}
+	}
+	defer r.Body.Close()
+
+	type body struct {
+		ToID string `json:"to_id" validate:"required"`
+	}
+	var message body
+	if err := json.Unmarshal(content, &message); err != nil {
+		return &ValidationError{Message: fmt.Sprintf("invalid json: %v", err)}
+	}
+	if err := validator.Validate(message); err != nil {
+		return err
+	}
+
+	request, err := h.UserService.SendFriendRequest(r.Context(), userID, message.ToID)
+	if err != nil {
+		return err
+	}
+
+	// answer with created request
+	return response.JSON(w, http.StatusCreated, request, nil)
+}
+
+// ListFriendRequests - getting friend requests of user, direction (incoming by default or outgoing) and status are in query
+func (h *Handler) ListFriendRequests(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("List friend requests")
+
+	// getting id from url params
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	userID := params.ByName("id")
+	if err := AuthorizeUser(r.Context(), userID); err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+	direction := query.Get("direction")
+	if direction == "" {
+		direction = RequestsIncoming
+	}
+
+	requests, err := h.UserService.ListFriendRequests(r.Context(), userID, direction, query.Get("status"))
+	if err != nil {
+		return err
+	}
+
+	// empty list is answered with empty array, not null
+	if requests == nil {
+		requests = []FriendRequest{}
+	}
+	return response.JSON(w, http.StatusOK, requests, listMeta{Count: len(requests)})
+}
+
+// AcceptFriendRequest - accepting friend request by its recipient from path
+func (h *Handler) AcceptFriendRequest(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Accept friend request")
+	return h.changeFriendRequest(w, r, h.UserService.AcceptFriendRequest)
+}
+
+// DeclineFriendRequest - declining friend request by its recipient from path
+func (h *Handler) DeclineFriendRequest(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Decline friend request")
+	return h.changeFriendRequest(w, r, h.UserService.DeclineFriendRequest)
+}
+
+// CancelFriendRequest - canceling friend request by its sender from path
+func (h *Handler) CancelFriendRequest(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("Cancel friend request")
+	return h.changeFriendRequest(w, r, h.UserService.CancelFriendRequest)
+}
+
+// changeFriendRequest - call user-service to change request with user and request from path, answer with changed request
+func (h *Handler) changeFriendRequest(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, userID string, requestID string) (FriendRequest, error)) error {
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	if err := AuthorizeUser(r.Context(), params.ByName("id")); err != nil {
+		return err
+	}
+
+	request, err := change(r.Context(), params.ByName("id"), params.ByName("requestId"))
+	if err != nil {
+		return err
+	}
+
+	return response.JSON(w, http.StatusOK, request, nil)
+}
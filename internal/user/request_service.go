@@ -0,0 +1,198 @@
+package user
+
+// file for friend requests functions of user-service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"project/pkg/validator"
+	"time"
+)
+
+// SendFriendRequest - func for creating pending friend request from one user to another
+func (s service) SendFriendRequest(ctx context.Context, fromID string, toID string) (FriendRequest, error) {
+	if fromID == toID {
+		return FriendRequest{}, validator.Errors{{Field: "to_id", Message: "must be different from id"}}
+	}
+
+	// requests keep ids in form of storage
+	from, err := s.storage.FindOne(ctx, fromID)
+	if err != nil {
+		return FriendRequest{}, fmt.Errorf("failed to send friend request. error: %w", err)
+	}
+	to, err := s.storage.FindOne(ctx, toID)
+	if err != nil {
+		return FriendRequest{}, fmt.Errorf("failed to send friend request. error: %w", err)
+	}
+	if contains(from.Friends, to.ID) && contains(to.Friends, from.ID) {
+		return FriendRequest{}, fmt.Errorf("failed to send friend request. error: %w", ErrAlreadyFriends)
+	}
+	if err = s.checkNotBlocked(ctx, from.ID, to.ID); err != nil {
+		return FriendRequest{}, fmt.Errorf("failed to send friend request. error: %w", err)
+	}
+
+	now := requestTime()
+	request, err := s.requests.CreateRequest(ctx, FriendRequest{
+		FromID:    from.ID,
+		ToID:      to.ID,
+		Status:    RequestPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return request, fmt.Errorf("failed to send friend request. error: %w", err)
+	}
+	return request, nil
+}
+
+// ListFriendRequests - func for get incoming or outgoing friend requests of user, empty status matches all requests.
+// Incoming requests of blocked and muted users are not listed
+func (s service) ListFriendRequests(ctx context.Context, userID string, direction string, status string) ([]FriendRequest, error) {
+	if status != "" && !validRequestStatus(status) {
+		return nil, &ValidationError{Field: "status", Message: "must be one of pending, accepted, declined, canceled"}
+	}
+
+	user, err := s.storage.FindOne(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list friend requests. error: %w", err)
+	}
+
+	filter := RequestFilter{Status: status}
+	switch direction {
+	case RequestsIncoming:
+		filter.ToID = user.ID
+	case RequestsOutgoing:
+		filter.FromID = user.ID
+	default:
+		return nil, &ValidationError{Field: "direction", Message: "must be one of incoming, outgoing"}
+	}
+
+	requests, err := s.requests.FindRequests(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list friend requests. error: %w", err)
+	}
+	if direction == RequestsOutgoing {
+		return requests, nil
+	}
+
+	hidden, err := s.hiddenFrom(ctx, user.ID, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list friend requests. error: %w", err)
+	}
+	visible := make([]FriendRequest, 0, len(requests))
+	for _, request := range requests {
+		if !hidden[request.FromID] {
+			visible = append(visible, request)
+		}
+	}
+	return visible, nil
+}
+
+// AcceptFriendRequest - func for accepting pending request by its recipient, users become friends.
+// Users are linked before request gets status accepted, so accepted request always has friendship,
+// request stays pending if users can't be linked and can be accepted once more
+func (s service) AcceptFriendRequest(ctx context.Context, userID string, requestID string) (FriendRequest, error) {
+	request, err := s.checkRequest(ctx, userID, requestID, RequestAccepted)
+	if err != nil {
+		return request, fmt.Errorf("failed to accept friend request. error: %w", err)
+	}
+
+	// user can be blocked after request is sent, such request is canceled
+	if err = s.checkNotBlocked(ctx, request.FromID, request.ToID); err != nil {
+		if _, cancelErr := s.requests.UpdateRequestStatus(ctx, request.ID, RequestPending, RequestCanceled, requestTime()); cancelErr != nil {
+			s.logger.Errorf("failed to cancel friend request %s. error: %v", request.ID, cancelErr)
+		}
+		return request, fmt.Errorf("failed to accept friend request. error: %w", err)
+	}
+
+	// users can be friends already by request in other direction, that is not an error of request
+	_, _, err = s.storage.MakeFriends(ctx, request.FromID, request.ToID)
+	linked := err == nil
+	if err != nil && !errors.Is(err, ErrAlreadyFriends) {
+		return request, fmt.Errorf("failed to accept friend request. error: %w", err)
+	}
+
+	accepted, err := s.requests.UpdateRequestStatus(ctx, request.ID, RequestPending, RequestAccepted, requestTime())
+	if err != nil {
+		// request is answered at the same time, friendship made by this call is removed
+		if linked {
+			if _, _, unlinkErr := s.storage.Unfriend(ctx, request.FromID, request.ToID); unlinkErr != nil {
+				s.logger.Errorf("failed to unfriend users of friend request %s. error: %v", request.ID, unlinkErr)
+			}
+		}
+		return request, fmt.Errorf("failed to accept friend request. error: %w", err)
+	}
+	return accepted, nil
+}
+
+// DeclineFriendRequest - func for declining pending request by its recipient
+func (s service) DeclineFriendRequest(ctx context.Context, userID string, requestID string) (FriendRequest, error) {
+	request, err := s.changeRequest(ctx, userID, requestID, RequestDeclined)
+	if err != nil {
+		return request, fmt.Errorf("failed to decline friend request. error: %w", err)
+	}
+	return request, nil
+}
+
+// CancelFriendRequest - func for canceling pending request by its sender
+func (s service) CancelFriendRequest(ctx context.Context, userID string, requestID string) (FriendRequest, error) {
+	request, err := s.changeRequest(ctx, userID, requestID, RequestCanceled)
+	if err != nil {
+		return request, fmt.Errorf("failed to cancel friend request. error: %w", err)
+	}
+	return request, nil
+}
+
+// changeRequest - check that user can give request new status and change it
+func (s service) changeRequest(ctx context.Context, userID string, requestID string, status string) (FriendRequest, error) {
+	request, err := s.checkRequest(ctx, userID, requestID, status)
+	if err != nil {
+		return request, err
+	}
+
+	// status is changed only if request is still pending
+	return s.requests.UpdateRequestStatus(ctx, request.ID, request.Status, status, requestTime())
+}
+
+// checkRequest - check that user can give request new status, request of other users is not found for user
+func (s service) checkRequest(ctx context.Context, userID string, requestID string, status string) (FriendRequest, error) {
+	user, err := s.storage.FindOne(ctx, userID)
+	if err != nil {
+		return FriendRequest{}, err
+	}
+	request, err := s.requests.FindRequest(ctx, requestID)
+	if err != nil {
+		return request, err
+	}
+
+	// recipient answers request, sender cancels it
+	actor := request.ToID
+	if status == RequestCanceled {
+		actor = request.FromID
+	}
+	switch {
+	case user.ID != request.FromID && user.ID != request.ToID:
+		return FriendRequest{}, fmt.Errorf("request %s of user %s: %w", requestID, userID, ErrRequestNotFound)
+	case user.ID != actor:
+		return request, fmt.Errorf("request %s can't get status %s from user %s: %w", requestID, status, userID, ErrRequestForbidden)
+	case !CanTransition(request.Status, status):
+		return request, fmt.Errorf("request %s has status %s: %w", requestID, request.Status, ErrRequestState)
+	}
+	return request, nil
+}
+
+// requestTime - current time in precision of all storages
+func requestTime() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// contains - check that id is in friends
+func contains(friends []string, id string) bool {
+	for _, friend := range friends {
+		if friend == id {
+			return true
+		}
+	}
+	return false
+}
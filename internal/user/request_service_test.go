@@ -0,0 +1,94 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"project/internal/user"
+	"project/internal/user/memory"
+	"project/pkg/logging"
+	"testing"
+)
+
+// failingLink - user storage which fails to link users while fail is set
+type failingLink struct {
+	user.Storage
+	fail bool
+}
+
+var errLink = errors.New("link failed")
+
+func (s *failingLink) MakeFriends(ctx context.Context, firstUserID string, secondUserID string) (user.User, user.User, error) {
+	if s.fail {
+		return user.User{}, user.User{}, errLink
+	}
+	return s.Storage.MakeFriends(ctx, firstUserID, secondUserID)
+}
+
+// TestAcceptFriendRequest - accepted request always has friendship, request which is not accepted keeps status of state machine
+func TestAcceptFriendRequest(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.GetLogger()
+	users := &failingLink{Storage: memory.NewStorage(logger)}
+	relations := memory.NewRelationStorage(logger)
+	s, err := user.NewService(users, memory.NewRequestStorage(logger), relations, *logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]string)
+	for _, username := range []string{"alice", "bob", "carol"} {
+		if ids[username], err = s.Create(ctx, user.User{Username: username}); err != nil {
+			t.Fatalf("Create(%s) error: %v", username, err)
+		}
+	}
+	alice, bob, carol := ids["alice"], ids["bob"], ids["carol"]
+
+	tests := []struct {
+		name     string
+		from, to string
+		prepare  func(t *testing.T)
+		err      error
+		status   string
+		friends  bool
+	}{
+		{"link fails", alice, bob, func(t *testing.T) { users.fail = true }, errLink, user.RequestPending, false},
+		{"accepted", alice, carol, func(t *testing.T) { users.fail = false }, nil, user.RequestAccepted, true},
+		{"blocked after request", bob, carol, func(t *testing.T) {
+			if _, err := s.Block(ctx, carol, bob); err != nil {
+				t.Fatalf("Block error: %v", err)
+			}
+		}, user.ErrRequestState, user.RequestCanceled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := s.SendFriendRequest(ctx, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("SendFriendRequest error: %v", err)
+			}
+			tt.prepare(t)
+
+			if _, err = s.AcceptFriendRequest(ctx, tt.to, request.ID); !errors.Is(err, tt.err) {
+				t.Errorf("AcceptFriendRequest error = %v, want %v", err, tt.err)
+			}
+			requests, err := s.ListFriendRequests(ctx, tt.from, user.RequestsOutgoing, tt.status)
+			if err != nil || len(requests) != 1 || requests[0].ID != request.ID {
+				t.Errorf("requests of status %s = %+v, %v, want request %s", tt.status, requests, err, request.ID)
+			}
+			friends, err := s.GetUserFriends(ctx, tt.to)
+			if err != nil {
+				t.Fatalf("GetUserFriends error: %v", err)
+			}
+			if got := len(friends) == 1 && friends[0].ID == tt.from; got != tt.friends {
+				t.Errorf("users are friends = %t, want %t", got, tt.friends)
+			}
+		})
+	}
+
+	// request which stayed pending is accepted once storage links users
+	requests, err := s.ListFriendRequests(ctx, alice, user.RequestsOutgoing, user.RequestPending)
+	if err != nil || len(requests) != 1 {
+		t.Fatalf("pending requests = %+v, %v, want one", requests, err)
+	}
+	if accepted, err := s.AcceptFriendRequest(ctx, bob, requests[0].ID); err != nil || accepted.Status != user.RequestAccepted {
+		t.Errorf("second AcceptFriendRequest = %+v, %v, want accepted request", accepted, err)
+	}
+}
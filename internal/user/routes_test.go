@@ -0,0 +1,81 @@
+package user_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"project/internal/user/apitest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// TestDeprecatedRoutes - legacy paths answer as before with Deprecation, Sunset and Link to successor, paths under /api/v1 don't
+func TestDeprecatedRoutes(t *testing.T) {
+	h := apitest.MemoryHandler(t)
+	h.Auth, h.APIKeys = nil, nil
+	router := httprouter.New()
+	h.Register(router)
+	alice := createUser(t, h.UserService, "alice")
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		link   string
+	}{
+		{http.MethodGet, "/users/" + alice, http.StatusOK, "</api/v1/users/" + alice + `>; rel="successor-version"`},
+		{http.MethodGet, "/friends/" + alice, http.StatusOK, "</api/v1/users/" + alice + `/friends>; rel="successor-version"`},
+		{http.MethodGet, "/users", http.StatusOK, `</api/v1/users>; rel="successor-version"`},
+		{http.MethodGet, "/api/v1/users/" + alice, http.StatusOK, ""},
+		{http.MethodGet, "/api/v1/users/" + alice + "/friends", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s %s answered %d, want %d", tt.method, tt.path, w.Code, tt.status)
+		}
+
+		deprecated := tt.link != ""
+		headers := w.Header()
+		if (headers.Get("Deprecation") != "") != deprecated || (headers.Get("Sunset") != "") != deprecated || headers.Get("Link") != tt.link {
+			t.Errorf("%s %s headers Deprecation %q, Sunset %q, Link %q, want deprecated %t with link %q", tt.method, tt.path,
+				headers.Get("Deprecation"), headers.Get("Sunset"), headers.Get("Link"), deprecated, tt.link)
+		}
+		if deprecated && (headers.Get("Deprecation") != "@1793491200" || headers.Get("Sunset") != "Sat, 01 May 2027 00:00:00 GMT") {
+			t.Errorf("%s %s deprecated at %q with sunset %q", tt.method, tt.path, headers.Get("Deprecation"), headers.Get("Sunset"))
+		}
+	}
+}
+
+// TestRoutes - routes out of /api/v1 are deprecated, every legacy path is kept
+func TestRoutes(t *testing.T) {
+	h := apitest.MemoryHandler(t)
+	h.Register(httprouter.New())
+
+	legacy := map[string]bool{
+		"POST /create":                        false,
+		"GET /users":                          false,
+		"GET /users/:id":                      false,
+		"PUT /users/:id":                      false,
+		"PATCH /users/:id":                    false,
+		"DELETE /users":                       false,
+		"GET /friends/:id":                    false,
+		"POST /make_friends":                  false,
+		"DELETE /users/:id/friends/:friendId": false,
+	}
+	for _, route := range h.Routes() {
+		if route.Deprecated == strings.HasPrefix(route.Path, "/api/v1/") {
+			t.Errorf("%s %s is deprecated %t", route.Method, route.Path, route.Deprecated)
+		}
+		if _, ok := legacy[route.Method+" "+route.Path]; ok {
+			legacy[route.Method+" "+route.Path] = true
+		}
+	}
+	for route, registered := range legacy {
+		if !registered {
+			t.Errorf("legacy route %s is not registered", route)
+		}
+	}
+}
@@ -6,34 +6,69 @@ import (
 	"context"
 	"fmt"
 	"project/pkg/logging"
+	"project/pkg/validator"
+	"time"
 )
 
 // service struct with logging
 type service struct {
-	storage Storage
-	logger  logging.Logger
+	storage   Storage
+	graph     GraphStorage
+	requests  RequestStorage
+	relations RelationStorage
+	logger    logging.Logger
 }
 
 // NewService - func for initialization user-service
-func NewService(userStorage Storage, logger logging.Logger) (Service, error) {
+func NewService(userStorage Storage, requestStorage RequestStorage, relationStorage RelationStorage, logger logging.Logger) (Service, error) {
 	return &service{
-		storage: userStorage,
-		logger:  logger,
+		storage:   userStorage,
+		graph:     Graph(userStorage),
+		requests:  requestStorage,
+		relations: relationStorage,
+		logger:    logger,
 	}, nil
 }
 
 // Service - interface for description user-service functions
 type Service interface {
 	Create(ctx context.Context, user User) (userID string, err error)
-	GetUserFriends(ctx context.Context, userID string) (friends []string, err error)
-	UpdateAge(ctx context.Context, id string, age string) error
+	GetUser(ctx context.Context, userID string) (User, error)
+	ListUsers(ctx context.Context, filter Filter) (users []User, total int64, err error)
+	GetUsers(ctx context.Context, ids []string) ([]User, error)
+	GetUserFriends(ctx context.Context, userID string) (friends []Friend, err error)
+	UpdateAge(ctx context.Context, id string, age string) (User, error)
+	Update(ctx context.Context, id string, user User) (User, error)
+	Patch(ctx context.Context, id string, patch []byte) (User, error)
 	Delete(ctx context.Context, userID string) error
-	MakeFriends(ctx context.Context, firstUserID string, secondUserID string) (firstUser User, secondUser User, err error)
+	Restore(ctx context.Context, userID string) (User, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (purged int, err error)
+	RunPurger(ctx context.Context, interval time.Duration, retention time.Duration)
+	Unfriend(ctx context.Context, firstUserID string, secondUserID string) (firstUser User, secondUser User, err error)
+
+	SendFriendRequest(ctx context.Context, fromID string, toID string) (FriendRequest, error)
+	ListFriendRequests(ctx context.Context, userID string, direction string, status string) ([]FriendRequest, error)
+	AcceptFriendRequest(ctx context.Context, userID string, requestID string) (FriendRequest, error)
+	DeclineFriendRequest(ctx context.Context, userID string, requestID string) (FriendRequest, error)
+	CancelFriendRequest(ctx context.Context, userID string, requestID string) (FriendRequest, error)
+
+	MutualFriends(ctx context.Context, userID string, otherID string) ([]Friend, error)
+	Suggestions(ctx context.Context, userID string, limit int) ([]Suggestion, error)
+	ShortestPath(ctx context.Context, fromID string, toID string) ([]Friend, error)
+
+	Block(ctx context.Context, userID string, targetID string) (Relation, error)
+	Unblock(ctx context.Context, userID string, targetID string) error
+	Mute(ctx context.Context, userID string, targetID string) (Relation, error)
+	Unmute(ctx context.Context, userID string, targetID string) error
+	ListRelations(ctx context.Context, userID string, kind string) ([]Relation, error)
 }
 
 // Create - func for creating user
 func (s service) Create(ctx context.Context, user User) (userID string, err error) {
 	s.logger.Info("create user")
+	if err = validate(user); err != nil {
+		return "", err
+	}
 	userID, err = s.storage.Create(ctx, user)
 	if err != nil {
 		return "", fmt.Errorf("failed to create user. error: %w", err)
@@ -41,38 +76,181 @@ func (s service) Create(ctx context.Context, user User) (userID string, err erro
 	return userID, nil
 }
 
-// GetUserFriends - func for get all friends from one user
-func (s service) GetUserFriends(ctx context.Context, userID string) (friends []string, err error) {
+// GetUser - func for get one user
+func (s service) GetUser(ctx context.Context, userID string) (User, error) {
+	user, err := s.storage.FindOne(ctx, userID)
+	if err != nil {
+		return user, fmt.Errorf("failed to get user. error: %w", err)
+	}
+	return user, nil
+}
+
+// ListUsers - func for get page of users matched by filter, limit is set to default if it is empty
+func (s service) ListUsers(ctx context.Context, filter Filter) (users []User, total int64, err error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultLimit
+	}
+	if filter.Limit > MaxLimit {
+		return nil, 0, &ValidationError{Field: "limit", Message: fmt.Sprintf("must not be greater than %d", MaxLimit)}
+	}
+	if filter.Offset < 0 {
+		return nil, 0, &ValidationError{Field: "offset", Message: "must not be negative"}
+	}
+	if filter.MinAge != nil && filter.MaxAge != nil && *filter.MinAge > *filter.MaxAge {
+		return nil, 0, &ValidationError{Field: "min_age", Message: "must not be greater than max_age"}
+	}
+
+	users, total, err = s.storage.FindAll(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users. error: %w", err)
+	}
+	return users, total, nil
+}
+
+// GetUsers - func for get many users by one query of storage, unknown and deleted users are skipped, users are sorted by id
+func (s service) GetUsers(ctx context.Context, ids []string) ([]User, error) {
+	if len(ids) == 0 {
+		return []User{}, nil
+	}
+	users, _, err := s.storage.FindAll(ctx, Filter{IDs: ids, SortBy: SortByID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users. error: %w", err)
+	}
+	return users, nil
+}
+
+// GetUserFriends - func for get all friends from one user, users blocked by him or who blocked him are hidden
+func (s service) GetUserFriends(ctx context.Context, userID string) (friends []Friend, err error) {
 	friends, err = s.storage.GetUserFriends(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to delete user. error: %w", err)
+		return nil, fmt.Errorf("failed to get user friends. error: %w", err)
+	}
+	hidden, err := s.hiddenFrom(ctx, userID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user friends. error: %w", err)
 	}
-	return friends, nil
+	return visibleFriends(friends, hidden), nil
 }
 
-// UpdateAge - func for updating age of one user
-func (s service) UpdateAge(ctx context.Context, id string, age string) error {
-	err := s.storage.UpdateAge(ctx, id, age)
+// UpdateAge - func for updating age of one user, age is checked by rules of User for every transport, returns updated user
+func (s service) UpdateAge(ctx context.Context, id string, age string) (User, error) {
+	if err := validator.Validate(ageInput{Age: age}); err != nil {
+		return User{}, err
+	}
+	user, err := s.storage.UpdateAge(ctx, id, age)
 	if err != nil {
-		return fmt.Errorf("failed to delete user. error: %w", err)
+		return user, fmt.Errorf("failed to update user age. error: %w", err)
 	}
-	return err
+	return user, nil
 }
 
-// Delete - func for deleting one user from database
-func (s service) Delete(ctx context.Context, userID string) error {
-	err := s.storage.Delete(ctx, userID)
+// Update - func for replacing all mutable fields of one user, id and friends of user are not changed, returns updated user
+func (s service) Update(ctx context.Context, id string, user User) (User, error) {
+	if err := validate(user); err != nil {
+		return User{}, err
+	}
+	user, err := s.storage.Update(ctx, id, user)
+	if err != nil {
+		return user, fmt.Errorf("failed to update user. error: %w", err)
+	}
+	return user, nil
+}
+
+// Patch - func for changing one user by JSON Merge Patch (RFC 7396), returns updated user
+func (s service) Patch(ctx context.Context, id string, patch []byte) (User, error) {
+	user, err := s.storage.FindOne(ctx, id)
 	if err != nil {
+		return user, fmt.Errorf("failed to patch user. error: %w", err)
+	}
+	if user, err = applyMergePatch(user, patch); err != nil {
+		return user, err
+	}
+	return s.Update(ctx, id, user)
+}
+
+// Delete - func for soft deleting one user, user is hidden until he is restored or purged.
+// Friendships, friend requests, blocks and mutes are kept for Restore
+func (s service) Delete(ctx context.Context, userID string) error {
+	if err := s.storage.Delete(ctx, userID, requestTime()); err != nil {
 		return fmt.Errorf("failed to delete user. error: %w", err)
 	}
-	return err
+	return nil
 }
 
-// MakeFriends - func that create friendship between tow user by appending users names in friends array of each user
-func (s service) MakeFriends(ctx context.Context, firstUserID string, secondUserID string) (firstUser User, secondUser User, err error) {
-	firstUser, secondUser, err = s.storage.MakeFriends(ctx, firstUserID, secondUserID)
+// Unfriend - func that removes friendship between two users in both directions, returns updated users
+func (s service) Unfriend(ctx context.Context, firstUserID string, secondUserID string) (firstUser User, secondUser User, err error) {
+	if firstUserID == secondUserID {
+		return firstUser, secondUser, validator.Errors{{Field: "friendId", Message: "must be different from id"}}
+	}
+	firstUser, secondUser, err = s.storage.Unfriend(ctx, firstUserID, secondUserID)
 	if err != nil {
-		return firstUser, secondUser, fmt.Errorf("failed to make friends. error: %w", err)
+		return firstUser, secondUser, fmt.Errorf("failed to unfriend. error: %w", err)
 	}
 	return firstUser, secondUser, nil
 }
+
+// MutualFriends - func for get friends of both users, users hidden from the first user are not returned
+func (s service) MutualFriends(ctx context.Context, userID string, otherID string) ([]Friend, error) {
+	if userID == otherID {
+		return nil, validator.Errors{{Field: "other", Message: "must be different from id"}}
+	}
+	friends, err := s.graph.MutualFriends(ctx, userID, otherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mutual friends. error: %w", err)
+	}
+	hidden, err := s.hiddenFrom(ctx, userID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mutual friends. error: %w", err)
+	}
+	return visibleFriends(friends, hidden), nil
+}
+
+// Suggestions - func for get friends of friends ranked by number of mutual friends, limit is set to default if it is empty.
+// Blocked and muted users are never suggested
+func (s service) Suggestions(ctx context.Context, userID string, limit int) ([]Suggestion, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		return nil, &ValidationError{Field: "limit", Message: fmt.Sprintf("must not be greater than %d", MaxLimit)}
+	}
+	hidden, err := s.hiddenFrom(ctx, userID, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suggestions. error: %w", err)
+	}
+
+	// hidden users can take places in page, so page is bigger by their number
+	suggestions, err := s.graph.Suggestions(ctx, userID, limit+len(hidden))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suggestions. error: %w", err)
+	}
+	visible := make([]Suggestion, 0, limit)
+	for _, suggestion := range suggestions {
+		if len(visible) == limit {
+			break
+		}
+		if !hidden[suggestion.ID] {
+			visible = append(visible, suggestion)
+		}
+	}
+	return visible, nil
+}
+
+// ShortestPath - func for get the shortest chain of friends between two users, both users are included
+func (s service) ShortestPath(ctx context.Context, fromID string, toID string) ([]Friend, error) {
+	path, err := s.graph.ShortestPath(ctx, fromID, toID, MaxPathDepth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get path between users. error: %w", err)
+	}
+	return path, nil
+}
+
+// ageInput - age of UpdateAge with the same rules as age of User
+type ageInput struct {
+	Age string `json:"age" validate:"omitempty,numeric,gte=0,lte=150"`
+}
+
+// validate - check fields of user by validate tags before saving
+func validate(user User) error {
+	return validator.Validate(user)
+}
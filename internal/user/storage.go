@@ -4,12 +4,78 @@ package user
 
 import (
 	"context"
+	"time"
 )
 
+// Storage - storage of users, soft deleted users are found only by Restore, FindDeleted and Purge
 type Storage interface {
+	// Create - save new user, ErrEmailExists if user has email of another user
 	Create(ctx context.Context, user User) (string, error)
-	GetUserFriends(ctx context.Context, userID string) (friends []string, err error)
-	UpdateAge(ctx context.Context, id string, age string) error
-	Delete(ctx context.Context, id string) error
+	FindOne(ctx context.Context, id string) (User, error)
+	// FindByEmail - user with credentials by email, ErrNotFound if there is no such user
+	FindByEmail(ctx context.Context, email string) (User, error)
+	FindAll(ctx context.Context, filter Filter) (users []User, total int64, err error)
+	GetUserFriends(ctx context.Context, userID string) (friends []Friend, err error)
+	UpdateAge(ctx context.Context, id string, age string) (User, error)
+	Update(ctx context.Context, id string, user User) (User, error)
+	// Delete - soft deletion, user is kept with friends, so Restore brings friendships back
+	Delete(ctx context.Context, id string, deletedAt time.Time) error
+	// Restore - bring soft deleted user back, ErrNotDeleted if user is not deleted
+	Restore(ctx context.Context, id string) (User, error)
+	// FindDeleted - ids of users deleted at or before time
+	FindDeleted(ctx context.Context, before time.Time) ([]string, error)
+	// Purge - delete soft deleted user for good and pull him from friends of other users, ErrNotFound if user is not deleted
+	Purge(ctx context.Context, id string) error
 	MakeFriends(ctx context.Context, firstUserID string, secondUserID string) (firstUser User, secondUser User, err error)
+	Unfriend(ctx context.Context, firstUserID string, secondUserID string) (firstUser User, secondUser User, err error)
+}
+
+// RequestStorage - storage of friend requests, separate from users
+type RequestStorage interface {
+	// CreateRequest - save new request and return it with id, ErrRequestExists if pending request between users exists in any direction
+	CreateRequest(ctx context.Context, request FriendRequest) (FriendRequest, error)
+	FindRequest(ctx context.Context, id string) (FriendRequest, error)
+	// FindRequests - requests matched by filter, newer requests go first
+	FindRequests(ctx context.Context, filter RequestFilter) ([]FriendRequest, error)
+	// UpdateRequestStatus - change status only if request has status from, ErrRequestState otherwise
+	UpdateRequestStatus(ctx context.Context, id string, from, to string, updatedAt time.Time) (FriendRequest, error)
+	// DeleteRequests - delete all requests from and to user
+	DeleteRequests(ctx context.Context, userID string) error
+}
+
+// RelationStorage - storage of blocks and mutes, separate from users
+type RelationStorage interface {
+	// SaveRelation - save relation, existing relation of the same kind from user to target is kept and returned
+	SaveRelation(ctx context.Context, relation Relation) (Relation, error)
+	// DeleteRelation - delete relation of kind from user to target, ErrRelationNotFound if there is none
+	DeleteRelation(ctx context.Context, userID string, targetID string, kind string) error
+	// FindRelations - relations matched by filter, newer relations go first
+	FindRelations(ctx context.Context, filter RelationFilter) ([]Relation, error)
+	// DeleteRelations - delete all relations from and to user
+	DeleteRelations(ctx context.Context, userID string) error
+}
+
+// TokenStorage - storage of refresh tokens, tokens are found by hash of their value
+type TokenStorage interface {
+	SaveToken(ctx context.Context, token RefreshToken) error
+	// FindToken - token by hash, ErrTokenNotFound if there is none
+	FindToken(ctx context.Context, hash string) (RefreshToken, error)
+	// RevokeToken - revoke token only if it is not revoked yet, ErrTokenRevoked otherwise, so token is rotated only once
+	RevokeToken(ctx context.Context, hash string, revokedAt time.Time) error
+	// RevokeFamily - revoke all tokens of family which are not revoked yet
+	RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error
+}
+
+// APIKeyStorage - storage of API keys of service callers, keys are found by hash of their value
+type APIKeyStorage interface {
+	// CreateAPIKey - save new key and return it with id
+	CreateAPIKey(ctx context.Context, key APIKey) (APIKey, error)
+	// FindAPIKey - key by hash, ErrAPIKeyNotFound if there is none
+	FindAPIKey(ctx context.Context, hash string) (APIKey, error)
+	// FindAPIKeys - all keys, revoked ones included, newer keys go first
+	FindAPIKeys(ctx context.Context) ([]APIKey, error)
+	// RevokeAPIKey - revoke key and return it, time of the first revoke is kept, ErrAPIKeyNotFound if there is no key with id
+	RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) (APIKey, error)
+	// TouchAPIKey - save time of the last use of key, ErrAPIKeyNotFound if there is no key with id
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
 }
@@ -0,0 +1,170 @@
+package storagetest
+
+// file for conformance tests of user.APIKeyStorage implementations
+
+import (
+	"context"
+	"errors"
+	"project/internal/user"
+	"reflect"
+	"testing"
+)
+
+// NewAPIKeyStorage - func for creating new empty storage of API keys for one test
+type NewAPIKeyStorage func(t *testing.T) user.APIKeyStorage
+
+// RunAPIKeys - run all conformance tests of API keys, every test gets its own storage from newStorage
+func RunAPIKeys(t *testing.T, newStorage NewAPIKeyStorage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s user.APIKeyStorage)
+	}{
+		{"CreateAPIKey", testCreateAPIKey},
+		{"FindAPIKeys", testFindAPIKeys},
+		{"RevokeAPIKey", testRevokeAPIKey},
+		{"TouchAPIKey", testTouchAPIKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+// testCreateAPIKey - created key gets id and is found by hash with all fields, unknown hash is not found
+func testCreateAPIKey(t *testing.T, s user.APIKeyStorage) {
+	ctx := context.Background()
+
+	created := createAPIKey(t, s, "batch", "hash-1", 0)
+	if created.ID == "" {
+		t.Fatal("CreateAPIKey returned key without id")
+	}
+
+	got, err := s.FindAPIKey(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("FindAPIKey error: %v", err)
+	}
+	if !equalAPIKey(got, created) || got.LastUsedAt != nil || got.RevokedAt != nil {
+		t.Errorf("FindAPIKey returned %+v, want %+v", got, created)
+	}
+	if _, err = s.FindAPIKey(ctx, "hash-2"); !errors.Is(err, user.ErrAPIKeyNotFound) {
+		t.Errorf("FindAPIKey with unknown hash error = %v, want %v", err, user.ErrAPIKeyNotFound)
+	}
+}
+
+// testFindAPIKeys - all keys are found, newer keys go first
+func testFindAPIKeys(t *testing.T, s user.APIKeyStorage) {
+	keys, err := s.FindAPIKeys(context.Background())
+	if err != nil || keys == nil || len(keys) != 0 {
+		t.Fatalf("FindAPIKeys of empty storage = %v, %v, want empty list", keys, err)
+	}
+
+	first := createAPIKey(t, s, "first", "hash-1", 1)
+	second := createAPIKey(t, s, "second", "hash-2", 2)
+	third := createAPIKey(t, s, "third", "hash-3", 2)
+
+	if keys, err = s.FindAPIKeys(context.Background()); err != nil {
+		t.Fatalf("FindAPIKeys error: %v", err)
+	}
+	want := []user.APIKey{third, second, first}
+	if len(keys) != len(want) {
+		t.Fatalf("FindAPIKeys returned %d keys, want %d", len(keys), len(want))
+	}
+	for i := range want {
+		if !equalAPIKey(keys[i], want[i]) {
+			t.Errorf("FindAPIKeys()[%d] = %+v, want %+v", i, keys[i], want[i])
+		}
+	}
+}
+
+// testRevokeAPIKey - revoked key is found with time of the first revoke, unknown key is not found
+func testRevokeAPIKey(t *testing.T, s user.APIKeyStorage) {
+	ctx := context.Background()
+	key := createAPIKey(t, s, "batch", "hash-1", 0)
+
+	revoked, err := s.RevokeAPIKey(ctx, key.ID, requestAt(1))
+	if err != nil {
+		t.Fatalf("RevokeAPIKey error: %v", err)
+	}
+	if revoked.RevokedAt == nil || !revoked.RevokedAt.Equal(requestAt(1)) || !equalAPIKey(revoked, key) {
+		t.Errorf("RevokeAPIKey returned %+v, want key revoked at %v", revoked, requestAt(1))
+	}
+	if revoked, err = s.RevokeAPIKey(ctx, key.ID, requestAt(2)); err != nil || revoked.RevokedAt == nil || !revoked.RevokedAt.Equal(requestAt(1)) {
+		t.Errorf("RevokeAPIKey of revoked key = %+v, %v, want key revoked at %v", revoked, err, requestAt(1))
+	}
+	if found := findAPIKey(t, s, "hash-1"); found.RevokedAt == nil || !found.RevokedAt.Equal(requestAt(1)) {
+		t.Errorf("RevokedAt = %v, want %v", found.RevokedAt, requestAt(1))
+	}
+
+	for _, id := range []string{invalidID, otherAPIKeyID(key.ID)} {
+		if _, err = s.RevokeAPIKey(ctx, id, requestAt(2)); !errors.Is(err, user.ErrAPIKeyNotFound) {
+			t.Errorf("RevokeAPIKey(%s) error = %v, want %v", id, err, user.ErrAPIKeyNotFound)
+		}
+	}
+}
+
+// testTouchAPIKey - time of the last use is replaced by every touch, unknown key is not found
+func testTouchAPIKey(t *testing.T, s user.APIKeyStorage) {
+	ctx := context.Background()
+	key := createAPIKey(t, s, "batch", "hash-1", 0)
+
+	for _, step := range []int{1, 2} {
+		if err := s.TouchAPIKey(ctx, key.ID, requestAt(step)); err != nil {
+			t.Fatalf("TouchAPIKey error: %v", err)
+		}
+		if found := findAPIKey(t, s, "hash-1"); found.LastUsedAt == nil || !found.LastUsedAt.Equal(requestAt(step)) {
+			t.Errorf("LastUsedAt = %v, want %v", found.LastUsedAt, requestAt(step))
+		}
+	}
+
+	for _, id := range []string{invalidID, otherAPIKeyID(key.ID)} {
+		if err := s.TouchAPIKey(ctx, id, requestAt(3)); !errors.Is(err, user.ErrAPIKeyNotFound) {
+			t.Errorf("TouchAPIKey(%s) error = %v, want %v", id, err, user.ErrAPIKeyNotFound)
+		}
+	}
+}
+
+// createAPIKey - create key with all scopes and fail test on error
+func createAPIKey(t *testing.T, s user.APIKeyStorage, name, hash string, step int) user.APIKey {
+	t.Helper()
+
+	key, err := s.CreateAPIKey(context.Background(), user.APIKey{
+		Name:      name,
+		Prefix:    "uak_" + name,
+		Hash:      hash,
+		Scopes:    []string{"users:read", "users:write"},
+		CreatedAt: requestAt(step),
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIKey(%s) error: %v", name, err)
+	}
+	return key
+}
+
+// findAPIKey - find key by hash and fail test on error
+func findAPIKey(t *testing.T, s user.APIKeyStorage, hash string) user.APIKey {
+	t.Helper()
+
+	key, err := s.FindAPIKey(context.Background(), hash)
+	if err != nil {
+		t.Fatalf("FindAPIKey(%s) error: %v", hash, err)
+	}
+	return key
+}
+
+// otherAPIKeyID - id in form of storage which differs from id of the only key of storage in its last digit,
+// keys are never deleted, so there is no other way to get unknown id
+func otherAPIKeyID(id string) string {
+	last := id[len(id)-1]
+	if last == '9' || last == 'f' {
+		return id[:len(id)-1] + "8"
+	}
+	return id[:len(id)-1] + string(last+1)
+}
+
+// equalAPIKey - keys have equal fields which are set on creation, times are compared as instants
+func equalAPIKey(a, b user.APIKey) bool {
+	return a.ID == b.ID && a.Name == b.Name && a.Prefix == b.Prefix && a.Hash == b.Hash &&
+		reflect.DeepEqual(a.Scopes, b.Scopes) && a.CreatedAt.Equal(b.CreatedAt)
+}
@@ -0,0 +1,189 @@
+package storagetest
+
+// file for conformance tests of social graph queries, storages are checked through user.Graph
+
+import (
+	"context"
+	"errors"
+	"project/internal/user"
+	"testing"
+)
+
+// RunGraph - run all conformance tests of graph queries, every test gets its own storage from newStorage
+func RunGraph(t *testing.T, newStorage NewStorage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s user.Storage, g user.GraphStorage)
+	}{
+		{"MutualFriends", testMutualFriends},
+		{"Suggestions", testSuggestions},
+		{"SuggestionsLimit", testSuggestionsLimit},
+		{"ShortestPath", testShortestPath},
+		{"ShortestPathNotConnected", testShortestPathNotConnected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStorage(t)
+			tt.test(t, s, user.Graph(s))
+		})
+	}
+}
+
+// testMutualFriends - only friends of both users are returned, unknown users are not found
+func testMutualFriends(t *testing.T, s user.Storage, g user.GraphStorage) {
+	ctx := context.Background()
+
+	first := create(t, s, "first")
+	second := create(t, s, "second")
+	common := create(t, s, "common")
+	own := create(t, s, "own")
+	makeFriends(t, s, first, common)
+	makeFriends(t, s, first, own)
+	makeFriends(t, s, second, common)
+
+	mutual, err := g.MutualFriends(ctx, first, second)
+	if err != nil {
+		t.Fatalf("MutualFriends(%s, %s) error: %v", first, second, err)
+	}
+	if names := friendNames(mutual); !equalOrder(names, []string{"common"}) {
+		t.Fatalf("MutualFriends = %v, want [common]", names)
+	}
+
+	mutual, err = g.MutualFriends(ctx, first, own)
+	if err != nil {
+		t.Fatalf("MutualFriends(%s, %s) error: %v", first, own, err)
+	}
+	if len(mutual) != 0 {
+		t.Fatalf("MutualFriends of users without mutual friends = %v, want none", friendNames(mutual))
+	}
+
+	if _, err = g.MutualFriends(ctx, first, unknownID(t, s)); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("MutualFriends with unknown user error = %v, want %v", err, user.ErrNotFound)
+	}
+}
+
+// testSuggestions - friends of friends are ranked by mutual friends, user and his friends are never suggested
+func testSuggestions(t *testing.T, s user.Storage, g user.GraphStorage) {
+	ctx := context.Background()
+
+	me := create(t, s, "me")
+	first := create(t, s, "first")
+	second := create(t, s, "second")
+	popular := create(t, s, "popular")
+	other := create(t, s, "other")
+	makeFriends(t, s, me, first)
+	makeFriends(t, s, me, second)
+	makeFriends(t, s, first, second)
+	makeFriends(t, s, first, popular)
+	makeFriends(t, s, second, popular)
+	makeFriends(t, s, second, other)
+
+	suggestions, err := g.Suggestions(ctx, me, 0)
+	if err != nil {
+		t.Fatalf("Suggestions(%s) error: %v", me, err)
+	}
+	var names []string
+	var counts []int
+	for _, suggestion := range suggestions {
+		names = append(names, suggestion.Username)
+		counts = append(counts, suggestion.MutualCount)
+	}
+	if !equalOrder(names, []string{"popular", "other"}) || counts[0] != 2 || counts[1] != 1 {
+		t.Fatalf("Suggestions = %v with mutual counts %v, want [popular other] with [2 1]", names, counts)
+	}
+
+	if _, err = g.Suggestions(ctx, unknownID(t, s), 0); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("Suggestions of unknown user error = %v, want %v", err, user.ErrNotFound)
+	}
+}
+
+// testSuggestionsLimit - suggestions with the same mutual count are ordered by id and cut by limit
+func testSuggestionsLimit(t *testing.T, s user.Storage, g user.GraphStorage) {
+	ctx := context.Background()
+
+	me := create(t, s, "me")
+	friend := create(t, s, "friend")
+	makeFriends(t, s, me, friend)
+	for _, username := range []string{"a", "b", "c"} {
+		makeFriends(t, s, friend, create(t, s, username))
+	}
+
+	suggestions, err := g.Suggestions(ctx, me, 2)
+	if err != nil {
+		t.Fatalf("Suggestions(%s, 2) error: %v", me, err)
+	}
+	if names := suggestionNames(suggestions); !equalOrder(names, []string{"a", "b"}) {
+		t.Fatalf("Suggestions with limit 2 = %v, want [a b]", names)
+	}
+}
+
+// testShortestPath - path goes through friends and includes both users, path to user himself is the user
+func testShortestPath(t *testing.T, s user.Storage, g user.GraphStorage) {
+	ctx := context.Background()
+
+	first := create(t, s, "first")
+	second := create(t, s, "second")
+	third := create(t, s, "third")
+	last := create(t, s, "last")
+	makeFriends(t, s, first, second)
+	makeFriends(t, s, second, third)
+	makeFriends(t, s, third, last)
+	// longer way round must not be taken
+	detour := create(t, s, "detour")
+	makeFriends(t, s, first, detour)
+
+	path, err := g.ShortestPath(ctx, first, last, user.MaxPathDepth)
+	if err != nil {
+		t.Fatalf("ShortestPath(%s, %s) error: %v", first, last, err)
+	}
+	if names := friendNames(path); !equalOrder(names, []string{"first", "second", "third", "last"}) {
+		t.Fatalf("ShortestPath = %v, want [first second third last]", names)
+	}
+
+	path, err = g.ShortestPath(ctx, first, first, user.MaxPathDepth)
+	if err != nil {
+		t.Fatalf("ShortestPath(%s, %s) error: %v", first, first, err)
+	}
+	if names := friendNames(path); !equalOrder(names, []string{"first"}) {
+		t.Fatalf("ShortestPath to the same user = %v, want [first]", names)
+	}
+
+	if _, err = g.ShortestPath(ctx, first, last, 2); !errors.Is(err, user.ErrNotConnected) {
+		t.Fatalf("ShortestPath longer than max depth error = %v, want %v", err, user.ErrNotConnected)
+	}
+}
+
+// testShortestPathNotConnected - users in different components are not connected, unknown users are not found
+func testShortestPathNotConnected(t *testing.T, s user.Storage, g user.GraphStorage) {
+	ctx := context.Background()
+
+	first := create(t, s, "first")
+	second := create(t, s, "second")
+	makeFriends(t, s, first, create(t, s, "friend"))
+
+	if _, err := g.ShortestPath(ctx, first, second, user.MaxPathDepth); !errors.Is(err, user.ErrNotConnected) {
+		t.Fatalf("ShortestPath between not connected users error = %v, want %v", err, user.ErrNotConnected)
+	}
+	if _, err := g.ShortestPath(ctx, first, unknownID(t, s), user.MaxPathDepth); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("ShortestPath to unknown user error = %v, want %v", err, user.ErrNotFound)
+	}
+}
+
+// friendNames - usernames of friends in order
+func friendNames(friends []user.Friend) []string {
+	names := make([]string, 0, len(friends))
+	for _, friend := range friends {
+		names = append(names, friend.Username)
+	}
+	return names
+}
+
+// suggestionNames - usernames of suggestions in order
+func suggestionNames(suggestions []user.Suggestion) []string {
+	names := make([]string, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		names = append(names, suggestion.Username)
+	}
+	return names
+}
@@ -0,0 +1,185 @@
+package storagetest
+
+// file for conformance tests of user.RelationStorage implementations
+
+import (
+	"context"
+	"errors"
+	"project/internal/user"
+	"testing"
+	"time"
+)
+
+// NewRelationStorage - func for creating new empty storages of users and their blocks and mutes for one test
+type NewRelationStorage func(t *testing.T) (user.Storage, user.RelationStorage)
+
+// RunRelations - run all conformance tests of blocks and mutes, every test gets its own storages from newStorage
+func RunRelations(t *testing.T, newStorage NewRelationStorage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, users user.Storage, s user.RelationStorage)
+	}{
+		{"SaveRelation", testSaveRelation},
+		{"DeleteRelation", testDeleteRelation},
+		{"FindRelations", testFindRelations},
+		{"DeleteRelations", testDeleteRelations},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, relations := newStorage(t)
+			tt.test(t, users, relations)
+		})
+	}
+}
+
+// testSaveRelation - saved relation is found, saving it again keeps the first one, every kind is kept separately
+func testSaveRelation(t *testing.T, users user.Storage, s user.RelationStorage) {
+	ctx := context.Background()
+
+	alice := create(t, users, "alice")
+	bob := create(t, users, "bob")
+	block := saveRelation(t, s, alice, bob, user.RelationBlock, requestAt(0))
+	want := user.Relation{UserID: alice, TargetID: bob, Kind: user.RelationBlock, CreatedAt: requestAt(0)}
+	if !equalRelation(block, want) {
+		t.Fatalf("SaveRelation returned %+v, want %+v", block, want)
+	}
+
+	again := saveRelation(t, s, alice, bob, user.RelationBlock, requestAt(1))
+	if !equalRelation(again, want) {
+		t.Errorf("SaveRelation of existing relation returned %+v, want %+v", again, want)
+	}
+	saveRelation(t, s, alice, bob, user.RelationMute, requestAt(2))
+
+	relations, err := s.FindRelations(ctx, user.RelationFilter{UserID: alice})
+	if err != nil {
+		t.Fatalf("FindRelations(%s) error: %v", alice, err)
+	}
+	if kinds := relationKinds(relations); !equalOrder(kinds, []string{user.RelationMute, user.RelationBlock}) {
+		t.Errorf("kinds of relations of %s = %v, want [mute block]", alice, kinds)
+	}
+}
+
+// testDeleteRelation - only relation of kind is deleted, unknown relation is not found
+func testDeleteRelation(t *testing.T, users user.Storage, s user.RelationStorage) {
+	ctx := context.Background()
+
+	alice := create(t, users, "alice")
+	bob := create(t, users, "bob")
+	saveRelation(t, s, alice, bob, user.RelationBlock, requestAt(0))
+	saveRelation(t, s, alice, bob, user.RelationMute, requestAt(1))
+
+	if err := s.DeleteRelation(ctx, alice, bob, user.RelationBlock); err != nil {
+		t.Fatalf("DeleteRelation(%s, %s) error: %v", alice, bob, err)
+	}
+	if err := s.DeleteRelation(ctx, alice, bob, user.RelationBlock); !errors.Is(err, user.ErrRelationNotFound) {
+		t.Errorf("DeleteRelation of deleted relation error = %v, want %v", err, user.ErrRelationNotFound)
+	}
+	if err := s.DeleteRelation(ctx, bob, alice, user.RelationMute); !errors.Is(err, user.ErrRelationNotFound) {
+		t.Errorf("DeleteRelation in other direction error = %v, want %v", err, user.ErrRelationNotFound)
+	}
+
+	relations, err := s.FindRelations(ctx, user.RelationFilter{UserID: alice})
+	if err != nil {
+		t.Fatalf("FindRelations(%s) error: %v", alice, err)
+	}
+	if kinds := relationKinds(relations); !equalOrder(kinds, []string{user.RelationMute}) {
+		t.Errorf("kinds of relations of %s = %v, want [mute]", alice, kinds)
+	}
+}
+
+// testFindRelations - relations are filtered by user, target and kind, newer relations go first
+func testFindRelations(t *testing.T, users user.Storage, s user.RelationStorage) {
+	ctx := context.Background()
+
+	alice := create(t, users, "alice")
+	bob := create(t, users, "bob")
+	carol := create(t, users, "carol")
+	saveRelation(t, s, alice, bob, user.RelationBlock, requestAt(0))
+	saveRelation(t, s, carol, bob, user.RelationBlock, requestAt(1))
+	saveRelation(t, s, alice, carol, user.RelationMute, requestAt(2))
+
+	tests := []struct {
+		filter user.RelationFilter
+		want   []string
+	}{
+		{user.RelationFilter{UserID: alice}, []string{carol, bob}},
+		{user.RelationFilter{UserID: alice, Kind: user.RelationBlock}, []string{bob}},
+		{user.RelationFilter{TargetID: bob}, []string{bob, bob}},
+		{user.RelationFilter{UserID: bob}, []string{}},
+		{user.RelationFilter{UserID: carol, TargetID: bob, Kind: user.RelationBlock}, []string{bob}},
+		{user.RelationFilter{Kind: user.RelationMute}, []string{carol}},
+	}
+	for _, tt := range tests {
+		relations, err := s.FindRelations(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("FindRelations(%+v) error: %v", tt.filter, err)
+		}
+		if relations == nil {
+			t.Errorf("FindRelations(%+v) returned nil, want empty slice", tt.filter)
+		}
+		targets := make([]string, 0, len(relations))
+		for _, r := range relations {
+			targets = append(targets, r.TargetID)
+		}
+		if !equalOrder(targets, tt.want) {
+			t.Errorf("targets of FindRelations(%+v) = %v, want %v", tt.filter, targets, tt.want)
+		}
+	}
+
+	relations, err := s.FindRelations(ctx, user.RelationFilter{TargetID: bob})
+	if err != nil {
+		t.Fatalf("FindRelations(%s) error: %v", bob, err)
+	}
+	if len(relations) != 2 || relations[0].UserID != carol || relations[1].UserID != alice {
+		t.Errorf("FindRelations of target %s = %+v, want relations of %s and %s", bob, relations, carol, alice)
+	}
+}
+
+// testDeleteRelations - relations from and to user are deleted, others are kept
+func testDeleteRelations(t *testing.T, users user.Storage, s user.RelationStorage) {
+	ctx := context.Background()
+
+	alice := create(t, users, "alice")
+	bob := create(t, users, "bob")
+	carol := create(t, users, "carol")
+	saveRelation(t, s, alice, bob, user.RelationBlock, requestAt(0))
+	saveRelation(t, s, carol, alice, user.RelationMute, requestAt(1))
+	saveRelation(t, s, bob, carol, user.RelationBlock, requestAt(2))
+
+	if err := s.DeleteRelations(ctx, alice); err != nil {
+		t.Fatalf("DeleteRelations(%s) error: %v", alice, err)
+	}
+	relations, err := s.FindRelations(ctx, user.RelationFilter{})
+	if err != nil {
+		t.Fatalf("FindRelations error: %v", err)
+	}
+	if len(relations) != 1 || relations[0].UserID != bob || relations[0].TargetID != carol {
+		t.Errorf("relations after DeleteRelations(%s) = %+v, want only block of %s by %s", alice, relations, carol, bob)
+	}
+}
+
+// saveRelation - save relation of kind and fail test on error
+func saveRelation(t *testing.T, s user.RelationStorage, userID, targetID, kind string, at time.Time) user.Relation {
+	t.Helper()
+
+	r, err := s.SaveRelation(context.Background(), user.Relation{UserID: userID, TargetID: targetID, Kind: kind, CreatedAt: at})
+	if err != nil {
+		t.Fatalf("SaveRelation(%s, %s, %s) error: %v", userID, targetID, kind, err)
+	}
+	return r
+}
+
+// equalRelation - compare relations, time is compared as instant
+func equalRelation(a, b user.Relation) bool {
+	return a.UserID == b.UserID && a.TargetID == b.TargetID && a.Kind == b.Kind && a.CreatedAt.Equal(b.CreatedAt)
+}
+
+// relationKinds - kinds of relations in order
+func relationKinds(relations []user.Relation) []string {
+	kinds := make([]string, 0, len(relations))
+	for _, r := range relations {
+		kinds = append(kinds, r.Kind)
+	}
+	return kinds
+}
@@ -0,0 +1,202 @@
+package storagetest
+
+// file for conformance tests of user.RequestStorage implementations
+
+import (
+	"context"
+	"errors"
+	"project/internal/user"
+	"testing"
+	"time"
+)
+
+// NewRequestStorage - func for creating new empty storages of users and their friend requests for one test
+type NewRequestStorage func(t *testing.T) (user.Storage, user.RequestStorage)
+
+// RunRequests - run all conformance tests of friend requests, every test gets its own storages from newStorage
+func RunRequests(t *testing.T, newStorage NewRequestStorage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, users user.Storage, s user.RequestStorage)
+	}{
+		{"CreateRequest", testCreateRequest},
+		{"CreateRequestPendingPair", testCreateRequestPendingPair},
+		{"FindRequests", testFindRequests},
+		{"UpdateRequestStatus", testUpdateRequestStatus},
+		{"DeleteRequests", testDeleteRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, requests := newStorage(t)
+			tt.test(t, users, requests)
+		})
+	}
+}
+
+// testCreateRequest - created request gets id and is found by it, unknown and invalid ids are not found
+func testCreateRequest(t *testing.T, users user.Storage, s user.RequestStorage) {
+	ctx := context.Background()
+
+	alice := create(t, users, "alice")
+	bob := create(t, users, "bob")
+	created := createRequest(t, s, alice, bob, requestAt(0))
+	if created.ID == "" || created.FromID != alice || created.ToID != bob || created.Status != user.RequestPending {
+		t.Fatalf("CreateRequest returned %+v, want pending request from %s to %s with id", created, alice, bob)
+	}
+
+	found, err := s.FindRequest(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("FindRequest(%s) error: %v", created.ID, err)
+	}
+	if found != created {
+		t.Errorf("FindRequest(%s) = %+v, want %+v", created.ID, found, created)
+	}
+
+	if _, err = s.FindRequest(ctx, invalidID); !errors.Is(err, user.ErrRequestNotFound) {
+		t.Errorf("FindRequest with invalid id error = %v, want %v", err, user.ErrRequestNotFound)
+	}
+}
+
+// testCreateRequestPendingPair - only one pending request between users in any direction, finished requests don't count
+func testCreateRequestPendingPair(t *testing.T, users user.Storage, s user.RequestStorage) {
+	ctx := context.Background()
+
+	alice := create(t, users, "alice")
+	bob := create(t, users, "bob")
+	carol := create(t, users, "carol")
+	first := createRequest(t, s, alice, bob, requestAt(0))
+
+	for _, pair := range [][2]string{{alice, bob}, {bob, alice}} {
+		_, err := s.CreateRequest(ctx, pendingRequest(pair[0], pair[1], requestAt(1)))
+		if !errors.Is(err, user.ErrRequestExists) {
+			t.Errorf("CreateRequest(%s, %s) with pending request error = %v, want %v", pair[0], pair[1], err, user.ErrRequestExists)
+		}
+	}
+	createRequest(t, s, alice, carol, requestAt(1))
+
+	// declined request doesn't prevent new one
+	if _, err := s.UpdateRequestStatus(ctx, first.ID, user.RequestPending, user.RequestDeclined, requestAt(2)); err != nil {
+		t.Fatalf("UpdateRequestStatus(%s) error: %v", first.ID, err)
+	}
+	createRequest(t, s, bob, alice, requestAt(3))
+}
+
+// testFindRequests - requests are filtered by sender, recipient and status, newer requests go first
+func testFindRequests(t *testing.T, users user.Storage, s user.RequestStorage) {
+	ctx := context.Background()
+
+	alice := create(t, users, "alice")
+	bob := create(t, users, "bob")
+	carol := create(t, users, "carol")
+	fromBob := createRequest(t, s, bob, alice, requestAt(0))
+	fromCarol := createRequest(t, s, carol, alice, requestAt(1))
+	toBob := createRequest(t, s, carol, bob, requestAt(2))
+	if _, err := s.UpdateRequestStatus(ctx, fromBob.ID, user.RequestPending, user.RequestAccepted, requestAt(3)); err != nil {
+		t.Fatalf("UpdateRequestStatus(%s) error: %v", fromBob.ID, err)
+	}
+
+	tests := []struct {
+		filter user.RequestFilter
+		want   []string
+	}{
+		{user.RequestFilter{ToID: alice}, []string{fromCarol.ID, fromBob.ID}},
+		{user.RequestFilter{ToID: alice, Status: user.RequestPending}, []string{fromCarol.ID}},
+		{user.RequestFilter{FromID: carol}, []string{toBob.ID, fromCarol.ID}},
+		{user.RequestFilter{FromID: alice}, []string{}},
+		{user.RequestFilter{Status: user.RequestAccepted}, []string{fromBob.ID}},
+	}
+	for _, tt := range tests {
+		requests, err := s.FindRequests(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("FindRequests(%+v) error: %v", tt.filter, err)
+		}
+		if requests == nil {
+			t.Errorf("FindRequests(%+v) returned nil, want empty slice", tt.filter)
+		}
+		ids := make([]string, 0, len(requests))
+		for _, r := range requests {
+			ids = append(ids, r.ID)
+		}
+		if !equalOrder(ids, tt.want) {
+			t.Errorf("FindRequests(%+v) = %v, want %v", tt.filter, ids, tt.want)
+		}
+	}
+}
+
+// testUpdateRequestStatus - status is changed only from expected status
+func testUpdateRequestStatus(t *testing.T, users user.Storage, s user.RequestStorage) {
+	ctx := context.Background()
+
+	alice := create(t, users, "alice")
+	bob := create(t, users, "bob")
+	r := createRequest(t, s, alice, bob, requestAt(0))
+
+	updated, err := s.UpdateRequestStatus(ctx, r.ID, user.RequestPending, user.RequestCanceled, requestAt(1))
+	if err != nil {
+		t.Fatalf("UpdateRequestStatus(%s) error: %v", r.ID, err)
+	}
+	if updated.ID != r.ID || updated.Status != user.RequestCanceled || !updated.UpdatedAt.Equal(requestAt(1)) || !updated.CreatedAt.Equal(r.CreatedAt) {
+		t.Errorf("UpdateRequestStatus returned %+v, want canceled request %s updated at %v", updated, r.ID, requestAt(1))
+	}
+
+	if _, err = s.UpdateRequestStatus(ctx, r.ID, user.RequestPending, user.RequestAccepted, requestAt(2)); !errors.Is(err, user.ErrRequestState) {
+		t.Errorf("UpdateRequestStatus of canceled request error = %v, want %v", err, user.ErrRequestState)
+	}
+	found, err := s.FindRequest(ctx, r.ID)
+	if err != nil {
+		t.Fatalf("FindRequest(%s) error: %v", r.ID, err)
+	}
+	if found.Status != user.RequestCanceled {
+		t.Errorf("status after failed update = %s, want %s", found.Status, user.RequestCanceled)
+	}
+
+	if _, err = s.UpdateRequestStatus(ctx, invalidID, user.RequestPending, user.RequestAccepted, requestAt(2)); !errors.Is(err, user.ErrRequestNotFound) {
+		t.Errorf("UpdateRequestStatus with invalid id error = %v, want %v", err, user.ErrRequestNotFound)
+	}
+}
+
+// testDeleteRequests - requests from and to user are deleted, others are kept
+func testDeleteRequests(t *testing.T, users user.Storage, s user.RequestStorage) {
+	ctx := context.Background()
+
+	alice := create(t, users, "alice")
+	bob := create(t, users, "bob")
+	carol := create(t, users, "carol")
+	fromAlice := createRequest(t, s, alice, bob, requestAt(0))
+	toAlice := createRequest(t, s, carol, alice, requestAt(1))
+	other := createRequest(t, s, bob, carol, requestAt(2))
+
+	if err := s.DeleteRequests(ctx, alice); err != nil {
+		t.Fatalf("DeleteRequests(%s) error: %v", alice, err)
+	}
+	for _, id := range []string{fromAlice.ID, toAlice.ID} {
+		if _, err := s.FindRequest(ctx, id); !errors.Is(err, user.ErrRequestNotFound) {
+			t.Errorf("FindRequest of deleted request %s error = %v, want %v", id, err, user.ErrRequestNotFound)
+		}
+	}
+	if _, err := s.FindRequest(ctx, other.ID); err != nil {
+		t.Errorf("FindRequest(%s) of other users error: %v", other.ID, err)
+	}
+}
+
+// createRequest - create pending request and fail test on error
+func createRequest(t *testing.T, s user.RequestStorage, fromID, toID string, at time.Time) user.FriendRequest {
+	t.Helper()
+
+	r, err := s.CreateRequest(context.Background(), pendingRequest(fromID, toID, at))
+	if err != nil {
+		t.Fatalf("CreateRequest(%s, %s) error: %v", fromID, toID, err)
+	}
+	return r
+}
+
+// pendingRequest - new pending request created at time
+func pendingRequest(fromID, toID string, at time.Time) user.FriendRequest {
+	return user.FriendRequest{FromID: fromID, ToID: toID, Status: user.RequestPending, CreatedAt: at, UpdatedAt: at}
+}
+
+// requestAt - time of request in precision of all storages, every minute is one step of test
+func requestAt(step int) time.Time {
+	return time.Date(2022, 1, 1, 0, step, 0, 0, time.UTC)
+}
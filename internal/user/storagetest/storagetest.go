@@ -0,0 +1,718 @@
+// Package storagetest - conformance tests which every user.Storage and user.RequestStorage implementation must pass
+//
+// Storage package runs the suite against itself from its own test:
+//
+//	func TestStorage(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) user.Storage {
+//			return memory.NewStorage(logging.GetLogger())
+//		})
+//	}
+//
+// Friend request, relation, refresh token and API key storages are checked the same way with RunRequests, RunRelations,
+// RunTokens and RunAPIKeys,
+// graph queries with RunGraph.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"project/internal/user"
+	"sort"
+	"testing"
+)
+
+// invalidID - id which can't be produced by any storage
+const invalidID = "not-an-id"
+
+// NewStorage - func for creating new empty storage for one test
+type NewStorage func(t *testing.T) user.Storage
+
+// Run - run all conformance tests, every test gets its own storage from newStorage
+func Run(t *testing.T, newStorage NewStorage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s user.Storage)
+	}{
+		{"Create", testCreate},
+		{"Credentials", testCredentials},
+		{"FindOne", testFindOne},
+		{"FindAllFilter", testFindAllFilter},
+		{"FindAllSort", testFindAllSort},
+		{"FindAllPage", testFindAllPage},
+		{"GetUserFriends", testGetUserFriends},
+		{"UpdateAge", testUpdateAge},
+		{"Update", testUpdate},
+		{"UpdateRename", testUpdateRename},
+		{"Delete", testDelete},
+		{"DeleteCascade", testDeleteCascade},
+		{"Restore", testRestore},
+		{"FindDeleted", testFindDeleted},
+		{"Purge", testPurge},
+		{"MakeFriends", testMakeFriends},
+		{"MakeFriendsIdempotent", testMakeFriendsIdempotent},
+		{"MakeFriendsUnknown", testMakeFriendsUnknown},
+		{"FriendsByID", testFriendsByID},
+		{"Unfriend", testUnfriend},
+		{"UnfriendUnknown", testUnfriendUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+// testCreate - created users get unique ids and no friends
+func testCreate(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	first := create(t, s, "first")
+	second := create(t, s, "second")
+	if first == "" || second == "" {
+		t.Fatalf("Create returned empty id: %q, %q", first, second)
+	}
+	if first == second {
+		t.Fatalf("Create returned the same id %q for two users", first)
+	}
+
+	friends, err := s.GetUserFriends(ctx, first)
+	if err != nil {
+		t.Fatalf("GetUserFriends(%s) error: %v", first, err)
+	}
+	if len(friends) != 0 {
+		t.Fatalf("new user has friends: %v", friends)
+	}
+}
+
+// testCredentials - user is found by email with credentials, email is unique among all users, deleted ones included
+func testCredentials(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	id, err := s.Create(ctx, user.User{Username: "alice", Email: "alice@example.com", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Create with email error: %v", err)
+	}
+	friend := create(t, s, "friend")
+	makeFriends(t, s, id, friend)
+	if _, err = s.Update(ctx, id, user.User{Username: "alicia", Age: "30"}); err != nil {
+		t.Fatalf("Update(%s) error: %v", id, err)
+	}
+
+	u, err := s.FindByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("FindByEmail error: %v", err)
+	}
+	if u.ID != id || u.Username != "alicia" || u.Email != "alice@example.com" || u.PasswordHash != "hash" || !equalUnordered(u.Friends, friend) {
+		t.Errorf("FindByEmail returned %+v, want updated user %s with credentials and friend %s", u, id, friend)
+	}
+	if _, err = s.FindByEmail(ctx, "bob@example.com"); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("FindByEmail with unknown email error = %v, want %v", err, user.ErrNotFound)
+	}
+	if _, err = s.FindByEmail(ctx, ""); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("FindByEmail with empty email error = %v, want %v", err, user.ErrNotFound)
+	}
+
+	// users without email don't take empty email
+	create(t, s, "bob")
+	if _, err = s.Create(ctx, user.User{Username: "alice2", Email: "alice@example.com", PasswordHash: "other"}); !errors.Is(err, user.ErrEmailExists) {
+		t.Errorf("Create with taken email error = %v, want %v", err, user.ErrEmailExists)
+	}
+
+	if err = s.Delete(ctx, id, requestAt(0)); err != nil {
+		t.Fatalf("Delete(%s) error: %v", id, err)
+	}
+	if _, err = s.FindByEmail(ctx, "alice@example.com"); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("FindByEmail of deleted user error = %v, want %v", err, user.ErrNotFound)
+	}
+	if _, err = s.Create(ctx, user.User{Username: "alice2", Email: "alice@example.com", PasswordHash: "other"}); !errors.Is(err, user.ErrEmailExists) {
+		t.Errorf("Create with email of deleted user error = %v, want %v", err, user.ErrEmailExists)
+	}
+
+	// email is free after purge
+	if err = s.Purge(ctx, id); err != nil {
+		t.Fatalf("Purge(%s) error: %v", id, err)
+	}
+	if _, err = s.Create(ctx, user.User{Username: "alice2", Email: "alice@example.com", PasswordHash: "other"}); err != nil {
+		t.Errorf("Create with email of purged user error: %v", err)
+	}
+}
+
+// testFindOne - created user is found by id, unknown and invalid ids are errors
+func testFindOne(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	id := create(t, s, "user")
+	u, err := s.FindOne(ctx, id)
+	if err != nil {
+		t.Fatalf("FindOne(%s) error: %v", id, err)
+	}
+	if u.ID != id || u.Username != "user" || u.Age != "20" || len(u.Friends) != 0 {
+		t.Errorf("FindOne(%s) = %+v, want user with age 20 and without friends", id, u)
+	}
+
+	if _, err = s.FindOne(ctx, unknownID(t, s)); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("FindOne with unknown id error = %v, want %v", err, user.ErrNotFound)
+	}
+	if _, err = s.FindOne(ctx, invalidID); !errors.Is(err, user.ErrInvalidID) {
+		t.Errorf("FindOne with invalid id error = %v, want %v", err, user.ErrInvalidID)
+	}
+}
+
+// testFindAllFilter - users are filtered by ids, username prefix and age range, not numeric age is out of any range
+func testFindAllFilter(t *testing.T, s user.Storage) {
+	createWithAge(t, s, "alice", "17")
+	alina := createWithAge(t, s, "alina", "30")
+	createWithAge(t, s, "albert", "unknown")
+	bob := createWithAge(t, s, "bob", "25")
+	createWithAge(t, s, "al%_", "40")
+
+	min, max := 18, 35
+	tests := []struct {
+		name   string
+		filter user.Filter
+		want   []string
+	}{
+		{"all", user.Filter{}, []string{"alice", "alina", "albert", "bob", "al%_"}},
+		{"prefix", user.Filter{UsernamePrefix: "ali"}, []string{"alice", "alina"}},
+		{"prefix with special characters", user.Filter{UsernamePrefix: "al%"}, []string{"al%_"}},
+		{"min age", user.Filter{MinAge: &min}, []string{"alina", "bob", "al%_"}},
+		{"max age", user.Filter{MaxAge: &max}, []string{"alice", "alina", "bob"}},
+		{"age range", user.Filter{MinAge: &min, MaxAge: &max}, []string{"alina", "bob"}},
+		{"prefix and age range", user.Filter{UsernamePrefix: "al", MinAge: &min, MaxAge: &max}, []string{"alina"}},
+		{"ids", user.Filter{IDs: []string{bob, alina, unknownID(t, s), invalidID}}, []string{"alina", "bob"}},
+		{"ids and prefix", user.Filter{IDs: []string{bob, alina}, UsernamePrefix: "al"}, []string{"alina"}},
+		{"no ids", user.Filter{IDs: []string{}}, []string{}},
+	}
+	for _, tt := range tests {
+		users, total := findAll(t, s, tt.filter)
+		if !equalUnordered(usernames(users), tt.want...) || total != int64(len(tt.want)) {
+			t.Errorf("%s: FindAll = %v (total %d), want %v", tt.name, usernames(users), total, tt.want)
+		}
+	}
+}
+
+// testFindAllSort - users are sorted by field, equal fields are sorted by id in the same direction
+func testFindAllSort(t *testing.T, s user.Storage) {
+	createWithAge(t, s, "carol", "9")
+	createWithAge(t, s, "alice", "30")
+	createWithAge(t, s, "bob", "100")
+	createWithAge(t, s, "dave", "30")
+	createWithAge(t, s, "eve", "none")
+
+	tests := []struct {
+		filter user.Filter
+		want   []string
+	}{
+		{user.Filter{}, []string{"carol", "alice", "bob", "dave", "eve"}},
+		{user.Filter{SortBy: user.SortByID, Desc: true}, []string{"eve", "dave", "bob", "alice", "carol"}},
+		{user.Filter{SortBy: user.SortByUsername}, []string{"alice", "bob", "carol", "dave", "eve"}},
+		{user.Filter{SortBy: user.SortByAge}, []string{"eve", "carol", "alice", "dave", "bob"}},
+		{user.Filter{SortBy: user.SortByAge, Desc: true}, []string{"bob", "dave", "alice", "carol", "eve"}},
+	}
+	for _, tt := range tests {
+		users, _ := findAll(t, s, tt.filter)
+		if got := usernames(users); !equalOrder(got, tt.want) {
+			t.Errorf("FindAll(sort %q, desc %v) = %v, want %v", tt.filter.SortBy, tt.filter.Desc, got, tt.want)
+		}
+	}
+}
+
+// testFindAllPage - limit and offset select page, total counts all matched users
+func testFindAllPage(t *testing.T, s user.Storage) {
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		create(t, s, name)
+	}
+
+	tests := []struct {
+		limit, offset int
+		want          []string
+	}{
+		{2, 0, []string{"a", "b"}},
+		{2, 2, []string{"c", "d"}},
+		{2, 4, []string{"e"}},
+		{2, 10, []string{}},
+		{0, 3, []string{"d", "e"}},
+	}
+	for _, tt := range tests {
+		users, total := findAll(t, s, user.Filter{SortBy: user.SortByUsername, Limit: tt.limit, Offset: tt.offset})
+		if got := usernames(users); !equalOrder(got, tt.want) || total != 5 {
+			t.Errorf("FindAll(limit %d, offset %d) = %v (total %d), want %v (total 5)", tt.limit, tt.offset, got, total, tt.want)
+		}
+	}
+}
+
+// testGetUserFriends - unknown and invalid ids are errors, not empty friends
+func testGetUserFriends(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	if _, err := s.GetUserFriends(ctx, unknownID(t, s)); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("GetUserFriends with unknown id error = %v, want %v", err, user.ErrNotFound)
+	}
+	if _, err := s.GetUserFriends(ctx, invalidID); !errors.Is(err, user.ErrInvalidID) {
+		t.Errorf("GetUserFriends with invalid id error = %v, want %v", err, user.ErrInvalidID)
+	}
+}
+
+// testUpdateAge - age of existing user is updated and returned, unknown and invalid ids are errors
+func testUpdateAge(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	id := create(t, s, "user")
+	u, err := s.UpdateAge(ctx, id, "42")
+	if err != nil {
+		t.Fatalf("UpdateAge(%s) error: %v", id, err)
+	}
+	if u.ID != id || u.Username != "user" || u.Age != "42" {
+		t.Errorf("UpdateAge returned %+v, want user with id %s and age 42", u, id)
+	}
+	if _, err = s.UpdateAge(ctx, unknownID(t, s), "42"); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("UpdateAge with unknown id error = %v, want %v", err, user.ErrNotFound)
+	}
+	if _, err = s.UpdateAge(ctx, invalidID, "42"); !errors.Is(err, user.ErrInvalidID) {
+		t.Errorf("UpdateAge with invalid id error = %v, want %v", err, user.ErrInvalidID)
+	}
+}
+
+// testUpdate - username and age of existing user are replaced, friends are kept, unknown and invalid ids are errors
+func testUpdate(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	id := create(t, s, "user")
+	friend := create(t, s, "friend")
+	makeFriends(t, s, id, friend)
+
+	u, err := s.Update(ctx, id, user.User{Username: "user", Age: "42"})
+	if err != nil {
+		t.Fatalf("Update(%s) error: %v", id, err)
+	}
+	if u.ID != id || u.Username != "user" || u.Age != "42" || !equalUnordered(u.Friends, friend) {
+		t.Errorf("Update returned %+v, want user with id %s, age 42 and friend %s", u, id, friend)
+	}
+	if _, err = s.Update(ctx, unknownID(t, s), user.User{Username: "user"}); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("Update with unknown id error = %v, want %v", err, user.ErrNotFound)
+	}
+	if _, err = s.Update(ctx, invalidID, user.User{Username: "user"}); !errors.Is(err, user.ErrInvalidID) {
+		t.Errorf("Update with invalid id error = %v, want %v", err, user.ErrInvalidID)
+	}
+}
+
+// testUpdateRename - friends of other users are resolved to new username
+func testUpdateRename(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	alice := create(t, s, "alice")
+	bob := create(t, s, "bob")
+	carol := create(t, s, "carol")
+	makeFriends(t, s, alice, bob)
+	makeFriends(t, s, alice, carol)
+
+	u, err := s.Update(ctx, alice, user.User{Username: "alicia", Age: "20"})
+	if err != nil {
+		t.Fatalf("Update(%s) error: %v", alice, err)
+	}
+	if u.Username != "alicia" {
+		t.Errorf("Update returned username %s, want alicia", u.Username)
+	}
+
+	assertFriends(t, s, alice, "bob", "carol")
+	assertFriends(t, s, bob, "alicia")
+	assertFriends(t, s, carol, "alicia")
+	if _, _, err = s.MakeFriends(ctx, alice, bob); !errors.Is(err, user.ErrAlreadyFriends) {
+		t.Errorf("MakeFriends after rename error = %v, want %v", err, user.ErrAlreadyFriends)
+	}
+}
+
+// testDelete - soft deleted user can't be found or deleted again
+func testDelete(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	id := create(t, s, "user")
+	if err := s.Delete(ctx, id, requestAt(0)); err != nil {
+		t.Fatalf("Delete(%s) error: %v", id, err)
+	}
+	if _, err := s.GetUserFriends(ctx, id); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("GetUserFriends of deleted user error = %v, want %v", err, user.ErrNotFound)
+	}
+	if err := s.Delete(ctx, id, requestAt(0)); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("second Delete of the same user error = %v, want %v", err, user.ErrNotFound)
+	}
+	if err := s.Delete(ctx, invalidID, requestAt(0)); !errors.Is(err, user.ErrInvalidID) {
+		t.Errorf("Delete with invalid id error = %v, want %v", err, user.ErrInvalidID)
+	}
+}
+
+// testDeleteCascade - deleted user disappears from friends of all other users
+func testDeleteCascade(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	alice := create(t, s, "alice")
+	bob := create(t, s, "bob")
+	carol := create(t, s, "carol")
+	makeFriends(t, s, alice, bob)
+	makeFriends(t, s, alice, carol)
+	makeFriends(t, s, bob, carol)
+
+	if err := s.Delete(ctx, bob, requestAt(0)); err != nil {
+		t.Fatalf("Delete(%s) error: %v", bob, err)
+	}
+
+	assertFriends(t, s, alice, "carol")
+	assertFriends(t, s, carol, "alice")
+}
+
+// testRestore - restored user comes back with his friendships
+func testRestore(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	alice := create(t, s, "alice")
+	bob := create(t, s, "bob")
+	makeFriends(t, s, alice, bob)
+
+	if _, err := s.Restore(ctx, bob); !errors.Is(err, user.ErrNotDeleted) {
+		t.Errorf("Restore of not deleted user error = %v, want %v", err, user.ErrNotDeleted)
+	}
+	if err := s.Delete(ctx, bob, requestAt(0)); err != nil {
+		t.Fatalf("Delete(%s) error: %v", bob, err)
+	}
+	restored, err := s.Restore(ctx, bob)
+	if err != nil {
+		t.Fatalf("Restore(%s) error: %v", bob, err)
+	}
+	if restored.Username != "bob" || restored.DeletedAt != nil {
+		t.Errorf("Restore(%s) = %+v, want active bob", bob, restored)
+	}
+	if !equalUnordered(restored.Friends, alice) {
+		t.Errorf("friends of restored user = %v, want [%s]", restored.Friends, alice)
+	}
+	assertFriends(t, s, alice, "bob")
+	assertFriends(t, s, bob, "alice")
+
+	if _, err := s.Restore(ctx, unknownID(t, s)); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("Restore of unknown user error = %v, want %v", err, user.ErrNotFound)
+	}
+}
+
+// testFindDeleted - only users deleted before the given time are found
+func testFindDeleted(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	create(t, s, "alice")
+	bob := create(t, s, "bob")
+	carol := create(t, s, "carol")
+	if err := s.Delete(ctx, bob, requestAt(1)); err != nil {
+		t.Fatalf("Delete(%s) error: %v", bob, err)
+	}
+	if err := s.Delete(ctx, carol, requestAt(3)); err != nil {
+		t.Fatalf("Delete(%s) error: %v", carol, err)
+	}
+
+	ids, err := s.FindDeleted(ctx, requestAt(2))
+	if err != nil {
+		t.Fatalf("FindDeleted error: %v", err)
+	}
+	if !equalUnordered(ids, bob) {
+		t.Errorf("FindDeleted(%v) = %v, want [%s]", requestAt(2), ids, bob)
+	}
+	ids, err = s.FindDeleted(ctx, requestAt(4))
+	if err != nil {
+		t.Fatalf("FindDeleted error: %v", err)
+	}
+	if !equalUnordered(ids, bob, carol) {
+		t.Errorf("FindDeleted(%v) = %v, want [%s %s]", requestAt(4), ids, bob, carol)
+	}
+}
+
+// testPurge - only deleted user can be purged, purged user can't be restored
+func testPurge(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	alice := create(t, s, "alice")
+	bob := create(t, s, "bob")
+	makeFriends(t, s, alice, bob)
+
+	if err := s.Purge(ctx, bob); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("Purge of not deleted user error = %v, want %v", err, user.ErrNotFound)
+	}
+	if err := s.Delete(ctx, bob, requestAt(0)); err != nil {
+		t.Fatalf("Delete(%s) error: %v", bob, err)
+	}
+	if err := s.Purge(ctx, bob); err != nil {
+		t.Fatalf("Purge(%s) error: %v", bob, err)
+	}
+
+	if _, err := s.Restore(ctx, bob); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("Restore of purged user error = %v, want %v", err, user.ErrNotFound)
+	}
+	a, err := s.FindOne(ctx, alice)
+	if err != nil {
+		t.Fatalf("FindOne(%s) error: %v", alice, err)
+	}
+	if len(a.Friends) != 0 {
+		t.Errorf("friends after purge = %v, want none", a.Friends)
+	}
+	ids, err := s.FindDeleted(ctx, requestAt(1))
+	if err != nil {
+		t.Fatalf("FindDeleted error: %v", err)
+	}
+	if len(ids) != 0 {
+		t.Errorf("FindDeleted after purge = %v, want none", ids)
+	}
+}
+
+// testMakeFriends - friendship is visible from both users and returned users
+func testMakeFriends(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	alice := create(t, s, "alice")
+	bob := create(t, s, "bob")
+	create(t, s, "carol")
+
+	first, second, err := s.MakeFriends(ctx, alice, bob)
+	if err != nil {
+		t.Fatalf("MakeFriends(%s, %s) error: %v", alice, bob, err)
+	}
+	if first.ID != alice || first.Username != "alice" || !equalUnordered(first.Friends, bob) {
+		t.Errorf("MakeFriends returned first user %+v, want alice with id %s and friend %s", first, alice, bob)
+	}
+	if second.ID != bob || second.Username != "bob" || !equalUnordered(second.Friends, alice) {
+		t.Errorf("MakeFriends returned second user %+v, want bob with id %s and friend %s", second, bob, alice)
+	}
+
+	assertFriends(t, s, alice, "bob")
+	assertFriends(t, s, bob, "alice")
+}
+
+// testMakeFriendsIdempotent - repeated friendship is a conflict in any direction and doesn't duplicate friends
+func testMakeFriendsIdempotent(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	alice := create(t, s, "alice")
+	bob := create(t, s, "bob")
+	makeFriends(t, s, alice, bob)
+
+	if _, _, err := s.MakeFriends(ctx, alice, bob); !errors.Is(err, user.ErrAlreadyFriends) {
+		t.Errorf("repeated MakeFriends error = %v, want %v", err, user.ErrAlreadyFriends)
+	}
+	if _, _, err := s.MakeFriends(ctx, bob, alice); !errors.Is(err, user.ErrAlreadyFriends) {
+		t.Errorf("reversed MakeFriends error = %v, want %v", err, user.ErrAlreadyFriends)
+	}
+
+	assertFriends(t, s, alice, "bob")
+	assertFriends(t, s, bob, "alice")
+}
+
+// testMakeFriendsUnknown - friendship with unknown or invalid user is an error and changes nothing
+func testMakeFriendsUnknown(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	alice := create(t, s, "alice")
+	unknown := unknownID(t, s)
+
+	if _, _, err := s.MakeFriends(ctx, alice, unknown); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("MakeFriends with unknown second user error = %v, want %v", err, user.ErrNotFound)
+	}
+	if _, _, err := s.MakeFriends(ctx, unknown, alice); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("MakeFriends with unknown first user error = %v, want %v", err, user.ErrNotFound)
+	}
+	if _, _, err := s.MakeFriends(ctx, alice, invalidID); !errors.Is(err, user.ErrInvalidID) {
+		t.Errorf("MakeFriends with invalid id error = %v, want %v", err, user.ErrInvalidID)
+	}
+
+	assertFriends(t, s, alice)
+}
+
+// testFriendsByID - users with the same username don't share friends, friends are resolved with ids
+func testFriendsByID(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	alice := create(t, s, "alice")
+	firstBob := create(t, s, "bob")
+	secondBob := create(t, s, "bob")
+	makeFriends(t, s, alice, firstBob)
+
+	friends, err := s.GetUserFriends(ctx, alice)
+	if err != nil {
+		t.Fatalf("GetUserFriends(%s) error: %v", alice, err)
+	}
+	if len(friends) != 1 || friends[0].ID != firstBob || friends[0].Username != "bob" {
+		t.Errorf("friends of alice = %+v, want bob with id %s", friends, firstBob)
+	}
+	assertFriends(t, s, secondBob)
+
+	// friendship with the second bob is not the same as with the first one
+	makeFriends(t, s, alice, secondBob)
+	if err = s.Delete(ctx, firstBob, requestAt(0)); err != nil {
+		t.Fatalf("Delete(%s) error: %v", firstBob, err)
+	}
+	u, err := s.FindOne(ctx, alice)
+	if err != nil {
+		t.Fatalf("FindOne(%s) error: %v", alice, err)
+	}
+	if !equalUnordered(u.Friends, secondBob) {
+		t.Errorf("friends of alice = %v, want %s", u.Friends, secondBob)
+	}
+}
+
+// testUnfriend - friendship is removed from both users, repeated unfriend is an error
+func testUnfriend(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	alice := create(t, s, "alice")
+	bob := create(t, s, "bob")
+	carol := create(t, s, "carol")
+	makeFriends(t, s, alice, bob)
+	makeFriends(t, s, alice, carol)
+
+	first, second, err := s.Unfriend(ctx, bob, alice)
+	if err != nil {
+		t.Fatalf("Unfriend(%s, %s) error: %v", bob, alice, err)
+	}
+	if first.ID != bob || len(first.Friends) != 0 {
+		t.Errorf("Unfriend returned first user %+v, want bob with id %s and without friends", first, bob)
+	}
+	if second.ID != alice || !equalUnordered(second.Friends, carol) {
+		t.Errorf("Unfriend returned second user %+v, want alice with id %s and friend %s", second, alice, carol)
+	}
+
+	assertFriends(t, s, alice, "carol")
+	assertFriends(t, s, bob)
+	if _, _, err = s.Unfriend(ctx, alice, bob); !errors.Is(err, user.ErrNotFriends) {
+		t.Errorf("repeated Unfriend error = %v, want %v", err, user.ErrNotFriends)
+	}
+
+	// friendship can be made again
+	makeFriends(t, s, alice, bob)
+	assertFriends(t, s, bob, "alice")
+}
+
+// testUnfriendUnknown - unfriend with unknown or invalid user is an error and changes nothing
+func testUnfriendUnknown(t *testing.T, s user.Storage) {
+	ctx := context.Background()
+
+	alice := create(t, s, "alice")
+	bob := create(t, s, "bob")
+	makeFriends(t, s, alice, bob)
+	unknown := unknownID(t, s)
+
+	if _, _, err := s.Unfriend(ctx, alice, unknown); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("Unfriend with unknown second user error = %v, want %v", err, user.ErrNotFound)
+	}
+	if _, _, err := s.Unfriend(ctx, unknown, alice); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("Unfriend with unknown first user error = %v, want %v", err, user.ErrNotFound)
+	}
+	if _, _, err := s.Unfriend(ctx, alice, invalidID); !errors.Is(err, user.ErrInvalidID) {
+		t.Errorf("Unfriend with invalid id error = %v, want %v", err, user.ErrInvalidID)
+	}
+
+	assertFriends(t, s, alice, "bob")
+}
+
+// create - create user with username and return its id
+func create(t *testing.T, s user.Storage, username string) string {
+	t.Helper()
+
+	return createWithAge(t, s, username, "20")
+}
+
+// createWithAge - create user with username and age and return its id
+func createWithAge(t *testing.T, s user.Storage, username, age string) string {
+	t.Helper()
+
+	id, err := s.Create(context.Background(), user.User{Username: username, Age: age})
+	if err != nil {
+		t.Fatalf("Create(%s) error: %v", username, err)
+	}
+	return id
+}
+
+// findAll - find users and fail test on error
+func findAll(t *testing.T, s user.Storage, filter user.Filter) ([]user.User, int64) {
+	t.Helper()
+
+	users, total, err := s.FindAll(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("FindAll(%+v) error: %v", filter, err)
+	}
+	return users, total
+}
+
+// usernames - usernames of users in the same order
+func usernames(users []user.User) []string {
+	names := make([]string, 0, len(users))
+	for _, u := range users {
+		names = append(names, u.Username)
+	}
+	return names
+}
+
+// equalOrder - compare strings with order
+func equalOrder(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// makeFriends - make friends and fail test on error
+func makeFriends(t *testing.T, s user.Storage, firstID, secondID string) {
+	t.Helper()
+
+	if _, _, err := s.MakeFriends(context.Background(), firstID, secondID); err != nil {
+		t.Fatalf("MakeFriends(%s, %s) error: %v", firstID, secondID, err)
+	}
+}
+
+// unknownID - id in format of storage which doesn't belong to any user
+func unknownID(t *testing.T, s user.Storage) string {
+	t.Helper()
+
+	ctx := context.Background()
+	id := create(t, s, "deleted")
+	if err := s.Delete(ctx, id, requestAt(0)); err != nil {
+		t.Fatalf("Delete(%s) error: %v", id, err)
+	}
+	if err := s.Purge(ctx, id); err != nil {
+		t.Fatalf("Purge(%s) error: %v", id, err)
+	}
+	return id
+}
+
+// assertFriends - check usernames of resolved friends of user ignoring order
+func assertFriends(t *testing.T, s user.Storage, id string, want ...string) {
+	t.Helper()
+
+	friends, err := s.GetUserFriends(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetUserFriends(%s) error: %v", id, err)
+	}
+	names := make([]string, 0, len(friends))
+	for _, friend := range friends {
+		names = append(names, friend.Username)
+	}
+	if !equalUnordered(names, want...) {
+		t.Fatalf("friends of %s = %v, want %v", id, names, want)
+	}
+}
+
+// equalUnordered - compare strings ignoring order
+func equalUnordered(friends []string, want ...string) bool {
+	got := append([]string(nil), friends...)
+	want = append([]string(nil), want...)
+	sort.Strings(got)
+	sort.Strings(want)
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package storagetest
+
+// file for conformance tests of user.TokenStorage implementations
+
+import (
+	"context"
+	"errors"
+	"project/internal/user"
+	"testing"
+	"time"
+)
+
+// NewTokenStorage - func for creating new empty storages of users and their refresh tokens for one test
+type NewTokenStorage func(t *testing.T) (user.Storage, user.TokenStorage)
+
+// RunTokens - run all conformance tests of refresh tokens, every test gets its own storages from newStorage
+func RunTokens(t *testing.T, newStorage NewTokenStorage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, users user.Storage, s user.TokenStorage)
+	}{
+		{"SaveToken", testSaveToken},
+		{"RevokeToken", testRevokeToken},
+		{"RevokeFamily", testRevokeFamily},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, tokens := newStorage(t)
+			tt.test(t, users, tokens)
+		})
+	}
+}
+
+// testSaveToken - saved token is found by hash with all fields, unknown hash is not found
+func testSaveToken(t *testing.T, users user.Storage, s user.TokenStorage) {
+	ctx := context.Background()
+
+	alice := create(t, users, "alice")
+	want := saveToken(t, s, "hash-1", alice, "family-1")
+
+	got, err := s.FindToken(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("FindToken error: %v", err)
+	}
+	if !equalToken(got, want) {
+		t.Errorf("FindToken returned %+v, want %+v", got, want)
+	}
+	if _, err = s.FindToken(ctx, "hash-2"); !errors.Is(err, user.ErrTokenNotFound) {
+		t.Errorf("FindToken with unknown hash error = %v, want %v", err, user.ErrTokenNotFound)
+	}
+}
+
+// testRevokeToken - token is revoked only once, revoke of unknown token is not found
+func testRevokeToken(t *testing.T, users user.Storage, s user.TokenStorage) {
+	ctx := context.Background()
+
+	alice := create(t, users, "alice")
+	saveToken(t, s, "hash-1", alice, "family-1")
+
+	if err := s.RevokeToken(ctx, "hash-1", requestAt(1)); err != nil {
+		t.Fatalf("RevokeToken error: %v", err)
+	}
+	if err := s.RevokeToken(ctx, "hash-1", requestAt(2)); !errors.Is(err, user.ErrTokenRevoked) {
+		t.Errorf("RevokeToken of revoked token error = %v, want %v", err, user.ErrTokenRevoked)
+	}
+	if err := s.RevokeToken(ctx, "hash-2", requestAt(2)); !errors.Is(err, user.ErrTokenNotFound) {
+		t.Errorf("RevokeToken with unknown hash error = %v, want %v", err, user.ErrTokenNotFound)
+	}
+
+	// time of the first revoke is kept
+	got := findToken(t, s, "hash-1")
+	if got.RevokedAt == nil || !got.RevokedAt.Equal(requestAt(1)) {
+		t.Errorf("RevokedAt = %v, want %v", got.RevokedAt, requestAt(1))
+	}
+}
+
+// testRevokeFamily - only tokens of family are revoked, revoked tokens keep time of their revoke
+func testRevokeFamily(t *testing.T, users user.Storage, s user.TokenStorage) {
+	ctx := context.Background()
+
+	alice := create(t, users, "alice")
+	saveToken(t, s, "hash-1", alice, "family-1")
+	saveToken(t, s, "hash-2", alice, "family-1")
+	saveToken(t, s, "hash-3", alice, "family-2")
+	if err := s.RevokeToken(ctx, "hash-1", requestAt(1)); err != nil {
+		t.Fatalf("RevokeToken error: %v", err)
+	}
+
+	if err := s.RevokeFamily(ctx, "family-1", requestAt(2)); err != nil {
+		t.Fatalf("RevokeFamily error: %v", err)
+	}
+	want := map[string]*time.Time{"hash-1": timePtr(requestAt(1)), "hash-2": timePtr(requestAt(2)), "hash-3": nil}
+	for hash, revokedAt := range want {
+		got := findToken(t, s, hash)
+		if (got.RevokedAt == nil) != (revokedAt == nil) || (revokedAt != nil && !got.RevokedAt.Equal(*revokedAt)) {
+			t.Errorf("RevokedAt of %s = %v, want %v", hash, got.RevokedAt, revokedAt)
+		}
+	}
+
+	if err := s.RevokeFamily(ctx, "family-3", requestAt(3)); err != nil {
+		t.Errorf("RevokeFamily of unknown family error: %v", err)
+	}
+}
+
+// saveToken - save active token of user and fail test on error
+func saveToken(t *testing.T, s user.TokenStorage, hash, userID, familyID string) user.RefreshToken {
+	t.Helper()
+
+	token := user.RefreshToken{Hash: hash, UserID: userID, FamilyID: familyID, CreatedAt: requestAt(0), ExpiresAt: requestAt(60)}
+	if err := s.SaveToken(context.Background(), token); err != nil {
+		t.Fatalf("SaveToken(%s) error: %v", hash, err)
+	}
+	return token
+}
+
+// findToken - find token and fail test on error
+func findToken(t *testing.T, s user.TokenStorage, hash string) user.RefreshToken {
+	t.Helper()
+
+	token, err := s.FindToken(context.Background(), hash)
+	if err != nil {
+		t.Fatalf("FindToken(%s) error: %v", hash, err)
+	}
+	return token
+}
+
+// equalToken - tokens are equal, times are compared as instants
+func equalToken(a, b user.RefreshToken) bool {
+	return a.Hash == b.Hash && a.UserID == b.UserID && a.FamilyID == b.FamilyID &&
+		a.CreatedAt.Equal(b.CreatedAt) && a.ExpiresAt.Equal(b.ExpiresAt) && (a.RevokedAt == nil) == (b.RevokedAt == nil)
+}
+
+// timePtr - pointer to time
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
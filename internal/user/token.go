@@ -0,0 +1,24 @@
+package user
+
+// file for refresh token description
+
+import (
+	"time"
+)
+
+// RefreshToken - refresh token of user, only hash of its value is stored.
+// Every refresh revokes used token and issues new one of the same family, so reuse of revoked token is noticed
+// and revokes the whole family, that is every token of one login
+type RefreshToken struct {
+	Hash      string     `json:"hash" bson:"_id"`
+	UserID    string     `json:"user_id" bson:"user_id"`
+	FamilyID  string     `json:"family_id" bson:"family_id"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at" bson:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// Active - check that token is neither revoked nor expired at time
+func (t RefreshToken) Active(at time.Time) bool {
+	return t.RevokedAt == nil && at.Before(t.ExpiresAt)
+}
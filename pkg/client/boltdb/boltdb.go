@@ -0,0 +1,26 @@
+// Package boltdb - for creating storage in embedded BoltDB file
+package boltdb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// NewClient - func for opening BoltDB file, file and its directory are created if not exist
+func NewClient(path string) (db *bbolt.DB, err error) {
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for boltDB file due to error: %v", err)
+	}
+
+	// file is locked by one process, so don't wait forever if it is already opened
+	db, err = bbolt.Open(path, 0600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open boltDB file %s due to error: %v", path, err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,44 @@
+// Package postgresql - for creating storage and connecting with PostgreSQL database
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+
+	// register postgres driver for database/sql
+	_ "github.com/lib/pq"
+)
+
+// NewClient - func for creating connection with PostgreSQL
+func NewClient(ctx context.Context, host, port, username, password, database, sslMode string) (db *sql.DB, err error) {
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	// creating url for connection
+	dsn := url.URL{
+		Scheme:   "postgres",
+		Host:     fmt.Sprintf("%s:%s", host, port),
+		Path:     database,
+		RawQuery: url.Values{"sslmode": []string{sslMode}}.Encode(),
+	}
+	if username != "" {
+		dsn.User = url.UserPassword(username, password)
+	}
+
+	// connection to database
+	db, err = sql.Open("postgres", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgreSQL due to error: %v", err)
+	}
+
+	// ping database for check stable connection
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping postgreSQL due to error: %v", err)
+	}
+
+	return db, nil
+}
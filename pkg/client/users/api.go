@@ -0,0 +1,283 @@
+package users
+
+// file for calls of API, one method for every route of /api/v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Create - create user, id and friends of u are ignored
+func (c *Client) Create(ctx context.Context, u User) (User, error) {
+	var created User
+	err := c.do(ctx, http.MethodPost, "/users", nil, User{Username: u.Username, Age: u.Age}, &created, nil)
+	return created, err
+}
+
+// GetUser - get user by id
+func (c *Client) GetUser(ctx context.Context, id string) (User, error) {
+	var u User
+	err := c.do(ctx, http.MethodGet, userPath(id), nil, nil, &u, nil)
+	return u, err
+}
+
+// ListUsers - get page of users matching filter
+func (c *Client) ListUsers(ctx context.Context, filter Filter) (Page, error) {
+	query := url.Values{}
+	if filter.UsernamePrefix != "" {
+		query.Set("username", filter.UsernamePrefix)
+	}
+	if filter.MinAge != nil {
+		query.Set("min_age", strconv.Itoa(*filter.MinAge))
+	}
+	if filter.MaxAge != nil {
+		query.Set("max_age", strconv.Itoa(*filter.MaxAge))
+	}
+	if filter.SortBy != "" {
+		sortBy := filter.SortBy
+		if filter.Desc {
+			sortBy = "-" + sortBy
+		}
+		query.Set("sort", sortBy)
+	}
+	if filter.Limit != 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if filter.Offset != 0 {
+		query.Set("offset", strconv.Itoa(filter.Offset))
+	}
+
+	var page Page
+	var meta struct {
+		Total  int64 `json:"total"`
+		Limit  int   `json:"limit"`
+		Offset int   `json:"offset"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/users", query, nil, &page.Users, &meta); err != nil {
+		return page, err
+	}
+	page.Total, page.Limit, page.Offset = meta.Total, meta.Limit, meta.Offset
+	return page, nil
+}
+
+// Update - replace username and age of user
+func (c *Client) Update(ctx context.Context, id string, u User) (User, error) {
+	var updated User
+	err := c.do(ctx, http.MethodPut, userPath(id), nil, User{Username: u.Username, Age: u.Age}, &updated, nil)
+	return updated, err
+}
+
+// UpdateAge - change only age of user, empty age clears it
+func (c *Client) UpdateAge(ctx context.Context, id string, age string) (User, error) {
+	return c.Patch(ctx, id, map[string]interface{}{"age": age})
+}
+
+// Patch - change user by JSON Merge Patch, nil value removes field
+func (c *Client) Patch(ctx context.Context, id string, patch map[string]interface{}) (User, error) {
+	var updated User
+	err := c.do(ctx, http.MethodPatch, userPath(id), nil, patch, &updated, nil)
+	return updated, err
+}
+
+// Delete - soft delete user, he can be restored until he is purged
+func (c *Client) Delete(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, userPath(id), nil, nil, nil, nil)
+}
+
+// Restore - restore soft deleted user with his friendships
+func (c *Client) Restore(ctx context.Context, id string) (User, error) {
+	var u User
+	err := c.do(ctx, http.MethodPost, "/admin"+userPath(id)+"/restore", nil, nil, &u, nil)
+	return u, err
+}
+
+// GetFriends - get friends of user
+func (c *Client) GetFriends(ctx context.Context, id string) ([]Friend, error) {
+	var friends []Friend
+	err := c.do(ctx, http.MethodGet, userPath(id)+"/friends", nil, nil, &friends, nil)
+	return friends, err
+}
+
+// Unfriend - remove friendship of user and friend in both directions, returns both users
+func (c *Client) Unfriend(ctx context.Context, id string, friendID string) (User, User, error) {
+	return c.pair(ctx, http.MethodDelete, userPath(id)+"/friends/"+url.PathEscape(friendID), nil)
+}
+
+// MutualFriends - get friends of both users
+func (c *Client) MutualFriends(ctx context.Context, id string, otherID string) ([]Friend, error) {
+	var friends []Friend
+	err := c.do(ctx, http.MethodGet, userPath(id)+"/friends/mutual/"+url.PathEscape(otherID), nil, nil, &friends, nil)
+	return friends, err
+}
+
+// Suggestions - get friends of friends ranked by mutual friends, zero limit is default of server
+func (c *Client) Suggestions(ctx context.Context, id string, limit int) ([]Suggestion, error) {
+	query := url.Values{}
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var suggestions []Suggestion
+	err := c.do(ctx, http.MethodGet, userPath(id)+"/suggestions", query, nil, &suggestions, nil)
+	return suggestions, err
+}
+
+// ShortestPath - get the shortest chain of friends from user to other user, both included
+func (c *Client) ShortestPath(ctx context.Context, id string, otherID string) ([]Friend, error) {
+	var path []Friend
+	err := c.do(ctx, http.MethodGet, userPath(id)+"/path/"+url.PathEscape(otherID), nil, nil, &path, nil)
+	return path, err
+}
+
+// SendFriendRequest - send friend request from user to another user
+func (c *Client) SendFriendRequest(ctx context.Context, fromID string, toID string) (FriendRequest, error) {
+	body := struct {
+		ToID string `json:"to_id"`
+	}{ToID: toID}
+	var request FriendRequest
+	err := c.do(ctx, http.MethodPost, userPath(fromID)+"/friend_requests", nil, body, &request, nil)
+	return request, err
+}
+
+// ListFriendRequests - get friend requests of user, empty direction is incoming, empty status is any
+func (c *Client) ListFriendRequests(ctx context.Context, id string, direction string, status string) ([]FriendRequest, error) {
+	query := url.Values{}
+	if direction != "" {
+		query.Set("direction", direction)
+	}
+	if status != "" {
+		query.Set("status", status)
+	}
+	var requests []FriendRequest
+	err := c.do(ctx, http.MethodGet, userPath(id)+"/friend_requests", query, nil, &requests, nil)
+	return requests, err
+}
+
+// AcceptFriendRequest - accept friend request by its recipient
+func (c *Client) AcceptFriendRequest(ctx context.Context, id string, requestID string) (FriendRequest, error) {
+	return c.changeFriendRequest(ctx, id, requestID, "accept")
+}
+
+// DeclineFriendRequest - decline friend request by its recipient
+func (c *Client) DeclineFriendRequest(ctx context.Context, id string, requestID string) (FriendRequest, error) {
+	return c.changeFriendRequest(ctx, id, requestID, "decline")
+}
+
+// CancelFriendRequest - cancel friend request by its sender
+func (c *Client) CancelFriendRequest(ctx context.Context, id string, requestID string) (FriendRequest, error) {
+	return c.changeFriendRequest(ctx, id, requestID, "cancel")
+}
+
+// Block - block target by user, blocking twice is not an error
+func (c *Client) Block(ctx context.Context, id string, targetID string) (Relation, error) {
+	return c.relate(ctx, id, targetID, "blocks")
+}
+
+// Unblock - remove block of target by user
+func (c *Client) Unblock(ctx context.Context, id string, targetID string) error {
+	return c.do(ctx, http.MethodDelete, userPath(id)+"/blocks/"+url.PathEscape(targetID), nil, nil, nil, nil)
+}
+
+// ListBlocks - get blocks of user
+func (c *Client) ListBlocks(ctx context.Context, id string) ([]Relation, error) {
+	var relations []Relation
+	err := c.do(ctx, http.MethodGet, userPath(id)+"/blocks", nil, nil, &relations, nil)
+	return relations, err
+}
+
+// Mute - mute target by user, muting twice is not an error
+func (c *Client) Mute(ctx context.Context, id string, targetID string) (Relation, error) {
+	return c.relate(ctx, id, targetID, "mutes")
+}
+
+// Unmute - remove mute of target by user
+func (c *Client) Unmute(ctx context.Context, id string, targetID string) error {
+	return c.do(ctx, http.MethodDelete, userPath(id)+"/mutes/"+url.PathEscape(targetID), nil, nil, nil, nil)
+}
+
+// ListMutes - get mutes of user
+func (c *Client) ListMutes(ctx context.Context, id string) ([]Relation, error) {
+	var relations []Relation
+	err := c.do(ctx, http.MethodGet, userPath(id)+"/mutes", nil, nil, &relations, nil)
+	return relations, err
+}
+
+// Register - create user with email and password, he logs in with Login
+func (c *Client) Register(ctx context.Context, registration Registration) (User, error) {
+	var u User
+	err := c.do(ctx, http.MethodPost, "/auth/register", nil, registration, &u, nil)
+	return u, err
+}
+
+// Login - start session of user with email and password
+func (c *Client) Login(ctx context.Context, email string, password string) (Tokens, error) {
+	var tokens Tokens
+	err := c.do(ctx, http.MethodPost, "/auth/login", nil, map[string]string{"email": email, "password": password}, &tokens, nil)
+	return tokens, err
+}
+
+// Refresh - get new tokens of session, refreshToken can't be used again
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (Tokens, error) {
+	var tokens Tokens
+	err := c.do(ctx, http.MethodPost, "/auth/refresh", nil, map[string]string{"refresh_token": refreshToken}, &tokens, nil)
+	return tokens, err
+}
+
+// Logout - revoke session of refresh token
+func (c *Client) Logout(ctx context.Context, refreshToken string) error {
+	return c.do(ctx, http.MethodPost, "/auth/logout", nil, map[string]string{"refresh_token": refreshToken}, nil, nil)
+}
+
+// IssueAPIKey - create API key of service with scopes, caller must be admin
+func (c *Client) IssueAPIKey(ctx context.Context, name string, scopes ...string) (APIKey, error) {
+	var key APIKey
+	err := c.do(ctx, http.MethodPost, "/admin/api_keys", nil, map[string]interface{}{"name": name, "scopes": scopes}, &key, nil)
+	return key, err
+}
+
+// ListAPIKeys - get all API keys without their values, caller must be admin
+func (c *Client) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	var keys []APIKey
+	err := c.do(ctx, http.MethodGet, "/admin/api_keys", nil, nil, &keys, nil)
+	return keys, err
+}
+
+// RevokeAPIKey - revoke API key, caller must be admin
+func (c *Client) RevokeAPIKey(ctx context.Context, id string) (APIKey, error) {
+	var key APIKey
+	err := c.do(ctx, http.MethodDelete, "/admin/api_keys/"+url.PathEscape(id), nil, nil, &key, nil)
+	return key, err
+}
+
+// userPath - path of user with escaped id
+func userPath(id string) string {
+	return "/users/" + url.PathEscape(id)
+}
+
+// pair - call route which answers with both users
+func (c *Client) pair(ctx context.Context, method string, path string, body interface{}) (User, User, error) {
+	var users []User
+	if err := c.do(ctx, method, path, nil, body, &users, nil); err != nil {
+		return User{}, User{}, err
+	}
+	if len(users) != 2 {
+		return User{}, User{}, fmt.Errorf("answer of %s %s has %d users, want 2", method, path, len(users))
+	}
+	return users[0], users[1], nil
+}
+
+// changeFriendRequest - call route of action with friend request of user
+func (c *Client) changeFriendRequest(ctx context.Context, id string, requestID string, action string) (FriendRequest, error) {
+	var request FriendRequest
+	err := c.do(ctx, http.MethodPost, userPath(id)+"/friend_requests/"+url.PathEscape(requestID)+"/"+action, nil, nil, &request, nil)
+	return request, err
+}
+
+// relate - call route which saves block or mute of target by user
+func (c *Client) relate(ctx context.Context, id string, targetID string, kind string) (Relation, error) {
+	var relation Relation
+	err := c.do(ctx, http.MethodPut, userPath(id)+"/"+kind+"/"+url.PathEscape(targetID), nil, nil, &relation, nil)
+	return relation, err
+}
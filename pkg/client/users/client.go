@@ -0,0 +1,214 @@
+// Package users - typed client of users API /api/v1, answers are decoded from envelope and errors into *Error
+//
+//	client := users.NewClient("http://users:9090", users.WithRetries(3, 200*time.Millisecond))
+//	u, err := client.Create(ctx, users.User{Username: "alice"})
+//	if errors.Is(err, users.ErrValidation) {
+//		...
+//	}
+package users
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// apiPrefix - prefix of all paths of api version of client
+const apiPrefix = "/api/v1"
+
+// defaults of client which can be changed by options
+const (
+	defaultRetries = 2
+	defaultBackoff = 100 * time.Millisecond
+	defaultTimeout = 15 * time.Second
+)
+
+// Client - client of users API, it is safe for concurrent use
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	retries    int
+	backoff    time.Duration
+	// authorization - value of Authorization header, empty for anonymous client
+	authorization string
+}
+
+// Option - option of client for NewClient
+type Option func(c *Client)
+
+// WithHTTPClient - use http client instead of client with default timeout
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries - retry idempotent requests retries times after network errors and 429, 502, 503, 504 answers,
+// waiting backoff before the first retry and twice longer before every next one
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithToken - send JWT as bearer token in every request, API answers 401 to requests without it
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.authorization = "Bearer " + token
+	}
+}
+
+// WithAPIKey - send API key of service in every request instead of JWT, key must have scopes of called routes
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.authorization = "ApiKey " + key
+	}
+}
+
+// NewClient - create client of API at baseURL, for example http://users:9090
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		retries:    defaultRetries,
+		backoff:    defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// envelope - body of every answer of API
+type envelope struct {
+	Data  json.RawMessage `json:"data"`
+	Error *Error          `json:"error"`
+	Meta  json.RawMessage `json:"meta"`
+}
+
+// do - send request with body encoded to json, decode data and meta of answer, nil data and meta are skipped
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body interface{}, data interface{}, meta interface{}) error {
+	var content []byte
+	if body != nil {
+		var err error
+		if content, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to encode request. error: %w", err)
+		}
+	}
+	target := c.baseURL + apiPrefix + path
+	if len(query) != 0 {
+		target += "?" + query.Encode()
+	}
+
+	answer, err := c.send(ctx, method, target, content)
+	if err != nil {
+		return err
+	}
+
+	if answer.Error != nil {
+		return answer.Error
+	}
+	if data != nil {
+		if err := json.Unmarshal(answer.Data, data); err != nil {
+			return fmt.Errorf("failed to decode data of %s %s. error: %w", method, path, err)
+		}
+	}
+	if meta != nil {
+		if err := json.Unmarshal(answer.Meta, meta); err != nil {
+			return fmt.Errorf("failed to decode meta of %s %s. error: %w", method, path, err)
+		}
+	}
+	return nil
+}
+
+// send - send request and read envelope of answer, idempotent requests are retried
+func (c *Client) send(ctx context.Context, method string, target string, content []byte) (envelope, error) {
+	retries := 0
+	if idempotent(method) {
+		retries = c.retries
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		answer, status, err := c.sendOnce(ctx, method, target, content)
+		if !retryable(status) || attempt >= retries {
+			return answer, err
+		}
+
+		// wait before next attempt, but not longer than context lives
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return answer, err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// sendOnce - one attempt of request, answer without envelope is an error with status of answer
+func (c *Client) sendOnce(ctx context.Context, method string, target string, content []byte) (envelope, int, error) {
+	var answer envelope
+
+	var body io.Reader
+	if content != nil {
+		body = bytes.NewReader(content)
+	}
+	r, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return answer, 0, fmt.Errorf("failed to create request. error: %w", err)
+	}
+	if content != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	r.Header.Set("Accept", "application/json")
+	if c.authorization != "" {
+		r.Header.Set("Authorization", c.authorization)
+	}
+
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		return answer, 0, fmt.Errorf("failed to send %s %s. error: %w", method, target, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return answer, resp.StatusCode, fmt.Errorf("failed to read answer of %s %s. error: %w", method, target, err)
+	}
+	if err := json.Unmarshal(raw, &answer); err != nil {
+		// proxies answer without envelope, status is the only thing client knows
+		return answer, resp.StatusCode, &Error{Status: resp.StatusCode, Code: codeUnknown, Message: http.StatusText(resp.StatusCode)}
+	}
+	if answer.Error != nil {
+		answer.Error.Status = resp.StatusCode
+	}
+	return answer, resp.StatusCode, nil
+}
+
+// idempotent - requests which can be retried without changing result
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryable - answers which can be different on next attempt, status is 0 if request failed without answer
+func retryable(status int) bool {
+	switch status {
+	case 0, http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,419 @@
+package users_test
+
+import (
+	"context"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"net/http/httptest"
+	"project/internal/middleware"
+	"project/internal/user"
+	"project/internal/user/apitest"
+	"project/pkg/client/users"
+	"sync"
+	"testing"
+	"time"
+)
+
+// invalidID - id which can't be produced by any storage
+const invalidID = "not-an-id"
+
+// TestClient - call every method of client against server with handler on memory storages, client is authenticated as admin
+func TestClient(t *testing.T) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, c *users.Client)
+	}{
+		{"Users", testClientUsers},
+		{"Friends", testClientFriends},
+		{"FriendRequests", testClientFriendRequests},
+		{"Relations", testClientRelations},
+		{"Errors", testClientErrors},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := apitest.MemoryHandler(t)
+			server := newServer(t, h)
+			tt.test(t, users.NewClient(server.URL, users.WithToken(token(t, h.Auth, "admin-1", middleware.RoleAdmin))))
+		})
+	}
+	t.Run("Retries", func(t *testing.T) {
+		h := apitest.MemoryHandler(t)
+		testClientRetries(t, h)
+	})
+	t.Run("Auth", func(t *testing.T) {
+		testClientAuth(t, apitest.MemoryHandler(t))
+	})
+}
+
+// newServer - start server with routes of handler, it is closed after test
+func newServer(t *testing.T, h *user.Handler) *httptest.Server {
+	router := httprouter.New()
+	h.Register(router)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// testClientUsers - create, read, update, delete and restore user
+func testClientUsers(t *testing.T, c *users.Client) {
+	ctx := context.Background()
+
+	alice, err := c.Create(ctx, users.User{Username: "alice", Age: "30"})
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if alice.ID == "" || alice.Username != "alice" || alice.Age != "30" {
+		t.Fatalf("Create = %+v, want alice with id", alice)
+	}
+	if _, err := c.Create(ctx, users.User{Username: "bob", Age: "40"}); err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	if got, err := c.GetUser(ctx, alice.ID); err != nil || got.Username != "alice" {
+		t.Errorf("GetUser = %+v, %v, want alice", got, err)
+	}
+	minAge := 35
+	page, err := c.ListUsers(ctx, users.Filter{MinAge: &minAge, SortBy: users.SortByUsername, Desc: true, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListUsers error: %v", err)
+	}
+	if page.Total != 1 || page.Limit != 10 || len(page.Users) != 1 || page.Users[0].Username != "bob" {
+		t.Errorf("ListUsers = %+v, want page with bob", page)
+	}
+
+	if got, err := c.Update(ctx, alice.ID, users.User{Username: "alice2", Age: "31"}); err != nil || got.Username != "alice2" || got.Age != "31" {
+		t.Errorf("Update = %+v, %v, want alice2 of 31", got, err)
+	}
+	if got, err := c.UpdateAge(ctx, alice.ID, "32"); err != nil || got.Username != "alice2" || got.Age != "32" {
+		t.Errorf("UpdateAge = %+v, %v, want alice2 of 32", got, err)
+	}
+	if got, err := c.Patch(ctx, alice.ID, map[string]interface{}{"username": "alice"}); err != nil || got.Username != "alice" || got.Age != "32" {
+		t.Errorf("Patch = %+v, %v, want alice of 32", got, err)
+	}
+
+	if err := c.Delete(ctx, alice.ID); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, err := c.GetUser(ctx, alice.ID); !errors.Is(err, users.ErrNotFound) {
+		t.Errorf("GetUser of deleted user error = %v, want %v", err, users.ErrNotFound)
+	}
+	if got, err := c.Restore(ctx, alice.ID); err != nil || got.ID != alice.ID {
+		t.Errorf("Restore = %+v, %v, want alice", got, err)
+	}
+	if _, err := c.Restore(ctx, alice.ID); !errors.Is(err, users.ErrNotDeleted) {
+		t.Errorf("Restore of not deleted user error = %v, want %v", err, users.ErrNotDeleted)
+	}
+}
+
+// testClientFriends - friendships and graph queries
+func testClientFriends(t *testing.T, c *users.Client) {
+	ctx := context.Background()
+	alice := create(t, c, "alice")
+	bob := create(t, c, "bob")
+	carol := create(t, c, "carol")
+	dave := create(t, c, "dave")
+
+	befriend(t, c, alice, bob)
+	if _, err := c.SendFriendRequest(ctx, alice, bob); !errors.Is(err, users.ErrAlreadyFriends) {
+		t.Errorf("SendFriendRequest to friend error = %v, want %v", err, users.ErrAlreadyFriends)
+	}
+	befriend(t, c, bob, carol)
+
+	if friends, err := c.GetFriends(ctx, bob); err != nil || len(friends) != 2 {
+		t.Errorf("GetFriends = %+v, %v, want alice and carol", friends, err)
+	}
+	if mutual, err := c.MutualFriends(ctx, alice, carol); err != nil || len(mutual) != 1 || mutual[0].ID != bob {
+		t.Errorf("MutualFriends = %+v, %v, want bob", mutual, err)
+	}
+	if suggestions, err := c.Suggestions(ctx, alice, 5); err != nil || len(suggestions) != 1 || suggestions[0].ID != carol || suggestions[0].MutualCount != 1 {
+		t.Errorf("Suggestions = %+v, %v, want carol with one mutual friend", suggestions, err)
+	}
+	if path, err := c.ShortestPath(ctx, alice, carol); err != nil || len(path) != 3 {
+		t.Errorf("ShortestPath = %+v, %v, want alice, bob, carol", path, err)
+	}
+	if _, err := c.ShortestPath(ctx, alice, dave); !errors.Is(err, users.ErrNotConnected) {
+		t.Errorf("ShortestPath to not connected user error = %v, want %v", err, users.ErrNotConnected)
+	}
+
+	if _, _, err := c.Unfriend(ctx, alice, bob); err != nil {
+		t.Fatalf("Unfriend error: %v", err)
+	}
+	if _, _, err := c.Unfriend(ctx, alice, bob); !errors.Is(err, users.ErrNotFriends) {
+		t.Errorf("second Unfriend error = %v, want %v", err, users.ErrNotFriends)
+	}
+}
+
+// testClientFriendRequests - sending, listing and changing friend requests
+func testClientFriendRequests(t *testing.T, c *users.Client) {
+	ctx := context.Background()
+	alice := create(t, c, "alice")
+	bob := create(t, c, "bob")
+	carol := create(t, c, "carol")
+
+	request, err := c.SendFriendRequest(ctx, alice, bob)
+	if err != nil || request.Status != users.RequestPending {
+		t.Fatalf("SendFriendRequest = %+v, %v, want pending request", request, err)
+	}
+	if _, err := c.SendFriendRequest(ctx, bob, alice); !errors.Is(err, users.ErrRequestExists) {
+		t.Errorf("SendFriendRequest in opposite direction error = %v, want %v", err, users.ErrRequestExists)
+	}
+	if requests, err := c.ListFriendRequests(ctx, bob, users.RequestsIncoming, users.RequestPending); err != nil || len(requests) != 1 {
+		t.Errorf("ListFriendRequests = %+v, %v, want one request", requests, err)
+	}
+	if _, err := c.AcceptFriendRequest(ctx, alice, request.ID); !errors.Is(err, users.ErrRequestForbidden) {
+		t.Errorf("AcceptFriendRequest by sender error = %v, want %v", err, users.ErrRequestForbidden)
+	}
+	if accepted, err := c.AcceptFriendRequest(ctx, bob, request.ID); err != nil || accepted.Status != users.RequestAccepted {
+		t.Errorf("AcceptFriendRequest = %+v, %v, want accepted request", accepted, err)
+	}
+	if _, err := c.DeclineFriendRequest(ctx, bob, request.ID); !errors.Is(err, users.ErrRequestState) {
+		t.Errorf("DeclineFriendRequest of accepted request error = %v, want %v", err, users.ErrRequestState)
+	}
+
+	declined, err := c.SendFriendRequest(ctx, alice, carol)
+	if err != nil {
+		t.Fatalf("SendFriendRequest error: %v", err)
+	}
+	if declined, err = c.DeclineFriendRequest(ctx, carol, declined.ID); err != nil || declined.Status != users.RequestDeclined {
+		t.Errorf("DeclineFriendRequest = %+v, %v, want declined request", declined, err)
+	}
+	canceled, err := c.SendFriendRequest(ctx, carol, bob)
+	if err != nil {
+		t.Fatalf("SendFriendRequest error: %v", err)
+	}
+	if canceled, err = c.CancelFriendRequest(ctx, carol, canceled.ID); err != nil || canceled.Status != users.RequestCanceled {
+		t.Errorf("CancelFriendRequest = %+v, %v, want canceled request", canceled, err)
+	}
+}
+
+// testClientRelations - blocks and mutes
+func testClientRelations(t *testing.T, c *users.Client) {
+	ctx := context.Background()
+	alice := create(t, c, "alice")
+	bob := create(t, c, "bob")
+
+	if relation, err := c.Block(ctx, alice, bob); err != nil || relation.Kind != users.RelationBlock {
+		t.Fatalf("Block = %+v, %v, want block", relation, err)
+	}
+	if blocks, err := c.ListBlocks(ctx, alice); err != nil || len(blocks) != 1 || blocks[0].TargetID != bob {
+		t.Errorf("ListBlocks = %+v, %v, want block of bob", blocks, err)
+	}
+	if _, err := c.SendFriendRequest(ctx, bob, alice); !errors.Is(err, users.ErrBlocked) {
+		t.Errorf("SendFriendRequest to blocking user error = %v, want %v", err, users.ErrBlocked)
+	}
+	if err := c.Unblock(ctx, alice, bob); err != nil {
+		t.Errorf("Unblock error: %v", err)
+	}
+	if err := c.Unblock(ctx, alice, bob); !errors.Is(err, users.ErrRelationNotFound) {
+		t.Errorf("second Unblock error = %v, want %v", err, users.ErrRelationNotFound)
+	}
+
+	if relation, err := c.Mute(ctx, alice, bob); err != nil || relation.Kind != users.RelationMute {
+		t.Fatalf("Mute = %+v, %v, want mute", relation, err)
+	}
+	if mutes, err := c.ListMutes(ctx, alice); err != nil || len(mutes) != 1 {
+		t.Errorf("ListMutes = %+v, %v, want one mute", mutes, err)
+	}
+	if err := c.Unmute(ctx, alice, bob); err != nil {
+		t.Errorf("Unmute error: %v", err)
+	}
+}
+
+// testClientErrors - errors of server are decoded with status, code and fields
+func testClientErrors(t *testing.T, c *users.Client) {
+	ctx := context.Background()
+
+	_, err := c.Create(ctx, users.User{Username: "x"})
+	var apiErr *users.Error
+	if !errors.As(err, &apiErr) || !errors.Is(err, users.ErrValidation) {
+		t.Fatalf("Create with short username error = %v, want %v", err, users.ErrValidation)
+	}
+	if apiErr.Status != http.StatusUnprocessableEntity || len(apiErr.Details) != 1 || apiErr.Details[0].Field != "username" {
+		t.Errorf("Create with short username error = %+v, want 422 with username in details", apiErr)
+	}
+
+	if _, err := c.GetUser(ctx, invalidID); !errors.Is(err, users.ErrInvalidID) {
+		t.Errorf("GetUser with invalid id error = %v, want %v", err, users.ErrInvalidID)
+	}
+	if _, err := c.GetFriends(ctx, unknownID(t, c)); !errors.Is(err, users.ErrNotFound) {
+		t.Errorf("GetFriends of unknown user error = %v, want %v", err, users.ErrNotFound)
+	}
+}
+
+// testClientAuth - client sends its token, errors of authentication and authorization are matched by errors.Is
+func testClientAuth(t *testing.T, h *user.Handler) {
+	server := newServer(t, h)
+	alice, bob := createUser(t, h, "alice"), createUser(t, h, "bob")
+	ctx := context.Background()
+
+	if _, err := users.NewClient(server.URL).GetUser(ctx, alice); !errors.Is(err, users.ErrUnauthorized) {
+		t.Errorf("GetUser without token error = %v, want %v", err, users.ErrUnauthorized)
+	}
+	c := users.NewClient(server.URL, users.WithToken(token(t, h.Auth, alice)))
+	if u, err := c.UpdateAge(ctx, alice, "30"); err != nil || u.Age != "30" {
+		t.Errorf("UpdateAge of own user = %+v, %v, want age 30", u, err)
+	}
+	if _, err := c.UpdateAge(ctx, bob, "30"); !errors.Is(err, users.ErrForbidden) {
+		t.Errorf("UpdateAge of other user error = %v, want %v", err, users.ErrForbidden)
+	}
+
+	// session of registered user
+	anonymous := users.NewClient(server.URL)
+	registration := users.Registration{Username: "carol", Email: "carol@example.com", Password: "correct horse"}
+	carol, err := anonymous.Register(ctx, registration)
+	if err != nil {
+		t.Fatalf("Register error: %v", err)
+	}
+	if _, err = anonymous.Register(ctx, registration); !errors.Is(err, users.ErrEmailExists) {
+		t.Errorf("Register twice error = %v, want %v", err, users.ErrEmailExists)
+	}
+	if _, err = anonymous.Login(ctx, registration.Email, "wrong password"); !errors.Is(err, users.ErrInvalidCredentials) {
+		t.Errorf("Login with wrong password error = %v, want %v", err, users.ErrInvalidCredentials)
+	}
+	tokens, err := anonymous.Login(ctx, registration.Email, registration.Password)
+	if err != nil || tokens.UserID != carol.ID {
+		t.Fatalf("Login = %+v, %v, want tokens of %s", tokens, err, carol.ID)
+	}
+	if _, err = users.NewClient(server.URL, users.WithToken(tokens.AccessToken)).UpdateAge(ctx, carol.ID, "30"); err != nil {
+		t.Errorf("UpdateAge with access token of login error: %v", err)
+	}
+	if err = anonymous.Logout(ctx, tokens.RefreshToken); err != nil {
+		t.Errorf("Logout error: %v", err)
+	}
+	if _, err = anonymous.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, users.ErrInvalidRefreshToken) {
+		t.Errorf("Refresh after logout error = %v, want %v", err, users.ErrInvalidRefreshToken)
+	}
+
+	// API key of service issued by admin
+	admin := users.NewClient(server.URL, users.WithToken(token(t, h.Auth, "admin-1", middleware.RoleAdmin)))
+	key, err := admin.IssueAPIKey(ctx, "reader", users.ScopeUsersRead)
+	if err != nil || key.Key == "" {
+		t.Fatalf("IssueAPIKey = %+v, %v, want key with value", key, err)
+	}
+	service := users.NewClient(server.URL, users.WithAPIKey(key.Key))
+	if _, err = service.GetUser(ctx, alice); err != nil {
+		t.Errorf("GetUser with api key error: %v", err)
+	}
+	if _, err = service.UpdateAge(ctx, alice, "40"); !errors.Is(err, users.ErrForbidden) {
+		t.Errorf("UpdateAge with read key error = %v, want %v", err, users.ErrForbidden)
+	}
+	if keys, err := admin.ListAPIKeys(ctx); err != nil || len(keys) != 1 || keys[0].Key != "" {
+		t.Errorf("ListAPIKeys = %+v, %v, want one key without value", keys, err)
+	}
+	if _, err = admin.RevokeAPIKey(ctx, key.ID); err != nil {
+		t.Errorf("RevokeAPIKey error: %v", err)
+	}
+	if _, err = service.GetUser(ctx, alice); !errors.Is(err, users.ErrUnauthorized) {
+		t.Errorf("GetUser with revoked key error = %v, want %v", err, users.ErrUnauthorized)
+	}
+}
+
+// testClientRetries - idempotent requests are retried after 503 answers, other requests are not
+func testClientRetries(t *testing.T, h *user.Handler) {
+	ctx := context.Background()
+	router := httprouter.New()
+	h.Register(router)
+
+	// every request fails while failures are left
+	var mu sync.Mutex
+	failures, calls := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		fail := failures > 0
+		if fail {
+			failures--
+		}
+		mu.Unlock()
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	setFailures := func(n int) {
+		mu.Lock()
+		failures, calls = n, 0
+		mu.Unlock()
+	}
+
+	c := users.NewClient(server.URL, users.WithToken(token(t, h.Auth, "admin-1", middleware.RoleAdmin)), users.WithRetries(2, time.Millisecond))
+	alice := create(t, c, "alice")
+
+	setFailures(2)
+	if _, err := c.GetUser(ctx, alice); err != nil || calls != 3 {
+		t.Errorf("GetUser after two failures = %v with %d calls, want success with 3 calls", err, calls)
+	}
+
+	setFailures(3)
+	var apiErr *users.Error
+	if _, err := c.GetUser(ctx, alice); !errors.As(err, &apiErr) || apiErr.Status != http.StatusServiceUnavailable || calls != 3 {
+		t.Errorf("GetUser after three failures = %v with %d calls, want 503 with 3 calls", err, calls)
+	}
+
+	setFailures(1)
+	if _, err := c.Create(ctx, users.User{Username: "bob"}); err == nil || calls != 1 {
+		t.Errorf("Create after failure = %v with %d calls, want error with one call", err, calls)
+	}
+}
+
+// unknownID - id of user who is deleted
+func unknownID(t *testing.T, c *users.Client) string {
+	t.Helper()
+
+	id := create(t, c, "deleted")
+	if err := c.Delete(context.Background(), id); err != nil {
+		t.Fatalf("Delete(%s) error: %v", id, err)
+	}
+	return id
+}
+
+// create - create user through client and return his id
+func create(t *testing.T, c *users.Client, username string) string {
+	t.Helper()
+
+	u, err := c.Create(context.Background(), users.User{Username: username})
+	if err != nil {
+		t.Fatalf("Create(%s) error: %v", username, err)
+	}
+	return u.ID
+}
+
+// befriend - make users friends through friend request which second user accepts
+func befriend(t *testing.T, c *users.Client, firstID string, secondID string) {
+	t.Helper()
+
+	ctx := context.Background()
+	request, err := c.SendFriendRequest(ctx, firstID, secondID)
+	if err != nil {
+		t.Fatalf("SendFriendRequest(%s, %s) error: %v", firstID, secondID, err)
+	}
+	if _, err = c.AcceptFriendRequest(ctx, secondID, request.ID); err != nil {
+		t.Fatalf("AcceptFriendRequest(%s, %s) error: %v", secondID, request.ID, err)
+	}
+}
+
+// createUser - create user through service of handler
+func createUser(t *testing.T, h *user.Handler, username string) string {
+	t.Helper()
+
+	id, err := h.UserService.Create(context.Background(), user.User{Username: username})
+	if err != nil {
+		t.Fatalf("Create(%s) error: %v", username, err)
+	}
+	return id
+}
+
+// token - access token of subject with roles signed by auth
+func token(t *testing.T, auth *middleware.JWTAuth, subject string, roles ...string) string {
+	t.Helper()
+
+	signed, _, err := auth.Sign(middleware.Principal{Subject: subject, Roles: roles})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
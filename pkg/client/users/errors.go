@@ -0,0 +1,129 @@
+package users
+
+// file for errors of API, they have the same messages as errors of server and are checked with errors.Is
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrValidation - request or its body is malformed or fails validation, fields are in Error.Field and Error.Details
+	ErrValidation = errors.New("validation failed")
+
+	// ErrNotFound - user with such id doesn't exist
+	ErrNotFound = errors.New("user not found")
+
+	// ErrInvalidID - id has wrong format for storage
+	ErrInvalidID = errors.New("invalid user id")
+
+	// ErrAlreadyFriends - users are friends already
+	ErrAlreadyFriends = errors.New("users are already friends")
+
+	// ErrNotFriends - users are not friends in any direction
+	ErrNotFriends = errors.New("users are not friends")
+
+	// ErrNotDeleted - user can't be restored, because he is not deleted
+	ErrNotDeleted = errors.New("user is not deleted")
+
+	// ErrNotConnected - there is no path of friendships between users
+	ErrNotConnected = errors.New("users are not connected")
+
+	// ErrRequestNotFound - friend request with such id doesn't exist or doesn't belong to user
+	ErrRequestNotFound = errors.New("friend request not found")
+
+	// ErrRequestExists - pending friend request between users exists in any direction
+	ErrRequestExists = errors.New("friend request already exists")
+
+	// ErrRequestState - friend request can't get new status from its current status
+	ErrRequestState = errors.New("friend request is not pending")
+
+	// ErrRequestForbidden - user can't change friend request in such way, only recipient accepts and declines, only sender cancels
+	ErrRequestForbidden = errors.New("friend request can't be changed by user")
+
+	// ErrBlocked - one of users blocked another, so they can't become friends
+	ErrBlocked = errors.New("user is blocked")
+
+	// ErrRelationNotFound - user has no block or mute of such kind for target
+	ErrRelationNotFound = errors.New("relation not found")
+
+	// ErrUnauthorized - request has no bearer token or API key, or they are invalid, expired or revoked
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrForbidden - caller can't change other users or call admin routes, or API key has no scope of route
+	ErrForbidden = errors.New("operation is not allowed for caller")
+
+	// ErrEmailExists - email is registered by another user
+	ErrEmailExists = errors.New("email is already registered")
+
+	// ErrInvalidCredentials - there is no user with such email and password
+	ErrInvalidCredentials = errors.New("invalid email or password")
+
+	// ErrInvalidRefreshToken - refresh token is unknown, expired or revoked, user must log in again
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+	// ErrAPIKeyNotFound - API key with such id doesn't exist
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	// ErrInternal - server failed, details are only in its logs
+	ErrInternal = errors.New("internal server error")
+)
+
+// codeUnknown - code of answers without envelope, for example from proxy
+const codeUnknown = "unknown"
+
+// codeErrors - errors of codes of API error
+var codeErrors = map[string]error{
+	"validation_failed":     ErrValidation,
+	"validation_error":      ErrValidation,
+	"invalid_id":            ErrInvalidID,
+	"not_found":             ErrNotFound,
+	"already_friends":       ErrAlreadyFriends,
+	"not_friends":           ErrNotFriends,
+	"not_deleted":           ErrNotDeleted,
+	"not_connected":         ErrNotConnected,
+	"request_not_found":     ErrRequestNotFound,
+	"request_exists":        ErrRequestExists,
+	"request_not_pending":   ErrRequestState,
+	"request_forbidden":     ErrRequestForbidden,
+	"blocked":               ErrBlocked,
+	"relation_not_found":    ErrRelationNotFound,
+	"unauthorized":          ErrUnauthorized,
+	"invalid_token":         ErrUnauthorized,
+	"invalid_api_key":       ErrUnauthorized,
+	"forbidden":             ErrForbidden,
+	"insufficient_scope":    ErrForbidden,
+	"api_key_not_found":     ErrAPIKeyNotFound,
+	"email_exists":          ErrEmailExists,
+	"invalid_credentials":   ErrInvalidCredentials,
+	"invalid_refresh_token": ErrInvalidRefreshToken,
+	"internal_error":        ErrInternal,
+}
+
+// FieldError - failed validation rule of one field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error - error answered by API, errors.Is matches it with error of its code
+type Error struct {
+	Status  int          `json:"-"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Field   string       `json:"field,omitempty"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// Error - text of error with status and code
+func (e *Error) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("users api: %d %s: %s: %s", e.Status, e.Code, e.Field, e.Message)
+	}
+	return fmt.Sprintf("users api: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// Unwrap - error of code, nil for unknown codes
+func (e *Error) Unwrap() error {
+	return codeErrors[e.Code]
+}
@@ -0,0 +1,128 @@
+package users
+
+// file for data of API
+
+import (
+	"time"
+)
+
+// statuses of friend request
+const (
+	RequestPending  = "pending"
+	RequestAccepted = "accepted"
+	RequestDeclined = "declined"
+	RequestCanceled = "canceled"
+)
+
+// directions of list of friend requests
+const (
+	RequestsIncoming = "incoming"
+	RequestsOutgoing = "outgoing"
+)
+
+// kinds of relation from one user to another
+const (
+	RelationBlock = "block"
+	RelationMute  = "mute"
+)
+
+// fields of users order
+const (
+	SortByID       = "id"
+	SortByUsername = "username"
+	SortByAge      = "age"
+)
+
+// User - user with ids of friends, Age is numeric string from 0 to 150 or empty
+type User struct {
+	ID       string   `json:"id,omitempty"`
+	Username string   `json:"username"`
+	Age      string   `json:"age"`
+	Friends  []string `json:"friends,omitempty"`
+}
+
+// Friend - friend of user resolved by id
+type Friend struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// Suggestion - friend of friends of user with number of mutual friends
+type Suggestion struct {
+	Friend
+	MutualCount int `json:"mutual_count"`
+}
+
+// FriendRequest - request of friendship from one user to another
+type FriendRequest struct {
+	ID        string    `json:"id"`
+	FromID    string    `json:"from_id"`
+	ToID      string    `json:"to_id"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Relation - block or mute of target by user
+type Relation struct {
+	UserID    string    `json:"user_id"`
+	TargetID  string    `json:"target_id"`
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Registration - new user with email and password of login
+type Registration struct {
+	Username string `json:"username"`
+	Age      string `json:"age,omitempty"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Tokens - access token for WithToken and single use refresh token of session
+type Tokens struct {
+	UserID           string    `json:"user_id"`
+	AccessToken      string    `json:"access_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresIn        int64     `json:"expires_in"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// scopes of API keys, admin includes users:write and users:write includes users:read, only admin changes existing users
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+	ScopeAdmin      = "admin"
+)
+
+// APIKey - API key of service, Key is its value which is answered only by IssueAPIKey
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Key        string     `json:"key,omitempty"`
+}
+
+// Filter - filter, order and page of list of users, zero fields are not sent
+type Filter struct {
+	UsernamePrefix string
+	MinAge         *int
+	MaxAge         *int
+	SortBy         string
+	Desc           bool
+	Limit          int
+	Offset         int
+}
+
+// Page - page of users with total number of users matching filter
+type Page struct {
+	Users  []User
+	Total  int64
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,174 @@
+// Package validator - declarative validation of structs by `validate` tags
+//
+// Rules are separated by comma, fields are named in errors by their json tags:
+//
+//	required      - value is not zero (not empty string)
+//	omitempty     - other rules are skipped for zero value
+//	min=N, max=N  - length of string in characters, value of integer
+//	numeric       - string is an integer
+//	gte=N, lte=N  - numeric string or integer is in range
+//	nefield=Name  - value is not equal to value of other field of struct
+//	email         - string is a bare email address without display name
+package validator
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError - failed rule of one field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors - all failed fields of struct, only the first failed rule of field is reported
+type Errors []FieldError
+
+// Error - text of all failed fields
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		messages = append(messages, fmt.Sprintf("%s: %s", fieldErr.Field, fieldErr.Message))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate - check fields of struct or pointer to struct by their tags, returns Errors or nil
+func Validate(v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validator: %T is not a struct", v))
+	}
+
+	var errs Errors
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		if message := check(value, value.Field(i), tag); message != "" {
+			errs = append(errs, FieldError{Field: fieldName(field), Message: message})
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// check - apply rules of tag to field, returns message of the first failed rule
+func check(parent, field reflect.Value, tag string) string {
+	for _, rule := range strings.Split(tag, ",") {
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+
+		switch name {
+		case "required":
+			if field.IsZero() {
+				return "is required"
+			}
+		case "omitempty":
+			if field.IsZero() {
+				return ""
+			}
+		case "min":
+			if size(field) < mustInt(param) {
+				return fmt.Sprintf("must be at least %s%s", param, unit(field))
+			}
+		case "max":
+			if size(field) > mustInt(param) {
+				return fmt.Sprintf("must be at most %s%s", param, unit(field))
+			}
+		case "numeric":
+			if _, ok := number(field); !ok {
+				return "must be an integer"
+			}
+		case "gte":
+			if n, ok := number(field); ok && n < mustInt(param) {
+				return fmt.Sprintf("must be greater than or equal to %s", param)
+			}
+		case "lte":
+			if n, ok := number(field); ok && n > mustInt(param) {
+				return fmt.Sprintf("must be less than or equal to %s", param)
+			}
+		case "nefield":
+			other, ok := parent.Type().FieldByName(param)
+			if !ok {
+				panic(fmt.Sprintf("validator: unknown field %s in nefield", param))
+			}
+			if field.Interface() == parent.FieldByIndex(other.Index).Interface() {
+				return fmt.Sprintf("must be different from %s", fieldName(other))
+			}
+		case "email":
+			if !email(field.String()) {
+				return "must be an email address"
+			}
+		default:
+			panic(fmt.Sprintf("validator: unknown rule %s", name))
+		}
+	}
+	return ""
+}
+
+// size - length of string in characters or value of integer
+func size(field reflect.Value) int64 {
+	if field.Kind() == reflect.String {
+		return int64(utf8.RuneCountInString(field.String()))
+	}
+	n, _ := number(field)
+	return n
+}
+
+// unit - unit of size for messages
+func unit(field reflect.Value) string {
+	if field.Kind() == reflect.String {
+		return " characters"
+	}
+	return ""
+}
+
+// number - integer field or string field with integer
+func number(field reflect.Value) (int64, bool) {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int(), true
+	case reflect.String:
+		n, err := strconv.ParseInt(field.String(), 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// email - check that value is an address like user@example.com, "Name <user@example.com>" is rejected
+func email(value string) bool {
+	address, err := mail.ParseAddress(value)
+	return err == nil && address.Address == value
+}
+
+// mustInt - parameter of rule, wrong tags are errors of programmer
+func mustInt(param string) int64 {
+	n, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validator: rule parameter %q is not an integer", param))
+	}
+	return n
+}
+
+// fieldName - name of field from json tag, so client sees names from request
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
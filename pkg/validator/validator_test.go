@@ -0,0 +1,110 @@
+package validator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestValidate - rules of tags are checked in their order, only the first failed rule of field is reported
+func TestValidate(t *testing.T) {
+	type names struct {
+		Name  string `json:"name" validate:"required,min=2,max=4"`
+		Other string `json:"other" validate:"omitempty,nefield=Name"`
+	}
+	type ordered struct {
+		Before string `json:"before" validate:"min=2,omitempty"`
+		After  string `json:"after" validate:"omitempty,min=2"`
+	}
+	type age struct {
+		Age string `json:"age" validate:"omitempty,numeric,gte=0,lte=150"`
+	}
+	type contact struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	type counters struct {
+		First  int `validate:"nefield=Second"`
+		Second int
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  Errors
+	}{
+		{"valid", names{Name: "bob"}, nil},
+		{"pointer", &names{Name: "bob"}, nil},
+		{"required", names{}, Errors{{Field: "name", Message: "is required"}}},
+		{"min in characters", names{Name: "ж"}, Errors{{Field: "name", Message: "must be at least 2 characters"}}},
+		{"max in characters", names{Name: "жжжж"}, nil},
+		{"max of bytes is not checked", names{Name: "日本語"}, nil},
+		{"max", names{Name: "alice"}, Errors{{Field: "name", Message: "must be at most 4 characters"}}},
+		{"nefield of strings", names{Name: "bob", Other: "bob"}, Errors{{Field: "other", Message: "must be different from name"}}},
+		{"nefield of other string", names{Name: "bob", Other: "eve"}, nil},
+		{"nefield of integers", counters{First: 1, Second: 1}, Errors{{Field: "First", Message: "must be different from Second"}}},
+		{"nefield of zero integers", counters{}, Errors{{Field: "First", Message: "must be different from Second"}}},
+		{"rules before omitempty are checked", ordered{}, Errors{{Field: "before", Message: "must be at least 2 characters"}}},
+		{"rules after omitempty are checked for value", ordered{Before: "ab", After: "a"}, Errors{{Field: "after", Message: "must be at least 2 characters"}}},
+		{"empty age", age{}, nil},
+		{"numeric age", age{Age: "30"}, nil},
+		{"not numeric age", age{Age: "old"}, Errors{{Field: "age", Message: "must be an integer"}}},
+		{"negative age", age{Age: "-1"}, Errors{{Field: "age", Message: "must be greater than or equal to 0"}}},
+		{"too old", age{Age: "151"}, Errors{{Field: "age", Message: "must be less than or equal to 150"}}},
+		{"email", contact{Email: "alice@example.com"}, nil},
+		{"email with display name", contact{Email: "Alice <alice@example.com>"}, Errors{{Field: "email", Message: "must be an email address"}}},
+		{"email in angle brackets", contact{Email: "<alice@example.com>"}, Errors{{Field: "email", Message: "must be an email address"}}},
+		{"not email", contact{Email: "alice"}, Errors{{Field: "email", Message: "must be an email address"}}},
+		{"all failed fields", names{Name: "alice", Other: "alice"}, Errors{
+			{Field: "name", Message: "must be at most 4 characters"},
+			{Field: "other", Message: "must be different from name"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.value)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate(%+v) error = %v, want nil", tt.value, err)
+				}
+				return
+			}
+			var errs Errors
+			if !errors.As(err, &errs) || !reflect.DeepEqual(errs, tt.want) {
+				t.Errorf("Validate(%+v) error = %#v, want %#v", tt.value, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidatePanics - wrong tags and values are errors of programmer, they panic
+func TestValidatePanics(t *testing.T) {
+	type unknownRule struct {
+		Name string `validate:"required,uuid"`
+	}
+	type unknownField struct {
+		Name string `validate:"nefield=Missing"`
+	}
+	type badParam struct {
+		Name string `validate:"min=two"`
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"unknown rule", unknownRule{Name: "bob"}},
+		{"unknown field of nefield", unknownField{Name: "bob"}},
+		{"parameter is not integer", badParam{Name: "bob"}},
+		{"not a struct", "bob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Validate(%+v) didn't panic", tt.value)
+				}
+			}()
+			_ = Validate(tt.value)
+		})
+	}
+}